@@ -1,6 +1,9 @@
 package config
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestLoad(t *testing.T) {
 	c, err := load("../config.yaml")
@@ -12,6 +15,10 @@ func TestLoad(t *testing.T) {
 		t.Fatal("port not parsed correctly")
 	}
 
+	if c.AccessTokenDuration != 15*time.Minute || c.RefreshTokenDuration != 720*time.Hour {
+		t.Fatal("token durations not parsed correctly")
+	}
+
 	want := "./web/statics"
 	if c.FrontendDir != want {
 		t.Fatal("static dir not parsed correctly, want: " + want + " got: " + c.FrontendDir)
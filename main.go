@@ -5,6 +5,8 @@ import (
 	"github.com/Project-Planner/backend/web"
 	"github.com/Project-Planner/backend/xmldb"
 	"log"
+	// the docker image has no time zone database, which the time zones of the profiles need
+	_ "time/tzdata"
 )
 
 // main is the entry point of the application and basically an "Avengers assemble!"
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AgendaRange is the length of the time window of an agenda
+type AgendaRange string
+
+const (
+	AgendaDay   AgendaRange = "day"
+	AgendaWeek  AgendaRange = "week"
+	AgendaMonth AgendaRange = "month"
+)
+
+// Agenda is a chronological timeline of the items of several calendars
+type Agenda struct {
+	XMLName xml.Name      `xml:"agenda" json:"-"`
+	Range   AgendaRange   `xml:"range,attr" json:"range"`
+	From    string        `xml:"from,attr" json:"from"`
+	To      string        `xml:"to,attr" json:"to"`
+	Entries []AgendaEntry `xml:"entry" json:"entries"`
+}
+
+// AgendaEntry is an item of an agenda, annotated with the calendar it stems from and the permission the viewing
+// user has for that calendar.
+type AgendaEntry struct {
+	Kind      ItemKind `xml:"kind,attr" json:"kind"`
+	ID        string   `xml:"id,attr" json:"id"`
+	Calendar  string   `xml:"calendar,attr" json:"calendar"`
+	Perm      string   `xml:"perm,attr" json:"perm"`
+	Name      string   `xml:"name" json:"name"`
+	StartDate string   `xml:"startDate" json:"startDate"`
+	StartTime string   `xml:"startTime" json:"startTime"`
+	EndDate   string   `xml:"endDate" json:"endDate"`
+	EndTime   string   `xml:"endTime" json:"endTime"`
+	Desc      string   `xml:"desc" json:"desc"`
+}
+
+// NewAgenda returns an empty agenda of range rng containing the given day. Weeks start at weekStart.
+func NewAgenda(rng AgendaRange, day time.Time, weekStart time.Weekday) (Agenda, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	var from, to time.Time
+	switch rng {
+	case AgendaDay:
+		from, to = day, day
+	case AgendaWeek:
+		from = day.AddDate(0, 0, -((int(day.Weekday()) - int(weekStart) + 7) % 7))
+		to = from.AddDate(0, 0, 6)
+	case AgendaMonth:
+		from = day.AddDate(0, 0, 1-day.Day())
+		to = from.AddDate(0, 1, -1)
+	default:
+		return Agenda{}, ErrBadQuery
+	}
+
+	return Agenda{Range: rng, From: from.Format(DateLayout), To: to.Format(DateLayout)}, nil
+}
+
+// Window returns the first and last day of the agenda.
+func (a Agenda) Window() (time.Time, time.Time) {
+	from, _ := ParseDate(a.From)
+	to, _ := ParseDate(a.To)
+	return from, to
+}
+
+// Add appends all items of c as entries, annotated with the calendar and the permission perm.
+func (a *Agenda) Add(c Calendar, perm Permission) {
+	e := AgendaEntry{Calendar: c.GetID(), Perm: perm.String()}
+
+	for _, v := range c.Items.Appointments.Appointment {
+		e.Kind, e.ID, e.Name, e.Desc = KindAppointment, v.ID, v.Name.Val, v.Desc
+		e.StartDate, e.StartTime, e.EndDate, e.EndTime = v.StartDate.Val, v.StartTime.Val, v.EndDate.Val, v.EndTime.Val
+		a.Entries = append(a.Entries, e)
+	}
+
+	for _, v := range c.Items.Milestones.Milestone {
+		e.Kind, e.ID, e.Name, e.Desc = KindMilestone, v.ID, v.Name.Val, v.Desc
+		e.StartDate, e.StartTime, e.EndDate, e.EndTime = v.Duedate.Val, v.Duetime.Val, v.Duedate.Val, v.Duetime.Val
+		a.Entries = append(a.Entries, e)
+	}
+
+	for _, v := range c.Items.Tasks.Task {
+		e.Kind, e.ID, e.Name, e.Desc = KindTask, v.ID, v.Name.Val, v.Desc
+		e.StartDate, e.StartTime, e.EndDate, e.EndTime = v.StartDate.Val, v.StartTime.Val, v.Duedate.Val, v.Duetime.Val
+		if e.StartDate == "" {
+			e.StartDate, e.StartTime = e.EndDate, e.EndTime
+		}
+		a.Entries = append(a.Entries, e)
+	}
+}
+
+// Sort sorts the entries chronologically by their start. Entries without a valid start date come last.
+func (a *Agenda) Sort() {
+	start := func(e AgendaEntry) time.Time {
+		d, err := ParseDate(e.StartDate)
+		if err != nil {
+			return time.Time{}
+		}
+		if t, err := time.Parse("15:04", strings.TrimSpace(e.StartTime)); err == nil {
+			d = d.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+		}
+		return d
+	}
+
+	sort.SliceStable(a.Entries, func(i, j int) bool {
+		si, sj := start(a.Entries[i]), start(a.Entries[j])
+		if si.IsZero() || sj.IsZero() {
+			return !si.IsZero() && sj.IsZero()
+		}
+		return si.Before(sj)
+	})
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAgenda(t *testing.T) {
+	// a Wednesday
+	day := time.Date(2021, 6, 16, 13, 37, 0, 0, time.Local)
+
+	tt := []struct {
+		rng       AgendaRange
+		weekStart time.Weekday
+		from, to  string
+		err       error
+	}{
+		{rng: AgendaDay, from: "16.06.2021", to: "16.06.2021"},
+		{rng: AgendaWeek, weekStart: time.Monday, from: "14.06.2021", to: "20.06.2021"},
+		{rng: AgendaWeek, weekStart: time.Sunday, from: "13.06.2021", to: "19.06.2021"},
+		{rng: AgendaWeek, weekStart: time.Thursday, from: "10.06.2021", to: "16.06.2021"},
+		{rng: AgendaMonth, from: "01.06.2021", to: "30.06.2021"},
+		{rng: "year", err: ErrBadQuery},
+	}
+
+	for _, tc := range tt {
+		a, err := NewAgenda(tc.rng, day, tc.weekStart)
+		if err != tc.err {
+			t.Errorf("%v: got error %v want %v", tc.rng, err, tc.err)
+			continue
+		}
+		if a.From != tc.from || a.To != tc.to {
+			t.Errorf("%v starting %v: got %s - %s want %s - %s", tc.rng, tc.weekStart, a.From, a.To, tc.from, tc.to)
+		}
+	}
+}
+
+func TestAgendaSort(t *testing.T) {
+	var c Calendar
+	c.ID.Val = "lambda/work"
+	c.Items.Appointments.Appointment = []Appointment{
+		{ID: "late", StartDate: Attribute{Val: "16.06.2021"}, StartTime: Attribute{Val: "18:00"}},
+		{ID: "early", StartDate: Attribute{Val: "16.06.2021"}, StartTime: Attribute{Val: "08:00"}},
+	}
+	c.Items.Milestones.Milestone = []Milestone{{ID: "undated"}, {ID: "first", Duedate: Attribute{Val: "1.6.2021"}}}
+	c.Items.Tasks.Task = []Task{{ID: "due", Duedate: Attribute{Val: "17.06.2021"}}}
+
+	var a Agenda
+	a.Add(c, Edit)
+	a.Sort()
+
+	want := []string{"first", "early", "late", "due", "undated"}
+	for i, e := range a.Entries {
+		if e.ID != want[i] {
+			t.Fatalf("entry %d: got %s want %s", i, e.ID, want[i])
+		}
+		if e.Calendar != c.ID.Val || e.Perm != Edit.String() {
+			t.Fatalf("entry %s not annotated: %+v", e.ID, e)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// CalendarAlias redirects the former ID of a renamed calendar to its current one for a grace period, so that
+// bookmarks and feed URLs don't break
+type CalendarAlias struct {
+	XMLName xml.Name `xml:"alias" json:"-"`
+	// From is the former ID of the calendar
+	From string `xml:"from,attr" json:"from"`
+	// To is the current ID of the calendar
+	To      string    `xml:"to,attr" json:"to"`
+	Expires time.Time `xml:"expires,attr" json:"expires"`
+}
+
+// Expired returns whether the grace period of the alias is over at time now
+func (a CalendarAlias) Expired(now time.Time) bool {
+	return now.After(a.Expires)
+}
+
+func (a CalendarAlias) String() string {
+	var parsed, _ = xml.MarshalIndent(a, "", "\t")
+	return string(parsed)
+}
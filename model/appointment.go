@@ -16,6 +16,8 @@ type Appointment struct {
 	StartTime Attribute `xml:"startTime"`
 	EndTime   Attribute `xml:"endTime"`
 	EndDate   Attribute `xml:"endDate"`
+	Assignee  Attribute `xml:"assignee"`
+	Labels    Labels    `xml:"labels"`
 	Desc      string    `xml:"desc"`
 }
 
@@ -60,6 +62,18 @@ func NewAppointment(r *http.Request) (Appointment, error) {
 		a.EndTime = Attribute{Val: vs[0]}
 	}
 
+	if vs, ok := r.Form["assignee"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		a.Assignee = Attribute{Val: vs[0]}
+	}
+
+	if vs, ok := r.Form["labels"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		a.Labels = NewLabels(vs[0])
+	}
+
 	if vs, ok := r.Form["desc"]; !ok || len(vs) != 1 {
 		retErr = ErrReqFieldMissing
 	} else {
@@ -98,6 +112,14 @@ func (a *Appointment) Update(o Appointment) {
 		a.EndTime.Val = o.EndTime.Val
 	}
 
+	if o.Assignee.Val != "" {
+		a.Assignee.Val = o.Assignee.Val
+	}
+
+	if len(o.Labels.Label) > 0 {
+		a.Labels = o.Labels
+	}
+
 	if o.Desc != "" {
 		a.Desc = o.Desc
 	}
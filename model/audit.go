@@ -0,0 +1,215 @@
+package model
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditAction is the kind of change an audit entry records
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+	// AuditMove is recorded in both calendars an item is moved between
+	AuditMove AuditAction = "move"
+	// AuditShare records changed permissions of a calendar, also by changed members of a group it is shared with
+	AuditShare AuditAction = "share"
+	// AuditRename records a renamed calendar, AuditTransfer one handed over to another owner. Both are recorded in
+	// the log of the calendar under its new ID.
+	AuditRename   AuditAction = "rename"
+	AuditTransfer AuditAction = "transfer"
+	// AuditRestore records an item restored from a revision, AuditRollback a calendar rolled back to a prior time
+	AuditRestore  AuditAction = "restore"
+	AuditRollback AuditAction = "rollback"
+	// AuditDeleteUser is recorded in the calendars shared with a user whose account has been deleted
+	AuditDeleteUser AuditAction = "delete-user"
+)
+
+// AuditEntry records who changed a calendar or one of its items when, and how. Entries are never changed once they
+// have been recorded.
+type AuditEntry struct {
+	XMLName xml.Name  `xml:"entry" json:"-"`
+	Time    time.Time `xml:"time,attr" json:"time"`
+	// User is who made the change
+	User string `xml:"user,attr" json:"user"`
+	// Calendar is the ID of the calendar changed
+	Calendar string      `xml:"calendar,attr" json:"calendar"`
+	Action   AuditAction `xml:"action,attr" json:"action"`
+	// Kind and Item identify the item changed, they are empty if the calendar itself has been changed
+	Kind    ItemKind `xml:"kind,attr,omitempty" json:"kind,omitempty"`
+	Item    string   `xml:"item,attr,omitempty" json:"item,omitempty"`
+	Changes []Change `xml:"change" json:"changes"`
+}
+
+// AuditLog is a list of audit entries, e.g. of a calendar
+type AuditLog struct {
+	XMLName xml.Name     `xml:"audit" json:"-"`
+	Entry   []AuditEntry `xml:"entry" json:"entries"`
+}
+
+// DeletedAuditLog is the audit log of a calendar deleted for good, which is kept for its owner
+type DeletedAuditLog struct {
+	XMLName xml.Name `xml:"log" json:"-"`
+	// ID identifies the log among the deleted logs of the owner
+	ID string `xml:"id,attr" json:"id"`
+	// Calendar is the ID the calendar had
+	Calendar string    `xml:"calendar,attr" json:"calendar"`
+	Deleted  time.Time `xml:"deleted,attr" json:"deleted"`
+}
+
+// DeletedAuditLogs is a list of the deleted audit logs, e.g. of a user
+type DeletedAuditLogs struct {
+	XMLName xml.Name          `xml:"deletedLogs" json:"-"`
+	Log     []DeletedAuditLog `xml:"log" json:"logs"`
+}
+
+// Change is a field which has been changed from Before to After. Fields are named by the path of their XML
+// elements, e.g. permissions.view.user[0], values of created fields are empty before, those of deleted ones after.
+type Change struct {
+	Field  string `xml:"field,attr" json:"field"`
+	Before string `xml:"before,attr,omitempty" json:"before,omitempty"`
+	After  string `xml:"after,attr,omitempty" json:"after,omitempty"`
+}
+
+func (e AuditEntry) String() string {
+	var parsed, _ = xml.Marshal(e)
+	return string(parsed)
+}
+
+// AuditQuery filters the entries of an audit log. Empty fields don't filter.
+type AuditQuery struct {
+	User string
+	Item string
+	// From and To limit the time of the entries, including From but not To
+	From time.Time
+	To   time.Time
+}
+
+// NewAuditQuery parses the query from the URL query parameters user, item, from and to of the request. Times are
+// either RFC 3339 timestamps or dates (yyyy-mm-dd or dd.mm.yyyy), which include the whole day. Returns ErrBadQuery
+// if a parameter could not be understood.
+func NewAuditQuery(r *http.Request) (AuditQuery, error) {
+	v := r.URL.Query()
+
+	q := AuditQuery{User: strings.TrimSpace(v.Get("user")), Item: strings.TrimSpace(v.Get("item"))}
+	var err error
+
+	if s := v.Get("from"); s != "" {
+		if q.From, err = time.Parse(time.RFC3339, s); err != nil {
+			if q.From, err = parseQueryDate(s); err != nil {
+				return q, ErrBadQuery
+			}
+		}
+	}
+
+	if s := v.Get("to"); s != "" {
+		if q.To, err = time.Parse(time.RFC3339, s); err != nil {
+			if q.To, err = parseQueryDate(s); err != nil {
+				return q, ErrBadQuery
+			}
+			q.To = q.To.AddDate(0, 0, 1)
+		}
+	}
+
+	return q, nil
+}
+
+// Matches returns whether the entry passes the query
+func (q AuditQuery) Matches(e AuditEntry) bool {
+	if q.User != "" && q.User != e.User {
+		return false
+	}
+	if q.Item != "" && q.Item != e.Item {
+		return false
+	}
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.Time.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// Diff returns the fields changed from before to after, which are items or calendars, ordered by field. Either may be
+// nil, e.g. for created or deleted items.
+func Diff(before, after interface{}) []Change {
+	b, a := map[string]string{}, map[string]string{}
+	flatten("", reflect.ValueOf(before), b)
+	flatten("", reflect.ValueOf(after), a)
+
+	var res []Change
+	for field, v := range b {
+		if a[field] != v {
+			res = append(res, Change{Field: field, Before: v, After: a[field]})
+		}
+	}
+	for field, v := range a {
+		if _, ok := b[field]; !ok && v != "" {
+			res = append(res, Change{Field: field, After: v})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Field < res[j].Field })
+	return res
+}
+
+// Metadata returns the calendar without its items, e.g. to diff changes of the calendar itself
+func (c Calendar) Metadata() Calendar {
+	c.Items = Calendar{}.Items
+	return c
+}
+
+// flatten adds the fields of v to fields, named by the path of their XML elements below prefix. Attributes are
+// represented by their value, character data is skipped, as it is only whitespace.
+func flatten(prefix string, v reflect.Value, fields map[string]string) {
+	if !v.IsValid() {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if !v.IsNil() {
+			flatten(prefix, v.Elem(), fields)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), v.Index(i), fields)
+		}
+	case reflect.Struct:
+		switch val := v.Interface().(type) {
+		case Attribute:
+			fields[prefix] = val.Val
+			return
+		case time.Time:
+			fields[prefix] = val.Format(time.RFC3339)
+			return
+		}
+
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("xml"), ",")[0]
+			if f.PkgPath != "" || f.Type == reflect.TypeOf(xml.Name{}) || name == "-" ||
+				strings.Contains(f.Tag.Get("xml"), ",chardata") {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			flatten(name, v.Field(i), fields)
+		}
+	default:
+		fields[prefix] = fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	before := Task{ID: "t1", Name: Attribute{Val: "Write docs"}, Labels: NewLabels("docs"), Desc: "first draft"}
+	before.Subtasks.Subtask = []Subtask{{ID: "s1", Name: Attribute{Val: "Outline"}}}
+	after := before
+	after.Name.Val = "Write the docs"
+	after.Labels = NewLabels("docs, urgent")
+	after.Milestone.ID = "m1"
+
+	expected := []Change{
+		{Field: "labels.label[1]", After: "urgent"},
+		{Field: "milestone.id", After: "m1"},
+		{Field: "name", Before: "Write docs", After: "Write the docs"},
+	}
+	if d := Diff(before, after); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %v, got %v", expected, d)
+	}
+
+	if d := Diff(before, before); len(d) != 0 {
+		t.Errorf("expected no changes, got %v", d)
+	}
+
+	// deleted items have no fields after, created ones none before
+	d := Diff(before, nil)
+	if len(d) != 6 || d[0] != (Change{Field: "desc", Before: "first draft"}) {
+		t.Errorf("expected all fields of a deleted item, got %v", d)
+	}
+	if d := Diff(nil, after); len(d) != 8 || d[4] != (Change{Field: "milestone.id", After: "m1"}) {
+		t.Errorf("expected all fields of a created item, got %v", d)
+	}
+
+	// calendars are compared without items
+	var c Calendar
+	c.Items.Tasks.Task = []Task{before}
+	shared := c.Metadata()
+	shared.Permissions.View.User = []Attribute{{Val: "viewer"}}
+	expected = []Change{{Field: "permissions.view.user[0]", After: "viewer"}}
+	if d := Diff(c.Metadata(), shared); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %v, got %v", expected, d)
+	}
+}
+
+func TestAuditQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?user=someuser&from=2021-03-01&to=2021-03-02", nil)
+	q, err := NewAuditQuery(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	tt := []struct {
+		e     AuditEntry
+		match bool
+	}{
+		{e: AuditEntry{User: "someuser", Time: day}, match: true},
+		// the last day is included
+		{e: AuditEntry{User: "someuser", Time: day.Add(47 * time.Hour)}, match: true},
+		{e: AuditEntry{User: "someuser", Time: day.Add(48 * time.Hour)}, match: false},
+		{e: AuditEntry{User: "someuser", Time: day.Add(-time.Second)}, match: false},
+		{e: AuditEntry{User: "someoneelse", Time: day}, match: false},
+	}
+	for i, tc := range tt {
+		if q.Matches(tc.e) != tc.match {
+			t.Errorf("%d: expected match to be %v", i, tc.match)
+		}
+	}
+
+	r = httptest.NewRequest("GET", "/?item=t1&to=2021-03-01T12:00:00Z", nil)
+	if q, err = NewAuditQuery(r); err != nil {
+		t.Fatal(err)
+	}
+	if !q.Matches(AuditEntry{Item: "t1", Time: day}) || q.Matches(AuditEntry{Item: "t2", Time: day}) ||
+		q.Matches(AuditEntry{Item: "t1", Time: day.Add(12 * time.Hour)}) {
+		t.Errorf("expected only changes of t1 before noon to match")
+	}
+
+	if _, err := NewAuditQuery(httptest.NewRequest("GET", "/?from=yesterday", nil)); err != ErrBadQuery {
+		t.Errorf("expected ErrBadQuery, got %v", err)
+	}
+}
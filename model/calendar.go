@@ -7,7 +7,9 @@ import (
 )
 
 type Calendar struct {
-	XMLName     xml.Name  `xml:"calendar"`
+	XMLName xml.Name `xml:"calendar"`
+	// Template calendars are blueprints of projects, which new calendars are cloned from
+	Template    bool      `xml:"template,attr,omitempty"`
 	Text        string    `xml:",chardata"`
 	Name        Attribute `xml:"name"`
 	Owner       Attribute `xml:"owner"`
@@ -16,12 +18,14 @@ type Calendar struct {
 	Permissions struct {
 		Text string `xml:",chardata"`
 		View struct {
-			Text string      `xml:",chardata"`
-			User []Attribute `xml:"user"`
+			Text  string      `xml:",chardata"`
+			User  []Attribute `xml:"user"`
+			Group []Attribute `xml:"group"`
 		} `xml:"view"`
 		Edit struct {
-			Text string      `xml:",chardata"`
-			User []Attribute `xml:"user"`
+			Text  string      `xml:",chardata"`
+			User  []Attribute `xml:"user"`
+			Group []Attribute `xml:"group"`
 		} `xml:"edit"`
 	} `xml:"permissions"`
 	Items struct {
@@ -80,7 +84,8 @@ func NewCalendar(r *http.Request, owner string) (Calendar, error) {
 
 // Update all non initial fields of o in the receiver.
 func (c *Calendar) Update(o Calendar) {
-	// you may only update description, because name is part of the ID
+	// you may only update description, because name is part of the ID. Renaming re-keys the calendar, see
+	// Database.RenameCalendar
 	if o.Desc != "" {
 		c.Desc = o.Desc
 	}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"math"
+	"time"
+)
+
+// Clone returns a calendar with copies of the items of c, e.g. to start a project from a template. All items get
+// fresh IDs and tasks are linked to the copies of their milestones; links to milestones that don't exist are dropped.
+// If start is not zero, all dates are shifted by the same number of days, so that the project starts on start.
+// Name, owner and permissions are left for the caller to set.
+func (c Calendar) Clone(start time.Time) Calendar {
+	var res Calendar
+	res.Desc = c.Desc
+
+	days := 0
+	if first, ok := c.ProjectStart(); ok && !start.IsZero() {
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		days = int(math.Round(start.Sub(first).Hours() / 24))
+	}
+
+	milestones := map[string]string{}
+	for _, m := range c.Items.Milestones.Milestone {
+		old := m.ID
+		m.ID = newItemID()
+		milestones[old] = m.ID
+		m.Duedate = shiftDate(m.Duedate, days)
+		m.Labels.Label = append([]Attribute(nil), m.Labels.Label...)
+		res.Items.Milestones.Milestone = append(res.Items.Milestones.Milestone, m)
+	}
+
+	for _, a := range c.Items.Appointments.Appointment {
+		a.ID = newItemID()
+		a.StartDate = shiftDate(a.StartDate, days)
+		a.EndDate = shiftDate(a.EndDate, days)
+		a.Labels.Label = append([]Attribute(nil), a.Labels.Label...)
+		res.Items.Appointments.Appointment = append(res.Items.Appointments.Appointment, a)
+	}
+
+	for _, t := range c.Items.Tasks.Task {
+		t.ID = newItemID()
+		t.Milestone.ID = milestones[t.Milestone.ID]
+		t.StartDate = shiftDate(t.StartDate, days)
+		t.Duedate = shiftDate(t.Duedate, days)
+		t.Labels.Label = append([]Attribute(nil), t.Labels.Label...)
+
+		subtasks := make([]Subtask, len(t.Subtasks.Subtask))
+		for i, s := range t.Subtasks.Subtask {
+			s.ID = newItemID()
+			s.StartDate = shiftDate(s.StartDate, days)
+			s.Duedate = shiftDate(s.Duedate, days)
+			subtasks[i] = s
+		}
+		t.Subtasks.Subtask = subtasks
+		res.Items.Tasks.Task = append(res.Items.Tasks.Task, t)
+	}
+
+	return res
+}
+
+// ProjectStart returns the first day any item of the calendar starts on, and false if no item has a valid date
+func (c Calendar) ProjectStart() (time.Time, bool) {
+	var first time.Time
+	found := false
+	add := func(start, _ time.Time, err error) {
+		if err == nil && (!found || start.Before(first)) {
+			first, found = start, true
+		}
+	}
+
+	for _, a := range c.Items.Appointments.Appointment {
+		add(a.Span())
+	}
+	for _, m := range c.Items.Milestones.Milestone {
+		add(m.Span())
+	}
+	for _, t := range c.Items.Tasks.Task {
+		add(t.Span())
+		for _, s := range t.Subtasks.Subtask {
+			add(span(s.StartDate.Val, s.Duedate.Val))
+		}
+	}
+	return first, found
+}
+
+// shiftDate returns the date of a moved by days. Invalid dates are returned as they are.
+func shiftDate(a Attribute, days int) Attribute {
+	d, err := ParseDate(a.Val)
+	if err != nil || days == 0 {
+		return a
+	}
+	a.Val = d.AddDate(0, 0, days).Format(DateLayout)
+	return a
+}
+
+// newItemID returns a fresh ID for an item
+func newItemID() string {
+	id, _ := uuid.NewRandom()
+	return id.String()
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarClone(t *testing.T) {
+	var c Calendar
+	c.Name.Val, c.Owner.Val, c.ID.Val, c.Desc = "template", "owner", "owner/template", "release plan"
+	c.Permissions.View.User = []Attribute{{Val: "viewer"}}
+	c.Items.Appointments.Appointment = []Appointment{
+		{ID: "a1", Name: Attribute{Val: "Kick-off"}, StartDate: Attribute{Val: "01.03.2021"},
+			EndDate: Attribute{Val: "02.03.2021"}, Labels: NewLabels("team")},
+		{ID: "a2", Name: Attribute{Val: "Undated"}},
+	}
+	c.Items.Milestones.Milestone = []Milestone{
+		{ID: "m1", Name: Attribute{Val: "Release"}, Duedate: Attribute{Val: "31.03.2021"}},
+	}
+	c.Items.Tasks.Task = []Task{
+		{ID: "t1", Name: Attribute{Val: "Write docs"}, StartDate: Attribute{Val: "15.03.2021"},
+			Duedate: Attribute{Val: "20.03.2021"}},
+		{ID: "t2", Name: Attribute{Val: "Dangling"}, Duedate: Attribute{Val: "25.03.2021"}},
+	}
+	c.Items.Tasks.Task[0].Milestone.ID = "m1"
+	c.Items.Tasks.Task[1].Milestone.ID = "gone"
+	c.Items.Tasks.Task[0].Subtasks.Subtask = []Subtask{
+		{ID: "s1", Name: Attribute{Val: "Outline"}, Duedate: Attribute{Val: "16.03.2021"}},
+	}
+
+	if start, ok := c.ProjectStart(); !ok || !start.Equal(time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected the project to start on 1.3.2021, got %v, %v", start, ok)
+	}
+	if _, ok := (Calendar{}).ProjectStart(); ok {
+		t.Error("expected an empty calendar to have no start")
+	}
+
+	// across the switch to daylight saving time, which must not change the number of days
+	clone := c.Clone(time.Date(2022, 3, 21, 15, 0, 0, 0, time.FixedZone("CET", 3600)))
+
+	if clone.Desc != c.Desc || clone.Name.Val != "" || clone.Owner.Val != "" ||
+		len(clone.Permissions.View.User) != 0 {
+		t.Errorf("expected only description and items to be cloned, got %v", clone)
+	}
+
+	ids := map[string]bool{"a1": true, "a2": true, "m1": true, "t1": true, "t2": true, "s1": true}
+	fresh := func(id string) {
+		if id == "" || ids[id] {
+			t.Errorf("expected a fresh ID, got %q", id)
+		}
+		ids[id] = true
+	}
+
+	as := clone.Items.Appointments.Appointment
+	if len(as) != 2 || as[0].StartDate.Val != "21.03.2022" || as[0].EndDate.Val != "22.03.2022" ||
+		as[1].StartDate.Val != "" || !as[0].Labels.Has("team") {
+		t.Errorf("appointments not cloned correctly: %v", as)
+	}
+	ms := clone.Items.Milestones.Milestone
+	if len(ms) != 1 || ms[0].Duedate.Val != "20.04.2022" {
+		t.Errorf("milestones not cloned correctly: %v", ms)
+	}
+	ts := clone.Items.Tasks.Task
+	if len(ts) != 2 || ts[0].StartDate.Val != "04.04.2022" || ts[0].Duedate.Val != "09.04.2022" ||
+		len(ts[0].Subtasks.Subtask) != 1 || ts[0].Subtasks.Subtask[0].Duedate.Val != "05.04.2022" {
+		t.Fatalf("tasks not cloned correctly: %v", ts)
+	}
+	for _, id := range []string{as[0].ID, as[1].ID, ms[0].ID, ts[0].ID, ts[1].ID, ts[0].Subtasks.Subtask[0].ID} {
+		fresh(id)
+	}
+
+	if ts[0].Milestone.ID != ms[0].ID {
+		t.Errorf("expected the task to be linked to the cloned milestone %s, got %s", ms[0].ID, ts[0].Milestone.ID)
+	}
+	if ts[1].Milestone.ID != "" {
+		t.Errorf("expected the dangling milestone link to be dropped, got %s", ts[1].Milestone.ID)
+	}
+
+	// the items of the original are untouched
+	clone.Items.Appointments.Appointment[0].Labels.Label[0].Val = "changed"
+	if c.Items.Tasks.Task[0].ID != "t1" || c.Items.Appointments.Appointment[0].Labels.Label[0].Val != "team" ||
+		c.Items.Tasks.Task[0].Subtasks.Subtask[0].ID != "s1" {
+		t.Error("cloning changed the original calendar")
+	}
+
+	if same := c.Clone(time.Time{}); same.Items.Milestones.Milestone[0].Duedate.Val != "31.03.2021" {
+		t.Errorf("expected dates to be kept without start, got %v", same.Items.Milestones.Milestone)
+	}
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // Database represents the interface for the web web to use for persistent storage.
 type Database interface {
 	// Here go all methods required by the web web
@@ -9,6 +11,34 @@ type Database interface {
 	// could not be found, or an error if something went wrong internally
 	GetLogin(userid string) (Login, error)
 
+	// SetLogin overwrites the login data of the user, e.g. to change the password. Returns model.ErrNotFound if the
+	// user could not be found
+	SetLogin(userid string, l Login) error
+
+	// UpdateLogin changes the login data of the user with update and stores it, atomically with all other updates of
+	// the login. If update returns an error, nothing is stored and the error is returned. Returns model.ErrNotFound if
+	// the user could not be found
+	UpdateLogin(userid string, update func(l *Login) error) error
+
+	// AddResetToken stores the password reset token. Only the latest few tokens of a user are kept, older ones are
+	// dropped.
+	AddResetToken(t ResetToken) error
+
+	// GetResetToken returns the unexpired reset token with the given hash without consuming it. Returns
+	// model.ErrNotFound if there is no such token or it has expired.
+	GetResetToken(hash string) (ResetToken, error)
+
+	// ConsumeResetToken returns the unexpired reset token with the given hash and deletes it along with all other
+	// reset tokens of its user, so that it can only be used once. Returns model.ErrNotFound if there is no such
+	// token or it has expired. DeleteUser deletes the reset tokens of the user as well.
+	ConsumeResetToken(hash string) (ResetToken, error)
+
+	// AddLockout records that an account or IP address has been locked out after too many failed attempts.
+	AddLockout(l Lockout) error
+
+	// GetLockouts returns all recorded lockouts, the most recent first.
+	GetLockouts() ([]Lockout, error)
+
 	// AddUser adds a user with the provided username and hashedPW to the persistence layer. It returns an error if
 	// the user ist not added. (Keep in mind to add a login to the auth file and to the user file).
 	AddUser(userid, hashedPW string) error
@@ -19,13 +49,29 @@ type Database interface {
 	// DeleteUser deletes the user AND LOGIN with the given ID. Returns model.ErrNotFound if user was not found
 	DeleteUser(userid string) error
 
+	// GetScheduledDeletions returns the IDs of the users whose accounts are due to be deleted until t, see
+	// Login.Deletion.
+	GetScheduledDeletions(t time.Time) ([]string, error)
+
 	// GetUser returns the user, model.ErrNotFound, or another internal server error
 	GetUser(userid string) (User, error)
 
+	// SetProfile overwrites the profile of the user, leaving the rest of the user untouched. Returns
+	// model.ErrNotFound if the user was not found.
+	SetProfile(userid string, p Profile) error
+
 	// GetCalendar returns the calendar for the specified user and calendar name. Return model.ErrNotFound if calendar
 	// not found.
 	GetCalendar(calendarid string) (Calendar, error)
 
+	// QueryCalendar returns the calendar for the specified calendar ID with only those items passing q. The items
+	// are sorted by their (due) end date, undated ones first. Return model.ErrNotFound if calendar not found.
+	QueryCalendar(calendarid string, q CalendarQuery) (Calendar, error)
+
+	// Search returns the calendars and items whose names or descriptions match q. Only the calendars listed in q
+	// are searched.
+	Search(q SearchQuery) (SearchResults, error)
+
 	// SetCalendar sets the given calendar to the given ID. This overrides any existing calendar or creates a new one.
 	// DO NOT forget to add the calendar to the user file
 	SetCalendar(calendarid string, c Calendar) error
@@ -34,6 +80,173 @@ type Database interface {
 	// DO NOT forget to remove the calendar from the user file
 	DeleteCalendar(calendarid string) error
 
+	// TransferCalendar hands the calendar with the given ID over to newOwner, named newName in the calendars of
+	// newOwner. References, shares, invitations and links move along, the previous owner keeps the permission keep.
+	// Returns model.ErrNotFound if calendar or newOwner was not found and model.ErrAlreadyExists if newOwner has a
+	// calendar named newName already. On error, nothing is changed.
+	TransferCalendar(calendarid, newOwner, newName string, keep Permission) error
+
+	// RenameCalendar renames the calendar with the given ID to newName. References, shares, invitations and links
+	// move along and the former ID redirects to the new one until redirectUntil. Returns model.ErrNotFound if
+	// calendar was not found and model.ErrAlreadyExists if the owner has a calendar named newName already. On error,
+	// nothing is changed.
+	RenameCalendar(calendarid, newName string, redirectUntil time.Time) error
+
+	// GetCalendarAlias returns the alias redirecting the former ID of a renamed calendar, or model.ErrNotFound if
+	// there is none or its grace period is over.
+	GetCalendarAlias(calendarid string) (CalendarAlias, error)
+
+	// MoveItem moves the item of the given kind and ID from calendar fromCalendar to calendar toCalendar, keeping its
+	// ID. Returns model.ErrNotFound if a calendar or the item was not found and model.ErrAlreadyExists if toCalendar
+	// has an item of this ID already. On error, neither calendar is changed.
+	MoveItem(fromCalendar, toCalendar string, kind ItemKind, itemid string) error
+
+	// CopyItem copies the item of the given kind and ID from calendar fromCalendar to calendar toCalendar and returns
+	// the ID of the copy. Returns model.ErrNotFound if a calendar or the item was not found. On error, toCalendar is
+	// not changed.
+	CopyItem(fromCalendar, toCalendar string, kind ItemKind, itemid string) (string, error)
+
+	// AddAuditEntry appends the entry to the audit log of its calendar. Entries are never changed afterwards. Once
+	// the calendar is deleted for good, its log is kept as a deleted log of its owner, until the owner is deleted.
+	// Returns model.ErrNotFound if calendar was not found.
+	AddAuditEntry(e AuditEntry) error
+
+	// GetAuditLog returns the entries of the audit log of the calendar with the given ID which pass the query, the
+	// most recent first. Returns model.ErrNotFound if calendar was not found.
+	GetAuditLog(calendarid string, q AuditQuery) ([]AuditEntry, error)
+
+	// GetDeletedAuditLogs returns the audit logs of the calendars of the user which have been deleted for good, the
+	// most recently deleted first.
+	GetDeletedAuditLogs(userid string) ([]DeletedAuditLog, error)
+
+	// GetDeletedAuditLog returns the entries of the deleted audit log of the user with the given ID which pass the
+	// query, the most recent first. Returns model.ErrNotFound if the user has no such log.
+	GetDeletedAuditLog(userid, logid string, q AuditQuery) ([]AuditEntry, error)
+
+	// GetRevisions returns the retained prior versions of the calendar with the given ID, the most recent first.
+	// Returns model.ErrNotFound if calendar was not found.
+	GetRevisions(calendarid string) ([]Revision, error)
+
+	// GetRevision returns the prior version of the calendar with the given ID and revision ID. Returns
+	// model.ErrNotFound if calendar or revision was not found, or the revision is not retained anymore.
+	GetRevision(calendarid, revisionid string) (Revision, error)
+
+	// RollbackCalendar restores the items and the description the calendar with the given ID had at time t, keeping
+	// the current version as a revision. Returns model.ErrNotFound if calendar was not found and model.ErrExpired if
+	// t lies beyond the retention of revisions.
+	RollbackCalendar(calendarid string, t time.Time) error
+
+	// TrashItem removes the item of the given kind and ID from the calendar and keeps it in the trash of the owner of
+	// the calendar, as deleted by userid. Returns model.ErrNotFound if calendar or item was not found.
+	TrashItem(calendarid string, kind ItemKind, itemid, userid string) error
+
+	// TrashCalendar deletes the calendar with the given ID like DeleteCalendar, but keeps it in the trash of its owner,
+	// as deleted by userid, along with its audit log and revisions. The deletion is recorded in the audit log.
+	// Returns model.ErrNotFound if calendar was not found.
+	TrashCalendar(calendarid, userid string) error
+
+	// GetTrash returns the unexpired entries of the trash of the user, the most recent first.
+	GetTrash(userid string) ([]TrashEntry, error)
+
+	// RestoreTrash restores the entry with the given ID from the trash of the user, removes it from the trash and
+	// returns it. Returns model.ErrNotFound if the entry was not found or has expired, or if the calendar of a deleted
+	// item was not found, and model.ErrAlreadyExists if the calendar has an item of the same ID or the user a calendar
+	// of the same name already.
+	RestoreTrash(userid, entryid string) (TrashEntry, error)
+
+	// PurgeTrash destroys the entries of all trashes which expired until t. DeleteUser destroys the trash of the user.
+	PurgeTrash(t time.Time) error
+
+	// AddSession stores the session of a newly issued authentication token.
+	AddSession(s Session) error
+
+	// SetSession overwrites the existing session with the same ID. Returns model.ErrNotFound if session not found.
+	SetSession(s Session) error
+
+	// GetSession returns the session with the given token ID, or model.ErrNotFound if it has been revoked, has
+	// expired or never existed.
+	GetSession(tokenid string) (Session, error)
+
+	// GetSessions returns all unexpired sessions of the user.
+	GetSessions(userid string) ([]Session, error)
+
+	// DeleteSession revokes the session with the given token ID. Returns model.ErrNotFound if session not found.
+	DeleteSession(tokenid string) error
+
+	// DeleteSessions revokes all sessions of the user, except the one with the token ID except, which may be empty.
+	// DeleteUser revokes all sessions of the user as well.
+	DeleteSessions(userid, except string) error
+
+	// PurgeSessions deletes all sessions which expired until t, as expired sessions are only rejected otherwise.
+	PurgeSessions(t time.Time) error
+
+	// AddAccessToken stores the personal access token.
+	AddAccessToken(t AccessToken) error
+
+	// GetAccessToken returns the personal access token with the given ID, or model.ErrNotFound if it has been
+	// revoked, has expired or never existed.
+	GetAccessToken(id string) (AccessToken, error)
+
+	// GetAccessTokens returns all unexpired personal access tokens of the user.
+	GetAccessTokens(userid string) ([]AccessToken, error)
+
+	// DeleteAccessToken revokes the personal access token with the given ID. Returns model.ErrNotFound if token not
+	// found. DeleteUser revokes all tokens of the user as well.
+	DeleteAccessToken(id string) error
+
+	// AddGroup stores the new group. Returns model.ErrAlreadyExists if a group with the same ID exists.
+	AddGroup(g Group) error
+
+	// GetGroup returns the group with the given ID, or model.ErrNotFound.
+	GetGroup(id string) (Group, error)
+
+	// GetGroups returns all groups the user is a member or an admin of.
+	GetGroups(userid string) ([]Group, error)
+
+	// SetGroup overwrites the existing group with the same ID. Returns model.ErrNotFound if group not found.
+	SetGroup(g Group) error
+
+	// DeleteGroup deletes the group with the given ID and revokes the permissions granted to it by calendars. Returns
+	// model.ErrNotFound if group not found. DeleteUser removes the user from all groups, deleting the ones left empty.
+	DeleteGroup(id string) error
+
+	// AddInvitation stores the invitation to a calendar. Returns model.ErrAlreadyExists if an invitation with the same
+	// ID exists.
+	AddInvitation(i Invitation) error
+
+	// GetInvitation returns the invitation with the given ID, or model.ErrNotFound if it has been accepted,
+	// declined, revoked, has expired or never existed.
+	GetInvitation(id string) (Invitation, error)
+
+	// GetInvitations returns all unexpired invitations sent to or by the user, the most recent first.
+	GetInvitations(userid string) ([]Invitation, error)
+
+	// DeleteInvitation deletes the invitation with the given ID. Returns model.ErrNotFound if invitation not found.
+	// DeleteUser deletes the invitations sent to or by the user, DeleteCalendar the ones to the calendar.
+	DeleteInvitation(id string) error
+
+	// AddShareLink stores the public share link of a calendar.
+	AddShareLink(l ShareLink) error
+
+	// GetShareLink returns the share link with the given ID, or model.ErrNotFound if it has been revoked, has expired
+	// or never existed.
+	GetShareLink(id string) (ShareLink, error)
+
+	// GetShareLinks returns all unexpired share links of the calendars of the user.
+	GetShareLinks(userid string) ([]ShareLink, error)
+
+	// CountShareLinkAccess increments the access counter of the share link with the given ID and records the time of
+	// the access. Returns model.ErrNotFound if link not found.
+	CountShareLinkAccess(id string, at time.Time) error
+
+	// DeleteShareLink revokes the share link with the given ID. Returns model.ErrNotFound if link not found.
+	// DeleteUser revokes the links of the user, DeleteCalendar the ones of the calendar.
+	DeleteShareLink(id string) error
+
+	// AssociateCalendar lists the calendar in the calendars of the user and grants the user the permission perm for
+	// it. Returns model.ErrAlreadyExists if the user has the calendar already.
+	AssociateCalendar(user User, cal Calendar, perm Permission) error
+
 	//AddCalendar creates a new calendar and appends it to the owner's
 	//collection of calendars.
 	AddCalendar(ownerID, calName string) error
@@ -9,4 +9,6 @@ var (
 	ErrReqFieldMissing = errors.New("error: required field for entity parsing is missing")
 	// ErrAlreadyExists should be returned when an item already exists.
 	ErrAlreadyExists = errors.New("error: item already exists")
+	// ErrExpired should be returned if an entity is asked for which is not retained anymore
+	ErrExpired = errors.New("error: entity not retained anymore")
 )
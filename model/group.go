@@ -0,0 +1,113 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// GroupRole is the role of a member of a group
+type GroupRole string
+
+const (
+	// RoleMember may see the calendars shared with the group
+	RoleMember GroupRole = "member"
+	// RoleAdmin may manage the members of the group and delete it. Admins are members as well.
+	RoleAdmin GroupRole = "admin"
+)
+
+// ParseGroupRole returns the role named s, or ErrReqFieldMissing if there is none
+func ParseGroupRole(s string) (GroupRole, error) {
+	switch r := GroupRole(s); r {
+	case RoleMember, RoleAdmin:
+		return r, nil
+	}
+	return "", ErrReqFieldMissing
+}
+
+// Group is a team calendars can be shared with. Its members get the permission granted to the group as long as they
+// are members.
+type Group struct {
+	XMLName xml.Name  `xml:"group" json:"-"`
+	ID      string    `xml:"id,attr" json:"id"`
+	Created time.Time `xml:"created,attr" json:"created"`
+	Admins  []string  `xml:"admin" json:"admins"`
+	Members []string  `xml:"member" json:"members"`
+	// Calendars are the calendars shared with the group, so that its members find them. The permission itself is
+	// granted by the calendar.
+	Calendars []CalendarReference `xml:"calendar" json:"calendars,omitempty"`
+}
+
+// Groups is a list of groups, e.g. of a user
+type Groups struct {
+	XMLName xml.Name `xml:"groups" json:"-"`
+	Group   []Group  `xml:"group" json:"groups"`
+}
+
+// NewGroup returns a group with the given ID, administrated by its creator
+func NewGroup(id, creator string) Group {
+	return Group{ID: id, Created: time.Now(), Admins: []string{creator}}
+}
+
+// IsMember returns whether the user is a member or an admin of the group
+func (g Group) IsMember(userID string) bool {
+	return contains(g.Members, userID) || g.IsAdmin(userID)
+}
+
+// IsAdmin returns whether the user is an admin of the group
+func (g Group) IsAdmin(userID string) bool {
+	return contains(g.Admins, userID)
+}
+
+// SetRole makes the user a member of the group with the given role
+func (g *Group) SetRole(userID string, r GroupRole) {
+	g.Remove(userID)
+	if r == RoleAdmin {
+		g.Admins = append(g.Admins, userID)
+	} else {
+		g.Members = append(g.Members, userID)
+	}
+}
+
+// Remove removes the user from the group
+func (g *Group) Remove(userID string) {
+	g.Admins = remove(g.Admins, userID)
+	g.Members = remove(g.Members, userID)
+}
+
+// ShareCalendar lists the calendar as shared with the group with permission perm, or unlists it, if perm is None
+func (g *Group) ShareCalendar(calendarID string, perm Permission) {
+	var refs []CalendarReference
+	for _, ref := range g.Calendars {
+		if ref.Link != calendarID {
+			refs = append(refs, ref)
+		}
+	}
+	if perm != None {
+		refs = append(refs, CalendarReference{Link: calendarID, Perm: perm.String()})
+	}
+	g.Calendars = refs
+}
+
+func (g Group) String() string {
+	var parsed, _ = xml.MarshalIndent(g, "", "\t")
+	return string(parsed)
+}
+
+func contains(vs []string, v string) bool {
+	for _, s := range vs {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(vs []string, v string) []string {
+	var res []string
+	for _, s := range vs {
+		if s != v {
+			res = append(res, s)
+		}
+	}
+	return res
+}
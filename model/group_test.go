@@ -0,0 +1,56 @@
+package model
+
+import "testing"
+
+func TestGroupRoles(t *testing.T) {
+	g := NewGroup("team", "alice")
+	g.SetRole("bob", RoleMember)
+	g.SetRole("carol", RoleAdmin)
+	if !g.IsMember("alice") || !g.IsAdmin("alice") || !g.IsMember("bob") || g.IsAdmin("bob") || !g.IsAdmin("carol") {
+		t.Fatalf("roles not set correctly: %+v", g)
+	}
+
+	// changing the role moves the user
+	g.SetRole("carol", RoleMember)
+	if g.IsAdmin("carol") || !g.IsMember("carol") || len(g.Members) != 2 {
+		t.Fatalf("role not changed correctly: %+v", g)
+	}
+
+	g.Remove("bob")
+	if g.IsMember("bob") {
+		t.Fatalf("member not removed: %+v", g)
+	}
+
+	if _, err := ParseGroupRole("owner"); err == nil {
+		t.Error("unknown role parsed")
+	}
+}
+
+func TestCalendarPermissionsWithGroups(t *testing.T) {
+	var c Calendar
+	c.Owner.Val = "alice"
+	c.Permissions.View.User = []Attribute{{Val: "bob"}}
+	c.Permissions.View.Group = []Attribute{{Val: "readers"}}
+	c.Permissions.Edit.Group = []Attribute{{Val: "editors"}}
+
+	tt := []struct {
+		user   string
+		groups []string
+		want   Permission
+	}{
+		{user: "alice", groups: []string{"readers"}, want: Owner},
+		{user: "bob", want: Read},
+		// the highest grant wins
+		{user: "bob", groups: []string{"editors"}, want: Edit},
+		{user: "carol", groups: []string{"readers"}, want: Read},
+		{user: "carol", groups: []string{"readers", "editors"}, want: Edit},
+		{user: "carol", groups: []string{"others"}, want: None},
+		{user: "carol", want: None},
+	}
+
+	for _, tc := range tt {
+		if got := CalendarPermissions(c, tc.user, tc.groups...); got != tc.want {
+			t.Errorf("%s in %v: got: %s want: %s", tc.user, tc.groups, got, tc.want)
+		}
+	}
+}
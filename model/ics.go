@@ -0,0 +1,149 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// icsDate and icsDateTime are the layouts of dates and of floating local times in iCalendar (RFC 5545). Items
+	// carry no time zone, so calendar clients show them in their own.
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+	// icsLineLength is the maximum length of a line in octets, longer ones are folded
+	icsLineLength = 75
+)
+
+// icsEscaper escapes the special characters of iCalendar text values
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// ICS returns the calendar in the iCalendar format, so that calendar clients can subscribe to it. Appointments and
+// milestones become events, tasks become to-dos. Items without a valid date are left out. now is the time the export
+// is created at.
+func (c Calendar) ICS(now time.Time) string {
+	var b icsBuilder
+	b.line("BEGIN", "VCALENDAR")
+	b.line("VERSION", "2.0")
+	b.line("PRODID", "-//Project-Planner//Plannet//EN")
+	b.line("CALSCALE", "GREGORIAN")
+	b.text("X-WR-CALNAME", c.Name.Val)
+	if desc := strings.TrimSpace(c.Desc); desc != "" {
+		b.text("X-WR-CALDESC", desc)
+	}
+
+	stamp := now.UTC().Format(icsDateTime) + "Z"
+	item := func(component, id, name, desc string, labels Labels) {
+		b.line("BEGIN", component)
+		b.line("UID", id+"@"+c.GetID())
+		b.line("DTSTAMP", stamp)
+		b.text("SUMMARY", name)
+		if desc = strings.TrimSpace(desc); desc != "" {
+			b.text("DESCRIPTION", desc)
+		}
+		if len(labels.Label) > 0 {
+			var ls []string
+			for _, l := range labels.Label {
+				ls = append(ls, icsEscaper.Replace(l.Val))
+			}
+			b.line("CATEGORIES", strings.Join(ls, ","))
+		}
+	}
+
+	for _, a := range c.Items.Appointments.Appointment {
+		start, end, err := a.Span()
+		if err != nil {
+			continue
+		}
+		item("VEVENT", a.ID, a.Name.Val, a.Desc, a.Labels)
+		st, sok := withClock(start, a.StartTime.Val)
+		et, eok := withClock(end, a.EndTime.Val)
+		if sok && eok && !et.Before(st) {
+			b.line("DTSTART", st.Format(icsDateTime))
+			b.line("DTEND", et.Format(icsDateTime))
+		} else {
+			// all-day events end exclusively
+			b.line("DTSTART;VALUE=DATE", start.Format(icsDate))
+			b.line("DTEND;VALUE=DATE", end.AddDate(0, 0, 1).Format(icsDate))
+		}
+		b.line("END", "VEVENT")
+	}
+
+	for _, m := range c.Items.Milestones.Milestone {
+		due, _, err := m.Span()
+		if err != nil {
+			continue
+		}
+		item("VEVENT", m.ID, m.Name.Val, m.Desc, m.Labels)
+		if dt, ok := withClock(due, m.Duetime.Val); ok {
+			b.line("DTSTART", dt.Format(icsDateTime))
+		} else {
+			b.line("DTSTART;VALUE=DATE", due.Format(icsDate))
+		}
+		b.line("END", "VEVENT")
+	}
+
+	for _, t := range c.Items.Tasks.Task {
+		due, err := ParseDate(t.Duedate.Val)
+		if err != nil {
+			continue
+		}
+		item("VTODO", t.ID, t.Name.Val, t.Desc, t.Labels)
+		dt, dok := withClock(due, t.Duetime.Val)
+		// start and due have to be of the same kind, either dates or times
+		if start, err := ParseDate(t.StartDate.Val); err == nil && !start.After(due) {
+			st, sok := withClock(start, t.StartTime.Val)
+			if sok && dok {
+				b.line("DTSTART", st.Format(icsDateTime))
+			} else {
+				b.line("DTSTART;VALUE=DATE", start.Format(icsDate))
+				dok = false
+			}
+		}
+		if dok {
+			b.line("DUE", dt.Format(icsDateTime))
+		} else {
+			b.line("DUE;VALUE=DATE", due.Format(icsDate))
+		}
+		b.line("END", "VTODO")
+	}
+
+	b.line("END", "VCALENDAR")
+	return b.String()
+}
+
+// withClock returns the day at the time of day clock (hh:mm), and whether clock is valid
+func withClock(day time.Time, clock string) (time.Time, bool) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return day, false
+	}
+	return day.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), true
+}
+
+// icsBuilder writes the content lines of an iCalendar object, folding long lines
+type icsBuilder struct {
+	strings.Builder
+}
+
+// text writes the property name with the escaped text value
+func (b *icsBuilder) text(name, value string) {
+	b.line(name, icsEscaper.Replace(value))
+}
+
+// line writes the property name with the value as it is. Lines longer than 75 octets are folded without splitting
+// characters.
+func (b *icsBuilder) line(name, value string) {
+	l, max := name+":"+value, icsLineLength
+	for len(l) > max {
+		n := max
+		for n > 0 && !utf8.RuneStart(l[n]) {
+			n--
+		}
+		b.WriteString(l[:n] + "\r\n ")
+		l = l[n:]
+		// continuation lines start with a space, which counts towards their length
+		max = icsLineLength - 1
+	}
+	b.WriteString(l + "\r\n")
+}
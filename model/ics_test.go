@@ -0,0 +1,78 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestCalendarICS(t *testing.T) {
+	var c Calendar
+	c.Name.Val, c.ID.Val = "project", "owner/project"
+	c.Items.Appointments.Appointment = []Appointment{
+		{ID: "a1", Name: Attribute{Val: "Kick-off; all, hands"}, StartDate: Attribute{Val: "01.03.2021"},
+			StartTime: Attribute{Val: "09:30"}, EndDate: Attribute{Val: "01.03.2021"}, EndTime: Attribute{Val: "11:00"},
+			Desc: "line one\nline two " + strings.Repeat("ä", 60), Labels: NewLabels("team, urgent")},
+		{ID: "a2", Name: Attribute{Val: "Offsite"}, StartDate: Attribute{Val: "02.03.2021"},
+			EndDate: Attribute{Val: "03.03.2021"}},
+		{ID: "a3", Name: Attribute{Val: "Undated"}},
+	}
+	c.Items.Milestones.Milestone = []Milestone{
+		{ID: "m1", Name: Attribute{Val: "Release"}, Duedate: Attribute{Val: "31.03.2021"}},
+	}
+	c.Items.Tasks.Task = []Task{
+		{ID: "t1", Name: Attribute{Val: "Write docs"}, StartDate: Attribute{Val: "15.03.2021"},
+			Duedate: Attribute{Val: "20.03.2021"}, Duetime: Attribute{Val: "17:00"}},
+	}
+
+	ics := c.ICS(time.Date(2021, 2, 1, 12, 0, 0, 0, time.UTC))
+	for _, s := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"UID:a1@owner/project\r\nDTSTAMP:20210201T120000Z\r\nSUMMARY:Kick-off\\; all\\, hands\r\n",
+		"DESCRIPTION:line one\\nline two ",
+		"CATEGORIES:team,urgent\r\nDTSTART:20210301T093000\r\nDTEND:20210301T110000\r\n",
+		// all-day events end exclusively
+		"DTSTART;VALUE=DATE:20210302\r\nDTEND;VALUE=DATE:20210304\r\n",
+		"SUMMARY:Release\r\nDTSTART;VALUE=DATE:20210331\r\nEND:VEVENT",
+		// start and due of to-dos are of the same kind
+		"BEGIN:VTODO\r\nUID:t1@owner/project",
+		"DTSTART;VALUE=DATE:20210315\r\nDUE;VALUE=DATE:20210320\r\nEND:VTODO",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, s) {
+			t.Errorf("%q missing in\n%s", s, ics)
+		}
+	}
+
+	if strings.Contains(ics, "Undated") {
+		t.Error("item without a date exported")
+	}
+
+	// long lines are folded without splitting characters
+	for _, l := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		if len(l) > 75 || !utf8.ValidString(l) {
+			t.Errorf("line not folded correctly: %q", l)
+		}
+	}
+}
+
+func TestShareLinkPublic(t *testing.T) {
+	var c Calendar
+	c.Permissions.View.User = []Attribute{{Val: "someone"}}
+	c.Items.Tasks.Task = []Task{{Desc: "secret"}}
+	c.Items.Tasks.Task[0].Subtasks.Subtask = []Subtask{{Desc: "secret"}}
+
+	p := ShareLink{}.Public(c)
+	if len(p.Permissions.View.User) != 0 || p.Items.Tasks.Task[0].Desc != "secret" {
+		t.Fatalf("calendar not made public correctly: %v", p)
+	}
+
+	p = ShareLink{HideDescriptions: true}.Public(c)
+	if p.Items.Tasks.Task[0].Desc != "" || p.Items.Tasks.Task[0].Subtasks.Subtask[0].Desc != "" {
+		t.Fatalf("descriptions not hidden: %v", p)
+	}
+	if c.Items.Tasks.Task[0].Desc != "secret" || c.Items.Tasks.Task[0].Subtasks.Subtask[0].Desc != "secret" {
+		t.Fatal("descriptions of the original calendar hidden")
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Invitation offers a user access to a calendar. The calendar is only shared with the invitee once the invitation is
+// accepted. Users may be invited before they register, the invitation waits for them until it expires.
+type Invitation struct {
+	XMLName xml.Name `xml:"invitation" json:"-"`
+	ID      string   `xml:"id,attr" json:"id"`
+	// Calendar is the ID of the calendar offered
+	Calendar string `xml:"calendar,attr" json:"calendar"`
+	// From is the owner of the calendar, who sent the invitation
+	From string `xml:"from,attr" json:"from"`
+	// To is the user invited
+	To string `xml:"to,attr" json:"to"`
+	// Perm is the permission offered, either view or edit
+	Perm    string    `xml:"perm,attr" json:"perm"`
+	Created time.Time `xml:"created,attr" json:"created"`
+	Expires time.Time `xml:"expires,attr" json:"expires"`
+}
+
+// Invitations lists the invitations a user has received and sent
+type Invitations struct {
+	XMLName  xml.Name     `xml:"invitations" json:"-"`
+	Received []Invitation `xml:"received>invitation" json:"received"`
+	Sent     []Invitation `xml:"sent>invitation" json:"sent"`
+}
+
+// Expired returns whether the invitation has expired at time now
+func (i Invitation) Expired(now time.Time) bool {
+	return now.After(i.Expires)
+}
+
+// Permission returns the permission offered, None if it is not understood
+func (i Invitation) Permission() Permission {
+	switch i.Perm {
+	case Read.String():
+		return Read
+	case Edit.String():
+		return Edit
+	}
+	return None
+}
+
+func (i Invitation) String() string {
+	var parsed, _ = xml.MarshalIndent(i, "", "\t")
+	return string(parsed)
+}
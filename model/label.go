@@ -0,0 +1,31 @@
+package model
+
+import "strings"
+
+// Labels of a calendar item, e.g. "backend" or "urgent"
+type Labels struct {
+	Text  string      `xml:",chardata"`
+	Label []Attribute `xml:"label"`
+}
+
+// NewLabels parses a comma separated list of labels. Empty labels are skipped
+func NewLabels(s string) Labels {
+	var l Labels
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			l.Label = append(l.Label, Attribute{Val: v})
+		}
+	}
+	return l
+}
+
+// Has returns whether the label with the given name is present, ignoring case
+func (l Labels) Has(name string) bool {
+	for _, v := range l.Label {
+		if strings.EqualFold(v.Val, name) {
+			return true
+		}
+	}
+	return false
+}
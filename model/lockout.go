@@ -0,0 +1,53 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// LockoutKind names what has been locked out
+type LockoutKind string
+
+const (
+	// LockoutAccount locks logins to an account after too many failed attempts
+	LockoutAccount LockoutKind = "account"
+	// LockoutIP locks logins from an IP address after too many failed attempts
+	LockoutIP LockoutKind = "ip"
+	// LockoutRegister locks registrations from an IP address after too many registrations
+	LockoutRegister LockoutKind = "register"
+	// LockoutReset locks password reset requests for an account after too many requests
+	LockoutReset LockoutKind = "reset"
+	// LockoutResetIP locks password reset requests from an IP address after too many requests
+	LockoutResetIP LockoutKind = "reset-ip"
+	// LockoutResetToken locks password resets from an IP address after too many invalid reset tokens
+	LockoutResetToken LockoutKind = "reset-token"
+)
+
+// Lockout records that an account or IP address has been locked out temporarily
+type Lockout struct {
+	XMLName xml.Name    `xml:"lockout" json:"-"`
+	Time    time.Time   `xml:"time,attr" json:"time"`
+	Kind    LockoutKind `xml:"kind,attr" json:"kind"`
+	// Subject is the username or IP address locked out
+	Subject string `xml:"subject,attr" json:"subject"`
+	// IP is the address of the attempt that triggered the lockout
+	IP       string    `xml:"ip,attr" json:"ip"`
+	Failures int       `xml:"failures,attr" json:"failures"`
+	Until    time.Time `xml:"until,attr" json:"until"`
+}
+
+// Lockouts is a list of lockouts, the most recent first
+type Lockouts struct {
+	XMLName xml.Name  `xml:"lockouts" json:"-"`
+	Lockout []Lockout `xml:"lockout" json:"lockouts"`
+}
+
+func (l Lockout) String() string {
+	var parsed, _ = xml.Marshal(l)
+	return string(parsed)
+}
+
+func (l Lockouts) String() string {
+	var parsed, _ = xml.MarshalIndent(l, "", "\t")
+	return string(parsed)
+}
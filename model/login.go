@@ -1,11 +1,29 @@
 package model
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+)
 
 type Login struct {
 	XMLName xml.Name  `xml:"login"`
 	Name    Attribute `xml:"name"`
 	Hash    Attribute `xml:"hash"`
+	// Email is where password reset tokens are sent to, optional
+	Email Attribute `xml:"email"`
+	// TOTP is the optional second factor
+	TOTP TOTP `xml:"totp"`
+	// OIDC is the identity of the single sign-on provider the login is linked to, nil if not linked
+	OIDC *OIDCIdentity `xml:"oidc,omitempty"`
+	// Deletion is when the account is deleted for good, nil unless the user has deleted it. Logging in again before
+	// cancels the deletion.
+	Deletion *time.Time `xml:"deletion,omitempty"`
+}
+
+// OIDCIdentity identifies a user at an OpenID Connect provider
+type OIDCIdentity struct {
+	Issuer  string `xml:"issuer,attr"`
+	Subject string `xml:"subject,attr"`
 }
 
 func NewLogin(name, hash string) Login {
@@ -7,12 +7,14 @@ import (
 )
 
 type Milestone struct {
-	Text    string    `xml:",chardata"`
-	ID      string    `xml:"id,attr"`
-	Name    Attribute `xml:"name"`
-	Duedate Attribute `xml:"duedate"`
-	Duetime Attribute `xml:"duetime"`
-	Desc    string    `xml:"desc"`
+	Text     string    `xml:",chardata"`
+	ID       string    `xml:"id,attr"`
+	Name     Attribute `xml:"name"`
+	Duedate  Attribute `xml:"duedate"`
+	Duetime  Attribute `xml:"duetime"`
+	Assignee Attribute `xml:"assignee"`
+	Labels   Labels    `xml:"labels"`
+	Desc     string    `xml:"desc"`
 }
 
 // NewMilestone parses milestone from the request. Returns ErrReqFieldMissing if it could not fully be parsed,
@@ -44,6 +46,18 @@ func NewMilestone(r *http.Request) (Milestone, error) {
 		m.Duetime = Attribute{Val: vs[0]}
 	}
 
+	if vs, ok := r.Form["assignee"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		m.Assignee = Attribute{Val: vs[0]}
+	}
+
+	if vs, ok := r.Form["labels"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		m.Labels = NewLabels(vs[0])
+	}
+
 	if vs, ok := r.Form["desc"]; !ok || len(vs) != 1 {
 		retErr = ErrReqFieldMissing
 	} else {
@@ -74,6 +88,14 @@ func (m *Milestone) Update(o Milestone) {
 		m.Duetime.Val = o.Duetime.Val
 	}
 
+	if o.Assignee.Val != "" {
+		m.Assignee.Val = o.Assignee.Val
+	}
+
+	if len(o.Labels.Label) > 0 {
+		m.Labels = o.Labels
+	}
+
 	if o.Desc != "" {
 		m.Desc = o.Desc
 	}
@@ -0,0 +1,133 @@
+package model
+
+// MoveItem moves the item of the given kind and ID from src to dst, which may be the same calendar when copying. If
+// copy is set, src is left untouched and the copy gets a fresh ID, as do its subtasks. Tasks keep their milestone
+// only if dst has it, tasks of src linked to a moved milestone are unlinked. Returns the ID of the item in dst,
+// ErrNotFound if src has no such item and ErrAlreadyExists if dst has an item of this ID already. The slices of the
+// calendars are never changed in place, so that src and dst may share them with stored calendars.
+func MoveItem(src, dst *Calendar, kind ItemKind, id string, copy bool) (string, error) {
+	if !src.HasItem(id) {
+		return "", ErrNotFound
+	} else if !copy && dst.HasItem(id) {
+		return "", ErrAlreadyExists
+	}
+
+	switch kind {
+	case KindAppointment:
+		as := src.Items.Appointments.Appointment
+		i := itemIndex(len(as), func(i int) string { return as[i].ID }, id)
+		if i < 0 {
+			return "", ErrNotFound
+		}
+
+		a := as[i]
+		a.Labels.Label = append([]Attribute(nil), a.Labels.Label...)
+		if copy {
+			a.ID = newItemID()
+		} else {
+			src.Items.Appointments.Appointment = append(as[:i:i], as[i+1:]...)
+		}
+		as = append([]Appointment(nil), dst.Items.Appointments.Appointment...)
+		dst.Items.Appointments.Appointment = append(as, a)
+		return a.ID, nil
+
+	case KindMilestone:
+		ms := src.Items.Milestones.Milestone
+		i := itemIndex(len(ms), func(i int) string { return ms[i].ID }, id)
+		if i < 0 {
+			return "", ErrNotFound
+		}
+
+		m := ms[i]
+		m.Labels.Label = append([]Attribute(nil), m.Labels.Label...)
+		if copy {
+			m.ID = newItemID()
+		} else {
+			src.Items.Milestones.Milestone = append(ms[:i:i], ms[i+1:]...)
+
+			// the tasks left behind would link to a milestone of another calendar
+			tasks := append([]Task(nil), src.Items.Tasks.Task...)
+			for j := range tasks {
+				if tasks[j].Milestone.ID == id {
+					tasks[j].Milestone.ID = ""
+				}
+			}
+			src.Items.Tasks.Task = tasks
+		}
+		ms = append([]Milestone(nil), dst.Items.Milestones.Milestone...)
+		dst.Items.Milestones.Milestone = append(ms, m)
+		return m.ID, nil
+
+	case KindTask:
+		ts := src.Items.Tasks.Task
+		i := itemIndex(len(ts), func(i int) string { return ts[i].ID }, id)
+		if i < 0 {
+			return "", ErrNotFound
+		}
+
+		t := ts[i]
+		t.Labels.Label = append([]Attribute(nil), t.Labels.Label...)
+		t.Subtasks.Subtask = append([]Subtask(nil), t.Subtasks.Subtask...)
+		if copy {
+			t.ID = newItemID()
+			for j := range t.Subtasks.Subtask {
+				t.Subtasks.Subtask[j].ID = newItemID()
+			}
+		} else {
+			src.Items.Tasks.Task = append(ts[:i:i], ts[i+1:]...)
+		}
+
+		ms := dst.Items.Milestones.Milestone
+		if itemIndex(len(ms), func(i int) string { return ms[i].ID }, t.Milestone.ID) < 0 {
+			t.Milestone.ID = ""
+		}
+		ts = append([]Task(nil), dst.Items.Tasks.Task...)
+		dst.Items.Tasks.Task = append(ts, t)
+		return t.ID, nil
+	}
+
+	return "", ErrNotFound
+}
+
+// HasItem returns whether the calendar has an item of any kind with the given ID
+func (c Calendar) HasItem(id string) bool {
+	as, ms, ts := c.Items.Appointments.Appointment, c.Items.Milestones.Milestone, c.Items.Tasks.Task
+	return itemIndex(len(as), func(i int) string { return as[i].ID }, id) >= 0 ||
+		itemIndex(len(ms), func(i int) string { return ms[i].ID }, id) >= 0 ||
+		itemIndex(len(ts), func(i int) string { return ts[i].ID }, id) >= 0
+}
+
+// Item returns the item of the given kind and ID, or false if the calendar has no such item
+func (c Calendar) Item(kind ItemKind, id string) (interface{}, bool) {
+	switch kind {
+	case KindAppointment:
+		as := c.Items.Appointments.Appointment
+		if i := itemIndex(len(as), func(i int) string { return as[i].ID }, id); i >= 0 {
+			return as[i], true
+		}
+	case KindMilestone:
+		ms := c.Items.Milestones.Milestone
+		if i := itemIndex(len(ms), func(i int) string { return ms[i].ID }, id); i >= 0 {
+			return ms[i], true
+		}
+	case KindTask:
+		ts := c.Items.Tasks.Task
+		if i := itemIndex(len(ts), func(i int) string { return ts[i].ID }, id); i >= 0 {
+			return ts[i], true
+		}
+	}
+	return nil, false
+}
+
+// itemIndex returns the index of the item with the given ID among n items, whose IDs are returned by idOf, or -1
+func itemIndex(n int, idOf func(int) string, id string) int {
+	if id == "" {
+		return -1
+	}
+	for i := 0; i < n; i++ {
+		if idOf(i) == id {
+			return i
+		}
+	}
+	return -1
+}
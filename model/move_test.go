@@ -0,0 +1,70 @@
+package model
+
+import "testing"
+
+func TestMoveItem(t *testing.T) {
+	newSrc := func() Calendar {
+		var c Calendar
+		c.Items.Appointments.Appointment = []Appointment{{ID: "a1"}, {ID: "a2"}}
+		c.Items.Milestones.Milestone = []Milestone{{ID: "m1"}}
+		c.Items.Tasks.Task = []Task{{ID: "t1"}, {ID: "t2"}}
+		c.Items.Tasks.Task[0].Milestone.ID = "m1"
+		c.Items.Tasks.Task[1].Milestone.ID = "m1"
+		c.Items.Tasks.Task[1].Subtasks.Subtask = []Subtask{{ID: "s1"}}
+		return c
+	}
+
+	// moving an appointment removes it from src without touching the slices shared with the original
+	src, dst := newSrc(), Calendar{}
+	original := src.Items.Appointments.Appointment
+	if id, err := MoveItem(&src, &dst, KindAppointment, "a1", false); err != nil || id != "a1" {
+		t.Fatalf("expected a1 to be moved, got %q, %v", id, err)
+	}
+	if src.HasItem("a1") || !dst.HasItem("a1") || len(src.Items.Appointments.Appointment) != 1 {
+		t.Errorf("a1 not moved: %v, %v", src.Items.Appointments, dst.Items.Appointments)
+	}
+	if original[0].ID != "a1" || original[1].ID != "a2" {
+		t.Errorf("expected the original slice to be unchanged, got %v", original)
+	}
+
+	if _, err := MoveItem(&src, &dst, KindAppointment, "a1", false); err != ErrNotFound {
+		t.Errorf("expected a moved item not to be found in src, got %v", err)
+	}
+	dst.Items.Appointments.Appointment = append(dst.Items.Appointments.Appointment, Appointment{ID: "a2"})
+	if _, err := MoveItem(&src, &dst, KindAppointment, "a2", false); err != ErrAlreadyExists {
+		t.Errorf("expected a conflict moving a2, got %v", err)
+	}
+
+	// tasks lose their milestone in dst, the tasks left behind lose the moved milestone
+	src, dst = newSrc(), Calendar{}
+	if _, err := MoveItem(&src, &dst, KindTask, "t1", false); err != nil {
+		t.Fatal(err)
+	}
+	if ts := dst.Items.Tasks.Task; len(ts) != 1 || ts[0].Milestone.ID != "" {
+		t.Errorf("expected t1 to be unlinked in dst, got %v", ts)
+	}
+	if _, err := MoveItem(&src, &dst, KindMilestone, "m1", false); err != nil {
+		t.Fatal(err)
+	}
+	if ts := src.Items.Tasks.Task; len(ts) != 1 || ts[0].Milestone.ID != "" {
+		t.Errorf("expected t2 to be unlinked in src, got %v", ts)
+	}
+	if _, err := MoveItem(&src, &dst, KindTask, "t2", false); err != nil {
+		t.Fatal(err)
+	}
+	if !dst.HasItem("m1") || !dst.HasItem("t2") || src.HasItem("t2") {
+		t.Errorf("milestone or task not moved: %v, %v", src.Items, dst.Items)
+	}
+
+	// copies get fresh IDs, also within the same calendar, and keep the milestone of the same calendar
+	src = newSrc()
+	id, err := MoveItem(&src, &src, KindTask, "t2", true)
+	if err != nil || id == "" || id == "t2" {
+		t.Fatalf("expected a fresh ID for the copy of t2, got %q, %v", id, err)
+	}
+	ts := src.Items.Tasks.Task
+	if len(ts) != 3 || ts[2].ID != id || ts[2].Milestone.ID != "m1" || len(ts[2].Subtasks.Subtask) != 1 ||
+		ts[2].Subtasks.Subtask[0].ID == "s1" || ts[1].Subtasks.Subtask[0].ID != "s1" {
+		t.Errorf("t2 not copied correctly: %v", ts)
+	}
+}
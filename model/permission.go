@@ -23,26 +23,29 @@ func (p Permission) String() string {
 	panic("permission: string: not implemented")
 }
 
-// CalendarPermissions takes a calendar c, and a user id and returns which permissions this user has for c.
-func CalendarPermissions(c Calendar, userID string) Permission {
+// CalendarPermissions takes a calendar c, and a user id and returns which permissions this user has for c. The
+// user gets the highest permission granted either directly or to one of the groups the user is a member of.
+func CalendarPermissions(c Calendar, userID string, groups ...string) Permission {
 	if c.Owner.Val == userID {
 		return Owner
 	}
 
-	s := func(u []Attribute, n string) bool {
+	s := func(u []Attribute, ns ...string) bool {
 		for _, v := range u {
-			if v.Val == n {
-				return true
+			for _, n := range ns {
+				if v.Val == n {
+					return true
+				}
 			}
 		}
 		return false
 	}
 
-	if s(c.Permissions.Edit.User, userID) {
+	if s(c.Permissions.Edit.User, userID) || s(c.Permissions.Edit.Group, groups...) {
 		return Edit
 	}
 
-	if s(c.Permissions.View.User, userID) {
+	if s(c.Permissions.View.User, userID) || s(c.Permissions.View.Group, groups...) {
 		return Read
 	}
 
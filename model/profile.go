@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/xml"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Profile holds the preferences of a user, used when rendering pages for the user. Empty fields fall back to the
+// defaults.
+type Profile struct {
+	// DisplayName is shown instead of the login ID, if set
+	DisplayName string `xml:"displayName,omitempty" json:"displayName,omitempty"`
+	// Locale is the preferred language as BCP 47 tag, e.g. de-DE
+	Locale string `xml:"locale,omitempty" json:"locale,omitempty"`
+	// TimeZone is the IANA name of the time zone dates are shown in, e.g. Europe/Berlin. The server's if empty.
+	TimeZone string `xml:"timeZone,omitempty" json:"timeZone,omitempty"`
+	// WeekStart is the lower case name of the first day of the week, monday if empty
+	WeekStart string `xml:"weekStart,omitempty" json:"weekStart,omitempty"`
+}
+
+// ProfileView shows the profile along with the login ID and the email address, which is stored in the login
+type ProfileView struct {
+	XMLName xml.Name `xml:"profile" json:"-"`
+	Name    string   `xml:"name,attr" json:"name"`
+	Email   string   `xml:"email,omitempty" json:"email,omitempty"`
+	Profile
+}
+
+var localeRegex = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// ValidLocale returns whether l looks like a BCP 47 language tag
+func ValidLocale(l string) bool {
+	return localeRegex.MatchString(l)
+}
+
+// ParseWeekday returns the weekday with the given name, e.g. monday, or ErrReqFieldMissing if there is none
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, nil
+		}
+	}
+	return time.Sunday, ErrReqFieldMissing
+}
+
+// ParseTimeZone returns the location with the given IANA name, or ErrReqFieldMissing if there is none
+func ParseTimeZone(s string) (*time.Location, error) {
+	// LoadLocation would understand the empty string and Local as the server's time zones
+	if s == "" || s == "Local" {
+		return nil, ErrReqFieldMissing
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return nil, ErrReqFieldMissing
+	}
+	return loc, nil
+}
+
+// Location returns the time zone of the profile, falling back to the server's
+func (p Profile) Location() *time.Location {
+	if loc, err := ParseTimeZone(p.TimeZone); err == nil {
+		return loc
+	}
+	return time.Local
+}
+
+// FirstWeekday returns the first day of the week of the profile, falling back to monday
+func (p Profile) FirstWeekday() time.Weekday {
+	if d, err := ParseWeekday(p.WeekStart); err == nil {
+		return d
+	}
+	return time.Monday
+}
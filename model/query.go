@@ -0,0 +1,202 @@
+package model
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ItemKind names the kind of a calendar item
+type ItemKind string
+
+const (
+	KindAppointment ItemKind = "appointment"
+	KindMilestone   ItemKind = "milestone"
+	KindTask        ItemKind = "task"
+)
+
+// ItemKinds are all kinds of calendar items
+var ItemKinds = []ItemKind{KindAppointment, KindMilestone, KindTask}
+
+// DateLayout is the layout of the dates stored in calendar items, see transformDate
+const DateLayout = "02.01.2006"
+
+// ErrBadQuery is returned if a query parameter could not be understood
+var ErrBadQuery = errors.New("error: query parameter not understood")
+
+// ParseDate parses a date of a calendar item. Leading zeros of day and month are optional.
+func ParseDate(d string) (time.Time, error) {
+	return time.Parse("2.1.2006", strings.TrimSpace(d))
+}
+
+// span returns the parsed start and end date. If start is missing or invalid, end is used for both.
+func span(start, end string) (time.Time, time.Time, error) {
+	e, err := ParseDate(end)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	s, err := ParseDate(start)
+	if err != nil || s.After(e) {
+		s = e
+	}
+
+	return s, e, nil
+}
+
+// Span returns the first and last day of the appointment.
+func (a Appointment) Span() (time.Time, time.Time, error) {
+	return span(a.StartDate.Val, a.EndDate.Val)
+}
+
+// Span returns the first and last day of the task, the latter being the due date.
+func (t Task) Span() (time.Time, time.Time, error) {
+	return span(t.StartDate.Val, t.Duedate.Val)
+}
+
+// Span returns the due date of the milestone as first and last day.
+func (m Milestone) Span() (time.Time, time.Time, error) {
+	return span("", m.Duedate.Val)
+}
+
+// CalendarQuery filters the items of a calendar. Initial fields do not filter.
+type CalendarQuery struct {
+	// From is the first day of the window; items ending before are filtered out
+	From time.Time
+	// To is the last day of the window; items starting after are filtered out
+	To time.Time
+	// Kinds of items to keep
+	Kinds []ItemKind
+	// Text that has to be contained in name or description, ignoring case
+	Text string
+	// Assignee the items have to be assigned to
+	Assignee string
+	// Labels the items have to carry, all of them
+	Labels []string
+}
+
+// NewCalendarQuery parses the query from the URL query parameters from, to, kind, q, assignee and label of the
+// request. Dates are either formatted like HTML date inputs (yyyy-mm-dd) or like dates of items (dd.mm.yyyy).
+// Parameters that may be given multiple times can also be comma separated. Returns ErrBadQuery if a parameter
+// could not be understood.
+func NewCalendarQuery(r *http.Request) (CalendarQuery, error) {
+	v := r.URL.Query()
+
+	var q CalendarQuery
+	var err error
+
+	if s := v.Get("from"); s != "" {
+		if q.From, err = parseQueryDate(s); err != nil {
+			return q, ErrBadQuery
+		}
+	}
+
+	if s := v.Get("to"); s != "" {
+		if q.To, err = parseQueryDate(s); err != nil {
+			return q, ErrBadQuery
+		}
+	}
+
+	for _, k := range SplitAll(v["kind"]) {
+		kind := ItemKind(strings.ToLower(k))
+		if !kind.valid() {
+			return q, ErrBadQuery
+		}
+		q.Kinds = append(q.Kinds, kind)
+	}
+
+	q.Text = strings.TrimSpace(v.Get("q"))
+	q.Assignee = strings.TrimSpace(v.Get("assignee"))
+	q.Labels = SplitAll(v["label"])
+
+	return q, nil
+}
+
+// IsZero returns whether the query does not filter anything.
+func (q CalendarQuery) IsZero() bool {
+	return q.From.IsZero() && q.To.IsZero() && len(q.Kinds) == 0 && q.Text == "" && q.Assignee == "" &&
+		len(q.Labels) == 0
+}
+
+// Dated returns whether the query restricts the time window.
+func (q CalendarQuery) Dated() bool {
+	return !q.From.IsZero() || !q.To.IsZero()
+}
+
+// HasKind returns whether items of kind k pass the query.
+func (q CalendarQuery) HasKind(k ItemKind) bool {
+	if len(q.Kinds) == 0 {
+		return true
+	}
+	for _, v := range q.Kinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+// InWindow returns whether the span start to end overlaps the window of the query.
+func (q CalendarQuery) InWindow(start, end time.Time) bool {
+	if !q.From.IsZero() && end.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && start.After(q.To) {
+		return false
+	}
+	return true
+}
+
+// Matches returns whether an item with the given fields passes the text, assignee and label filters of the query.
+// The window and kind filters are checked by InWindow and HasKind, respectively.
+func (q CalendarQuery) Matches(name, desc string, assignee Attribute, labels Labels) bool {
+	if q.Text != "" {
+		t := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(name), t) && !strings.Contains(strings.ToLower(desc), t) {
+			return false
+		}
+	}
+
+	if q.Assignee != "" && !strings.EqualFold(q.Assignee, assignee.Val) {
+		return false
+	}
+
+	for _, l := range q.Labels {
+		if !labels.Has(l) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (k ItemKind) valid() bool {
+	for _, v := range ItemKinds {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
+
+func parseQueryDate(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return ParseDate(s)
+}
+
+// SplitAll splits all comma separated values and drops empty ones, so that lists can be given either repeated or
+// comma separated
+func SplitAll(vs []string) []string {
+	var res []string
+	for _, v := range vs {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
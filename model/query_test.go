@@ -0,0 +1,90 @@
+package model
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewCalendarQuery(t *testing.T) {
+	tt := []struct {
+		query string
+		want  CalendarQuery
+		err   error
+	}{
+		// no filters
+		{query: "?date=1.1.1970&mode=project"},
+		// both date formats
+		{
+			query: "?from=2021-06-01&to=30.6.2021",
+			want: CalendarQuery{
+				From: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+				To:   time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		// comma separated and repeated
+		{
+			query: "?kind=task,Milestone&label=a&label=b,c&assignee=lambda&q=vendor",
+			want: CalendarQuery{
+				Kinds:    []ItemKind{KindTask, KindMilestone},
+				Labels:   []string{"a", "b", "c"},
+				Assignee: "lambda",
+				Text:     "vendor",
+			},
+		},
+		// bad date
+		{query: "?from=yesterday", err: ErrBadQuery},
+		// bad kind
+		{query: "?kind=meeting", err: ErrBadQuery},
+	}
+
+	for _, tc := range tt {
+		r, err := http.NewRequest("GET", "/c"+tc.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q, err := NewCalendarQuery(r)
+		if err != tc.err {
+			t.Errorf("%s: got error %v want %v", tc.query, err, tc.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if !q.From.Equal(tc.want.From) || !q.To.Equal(tc.want.To) || q.Text != tc.want.Text ||
+			q.Assignee != tc.want.Assignee || len(q.Kinds) != len(tc.want.Kinds) ||
+			len(q.Labels) != len(tc.want.Labels) {
+			t.Errorf("%s: got %+v want %+v", tc.query, q, tc.want)
+		}
+		if q.IsZero() != tc.want.IsZero() {
+			t.Errorf("%s: IsZero is %v", tc.query, q.IsZero())
+		}
+	}
+}
+
+func TestCalendarQueryMatches(t *testing.T) {
+	labels := NewLabels("backend, Urgent,")
+	assignee := Attribute{Val: "lambda"}
+
+	tt := []struct {
+		q    CalendarQuery
+		want bool
+	}{
+		{q: CalendarQuery{}, want: true},
+		{q: CalendarQuery{Text: "VENDOR"}, want: true},
+		{q: CalendarQuery{Text: "contract"}, want: true},
+		{q: CalendarQuery{Text: "invoice"}, want: false},
+		{q: CalendarQuery{Assignee: "lambda"}, want: true},
+		{q: CalendarQuery{Assignee: "someone"}, want: false},
+		{q: CalendarQuery{Labels: []string{"urgent", "backend"}}, want: true},
+		{q: CalendarQuery{Labels: []string{"urgent", "frontend"}}, want: false},
+	}
+
+	for _, tc := range tt {
+		if got := tc.q.Matches("Talk to vendor", "about the contract", assignee, labels); got != tc.want {
+			t.Errorf("%+v: got %v want %v", tc.q, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// ResetToken allows a user to set a new password without knowing the old one. Only the hash of the token is stored,
+// the token itself is mailed to the user.
+type ResetToken struct {
+	XMLName xml.Name  `xml:"reset"`
+	Hash    string    `xml:"hash,attr"`
+	User    string    `xml:"user,attr"`
+	Expires time.Time `xml:"expires,attr"`
+}
+
+// Expired returns whether the token has expired at time now
+func (t ResetToken) Expired(now time.Time) bool {
+	return now.After(t.Expires)
+}
+
+func (t ResetToken) String() string {
+	var parsed, _ = xml.MarshalIndent(t, "", "\t")
+	return string(parsed)
+}
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Revision is a prior version of a calendar, kept when the calendar has been changed, so that its items and its
+// metadata can be restored
+type Revision struct {
+	XMLName xml.Name `xml:"revision" json:"-"`
+	ID      string   `xml:"id,attr" json:"id"`
+	// Until is when this version has been replaced
+	Until    time.Time `xml:"until,attr" json:"until"`
+	Calendar Calendar  `xml:"calendar" json:"calendar"`
+}
+
+// Revisions is a list of revisions of a calendar
+type Revisions struct {
+	XMLName  xml.Name   `xml:"revisions" json:"-"`
+	Revision []Revision `xml:"revision" json:"revisions"`
+}
+
+// ItemRevision is a prior version of a calendar item. Only the field of the kind of the item is set.
+type ItemRevision struct {
+	XMLName xml.Name `xml:"revision" json:"-"`
+	// ID is the ID of the most recent revision of the calendar with this version of the item
+	ID string `xml:"id,attr" json:"id"`
+	// Until is when this version has been replaced
+	Until       time.Time    `xml:"until,attr" json:"until"`
+	Appointment *Appointment `xml:"appointment,omitempty" json:"appointment,omitempty"`
+	Milestone   *Milestone   `xml:"milestone,omitempty" json:"milestone,omitempty"`
+	Task        *Task        `xml:"task,omitempty" json:"task,omitempty"`
+}
+
+// ItemRevisions is a list of revisions of an item
+type ItemRevisions struct {
+	XMLName  xml.Name       `xml:"revisions" json:"-"`
+	Revision []ItemRevision `xml:"revision" json:"revisions"`
+}
+
+// ItemDiff lists the fields of an item changed from revision From to revision To
+type ItemDiff struct {
+	XMLName xml.Name `xml:"diff" json:"-"`
+	From    string   `xml:"from,attr" json:"from"`
+	To      string   `xml:"to,attr" json:"to"`
+	Changes []Change `xml:"change" json:"changes"`
+}
+
+func (r Revision) String() string {
+	var parsed, _ = xml.MarshalIndent(r, "", "\t")
+	return string(parsed)
+}
+
+// ItemHistory returns the prior versions of the item of the given kind and ID kept in revs, the revisions of the
+// calendar ordered the most recent first, as is the result. Revisions which have the same version of the item are
+// merged into the most recent one, versions equal to the item as the calendar has it now are left out.
+func (c Calendar) ItemHistory(revs []Revision, kind ItemKind, id string) []ItemRevision {
+	var res []ItemRevision
+	last, _ := c.Item(kind, id)
+	for _, rev := range revs {
+		item, ok := rev.Calendar.Item(kind, id)
+		if !ok {
+			last = nil
+			continue
+		}
+		if last != nil && len(Diff(last, item)) == 0 {
+			continue
+		}
+		last = item
+
+		ir := ItemRevision{ID: rev.ID, Until: rev.Until}
+		switch v := item.(type) {
+		case Appointment:
+			ir.Appointment = &v
+		case Milestone:
+			ir.Milestone = &v
+		case Task:
+			ir.Task = &v
+		}
+		res = append(res, ir)
+	}
+	return res
+}
+
+// RestoreItem puts the item of the given kind and ID as it is in rev into the calendar, replacing the version of the
+// calendar or adding it again, if it has been deleted in the meantime. Restored tasks lose their milestone, if the
+// calendar does not have it anymore. Returns ErrNotFound if rev has no such item. As by MoveItem, the slices of the
+// calendar are not changed in place.
+func (c *Calendar) RestoreItem(rev Calendar, kind ItemKind, id string) error {
+	item, ok := rev.Item(kind, id)
+	if !ok {
+		return ErrNotFound
+	}
+
+	switch v := item.(type) {
+	case Appointment:
+		as := append([]Appointment(nil), c.Items.Appointments.Appointment...)
+		if i := itemIndex(len(as), func(i int) string { return as[i].ID }, id); i >= 0 {
+			as[i] = v
+		} else {
+			as = append(as, v)
+		}
+		c.Items.Appointments.Appointment = as
+	case Milestone:
+		ms := append([]Milestone(nil), c.Items.Milestones.Milestone...)
+		if i := itemIndex(len(ms), func(i int) string { return ms[i].ID }, id); i >= 0 {
+			ms[i] = v
+		} else {
+			ms = append(ms, v)
+		}
+		c.Items.Milestones.Milestone = ms
+	case Task:
+		ms := c.Items.Milestones.Milestone
+		if itemIndex(len(ms), func(i int) string { return ms[i].ID }, v.Milestone.ID) < 0 {
+			v.Milestone.ID = ""
+		}
+		ts := append([]Task(nil), c.Items.Tasks.Task...)
+		if i := itemIndex(len(ts), func(i int) string { return ts[i].ID }, id); i >= 0 {
+			ts[i] = v
+		} else {
+			ts = append(ts, v)
+		}
+		c.Items.Tasks.Task = ts
+	}
+	return nil
+}
+
+// AsOf returns the calendar with the items and the description it had at time t, according to revs, which are
+// ordered the most recent first. Its identity and the users and groups it is shared with are kept, as they are
+// referenced by users and groups.
+func (c Calendar) AsOf(revs []Revision, t time.Time) Calendar {
+	// the version at t is the first one replaced after t
+	var then *Calendar
+	for i := range revs {
+		if !revs[i].Until.After(t) {
+			break
+		}
+		then = &revs[i].Calendar
+	}
+	if then == nil {
+		return c
+	}
+
+	c.Desc = then.Desc
+	c.Template = then.Template
+	c.Items = then.Items
+	return c
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestItemHistory(t *testing.T) {
+	now := time.Now()
+	version := func(name string) Calendar {
+		var c Calendar
+		if name != "" {
+			c.Items.Tasks.Task = []Task{{ID: "t1", Name: Attribute{Val: name}}}
+		}
+		c.Items.Milestones.Milestone = []Milestone{{ID: "m1"}}
+		return c
+	}
+
+	// the task has been created as draft, renamed twice, deleted and created again with the first name
+	current := version("draft")
+	revs := []Revision{
+		{ID: "r5", Until: now, Calendar: version("")},
+		{ID: "r4", Until: now.Add(-time.Hour), Calendar: version("final")},
+		{ID: "r3", Until: now.Add(-2 * time.Hour), Calendar: version("final")},
+		{ID: "r2", Until: now.Add(-3 * time.Hour), Calendar: version("second")},
+		{ID: "r1", Until: now.Add(-4 * time.Hour), Calendar: version("draft")},
+		{ID: "r0", Until: now.Add(-5 * time.Hour), Calendar: version("")},
+	}
+
+	h := current.ItemHistory(revs, KindTask, "t1")
+	var ids []string
+	for _, ir := range h {
+		if ir.Task == nil || ir.Appointment != nil || ir.Milestone != nil {
+			t.Fatalf("expected only tasks, got %v", ir)
+		}
+		ids = append(ids, ir.ID+":"+ir.Task.Name.Val)
+	}
+	if len(ids) != 3 || ids[0] != "r4:final" || ids[1] != "r2:second" || ids[2] != "r1:draft" {
+		t.Errorf("expected the versions final, second and draft, got %v", ids)
+	}
+
+	if h := current.ItemHistory(revs, KindMilestone, "m1"); len(h) != 0 {
+		t.Errorf("expected no prior versions of an unchanged milestone, got %v", h)
+	}
+}
+
+func TestRestoreItem(t *testing.T) {
+	var rev Calendar
+	rev.Items.Milestones.Milestone = []Milestone{{ID: "m1"}}
+	rev.Items.Tasks.Task = []Task{{ID: "t1", Name: Attribute{Val: "old"}}, {ID: "t2"}}
+	rev.Items.Tasks.Task[0].Milestone.ID = "m1"
+
+	var c Calendar
+	c.Items.Tasks.Task = []Task{{ID: "t1", Name: Attribute{Val: "new"}}}
+	tasks := c.Items.Tasks.Task
+
+	if err := c.RestoreItem(rev, KindTask, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if ts := c.Items.Tasks.Task; len(ts) != 1 || ts[0].Name.Val != "old" || ts[0].Milestone.ID != "" {
+		t.Errorf("expected t1 to be restored without its deleted milestone, got %v", ts)
+	}
+	if tasks[0].Name.Val != "new" {
+		t.Error("expected the tasks not to be changed in place")
+	}
+
+	// deleted items are added again
+	if err := c.RestoreItem(rev, KindTask, "t2"); err != nil || !c.HasItem("t2") {
+		t.Errorf("expected t2 to be restored, got %v", err)
+	}
+	if err := c.RestoreItem(rev, KindAppointment, "t1"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCalendarAsOf(t *testing.T) {
+	now := time.Now()
+	var c Calendar
+	c.ID.Val, c.Desc = "owner/project", "now"
+	c.Permissions.View.User = []Attribute{{Val: "viewer"}}
+	version := func(desc string) Calendar {
+		v := Calendar{Desc: desc, ID: Attribute{Val: "owner/former"}}
+		v.Items.Tasks.Task = []Task{{ID: desc}}
+		return v
+	}
+	revs := []Revision{
+		{ID: "r2", Until: now.Add(-time.Hour), Calendar: version("second")},
+		{ID: "r1", Until: now.Add(-2 * time.Hour), Calendar: version("first")},
+	}
+
+	tt := []struct {
+		t    time.Time
+		desc string
+	}{
+		{t: now, desc: "now"},
+		{t: now.Add(-time.Hour), desc: "now"},
+		{t: now.Add(-90 * time.Minute), desc: "second"},
+		{t: now.Add(-3 * time.Hour), desc: "first"},
+	}
+	for i, tc := range tt {
+		then := c.AsOf(revs, tc.t)
+		if then.Desc != tc.desc || then.ID.Val != c.ID.Val || len(then.Permissions.View.User) != 1 {
+			t.Errorf("%d: expected the calendar as of %s, got %v", i, tc.desc, then)
+		}
+		if tc.desc != "now" && (len(then.Items.Tasks.Task) != 1 || then.Items.Tasks.Task[0].ID != tc.desc) {
+			t.Errorf("%d: expected the items as of %s, got %v", i, tc.desc, then.Items)
+		}
+	}
+}
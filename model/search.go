@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// KindCalendar is used for search results matching a calendar itself rather than one of its items
+const KindCalendar ItemKind = "calendar"
+
+// DefaultSearchLimit is the number of results returned if the query does not specify a limit
+const DefaultSearchLimit = 50
+
+// SearchQuery for the full-text search over names and descriptions
+type SearchQuery struct {
+	// Terms are matched as prefixes of the words in names and descriptions; all of them have to match
+	Terms []string
+	// Kinds of results to return, all if empty. Facets are counted regardless
+	Kinds []ItemKind
+	// Calendars to search in. Nothing is searched if empty
+	Calendars []string
+	// Limit of returned results
+	Limit int
+}
+
+// SearchResults of a SearchQuery, the best matches first
+type SearchResults struct {
+	XMLName xml.Name       `xml:"search" json:"-"`
+	Query   string         `xml:"query,attr" json:"query"`
+	Total   int            `xml:"total,attr" json:"total"`
+	Facets  []SearchFacet  `xml:"facets>facet" json:"facets"`
+	Results []SearchResult `xml:"result" json:"results"`
+}
+
+// SearchFacet counts the matches of one kind
+type SearchFacet struct {
+	Kind  ItemKind `xml:"kind,attr" json:"kind"`
+	Count int      `xml:"count,attr" json:"count"`
+}
+
+// SearchResult is a calendar or item matching the query. The snippet is the part of the description around the
+// first match.
+type SearchResult struct {
+	Kind     ItemKind `xml:"kind,attr" json:"kind"`
+	ID       string   `xml:"id,attr" json:"id"`
+	Calendar string   `xml:"calendar,attr" json:"calendar"`
+	Name     string   `xml:"name" json:"name"`
+	Snippet  string   `xml:"snippet" json:"snippet"`
+	Score    int      `xml:"score,attr" json:"score"`
+}
+
+// NewSearchQuery parses the query from the URL query parameters q, kind and limit of the request. Calendars have to
+// be set by the caller. Returns ErrReqFieldMissing if q contains no words and ErrBadQuery if the other parameters
+// could not be understood.
+func NewSearchQuery(r *http.Request) (SearchQuery, error) {
+	v := r.URL.Query()
+
+	q := SearchQuery{
+		Terms: Tokenize(v.Get("q")),
+		Limit: DefaultSearchLimit,
+	}
+
+	for _, k := range SplitAll(v["kind"]) {
+		kind := ItemKind(strings.ToLower(k))
+		if !kind.valid() && kind != KindCalendar {
+			return q, ErrBadQuery
+		}
+		q.Kinds = append(q.Kinds, kind)
+	}
+
+	if l := v.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			return q, ErrBadQuery
+		}
+		q.Limit = n
+	}
+
+	if len(q.Terms) == 0 {
+		return q, ErrReqFieldMissing
+	}
+
+	return q, nil
+}
+
+// HasKind returns whether results of kind k are requested.
+func (q SearchQuery) HasKind(k ItemKind) bool {
+	return CalendarQuery{Kinds: q.Kinds}.HasKind(k)
+}
+
+// Tokenize splits s into lower case words consisting of letters and digits.
+func Tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Snippet returns about width characters of text around the first word starting with one of the terms. If none
+// matches, the beginning of text is returned.
+func Snippet(text string, terms []string, width int) string {
+	runes := []rune(strings.TrimSpace(text))
+	lower := []rune(strings.ToLower(string(runes)))
+
+	pos := -1
+	for i := range lower {
+		if i > 0 && (unicode.IsLetter(lower[i-1]) || unicode.IsDigit(lower[i-1])) {
+			continue // not the start of a word
+		}
+		for _, t := range terms {
+			if strings.HasPrefix(string(lower[i:]), t) {
+				pos = i
+				break
+			}
+		}
+		if pos != -1 {
+			break
+		}
+	}
+
+	start := 0
+	if pos > width/4 {
+		start = pos - width/4
+	}
+	end := start + width
+	if end > len(runes) {
+		// use the full width, if the match is near the end
+		end = len(runes)
+		if start = end - width; start < 0 {
+			start = 0
+		}
+	}
+
+	s := string(runes[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
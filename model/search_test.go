@@ -0,0 +1,39 @@
+package model
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	text := "We have to talk to the vendor about the contract before the end of the quarter, otherwise nothing happens"
+
+	tt := []struct {
+		terms []string
+		width int
+		want  string
+	}{
+		{terms: []string{"contr"}, width: 20, want: "… the contract before…"},
+		{terms: []string{"we"}, width: 11, want: "We have to …"},
+		// only whole words are matched by prefix
+		{terms: []string{"ract"}, width: 7, want: "We have…"},
+		{terms: []string{"nothing"}, width: 200, want: text},
+		{terms: []string{"happens"}, width: 17, want: "…e nothing happens"},
+	}
+
+	for _, tc := range tt {
+		if got := Snippet(text, tc.terms, tc.width); got != tc.want {
+			t.Errorf("%v: got %q want %q", tc.terms, got, tc.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Vendor-Contract, signed (2021)!")
+	want := []string{"vendor", "contract", "signed", "2021"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Session is the server-side record of a login. It issues short-lived access tokens as long as the client presents
+// its current refresh token; every refresh rotates the refresh token and extends Expires. Tokens are only accepted as
+// long as their session exists, so deleting it revokes all of them.
+type Session struct {
+	XMLName xml.Name  `xml:"session" json:"-"`
+	ID      string    `xml:"id,attr" json:"id"`
+	User    string    `xml:"user,attr" json:"user"`
+	Created time.Time `xml:"created,attr" json:"created"`
+	Expires time.Time `xml:"expires,attr" json:"expires"`
+	// Device is the user agent the session has been created with
+	Device string `xml:"device" json:"device"`
+	IP     string `xml:"ip" json:"ip"`
+	// RefreshHash is the hash of the current refresh token of the session
+	RefreshHash string `xml:"refreshHash,omitempty" json:"-"`
+	// PreviousHash is the hash of the refresh token replaced at Rotated. Presenting it again after a short grace
+	// period means that it has been stolen, so the session is revoked.
+	PreviousHash string    `xml:"previousHash,omitempty" json:"-"`
+	Rotated      time.Time `xml:"rotated,attr" json:"-"`
+	// CSRFToken has to be sent along with every state-changing request authenticated by the session's cookies
+	CSRFToken string `xml:"csrfToken,omitempty" json:"-"`
+	// Current marks the session of the request when listing sessions; it is not stored
+	Current bool `xml:"current,attr,omitempty" json:"current,omitempty"`
+}
+
+// Sessions is a list of sessions, e.g. of a user
+type Sessions struct {
+	XMLName xml.Name  `xml:"sessions" json:"-"`
+	Session []Session `xml:"session" json:"sessions"`
+}
+
+// Expired returns whether the session has expired at time t
+func (s Session) Expired(t time.Time) bool {
+	return t.After(s.Expires)
+}
+
+func (s Session) String() string {
+	var parsed, _ = xml.MarshalIndent(s, "", "\t")
+	return string(parsed)
+}
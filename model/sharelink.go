@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// ShareLink grants anyone who knows it read-only access to a calendar, without an account, e.g. to publish a
+// timeline to external stakeholders. Only the hash of its secret is stored.
+type ShareLink struct {
+	XMLName xml.Name `xml:"link" json:"-"`
+	ID      string   `xml:"id,attr" json:"id"`
+	// Calendar is the ID of the calendar shared
+	Calendar string `xml:"calendar,attr" json:"calendar"`
+	// Owner is the owner of the calendar, who created the link
+	Owner   string    `xml:"owner,attr" json:"owner"`
+	Created time.Time `xml:"created,attr" json:"created"`
+	// Expires is nil if the link does not expire
+	Expires *time.Time `xml:"expires,attr,omitempty" json:"expires,omitempty"`
+	// HideDescriptions hides the descriptions of the items from the viewers
+	HideDescriptions bool `xml:"hideDescriptions,attr,omitempty" json:"hideDescriptions"`
+	// Accesses counts how often the link has been used, LastAccess is when it was used last
+	Accesses   int        `xml:"accesses,attr" json:"accesses"`
+	LastAccess *time.Time `xml:"lastAccess,attr,omitempty" json:"lastAccess,omitempty"`
+	// Hash is the hash of the secret of the link
+	Hash string `xml:"hash,omitempty" json:"-"`
+	// Token is the ID and secret of the link as used in its URL. It is only shown in the response to its creation and
+	// never stored, as is URL.
+	Token string `xml:"token,omitempty" json:"token,omitempty"`
+	URL   string `xml:"url,omitempty" json:"url,omitempty"`
+}
+
+// ShareLinks is a list of share links, e.g. of a user
+type ShareLinks struct {
+	XMLName xml.Name    `xml:"links" json:"-"`
+	Link    []ShareLink `xml:"link" json:"links"`
+}
+
+// Expired returns whether the link has expired at time now
+func (l ShareLink) Expired(now time.Time) bool {
+	return l.Expires != nil && now.After(*l.Expires)
+}
+
+// Public returns the calendar as viewers of the link see it: without the users and groups it is shared with, and
+// without the descriptions of its items, if the link hides them.
+func (l ShareLink) Public(c Calendar) Calendar {
+	c.Permissions = Calendar{}.Permissions
+	if !l.HideDescriptions {
+		return c
+	}
+
+	c.Items.Appointments.Appointment = append([]Appointment(nil), c.Items.Appointments.Appointment...)
+	for i := range c.Items.Appointments.Appointment {
+		c.Items.Appointments.Appointment[i].Desc = ""
+	}
+	c.Items.Milestones.Milestone = append([]Milestone(nil), c.Items.Milestones.Milestone...)
+	for i := range c.Items.Milestones.Milestone {
+		c.Items.Milestones.Milestone[i].Desc = ""
+	}
+	c.Items.Tasks.Task = append([]Task(nil), c.Items.Tasks.Task...)
+	for i := range c.Items.Tasks.Task {
+		t := &c.Items.Tasks.Task[i]
+		t.Desc = ""
+		t.Subtasks.Subtask = append([]Subtask(nil), t.Subtasks.Subtask...)
+		for j := range t.Subtasks.Subtask {
+			t.Subtasks.Subtask[j].Desc = ""
+		}
+	}
+	return c
+}
+
+func (l ShareLink) String() string {
+	var parsed, _ = xml.MarshalIndent(l, "", "\t")
+	return string(parsed)
+}
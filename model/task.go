@@ -18,6 +18,8 @@ type Task struct {
 	StartTime Attribute `xml:"startTime"`
 	Duedate   Attribute `xml:"duedate"`
 	Duetime   Attribute `xml:"duetime"`
+	Assignee  Attribute `xml:"assignee"`
+	Labels    Labels    `xml:"labels"`
 	Desc      string    `xml:"desc"`
 	Subtasks  struct {
 		Text    string    `xml:",chardata"`
@@ -72,6 +74,18 @@ func NewTask(r *http.Request) (Task, error) {
 		t.Milestone.ID = vs[0]
 	}
 
+	if vs, ok := r.Form["assignee"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		t.Assignee = Attribute{Val: vs[0]}
+	}
+
+	if vs, ok := r.Form["labels"]; !ok || len(vs) != 1 {
+		// don't do anything for the optional field
+	} else {
+		t.Labels = NewLabels(vs[0])
+	}
+
 	if vs, ok := r.Form["desc"]; !ok || len(vs) != 1 {
 		retErr = ErrReqFieldMissing
 	} else {
@@ -108,6 +122,14 @@ func (t *Task) Update(o Task) {
 		t.Milestone.ID = o.Milestone.ID
 	}
 
+	if o.Assignee.Val != "" {
+		t.Assignee.Val = o.Assignee.Val
+	}
+
+	if len(o.Labels.Label) > 0 {
+		t.Labels = o.Labels
+	}
+
 	if o.Desc != "" {
 		t.Desc = o.Desc
 	}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// TokenScope is what a personal access token may be used for
+type TokenScope string
+
+const (
+	// ScopeRead allows to read the calendars, their items, the agenda and to search
+	ScopeRead TokenScope = "read"
+	// ScopeEdit allows to create, edit and delete items and to edit calendars. It includes ScopeRead.
+	ScopeEdit TokenScope = "edit"
+	// ScopeShare allows to manage who a calendar is shared with
+	ScopeShare TokenScope = "share"
+)
+
+// ParseTokenScope returns the scope named s, or ErrReqFieldMissing if there is none
+func ParseTokenScope(s string) (TokenScope, error) {
+	switch sc := TokenScope(s); sc {
+	case ScopeRead, ScopeEdit, ScopeShare:
+		return sc, nil
+	}
+	return "", ErrReqFieldMissing
+}
+
+// AccessToken is a personal access token, with which scripts and integrations act on behalf of its user without a
+// session. It may be restricted to some calendars and expire.
+type AccessToken struct {
+	XMLName xml.Name `xml:"token" json:"-"`
+	ID      string   `xml:"id,attr" json:"id"`
+	User    string   `xml:"user,attr" json:"user"`
+	// Name tells the tokens of a user apart, e.g. by the script using it
+	Name    string    `xml:"name,attr" json:"name"`
+	Created time.Time `xml:"created,attr" json:"created"`
+	// Expires is nil if the token does not expire
+	Expires *time.Time   `xml:"expires,attr,omitempty" json:"expires,omitempty"`
+	Scopes  []TokenScope `xml:"scope" json:"scopes"`
+	// Calendars are the IDs of the only calendars the token may be used for, all if empty
+	Calendars []string `xml:"calendar" json:"calendars,omitempty"`
+	// Hash is the hash of the secret of the token
+	Hash string `xml:"hash,omitempty" json:"-"`
+	// Token is the token itself. It is only shown in the response to its creation and never stored.
+	Token string `xml:"secret,omitempty" json:"token,omitempty"`
+}
+
+// AccessTokens is a list of personal access tokens, e.g. of a user
+type AccessTokens struct {
+	XMLName xml.Name      `xml:"tokens" json:"-"`
+	Token   []AccessToken `xml:"token" json:"tokens"`
+}
+
+// Expired returns whether the token has expired at time t
+func (t AccessToken) Expired(now time.Time) bool {
+	return t.Expires != nil && now.After(*t.Expires)
+}
+
+// HasScope returns whether the token may be used for s
+func (t AccessToken) HasScope(s TokenScope) bool {
+	for _, v := range t.Scopes {
+		if v == s || (v == ScopeEdit && s == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsCalendar returns whether the token may be used for the calendar with the given ID
+func (t AccessToken) AllowsCalendar(calendarID string) bool {
+	if len(t.Calendars) == 0 {
+		return true
+	}
+	for _, v := range t.Calendars {
+		if v == calendarID {
+			return true
+		}
+	}
+	return false
+}
+
+// Permission narrows the permission p of the token's user for the calendar down to what the token allows. Tokens
+// never act as owner.
+func (t AccessToken) Permission(calendarID string, p Permission) Permission {
+	max := None
+	if !t.AllowsCalendar(calendarID) {
+		return None
+	} else if t.HasScope(ScopeEdit) {
+		max = Edit
+	} else if t.HasScope(ScopeRead) {
+		max = Read
+	}
+
+	if p > max {
+		return max
+	}
+	return p
+}
+
+func (t AccessToken) String() string {
+	var parsed, _ = xml.MarshalIndent(t, "", "\t")
+	return string(parsed)
+}
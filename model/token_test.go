@@ -0,0 +1,29 @@
+package model
+
+import "testing"
+
+func TestAccessTokenPermission(t *testing.T) {
+	tt := []struct {
+		scopes    []TokenScope
+		calendars []string
+		perm      Permission
+		want      Permission
+	}{
+		{scopes: []TokenScope{ScopeRead}, perm: Owner, want: Read},
+		{scopes: []TokenScope{ScopeRead}, perm: None, want: None},
+		// edit includes read, but never owner
+		{scopes: []TokenScope{ScopeEdit}, perm: Read, want: Read},
+		{scopes: []TokenScope{ScopeEdit}, perm: Owner, want: Edit},
+		{scopes: []TokenScope{ScopeShare}, perm: Owner, want: None},
+		// restricted to calendars
+		{scopes: []TokenScope{ScopeEdit}, calendars: []string{"a/a"}, perm: Edit, want: Edit},
+		{scopes: []TokenScope{ScopeEdit}, calendars: []string{"a/b"}, perm: Edit, want: None},
+	}
+
+	for _, tc := range tt {
+		tok := AccessToken{Scopes: tc.scopes, Calendars: tc.calendars}
+		if got := tok.Permission("a/a", tc.perm); got != tc.want {
+			t.Errorf("%v %v with %s: got: %s want: %s", tc.scopes, tc.calendars, tc.perm, got, tc.want)
+		}
+	}
+}
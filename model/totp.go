@@ -0,0 +1,27 @@
+package model
+
+import "encoding/xml"
+
+// TOTP is the time-based one-time password second factor of a login (RFC 6238)
+type TOTP struct {
+	// Secret is the base32 encoded shared secret, empty if no second factor is set up
+	Secret string `xml:"secret,attr,omitempty"`
+	// Enabled is set once the user has confirmed the enrollment with a valid code
+	Enabled bool `xml:"enabled,attr,omitempty"`
+	// LastStep is the time step of the last accepted code, so that codes can not be replayed
+	LastStep int64 `xml:"lastStep,attr,omitempty"`
+	// RecoveryCodes are the hashes of the unused one-time recovery codes
+	RecoveryCodes []Attribute `xml:"recovery"`
+}
+
+// TOTPView shows the state of the second factor to its user. Secret, URI and RecoveryCodes are only shown once,
+// during enrollment and its confirmation, respectively.
+type TOTPView struct {
+	XMLName           xml.Name `xml:"totp" json:"-"`
+	Enabled           bool     `xml:"enabled,attr" json:"enabled"`
+	RecoveryCodesLeft int      `xml:"recoveryCodesLeft,attr" json:"recoveryCodesLeft"`
+	Secret            string   `xml:"secret,omitempty" json:"secret,omitempty"`
+	// URI is the otpauth:// provisioning URI, usually shown as QR code
+	URI           string   `xml:"uri,omitempty" json:"uri,omitempty"`
+	RecoveryCodes []string `xml:"recoveryCode" json:"recoveryCodes,omitempty"`
+}
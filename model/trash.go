@@ -0,0 +1,79 @@
+package model
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// TrashEntry is a deleted item or calendar, kept in the trash of the owner of its calendar until Expires, so that the
+// owner can restore it. Only the field of the kind of the entry is set.
+type TrashEntry struct {
+	XMLName xml.Name `xml:"entry" json:"-"`
+	ID      string   `xml:"id,attr" json:"id"`
+	// Owner is the owner of the calendar, whose trash the entry is in
+	Owner string `xml:"owner,attr" json:"owner"`
+	// Calendar is the ID of the deleted calendar or of the calendar the deleted item has been in
+	Calendar string `xml:"calendar,attr" json:"calendar"`
+	// Kind and Item identify the deleted item, they are empty if the calendar itself has been deleted
+	Kind ItemKind `xml:"kind,attr,omitempty" json:"kind,omitempty"`
+	Item string   `xml:"item,attr,omitempty" json:"item,omitempty"`
+	// DeletedBy is who deleted the item or calendar
+	DeletedBy   string       `xml:"deletedBy,attr" json:"deletedBy"`
+	Deleted     time.Time    `xml:"deleted,attr" json:"deleted"`
+	Expires     time.Time    `xml:"expires,attr" json:"expires"`
+	Appointment *Appointment `xml:"appointment,omitempty" json:"appointment,omitempty"`
+	Milestone   *Milestone   `xml:"milestone,omitempty" json:"milestone,omitempty"`
+	Task        *Task        `xml:"task,omitempty" json:"task,omitempty"`
+	Content     *Calendar    `xml:"calendar,omitempty" json:"content,omitempty"`
+}
+
+// Trash is a list of trash entries, e.g. of a user
+type Trash struct {
+	XMLName xml.Name     `xml:"trash" json:"-"`
+	Entry   []TrashEntry `xml:"entry" json:"entries"`
+}
+
+func (e TrashEntry) String() string {
+	var parsed, _ = xml.MarshalIndent(e, "", "\t")
+	return string(parsed)
+}
+
+// TrashItem removes the item of the given kind and ID from the calendar and returns the entry keeping it. Tasks of the
+// calendar linked to a removed milestone are unlinked. Returns ErrNotFound if the calendar has no such item.
+func (c *Calendar) TrashItem(kind ItemKind, id string) (TrashEntry, error) {
+	var trash Calendar
+	if _, err := MoveItem(c, &trash, kind, id, false); err != nil {
+		return TrashEntry{}, err
+	}
+
+	e := TrashEntry{Owner: c.Owner.Val, Calendar: c.GetID(), Kind: kind, Item: id}
+	switch kind {
+	case KindAppointment:
+		e.Appointment = &trash.Items.Appointments.Appointment[0]
+	case KindMilestone:
+		e.Milestone = &trash.Items.Milestones.Milestone[0]
+	case KindTask:
+		e.Task = &trash.Items.Tasks.Task[0]
+	}
+	return e, nil
+}
+
+// RestoreItem adds the item kept by the entry to the calendar again. A task loses its milestone, if the calendar
+// doesn't have it anymore. Returns ErrNotFound if the entry keeps no item and ErrAlreadyExists if the calendar has an
+// item of this ID already.
+func (e TrashEntry) RestoreItem(c *Calendar) error {
+	var trash Calendar
+	switch {
+	case e.Kind == KindAppointment && e.Appointment != nil:
+		trash.Items.Appointments.Appointment = []Appointment{*e.Appointment}
+	case e.Kind == KindMilestone && e.Milestone != nil:
+		trash.Items.Milestones.Milestone = []Milestone{*e.Milestone}
+	case e.Kind == KindTask && e.Task != nil:
+		trash.Items.Tasks.Task = []Task{*e.Task}
+	default:
+		return ErrNotFound
+	}
+
+	_, err := MoveItem(&trash, c, e.Kind, e.Item, false)
+	return err
+}
@@ -0,0 +1,36 @@
+package model
+
+import "testing"
+
+func TestTrashItem(t *testing.T) {
+	var c Calendar
+	c.ID.Val, c.Owner.Val = "owner/project", "owner"
+	c.Items.Milestones.Milestone = []Milestone{{ID: "m1"}}
+	c.Items.Tasks.Task = []Task{{ID: "t1"}}
+	c.Items.Tasks.Task[0].Milestone.ID = "m1"
+
+	e, err := c.TrashItem(KindMilestone, "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Owner != "owner" || e.Calendar != "owner/project" || e.Milestone == nil || e.Milestone.ID != "m1" ||
+		e.Task != nil || e.Content != nil {
+		t.Errorf("expected an entry keeping m1, got %v", e)
+	}
+	if c.HasItem("m1") || c.Items.Tasks.Task[0].Milestone.ID != "" {
+		t.Errorf("expected m1 to be removed and t1 to be unlinked, got %v", c.Items)
+	}
+	if _, err := c.TrashItem(KindMilestone, "m1"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := e.RestoreItem(&c); err != nil || !c.HasItem("m1") {
+		t.Errorf("expected m1 to be restored, got %v", err)
+	}
+	if err := e.RestoreItem(&c); err != ErrAlreadyExists {
+		t.Errorf("expected ErrAlreadyExists, got %v", err)
+	}
+	if err := (TrashEntry{Kind: KindTask, Item: "t2"}).RestoreItem(&c); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for an entry without item, got %v", err)
+	}
+}
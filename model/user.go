@@ -7,7 +7,9 @@ import (
 type User struct {
 	XMLName xml.Name  `xml:"user"`
 	Name    Attribute `xml:"name"`
-	Items   Items     `xml:"items"`
+	// Profile holds the preferences of the user
+	Profile Profile `xml:"profile"`
+	Items   Items   `xml:"items"`
 }
 
 type Items struct {
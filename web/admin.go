@@ -0,0 +1,30 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+)
+
+// getLockoutsHandler lists all recorded lockouts for review by an admin
+func getLockoutsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	if !conf.isAdmin(userid) {
+		writeError(w, "only admins may review lockouts", http.StatusForbidden)
+		return
+	}
+
+	ls, err := db.GetLockouts()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.Lockouts{Lockout: ls}, "")
+}
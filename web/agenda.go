@@ -0,0 +1,88 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+	"time"
+)
+
+// getAgendaHandler merges the items of all calendars the user can see into one chronological timeline of a day,
+// week or month. The query parameters range (day, week, month) and date (yyyy-mm-dd) select the window, which
+// defaults to the current week. The filters of getCalendarHandler may be used as well.
+func getAgendaHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	v := r.URL.Query()
+
+	rng := model.AgendaRange(v.Get("range"))
+	if rng == "" {
+		rng = model.AgendaWeek
+	}
+
+	// today and the weeks are those of the user
+	p := profile(r)
+	day := time.Now().In(p.Location())
+	if d := v.Get("date"); d != "" {
+		var err error
+		if day, err = time.Parse("2006-01-02", d); err != nil {
+			writeError(w, "date not understood, must be formatted yyyy-mm-dd", http.StatusBadRequest)
+			return
+		}
+	}
+
+	a, err := model.NewAgenda(rng, day, p.FirstWeekday())
+	if err != nil {
+		writeError(w, "range not understood, must be one of day, week, month", http.StatusBadRequest)
+		return
+	}
+
+	q, err := model.NewCalendarQuery(r)
+	if err != nil {
+		writeError(w, "query not understood: kind must be one of appointment, milestone, task",
+			http.StatusBadRequest)
+		return
+	}
+	q.From, q.To = a.Window()
+
+	u, err := db.GetUser(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	refs, err := visibleCalendars(u)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	for _, ref := range refs {
+		c, err := db.QueryCalendar(ref.Link, q)
+		if err == model.ErrNotFound {
+			continue // dangling reference, nothing to show
+		} else if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		// templates are blueprints, their dates are no appointments
+		perm := calendarPermission(r, c, userid)
+		if perm < model.Read || c.Template {
+			continue
+		}
+
+		a.Add(c, perm)
+	}
+
+	a.Sort()
+
+	writeView(w, r, a, "/agenda.xsl")
+}
@@ -0,0 +1,71 @@
+<?xml version="1.0" encoding="UTF-8"?>
+<!-- Default agenda view, used unless the frontend ships its own data/agenda.xsl -->
+<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+    <xsl:output method="html" encoding="UTF-8" indent="yes"/>
+
+    <xsl:template match="/agenda">
+        <html>
+            <head>
+                <meta charset="UTF-8"/>
+                <title>Agenda</title>
+            </head>
+            <body>
+                <h1>
+                    <xsl:text>Agenda of </xsl:text>
+                    <xsl:value-of select="$display_name"/>
+                </h1>
+                <p>
+                    <xsl:value-of select="@from"/>
+                    <xsl:if test="@to != @from">
+                        <xsl:text> – </xsl:text>
+                        <xsl:value-of select="@to"/>
+                    </xsl:if>
+                </p>
+                <xsl:choose>
+                    <xsl:when test="entry">
+                        <table class="agenda">
+                            <tr>
+                                <th>Start</th>
+                                <th>End</th>
+                                <th>Name</th>
+                                <th>Kind</th>
+                                <th>Calendar</th>
+                            </tr>
+                            <xsl:apply-templates select="entry"/>
+                        </table>
+                    </xsl:when>
+                    <xsl:otherwise>
+                        <p>Nothing planned.</p>
+                    </xsl:otherwise>
+                </xsl:choose>
+            </body>
+        </html>
+    </xsl:template>
+
+    <xsl:template match="entry">
+        <tr class="{@kind}">
+            <td>
+                <xsl:value-of select="startDate"/>
+                <xsl:text> </xsl:text>
+                <xsl:value-of select="startTime"/>
+            </td>
+            <td>
+                <xsl:value-of select="endDate"/>
+                <xsl:text> </xsl:text>
+                <xsl:value-of select="endTime"/>
+            </td>
+            <td title="{desc}">
+                <xsl:value-of select="name"/>
+            </td>
+            <td>
+                <xsl:value-of select="@kind"/>
+            </td>
+            <td>
+                <xsl:value-of select="@calendar"/>
+                <xsl:if test="@perm = 'view'">
+                    <xsl:text> (read only)</xsl:text>
+                </xsl:if>
+            </td>
+        </tr>
+    </xsl:template>
+</xsl:stylesheet>
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/Project-Planner/backend/model"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetAgendaHandler(t *testing.T) {
+	own := defCalendar
+	own.Items.Tasks.Task = []model.Task{{ID: "task", Duedate: model.Attribute{Val: "17.06.2021"}}}
+
+	shared := defCalendar
+	shared.Name.Val = "shared"
+	shared.Owner.Val = userEdit
+	shared.ID.Val = userEdit + "/shared"
+	shared.Items.Appointments.Appointment = []model.Appointment{
+		{ID: "appointment", StartDate: model.Attribute{Val: "15.06.2021"}, EndDate: model.Attribute{Val: "15.06.2021"}},
+	}
+
+	var queried model.CalendarQuery
+	calendars := map[string]model.Calendar{own.GetID(): own, shared.GetID(): shared}
+	agendaDB := dbMock{
+		queryCalendar: func(id string, q model.CalendarQuery) (model.Calendar, error) {
+			queried = q
+			c, ok := calendars[id]
+			if !ok {
+				return model.Calendar{}, model.ErrNotFound
+			}
+			return c, nil
+		},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{
+			"GetUser": {d: model.User{Items: model.Items{Calendars: []model.CalendarReference{
+				{Link: own.GetID()},
+				{Link: shared.GetID()},
+				{Link: "dangling/reference"},
+			}}}},
+		},
+	}
+
+	tt := []struct {
+		query   string
+		authed  string
+		profile model.Profile
+		code    int
+		want    []string // ids of the entries
+		from    string
+	}{
+		// Kosher case
+		{query: "?date=2021-06-16&format=json", authed: userView, code: http.StatusOK,
+			want: []string{"appointment", "task"}, from: "14.06.2021"},
+		// Calendars without permission are left out
+		{query: "?date=2021-06-16&format=json", authed: testOwner, code: http.StatusOK,
+			want: []string{"task"}, from: "14.06.2021"},
+		// Weeks start as set in the profile
+		{query: "?date=2021-06-16&format=json", authed: userView, profile: model.Profile{WeekStart: "sunday"},
+			code: http.StatusOK, want: []string{"appointment", "task"}, from: "13.06.2021"},
+		// Month
+		{query: "?date=2021-06-16&range=month&format=json", authed: userView, code: http.StatusOK,
+			want: []string{"appointment", "task"}, from: "01.06.2021"},
+		// XML
+		{query: "?date=2021-06-16", authed: testOwner, code: http.StatusOK},
+		// Bad range
+		{query: "?range=year", authed: testOwner, code: http.StatusBadRequest},
+		// Bad date
+		{query: "?date=tomorrow", authed: testOwner, code: http.StatusBadRequest},
+		// Unauthorized
+		{code: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tt {
+		db = agendaDB
+
+		r, err := http.NewRequest("GET", "/agenda"+tc.query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.authed != "" {
+			r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.authed))
+		}
+		r = r.WithContext(context.WithValue(r.Context(), profileStr, tc.profile))
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(getAgendaHandler).ServeHTTP(rr, r)
+
+		if rr.Code != tc.code {
+			t.Fatalf("wrong status code: got: %d want: %d \n%s\n%v", rr.Code, tc.code, rr.Body.String(), tc)
+		}
+
+		if tc.code == http.StatusOK && tc.want == nil {
+			if !strings.Contains(rr.Body.String(), conf.AuthedPathName+"/agenda.xsl?") {
+				t.Errorf("stylesheet missing in %s", rr.Body.String())
+			}
+		}
+		if tc.want == nil {
+			continue
+		}
+
+		var a model.Agenda
+		if err := json.Unmarshal(rr.Body.Bytes(), &a); err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for _, e := range a.Entries {
+			ids = append(ids, e.ID)
+		}
+		if strings.Join(ids, " ") != strings.Join(tc.want, " ") || a.From != tc.from {
+			t.Errorf("got entries %v from %s want %v from %s", ids, a.From, tc.want, tc.from)
+		}
+
+		if from, _ := a.Window(); !queried.From.Equal(from) || queried.To.Before(from.Add(24*time.Hour)) {
+			t.Errorf("calendars not queried for the window of the agenda: %+v", queried)
+		}
+	}
+}
+
+func TestDefaultAgendaView(t *testing.T) {
+	// the variables are injected after the opening tag of the stylesheet, which has to stay well-formed
+	xsl := varsIntoXSL(defaultAgenda, varXLS{name: "display_name", value: "Someone"})
+	d := xml.NewDecoder(strings.NewReader(xsl))
+	for {
+		if _, err := d.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("default agenda view not well-formed: %v\n%s", err, xsl)
+		}
+	}
+	if !strings.Contains(xsl, `<xsl:stylesheet version="1.0" xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
+<xsl:variable name="display_name"`) {
+		t.Errorf("variables not injected into the stylesheet:\n%s", xsl)
+	}
+}
@@ -18,7 +18,7 @@ func postAppointmentHandler(w http.ResponseWriter, r *http.Request) {
 
 	c.Items.Appointments.Appointment = append(c.Items.Appointments.Appointment, i)
 
-	finishItem(w, r, c)
+	finishItem(w, r, c, itemAuditEntry(r, c.GetID(), model.KindAppointment, i.ID, model.AuditCreate, nil, i))
 }
 
 func putAppointmentHandler(w http.ResponseWriter, r *http.Request) {
@@ -41,33 +41,15 @@ func putAppointmentHandler(w http.ResponseWriter, r *http.Request) {
 		return // err reporting already done by method call
 	}
 
+	before := items[idx]
 	items[idx].Update(a)
 
-	finishItem(w, r, c)
+	e := itemAuditEntry(r, c.GetID(), model.KindAppointment, before.ID, model.AuditUpdate, before, items[idx])
+	finishItem(w, r, c, e)
 }
 
 func deleteAppointmentHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := getCalendarIfPermission(w, r, model.Edit)
-	if err != nil {
-		// err reporting already done by method call
-		return
-	}
-
-	items := c.Items.Appointments.Appointment
-
-	ids := make([]model.Identifier, len(items))
-	for i, v := range items {
-		ids[i] = v
-	}
-	idx, err := itemIdx(w, r, ids...)
-	if err != nil {
-		return // err reporting already done by method call
-	}
-
-	items[idx] = items[len(items)-1]
-	c.Items.Appointments.Appointment = items[:len(items)-1]
-
-	finishItem(w, r, c)
+	trashItem(w, r, model.KindAppointment)
 }
 
 //preparePostItem handles error reporting and just returns an error to indicate to return early.
@@ -94,10 +76,21 @@ func preparePutItem(w http.ResponseWriter, r *http.Request, err error) (model.Ca
 	}
 
 	// get calendar, must be able to edit
-	return getCalendarIfPermission(w, r, model.Edit)
+	c, err := getCalendarIfPermission(w, r, model.Edit)
+	if err != nil {
+		return c, err
+	}
+
+	// items are updated in place, so they are copied: the calendar of the database must be kept as it is until set,
+	// as it is the revision replaced
+	c.Items.Appointments.Appointment = append([]model.Appointment(nil), c.Items.Appointments.Appointment...)
+	c.Items.Milestones.Milestone = append([]model.Milestone(nil), c.Items.Milestones.Milestone...)
+	c.Items.Tasks.Task = append([]model.Task(nil), c.Items.Tasks.Task...)
+	return c, nil
 }
 
-func finishItem(w http.ResponseWriter, r *http.Request, c model.Calendar) {
+// finishItem writes the changed calendar c and records the change e in its audit log
+func finishItem(w http.ResponseWriter, r *http.Request, c model.Calendar, e model.AuditEntry) {
 	err := db.SetCalendar(c.ID.Val, c)
 	if err == model.ErrNotFound {
 		writeError(w, "calendar " + c.ID.Val + " does not exist", http.StatusNotFound)
@@ -107,6 +100,7 @@ func finishItem(w http.ResponseWriter, r *http.Request, c model.Calendar) {
 		writeError(w, "", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(e)
 
 	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
 }
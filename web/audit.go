@@ -0,0 +1,117 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"time"
+)
+
+// getAuditLogHandler lists who changed the calendar and its items when, and how, the most recent first. The query
+// parameters user, item, from and to filter the entries. Only the owner may review the log of a calendar.
+func getAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := getCalendarIfPermission(w, r, model.Owner)
+	if err != nil {
+		return
+	}
+
+	q, err := model.NewAuditQuery(r)
+	if err != nil {
+		writeError(w, "query not understood: from and to must be formatted yyyy-mm-dd or as RFC 3339 timestamps",
+			http.StatusBadRequest)
+		return
+	}
+
+	es, err := db.GetAuditLog(c.GetID(), q)
+	if err == model.ErrNotFound {
+		writeError(w, "calendar not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.AuditLog{Entry: es}, "")
+}
+
+// getDeletedAuditLogsHandler lists the audit logs of the calendars of the logged in user which have been deleted
+// for good
+func getDeletedAuditLogsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	ls, err := db.GetDeletedAuditLogs(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.DeletedAuditLogs{Log: ls}, "")
+}
+
+// getDeletedAuditLogHandler shows the audit log of a calendar of the logged in user which has been deleted for good,
+// so that the owner finds out who made it disappear
+func getDeletedAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	q, err := model.NewAuditQuery(r)
+	if err != nil {
+		writeError(w, "query not understood: from and to must be formatted yyyy-mm-dd or as RFC 3339 timestamps",
+			http.StatusBadRequest)
+		return
+	}
+
+	es, err := db.GetDeletedAuditLog(userid, mux.Vars(r)[deletedLogIDStr], q)
+	if err == model.ErrNotFound {
+		writeError(w, "deleted audit log not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.AuditLog{Entry: es}, "")
+}
+
+// auditEntry returns the entry recording that the logged in user changed the calendar calID from before to after,
+// which are calendars without their items
+func auditEntry(r *http.Request, calID string, action model.AuditAction, before, after interface{}) model.AuditEntry {
+	userid, _ := r.Context().Value(userIDStr).(string)
+	return model.AuditEntry{
+		Time:     time.Now(),
+		User:     userid,
+		Calendar: calID,
+		Action:   action,
+		Changes:  model.Diff(before, after),
+	}
+}
+
+// itemAuditEntry returns the entry recording that the logged in user changed the item of the given kind and ID in
+// the calendar calID from before to after. Either is nil if the item has been created or deleted.
+func itemAuditEntry(r *http.Request, calID string, kind model.ItemKind, itemID string, action model.AuditAction,
+	before, after interface{}) model.AuditEntry {
+	e := auditEntry(r, calID, action, before, after)
+	e.Kind, e.Item = kind, itemID
+	return e
+}
+
+// recordAudit appends the entries to the audit logs of their calendars. Failures are only logged, as the changes
+// have been made already.
+func recordAudit(es ...model.AuditEntry) {
+	for _, e := range es {
+		if err := db.AddAuditEntry(e); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	owner, editor := "someowner", "someeditor"
+	c := model.Calendar{Name: model.Attribute{Val: "project"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/project"}}
+	c.Permissions.Edit.User = []model.Attribute{{Val: editor}}
+	c.Items.Tasks.Task = []model.Task{{ID: "t1", Name: model.Attribute{Val: "Write docs"}}, {ID: "t2"}}
+
+	var entries []model.AuditEntry
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		groups:    map[string]model.Group{},
+		trash:     map[string]model.TrashEntry{},
+		audit:     &entries,
+	}
+	mock.setCalendar = func(id string, c model.Calendar) error {
+		mock.calendars[id] = c
+		return nil
+	}
+	db = mock
+
+	request := func(method, user, item string, form url.Values) *http.Request {
+		r := httptest.NewRequest(method, "/", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		return mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "project", itemIDStr: item})
+	}
+
+	// the editor renames one task and deletes the other, which the owner finds in the log
+	rr := httptest.NewRecorder()
+	putTaskHandler(rr, request("PUT", editor, "t1", url.Values{"name": {"Write the docs"}}))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+	}
+	rr = httptest.NewRecorder()
+	deleteTaskHandler(rr, request("DELETE", editor, "t2", nil))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries, got %v", entries)
+	}
+	if e := entries[0]; e.User != editor || e.Calendar != c.GetID() || e.Action != model.AuditUpdate ||
+		e.Kind != model.KindTask || e.Item != "t1" || len(e.Changes) != 1 ||
+		e.Changes[0] != (model.Change{Field: "name", Before: "Write docs", After: "Write the docs"}) {
+		t.Errorf("update not recorded correctly: %v", e)
+	}
+	if e := entries[1]; e.Action != model.AuditDelete || e.Item != "t2" {
+		t.Errorf("delete not recorded correctly: %v", e)
+	}
+
+	tt := []struct {
+		user  string
+		query string
+		code  int
+		items []string
+	}{
+		{user: owner, code: http.StatusOK, items: []string{"t2", "t1"}},
+		{user: owner, query: "item=t1", code: http.StatusOK, items: []string{"t1"}},
+		{user: owner, query: "user=someoneelse", code: http.StatusOK},
+		{user: owner, query: "from=yesterday", code: http.StatusBadRequest},
+		// editors may change the calendar, but not review who did
+		{user: editor, code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		r := request("GET", tc.user, "", nil)
+		r.URL.RawQuery = tc.query
+		r.Header.Set("Accept", "application/json")
+		rr := httptest.NewRecorder()
+		getAuditLogHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+			continue
+		} else if rr.Code != http.StatusOK {
+			continue
+		}
+
+		var log model.AuditLog
+		if err := json.Unmarshal(rr.Body.Bytes(), &log); err != nil {
+			t.Fatal(err)
+		}
+		var items []string
+		for _, e := range log.Entry {
+			items = append(items, e.Item)
+		}
+		if strings.Join(items, ",") != strings.Join(tc.items, ",") {
+			t.Errorf("%d: expected entries of %v, got %v", i, tc.items, items)
+		}
+	}
+}
+
+func TestDeletedAuditLog(t *testing.T) {
+	owner := "someowner"
+	deleted := model.AuditEntry{User: "someeditor", Calendar: owner + "/project", Action: model.AuditDelete}
+	db = dbMock{deletedLogs: map[string]map[string][]model.AuditEntry{owner: {"project.1": {deleted}}}}
+
+	request := func(user, id string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", "application/json")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		return mux.SetURLVars(r, map[string]string{deletedLogIDStr: id})
+	}
+
+	rr := httptest.NewRecorder()
+	getDeletedAuditLogsHandler(rr, request(owner, ""))
+	var logs model.DeletedAuditLogs
+	if err := json.Unmarshal(rr.Body.Bytes(), &logs); err != nil || len(logs.Log) != 1 ||
+		logs.Log[0].Calendar != owner+"/project" {
+		t.Fatalf("deleted logs not listed correctly: %s %v", rr.Body.String(), err)
+	}
+
+	// the owner finds out who made the calendar disappear, nobody else finds the log
+	for i, tc := range []struct {
+		user string
+		code int
+	}{{user: owner, code: http.StatusOK}, {user: "someeditor", code: http.StatusNotFound}} {
+		rr := httptest.NewRecorder()
+		getDeletedAuditLogHandler(rr, request(tc.user, "project.1"))
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+			continue
+		} else if rr.Code != http.StatusOK {
+			continue
+		}
+
+		var log model.AuditLog
+		if err := json.Unmarshal(rr.Body.Bytes(), &log); err != nil || len(log.Entry) != 1 ||
+			log.Entry[0].User != "someeditor" {
+			t.Errorf("%d: deleted log not shown correctly: %s %v", i, rr.Body.String(), err)
+		}
+	}
+}
+
+func TestCalendarChangesAudited(t *testing.T) {
+	owner, heir := "someowner", "someheir"
+	c := model.Calendar{Name: model.Attribute{Val: "typo"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/typo"}}
+	g := model.NewGroup("team", owner)
+	g.Calendars = []model.CalendarReference{{Link: owner + "/fixed"}}
+
+	var entries []model.AuditEntry
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		users:     map[string]model.User{owner: model.NewUser(owner), heir: model.NewUser(heir)},
+		groups:    map[string]model.Group{g.ID: g},
+		audit:     &entries,
+	}
+	mock.setCalendar = func(id string, c model.Calendar) error {
+		mock.calendars[id] = c
+		return nil
+	}
+	mock.renameCalendar = func(calID, newName string, redirectUntil time.Time) error {
+		c := mock.calendars[calID]
+		delete(mock.calendars, calID)
+		c.Name.Val, c.ID.Val = newName, c.Owner.Val+"/"+newName
+		mock.calendars[c.GetID()] = c
+		return nil
+	}
+	mock.transferCalendar = func(calID, newOwner, newName string, keep model.Permission) error {
+		c := mock.calendars[calID]
+		delete(mock.calendars, calID)
+		c.Name.Val, c.Owner.Val, c.ID.Val = newName, newOwner, newOwner+"/"+newName
+		mock.calendars[c.GetID()] = c
+		return nil
+	}
+	db = mock
+
+	do := func(h http.HandlerFunc, user string, vars map[string]string, form url.Values) {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		rr := httptest.NewRecorder()
+		h(rr, mux.SetURLVars(r, vars))
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+		}
+	}
+
+	// the membership of the group is changed while the calendar is shared with it, which changes who may access it
+	do(postGroupMemberHandler, owner, map[string]string{groupIDStr: "team"}, url.Values{"user": {heir}})
+	do(deleteGroupMemberHandler, owner, map[string]string{groupIDStr: "team", memberIDStr: heir}, nil)
+	do(renameCalendarHandler, owner, map[string]string{userIDStr: owner, calendarIDStr: "typo"},
+		url.Values{"name": {"fixed"}})
+	do(cloneCalendarHandler, owner, map[string]string{userIDStr: owner, calendarIDStr: "fixed"},
+		url.Values{"name": {"copy"}})
+	do(transferCalendarHandler, owner, map[string]string{userIDStr: owner, calendarIDStr: "fixed"},
+		url.Values{"owner": {heir}})
+	do(deleteGroupHandler, owner, map[string]string{groupIDStr: "team"}, nil)
+
+	want := []struct {
+		calendar string
+		action   model.AuditAction
+		change   model.Change
+	}{
+		{owner + "/fixed", model.AuditShare, model.Change{Field: "group.team.member[0]", After: heir}},
+		{owner + "/fixed", model.AuditShare, model.Change{Field: "group.team.member[0]", Before: heir}},
+		{owner + "/fixed", model.AuditRename, model.Change{Field: "id", Before: owner + "/typo", After: owner + "/fixed"}},
+		{owner + "/copy", model.AuditCreate, model.Change{Field: "id", After: owner + "/copy"}},
+		{heir + "/fixed", model.AuditTransfer, model.Change{Field: "id", Before: owner + "/fixed", After: heir + "/fixed"}},
+		{owner + "/fixed", model.AuditShare, model.Change{Field: "group.team.admin[0]", Before: owner}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %v", len(want), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		found := false
+		for _, ch := range e.Changes {
+			found = found || ch == w.change
+		}
+		if e.User != owner || e.Calendar != w.calendar || e.Action != w.action || !found {
+			t.Errorf("%d: expected %s of %s with %v, got %v", i, w.action, w.calendar, w.change, e)
+		}
+	}
+}
@@ -3,9 +3,9 @@ package web
 import (
 	"errors"
 	"github.com/Project-Planner/backend/model"
-	"golang.org/x/crypto/bcrypt"
 	"log"
 	"net/http"
+	"net/mail"
 	"time"
 )
 
@@ -17,8 +17,15 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	errIncorrect := errors.New("username or password incorrect")
 
+	// failed attempts are delayed exponentially, per account and per IP address
+	if wait := loginWait(r, username); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
 	l, err := db.GetLogin(username)
 	if err == model.ErrNotFound {
+		loginFailed(r, username)
 		writeError(w, errIncorrect.Error(), http.StatusUnauthorized)
 		return
 	} else if err != nil {
@@ -27,43 +34,42 @@ func loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(l.Hash.Val), []byte(pw)); err != nil {
+	if err := comparePassword(r.Context(), l.Hash.Val, pw); err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		loginFailed(r, username)
 		writeError(w, errIncorrect.Error(), http.StatusUnauthorized)
 		return
 	}
+	loginSucceeded(username)
 
-	t, err := createToken(username)
+	// users with a second factor only get the password verified token, see loginMFAHandler
+	location, err := startLogin(w, r, l)
 	if err != nil {
 		writeError(w, "", http.StatusInternalServerError)
 		log.Println(err)
 		return
 	}
 
-	c := http.Cookie{
-		Name:     authStr,
-		Value:    t,
-		Expires:  time.Now().Add(jwtDuration),
-		HttpOnly: true,
-		Path: conf.AuthedPathName,
-	}
-	http.SetCookie(w, &c)
-
-	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+	http.Redirect(w, r, location, http.StatusSeeOther)
 }
 
 func logoutHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := r.Cookie(authStr)
-	if err != nil {
-		writeError(w, "no authentication token (jwt) provided, please log in.\n"+err.Error(),
-			http.StatusUnauthorized)
-		return
+	// revoke the session, so that the token can not be used anymore
+	if tokenID, ok := r.Context().Value(tokenIDStr).(string); ok {
+		if err := db.DeleteSession(tokenID); err != nil && err != model.ErrNotFound {
+			log.Println(err)
+		}
 	}
 
-	deleteCookie(w, c)
+	deleteAuthCookies(w)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// deleteUserHandler deletes the account of the logged in user, which is kept for the configured delay. Logging in
+// again within the delay cancels the deletion.
 func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 	userid, ok := r.Context().Value(userIDStr).(string)
 	if !ok {
@@ -71,21 +77,13 @@ func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := db.DeleteUser(userid)
-	if err != nil {
+	if err := scheduleDeletion(userid); err != nil {
 		log.Println(err)
 		writeError(w, "", http.StatusInternalServerError)
 		return
 	}
 
-	c, err := r.Cookie(authStr)
-	if err != nil {
-		writeError(w, "no authentication token (jwt) provided, please log in.\n"+err.Error(),
-			http.StatusUnauthorized)
-		return
-	}
-
-	deleteCookie(w, c)
+	deleteAuthCookies(w)
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -96,6 +94,12 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// registrations are delayed exponentially per IP address, as hashing the password is expensive
+	if wait := logins.wait(conf.LoginThrottle.withDefaults(), string(model.LockoutRegister)+":"+clientIP(r)); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
 	_, err = db.GetLogin(username)
 	if err != nil && err != model.ErrNotFound {
 		writeError(w, "", http.StatusInternalServerError)
@@ -111,38 +115,57 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(pw), 14)
-	if err != nil {
+	// don't do anything for the optional field
+	email := r.Form.Get("email")
+	if email != "" {
+		if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+			writeError(w, "illegal email address", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	hashed, err := hashPassword(r.Context(), pw)
+	if err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
 		writeError(w, "", http.StatusInternalServerError)
 		log.Println(err)
 		return
 	}
+	recordFailure(r, model.LockoutRegister, clientIP(r), conf.LoginThrottle.withDefaults().IPLockoutThreshold)
 
-	if err := db.AddUser(username, string(hashed)); err != nil {
+	if err := db.AddUser(username, hashed); err != nil {
 		writeError(w, "", http.StatusInternalServerError)
 		log.Println(err)
 		return
 	}
 
-	t, err := createToken(username)
-	if err != nil {
+	if email != "" {
+		if err := setEmail(username, email); err != nil {
+			writeError(w, "", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+	}
+
+	if err := setAuthCookie(w, r, username); err != nil {
 		writeError(w, "", http.StatusInternalServerError)
 		log.Println(err)
 		return
 	}
 
-	c := http.Cookie{
-		Name:     authStr,
-		Value:    t,
-		Expires:  time.Now().Add(jwtDuration),
-		HttpOnly: true,
-		Path: conf.AuthedPathName,
-	}
-	http.SetCookie(w, &c)
-
 	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
 }
 
+// setEmail stores the email address in the login of the user
+func setEmail(userid, email string) error {
+	return db.UpdateLogin(userid, func(l *model.Login) error {
+		l.Email.Val = email
+		return nil
+	})
+}
+
 func parseForm(w http.ResponseWriter, r *http.Request) (username string, pw string, error error) {
 	error = errors.New("parsing failed")
 
@@ -176,6 +199,7 @@ func deleteCookie(w http.ResponseWriter, c *http.Cookie) {
 		Expires:  time.Now().Add(-7 * 24 * time.Hour), // THIS DELETES THE COOKIE
 		MaxAge:   -1,                                  // Tells browser to delete cookie NOW, but doesn't work with IE, hence 'Expires'
 		HttpOnly: c.HttpOnly,
+		Secure:   c.Secure,
 		SameSite: c.SameSite,
 	}
 
@@ -9,14 +9,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoginHandler(t *testing.T) {
-	conf = ServerConfig{
-		JWTSecret: "some%secret",
-	}
+	useConfig(t, ServerConfig{
+		JWTSecret: testSecret,
+	})
 
 	hash := func(pw string) string {
 		hashedPw, err := bcrypt.GenerateFromPassword([]byte(pw), 12)
@@ -171,6 +173,9 @@ func TestLoginHandler(t *testing.T) {
 	}
 
 	for _, tc := range tt {
+		// failed attempts of previous cases must not throttle this one
+		logins = newThrottle()
+
 		db = tc.db
 
 		data := url.Values{}
@@ -220,6 +225,10 @@ func TestLoginHandler(t *testing.T) {
 }
 
 func TestRegisterHandler(t *testing.T) {
+	useConfig(t, ServerConfig{
+		JWTSecret: testSecret,
+	})
+
 	un := "nickname"
 	pw := "mypw"
 
@@ -292,6 +301,9 @@ func TestRegisterHandler(t *testing.T) {
 	}
 
 	for _, tc := range tt {
+		// failed attempts of previous cases must not throttle this one
+		logins = newThrottle()
+
 		db = tc.db
 
 		data := url.Values{}
@@ -316,7 +328,44 @@ func TestRegisterHandler(t *testing.T) {
 }
 
 type dbMock struct {
-	setCalendar func(string, model.Calendar) error
+	setCalendar   func(string, model.Calendar) error
+	queryCalendar func(string, model.CalendarQuery) (model.Calendar, error)
+	// transferCalendar is called by TransferCalendar, if not nil
+	transferCalendar func(calendarid, newOwner, newName string, keep model.Permission) error
+	// renameCalendar is called by RenameCalendar, if not nil
+	renameCalendar func(calendarid, newName string, redirectUntil time.Time) error
+	// calendars are used by GetCalendar instead of data, created by AddCalendar and changed by MoveItem and
+	// CopyItem, if not nil
+	calendars map[string]model.Calendar
+	// aliases are used by GetCalendarAlias, if not nil
+	aliases map[string]model.CalendarAlias
+	// sessions are stored by AddSession, if not nil
+	sessions map[string]model.Session
+	// logins are used by GetLogin and SetLogin instead of data, if not nil
+	logins map[string]model.Login
+	// resets are stored by AddResetToken, if not nil
+	resets map[string]model.ResetToken
+	// lockouts are recorded by AddLockout, if not nil
+	lockouts *[]model.Lockout
+	// audit entries are recorded by AddAuditEntry, if not nil
+	audit *[]model.AuditEntry
+	// deletedLogs are used by GetDeletedAuditLogs and GetDeletedAuditLog, keyed by user and then by log ID
+	deletedLogs map[string]map[string][]model.AuditEntry
+	// revisions are used by GetRevisions, GetRevision and RollbackCalendar, keyed by calendar ID
+	revisions map[string][]model.Revision
+	// trash entries are stored by TrashItem and TrashCalendar and used by GetTrash, RestoreTrash and PurgeTrash,
+	// keyed by entry ID, if not nil
+	trash map[string]model.TrashEntry
+	// tokens are stored by AddAccessToken, if not nil
+	tokens map[string]model.AccessToken
+	// users are used by GetUser and SetProfile instead of data, if not nil
+	users map[string]model.User
+	// groups are stored by AddGroup, if not nil
+	groups map[string]model.Group
+	// invitations are stored by AddInvitation, if not nil
+	invitations map[string]model.Invitation
+	// links are stored by AddShareLink, if not nil
+	links map[string]model.ShareLink
 	data        map[string]struct {
 		d interface{}
 		e error
@@ -324,7 +373,17 @@ type dbMock struct {
 }
 
 func (d dbMock) AddCalendar(ownerID, calName string) error {
-	panic("implement me")
+	if d.calendars == nil {
+		panic("implement me")
+	}
+
+	id := ownerID + "/" + calName
+	if _, ok := d.calendars[id]; ok {
+		return model.ErrAlreadyExists
+	}
+	d.calendars[id] = model.Calendar{Name: model.Attribute{Val: calName}, Owner: model.Attribute{Val: ownerID},
+		ID: model.Attribute{Val: id}}
+	return nil
 }
 
 func (d dbMock) SetUser(userid string, user model.User) error {
@@ -332,17 +391,249 @@ func (d dbMock) SetUser(userid string, user model.User) error {
 }
 
 func (d dbMock) DeleteUser(userid string) error {
-	panic("implement me")
+	if _, ok := d.users[userid]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.users, userid)
+	delete(d.logins, userid)
+	return nil
+}
+
+func (d dbMock) GetScheduledDeletions(t time.Time) ([]string, error) {
+	var res []string
+	for userid, l := range d.logins {
+		if l.Deletion != nil && !l.Deletion.After(t) {
+			res = append(res, userid)
+		}
+	}
+	return res, nil
 }
 
 func (d dbMock) GetUser(userid string) (model.User, error) {
-	panic("implement me")
+	if d.users != nil {
+		u, ok := d.users[userid]
+		if !ok {
+			return model.User{}, model.ErrNotFound
+		}
+		return u, nil
+	}
+
+	e := d.data["GetUser"].e
+	if e != nil {
+		return model.User{}, e
+	}
+
+	u, ok := d.data["GetUser"].d.(model.User)
+	if !ok {
+		return model.User{}, model.ErrNotFound
+	}
+	return u, e
 }
 
 func (d dbMock) DeleteCalendar(calendarid string) error {
 	panic("implement me")
 }
 
+func (d dbMock) TransferCalendar(calendarid, newOwner, newName string, keep model.Permission) error {
+	if d.transferCalendar != nil {
+		return d.transferCalendar(calendarid, newOwner, newName, keep)
+	}
+
+	return d.data["TransferCalendar"].e
+}
+
+func (d dbMock) RenameCalendar(calendarid, newName string, redirectUntil time.Time) error {
+	if d.renameCalendar != nil {
+		return d.renameCalendar(calendarid, newName, redirectUntil)
+	}
+
+	return d.data["RenameCalendar"].e
+}
+
+func (d dbMock) MoveItem(fromCalendar, toCalendar string, kind model.ItemKind, itemid string) error {
+	_, err := d.relocateItem(fromCalendar, toCalendar, kind, itemid, false)
+	return err
+}
+
+func (d dbMock) CopyItem(fromCalendar, toCalendar string, kind model.ItemKind, itemid string) (string, error) {
+	return d.relocateItem(fromCalendar, toCalendar, kind, itemid, true)
+}
+
+// relocateItem moves or copies an item between the stored calendars
+func (d dbMock) relocateItem(fromCalendar, toCalendar string, kind model.ItemKind, itemid string,
+	copy bool) (string, error) {
+	src, ok := d.calendars[fromCalendar]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+	dst, ok := d.calendars[toCalendar]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+
+	id, err := model.MoveItem(&src, &dst, kind, itemid, copy)
+	if err != nil {
+		return "", err
+	}
+	d.calendars[fromCalendar], d.calendars[toCalendar] = src, dst
+	return id, nil
+}
+
+func (d dbMock) AddAuditEntry(e model.AuditEntry) error {
+	if d.audit != nil {
+		*d.audit = append(*d.audit, e)
+	}
+	return nil
+}
+
+func (d dbMock) GetAuditLog(calendarid string, q model.AuditQuery) ([]model.AuditEntry, error) {
+	if d.audit == nil {
+		return nil, nil
+	}
+
+	var res []model.AuditEntry
+	for i := len(*d.audit) - 1; i >= 0; i-- {
+		if e := (*d.audit)[i]; e.Calendar == calendarid && q.Matches(e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) GetDeletedAuditLogs(userid string) ([]model.DeletedAuditLog, error) {
+	var res []model.DeletedAuditLog
+	for id, es := range d.deletedLogs[userid] {
+		res = append(res, model.DeletedAuditLog{ID: id, Calendar: es[0].Calendar, Deleted: es[0].Time})
+	}
+	return res, nil
+}
+
+func (d dbMock) GetDeletedAuditLog(userid, logid string, q model.AuditQuery) ([]model.AuditEntry, error) {
+	es, ok := d.deletedLogs[userid][logid]
+	if !ok {
+		return nil, model.ErrNotFound
+	}
+
+	var res []model.AuditEntry
+	for _, e := range es {
+		if q.Matches(e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) GetRevisions(calendarid string) ([]model.Revision, error) {
+	return d.revisions[calendarid], nil
+}
+
+func (d dbMock) GetRevision(calendarid, revisionid string) (model.Revision, error) {
+	for _, rev := range d.revisions[calendarid] {
+		if rev.ID == revisionid {
+			return rev, nil
+		}
+	}
+	return model.Revision{}, model.ErrNotFound
+}
+
+// RollbackCalendar retains revisions for the default duration of the database
+func (d dbMock) RollbackCalendar(calendarid string, t time.Time) error {
+	c, ok := d.calendars[calendarid]
+	if !ok {
+		return model.ErrNotFound
+	} else if time.Since(t) > 30*24*time.Hour {
+		return model.ErrExpired
+	}
+
+	d.calendars[calendarid] = c.AsOf(d.revisions[calendarid], t)
+	return nil
+}
+
+// TrashItem names the entry after the item and keeps it for the default duration of the database
+func (d dbMock) TrashItem(calendarid string, kind model.ItemKind, itemid, userid string) error {
+	c, ok := d.calendars[calendarid]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	e, err := c.TrashItem(kind, itemid)
+	if err != nil {
+		return err
+	}
+	e.ID, e.DeletedBy, e.Deleted, e.Expires = "trash-"+itemid, userid, time.Now(), time.Now().AddDate(0, 0, 30)
+	d.trash[e.ID] = e
+	d.calendars[calendarid] = c
+	return nil
+}
+
+// TrashCalendar names the entry after the calendar and keeps it for the default duration of the database
+func (d dbMock) TrashCalendar(calendarid, userid string) error {
+	c, ok := d.calendars[calendarid]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	d.trash["trash-"+calendarid] = model.TrashEntry{ID: "trash-" + calendarid, Owner: c.Owner.Val,
+		Calendar: calendarid, DeletedBy: userid, Deleted: time.Now(), Expires: time.Now().AddDate(0, 0, 30),
+		Content: &c}
+	delete(d.calendars, calendarid)
+	return nil
+}
+
+func (d dbMock) GetTrash(userid string) ([]model.TrashEntry, error) {
+	var res []model.TrashEntry
+	for _, e := range d.trash {
+		if e.Owner == userid && e.Expires.After(time.Now()) {
+			res = append(res, e)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Deleted.After(res[j].Deleted) })
+	return res, nil
+}
+
+func (d dbMock) RestoreTrash(userid, entryid string) (model.TrashEntry, error) {
+	e, ok := d.trash[entryid]
+	if !ok || e.Owner != userid || !e.Expires.After(time.Now()) {
+		return model.TrashEntry{}, model.ErrNotFound
+	}
+
+	if e.Content != nil {
+		if _, ok := d.calendars[e.Calendar]; ok {
+			return model.TrashEntry{}, model.ErrAlreadyExists
+		}
+		d.calendars[e.Calendar] = *e.Content
+	} else {
+		c, ok := d.calendars[e.Calendar]
+		if !ok {
+			return model.TrashEntry{}, model.ErrNotFound
+		}
+		if err := e.RestoreItem(&c); err != nil {
+			return model.TrashEntry{}, err
+		}
+		d.calendars[e.Calendar] = c
+	}
+
+	delete(d.trash, entryid)
+	return e, nil
+}
+
+func (d dbMock) PurgeTrash(t time.Time) error {
+	for id, e := range d.trash {
+		if !e.Expires.After(t) {
+			delete(d.trash, id)
+		}
+	}
+	return nil
+}
+
+func (d dbMock) GetCalendarAlias(calendarid string) (model.CalendarAlias, error) {
+	a, ok := d.aliases[calendarid]
+	if !ok {
+		return model.CalendarAlias{}, model.ErrNotFound
+	}
+	return a, nil
+}
+
 func (d dbMock) SetCalendar(calendarid string, c model.Calendar) error {
 	if d.setCalendar != nil {
 		return d.setCalendar(calendarid, c)
@@ -352,6 +643,14 @@ func (d dbMock) SetCalendar(calendarid string, c model.Calendar) error {
 }
 
 func (d dbMock) GetCalendar(calendarid string) (model.Calendar, error) {
+	if d.calendars != nil {
+		c, ok := d.calendars[calendarid]
+		if !ok {
+			return model.Calendar{}, model.ErrNotFound
+		}
+		return c, nil
+	}
+
 	e := d.data["GetCalendar"].e
 	if e != nil {
 		return model.Calendar{}, e
@@ -361,11 +660,350 @@ func (d dbMock) GetCalendar(calendarid string) (model.Calendar, error) {
 	return un, e
 }
 
+func (d dbMock) QueryCalendar(calendarid string, q model.CalendarQuery) (model.Calendar, error) {
+	if d.queryCalendar != nil {
+		return d.queryCalendar(calendarid, q)
+	}
+
+	e := d.data["QueryCalendar"].e
+	if e != nil {
+		return model.Calendar{}, e
+	}
+
+	return d.data["QueryCalendar"].d.(model.Calendar), e
+}
+
+func (d dbMock) Search(q model.SearchQuery) (model.SearchResults, error) {
+	e := d.data["Search"].e
+	if e != nil {
+		return model.SearchResults{}, e
+	}
+
+	return d.data["Search"].d.(model.SearchResults), e
+}
+
+func (d dbMock) SetProfile(userid string, p model.Profile) error {
+	if d.users != nil {
+		u, ok := d.users[userid]
+		if !ok {
+			return model.ErrNotFound
+		}
+		u.Profile = p
+		d.users[userid] = u
+	}
+	return d.data["SetProfile"].e
+}
+
 func (d dbMock) AddUser(userid, hashedPW string) error {
-	return d.data["AddUser"].e
+	if e := d.data["AddUser"].e; e != nil {
+		return e
+	}
+	if d.logins != nil {
+		d.logins[userid] = model.NewLogin(userid, hashedPW)
+	}
+	return nil
+}
+
+func (d dbMock) AddSession(s model.Session) error {
+	if d.sessions != nil {
+		d.sessions[s.ID] = s
+	}
+	return d.data["AddSession"].e
+}
+
+func (d dbMock) SetSession(s model.Session) error {
+	if _, ok := d.sessions[s.ID]; !ok {
+		return model.ErrNotFound
+	}
+	d.sessions[s.ID] = s
+	return nil
+}
+
+func (d dbMock) GetSession(tokenid string) (model.Session, error) {
+	s, ok := d.sessions[tokenid]
+	if !ok {
+		return model.Session{}, model.ErrNotFound
+	}
+	return s, nil
+}
+
+func (d dbMock) GetSessions(userid string) ([]model.Session, error) {
+	var res []model.Session
+	for _, s := range d.sessions {
+		if s.User == userid {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) DeleteSession(tokenid string) error {
+	if _, ok := d.sessions[tokenid]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.sessions, tokenid)
+	return nil
+}
+
+func (d dbMock) AddAccessToken(t model.AccessToken) error {
+	if d.tokens != nil {
+		t.Token = ""
+		d.tokens[t.ID] = t
+	}
+	return nil
+}
+
+func (d dbMock) GetAccessToken(id string) (model.AccessToken, error) {
+	t, ok := d.tokens[id]
+	if !ok {
+		return model.AccessToken{}, model.ErrNotFound
+	}
+	return t, nil
+}
+
+func (d dbMock) GetAccessTokens(userid string) ([]model.AccessToken, error) {
+	var res []model.AccessToken
+	for _, t := range d.tokens {
+		if t.User == userid {
+			res = append(res, t)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) DeleteAccessToken(id string) error {
+	if _, ok := d.tokens[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.tokens, id)
+	return nil
+}
+
+func (d dbMock) AddGroup(g model.Group) error {
+	if _, ok := d.groups[g.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	if d.groups != nil {
+		d.groups[g.ID] = g
+	}
+	return nil
+}
+
+func (d dbMock) GetGroup(id string) (model.Group, error) {
+	g, ok := d.groups[id]
+	if !ok {
+		return model.Group{}, model.ErrNotFound
+	}
+	return g, nil
+}
+
+func (d dbMock) GetGroups(userid string) ([]model.Group, error) {
+	var res []model.Group
+	for _, g := range d.groups {
+		if g.IsMember(userid) {
+			res = append(res, g)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) SetGroup(g model.Group) error {
+	if _, ok := d.groups[g.ID]; !ok {
+		return model.ErrNotFound
+	}
+	d.groups[g.ID] = g
+	return nil
+}
+
+func (d dbMock) DeleteGroup(id string) error {
+	if _, ok := d.groups[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.groups, id)
+	return nil
+}
+
+func (d dbMock) AddInvitation(i model.Invitation) error {
+	if d.invitations != nil {
+		d.invitations[i.ID] = i
+	}
+	return nil
+}
+
+func (d dbMock) GetInvitation(id string) (model.Invitation, error) {
+	i, ok := d.invitations[id]
+	if !ok {
+		return model.Invitation{}, model.ErrNotFound
+	}
+	return i, nil
+}
+
+func (d dbMock) GetInvitations(userid string) ([]model.Invitation, error) {
+	var res []model.Invitation
+	for _, i := range d.invitations {
+		if i.To == userid || i.From == userid {
+			res = append(res, i)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) DeleteInvitation(id string) error {
+	if _, ok := d.invitations[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.invitations, id)
+	return nil
+}
+
+func (d dbMock) AddShareLink(l model.ShareLink) error {
+	if d.links != nil {
+		l.Token, l.URL = "", ""
+		d.links[l.ID] = l
+	}
+	return nil
+}
+
+func (d dbMock) GetShareLink(id string) (model.ShareLink, error) {
+	l, ok := d.links[id]
+	if !ok || l.Expired(time.Now()) {
+		return model.ShareLink{}, model.ErrNotFound
+	}
+	return l, nil
+}
+
+func (d dbMock) GetShareLinks(userid string) ([]model.ShareLink, error) {
+	var res []model.ShareLink
+	for _, l := range d.links {
+		if l.Owner == userid {
+			res = append(res, l)
+		}
+	}
+	return res, nil
+}
+
+func (d dbMock) CountShareLinkAccess(id string, at time.Time) error {
+	l, ok := d.links[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	l.Accesses++
+	l.LastAccess = &at
+	d.links[id] = l
+	return nil
+}
+
+func (d dbMock) DeleteShareLink(id string) error {
+	if _, ok := d.links[id]; !ok {
+		return model.ErrNotFound
+	}
+	delete(d.links, id)
+	return nil
+}
+
+func (d dbMock) AssociateCalendar(user model.User, cal model.Calendar, perm model.Permission) error {
+	for _, ref := range user.Items.Calendars {
+		if ref.Link == cal.GetID() {
+			return model.ErrAlreadyExists
+		}
+	}
+
+	user.Items.Calendars = append(user.Items.Calendars, model.CalendarReference{Link: cal.GetID(),
+		Perm: perm.String()})
+	if d.users != nil {
+		d.users[user.Name.Val] = user
+	}
+	return nil
+}
+
+func (d dbMock) DeleteSessions(userid, except string) error {
+	for id, s := range d.sessions {
+		if s.User == userid && id != except {
+			delete(d.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (d dbMock) PurgeSessions(t time.Time) error {
+	for id, s := range d.sessions {
+		if s.Expired(t) {
+			delete(d.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (d dbMock) SetLogin(userid string, l model.Login) error {
+	if _, ok := d.logins[userid]; !ok {
+		return model.ErrNotFound
+	}
+	d.logins[userid] = l
+	return nil
+}
+
+func (d dbMock) UpdateLogin(userid string, update func(l *model.Login) error) error {
+	l, err := d.GetLogin(userid)
+	if err != nil {
+		return err
+	}
+	if err := update(&l); err != nil {
+		return err
+	}
+	return d.SetLogin(userid, l)
+}
+
+func (d dbMock) AddLockout(l model.Lockout) error {
+	if d.lockouts != nil {
+		*d.lockouts = append([]model.Lockout{l}, *d.lockouts...)
+	}
+	return nil
+}
+
+func (d dbMock) GetLockouts() ([]model.Lockout, error) {
+	if d.lockouts == nil {
+		return nil, nil
+	}
+	return *d.lockouts, nil
+}
+
+func (d dbMock) AddResetToken(t model.ResetToken) error {
+	if d.resets != nil {
+		d.resets[t.Hash] = t
+	}
+	return d.data["AddResetToken"].e
+}
+
+func (d dbMock) GetResetToken(hash string) (model.ResetToken, error) {
+	t, ok := d.resets[hash]
+	if !ok {
+		return model.ResetToken{}, model.ErrNotFound
+	}
+	return t, nil
+}
+
+func (d dbMock) ConsumeResetToken(hash string) (model.ResetToken, error) {
+	t, ok := d.resets[hash]
+	if !ok {
+		return model.ResetToken{}, model.ErrNotFound
+	}
+	for h, o := range d.resets {
+		if o.User == t.User {
+			delete(d.resets, h)
+		}
+	}
+	return t, nil
 }
 
 func (d dbMock) GetLogin(userid string) (model.Login, error) {
+	if d.logins != nil {
+		l, ok := d.logins[userid]
+		if !ok {
+			return model.Login{}, model.ErrNotFound
+		}
+		return l, nil
+	}
+
 	e := d.data["GetLogin"].e
 	if e != nil {
 		return model.Login{}, e
@@ -24,6 +24,27 @@ func getCalendarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// narrow the calendar down to the queried items, if filters are given
+	q, err := model.NewCalendarQuery(r)
+	if err != nil {
+		writeError(w, "query not understood: from and to must be dates, kind one of appointment, milestone, task",
+			http.StatusBadRequest)
+		return
+	}
+	if !q.IsZero() {
+		c, err = db.QueryCalendar(c.GetID(), q)
+		if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if wantsICS(r) {
+		writeICS(w, c)
+		return
+	}
+
 	m := r.URL.Query().Get("mode")
 	var xslLink string
 	switch m {
@@ -54,7 +75,8 @@ func deleteCalendarHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.DeleteCalendar(c.GetID())
+	// the calendar is kept in the trash of its owner, who is the only one to delete it
+	err = db.TrashCalendar(c.GetID(), c.Owner.Val)
 	if err == model.ErrNotFound {
 		writeError(w, "calendar not found", http.StatusNotFound)
 		return
@@ -75,6 +97,7 @@ func putCalendarHandler(w http.ResponseWriter, r *http.Request) {
 
 	o, err := model.NewCalendar(r, c.Owner.Val)
 
+	before := c.Metadata()
 	c.Update(o)
 
 	err = db.SetCalendar(c.ID.Val, c)
@@ -83,6 +106,7 @@ func putCalendarHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(auditEntry(r, c.GetID(), model.AuditUpdate, before, c.Metadata()))
 
 	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
 }
@@ -143,15 +167,39 @@ func getUserCalendarsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	visible, err := visibleCalendars(u)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// personal access tokens only see the calendars they are restricted to
+	var refs []model.CalendarReference
+	for _, ref := range visible {
+		if allowsCalendar(r, ref.Link) {
+			refs = append(refs, ref)
+		}
+	}
+	u.Items.Calendars = refs
+
 	b, _ := xml.Marshal(u)
 	xmlStr := addStylesheet(string(b), conf.AuthedPathName+"/showCalendars.xsl?"+r.URL.RawQuery)
 
 	w.Write([]byte(xmlStr))
 }
 
-// sendXSL sends the given xsl with the URL query params from r. No further call is required
+// sendXSL sends the given xsl with the URL query params, the CSRF token and the profile from r. No further call is
+// required
 func sendXSL(w http.ResponseWriter, r *http.Request, xsl string) {
-	vars := allFromURL(r.URL.Query())
+	// the query must not shadow the CSRF token or the profile
+	q := r.URL.Query()
+	q.Del(csrfStr)
+	for _, v := range profileVars(r) {
+		q.Del(v.name)
+	}
+	vars := append(allFromURL(q), csrfVars(r)...)
+	vars = append(vars, profileVars(r)...)
 
 	newXSL := varsIntoXSL(xsl, vars...)
 
@@ -167,6 +215,7 @@ func legalName(n string) bool {
 //getCalendarIfPermission returns the requested calendar, after it has checked whether the minPerm are met by the
 // requesting account. If err != nil is returned, then this error has already been dealt with via writeError and
 // is just returned to indicate a guard statement early return.
+// Requests to the former ID of a renamed calendar are redirected within the grace period.
 func getCalendarIfPermission(w http.ResponseWriter, r *http.Request, minPerm model.Permission) (model.Calendar, error) {
 	retErr := errors.New("error already reported")
 
@@ -187,7 +236,9 @@ func getCalendarIfPermission(w http.ResponseWriter, r *http.Request, minPerm mod
 	}
 
 	c, err := db.GetCalendar(uID + "/" + cID)
-	if err == model.ErrNotFound {
+	if err == model.ErrNotFound && redirectAlias(w, r, uID+"/"+cID, authedUser, minPerm) {
+		return model.Calendar{}, retErr
+	} else if err == model.ErrNotFound {
 		writeError(w, "", http.StatusNotFound)
 		return model.Calendar{}, retErr
 	} else if err != nil {
@@ -196,7 +247,7 @@ func getCalendarIfPermission(w http.ResponseWriter, r *http.Request, minPerm mod
 		return model.Calendar{}, retErr
 	}
 
-	perm := model.CalendarPermissions(c, authedUser)
+	perm := calendarPermission(r, c, authedUser)
 
 	if perm < minPerm {
 		writeError(w, "no permissions to view/edit/create this item", http.StatusForbidden)
@@ -206,6 +257,21 @@ func getCalendarIfPermission(w http.ResponseWriter, r *http.Request, minPerm mod
 	return c, nil
 }
 
+// calendarPermission returns the permissions of the user for c, granted directly or to the groups of the user, narrowed
+// down to what the personal access token of the request allows, if it has been authenticated with one
+func calendarPermission(r *http.Request, c model.Calendar, userID string) model.Permission {
+	groups, err := userGroups(userID)
+	if err != nil {
+		log.Println(err) // only the permissions granted directly apply
+	}
+
+	perm := model.CalendarPermissions(c, userID, groups...)
+	if t, ok := accessToken(r); ok {
+		perm = t.Permission(c.GetID(), perm)
+	}
+	return perm
+}
+
 func addStylesheet(xml, href string) string {
 	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <?xml-stylesheet type="text/xsl" href="%s"?>`, href) + "\n" + xml
@@ -2,6 +2,7 @@ package web
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Project-Planner/backend/model"
 	"net/http"
@@ -31,35 +32,41 @@ var defCalendar = model.Calendar{
 	Permissions: struct {
 		Text string `xml:",chardata"`
 		View struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		} `xml:"view"`
 		Edit struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		} `xml:"edit"`
 	}(struct {
 		Text string
 		View struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		}
 		Edit struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		}
 	}{
 		View: struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		}{User: []model.Attribute{
 			{
 				Val: userView,
 			},
 		}},
 		Edit: struct {
-			Text string            `xml:",chardata"`
-			User []model.Attribute `xml:"user"`
+			Text  string            `xml:",chardata"`
+			User  []model.Attribute `xml:"user"`
+			Group []model.Attribute `xml:"group"`
 		}{User: []model.Attribute{
 			{
 				Val: userEdit,
@@ -125,6 +132,42 @@ func TestGetCalendarHandler(t *testing.T) {
 			code:      http.StatusOK,
 			db:        defaultCalendar,
 		},
+		// filtered
+		{
+			path:      "/c/" + testOwner + "/" + testOwner,
+			urlParams: "?from=2021-06-01&to=2021-06-30&kind=task,milestone",
+			authed:    userView,
+			code:      http.StatusOK,
+			db: dbMock{data: map[string]struct {
+				d interface{}
+				e error
+			}{
+				"GetCalendar":   {d: defCalendar},
+				"QueryCalendar": {d: defCalendar},
+			}},
+		},
+		// filter not understood
+		{
+			path:      "/c/" + testOwner + "/" + testOwner,
+			urlParams: "?from=tomorrow",
+			authed:    userView,
+			code:      http.StatusBadRequest,
+			db:        defaultCalendar,
+		},
+		// filter is evaluated by the database
+		{
+			path:      "/c/" + testOwner + "/" + testOwner,
+			urlParams: "?q=vendor",
+			authed:    userView,
+			code:      http.StatusInternalServerError,
+			db: dbMock{data: map[string]struct {
+				d interface{}
+				e error
+			}{
+				"GetCalendar":   {d: defCalendar},
+				"QueryCalendar": {e: errors.New("whupsi, some error")},
+			}},
+		},
 	}
 
 	for _, tc := range tt {
@@ -243,6 +286,22 @@ func TestVarsIntoXLS(t *testing.T) {
 	}
 }
 
+func TestVarXLSQuoting(t *testing.T) {
+	tt := []struct {
+		value, want string
+	}{
+		{value: "plain", want: `select="'plain'"`},
+		{value: "it's", want: `select="&quot;it's&quot;"`},
+		{value: `<"'>&`, want: `select="concat('&lt;&quot;', &quot;'&quot;, '&gt;&amp;')"`},
+	}
+
+	for _, tc := range tt {
+		if got := (varXLS{"v", tc.value}).String(); !strings.Contains(got, tc.want) {
+			t.Errorf("%s quoted wrong: want: %s got: %s", tc.value, tc.want, got)
+		}
+	}
+}
+
 const xlsTruncated = `<?xml version="1.0" encoding="UTF-8"?>
 <xsl:stylesheet version="1.0"
   xmlns:xsl="http://www.w3.org/1999/XSL/Transform">
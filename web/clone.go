@@ -0,0 +1,84 @@
+package web
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cloneCalendarHandler copies a calendar the logged in user can view into a new calendar of the user, named after the
+// form field name, e.g. to start a project from a template shared with the user. The form field start (yyyy-mm-dd)
+// shifts all dates, so that the project starts on this day. If template is set, the copy is saved as a template.
+func cloneCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	src, err := getCalendarIfPermission(w, r, model.Read)
+	if err != nil {
+		return
+	}
+
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		writeError(w, "name missing, html input must have name 'name'", http.StatusUnprocessableEntity)
+		return
+	} else if !legalName(name) {
+		writeError(w, "illegal name", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var start time.Time
+	if v := r.Form.Get("start"); v != "" {
+		if start, err = time.Parse("2006-01-02", v); err != nil {
+			writeError(w, "start not understood, must be formatted yyyy-mm-dd", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	template, err := formFlag(r, "template")
+	if err != nil {
+		writeError(w, "template not understood, must be true or false", http.StatusUnprocessableEntity)
+		return
+	}
+
+	c := src.Clone(start)
+	c.Template = template
+	c.Name.Val = name
+	c.Owner.Val = userid
+	c.ID.Val = fmt.Sprintf("%s/%s", userid, name)
+
+	if err := db.AddCalendar(userid, name); err == model.ErrAlreadyExists {
+		writeError(w, "calendar already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the new calendar is empty until its items are written, so it is removed again if this fails
+	if err := db.SetCalendar(c.GetID(), c); err != nil {
+		log.Println(err)
+		if err := db.DeleteCalendar(c.GetID()); err != nil {
+			log.Println(err)
+		}
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(auditEntry(r, c.GetID(), model.AuditCreate, nil, c.Metadata()))
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCloneCalendar(t *testing.T) {
+	owner, user, other := "someowner", "someuser", "someoneelse"
+	src := model.Calendar{Template: true, Name: model.Attribute{Val: "template"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/template"}}
+	src.Permissions.View.User = []model.Attribute{{Val: user}}
+	src.Items.Milestones.Milestone = []model.Milestone{
+		{ID: "m1", Name: model.Attribute{Val: "Release"}, Duedate: model.Attribute{Val: "31.03.2021"}},
+	}
+
+	mock := dbMock{
+		calendars: map[string]model.Calendar{src.GetID(): src},
+		groups:    map[string]model.Group{},
+	}
+	mock.setCalendar = func(id string, c model.Calendar) error {
+		mock.calendars[id] = c
+		return nil
+	}
+	db = mock
+
+	tt := []struct {
+		user string
+		form url.Values
+		code int
+	}{
+		{user: user, form: url.Values{"name": {"project"}, "start": {"2021-04-01"}}, code: http.StatusSeeOther},
+		{user: user, form: url.Values{"name": {"copy"}, "template": {"on"}}, code: http.StatusSeeOther},
+		{user: user, form: url.Values{"name": {"project"}}, code: http.StatusConflict},
+		{user: user, form: url.Values{"name": {"other"}, "start": {"1.4.2021"}}, code: http.StatusUnprocessableEntity},
+		{user: user, form: url.Values{"name": {"other"}, "template": {"maybe"}},
+			code: http.StatusUnprocessableEntity},
+		{user: user, form: url.Values{"name": {"../other"}}, code: http.StatusUnprocessableEntity},
+		{user: user, form: url.Values{}, code: http.StatusUnprocessableEntity},
+		// templates are only shared with those who may view them
+		{user: other, form: url.Values{"name": {"project"}}, code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tc.form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "template"})
+		rr := httptest.NewRecorder()
+		cloneCalendarHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+
+	c := mock.calendars[user+"/project"]
+	if c.Template || c.Owner.Val != user || c.ID.Val != user+"/project" || len(c.Permissions.View.User) != 0 {
+		t.Errorf("project not cloned correctly: %v", c)
+	}
+	if ms := c.Items.Milestones.Milestone; len(ms) != 1 || ms[0].ID == "m1" || ms[0].Duedate.Val != "01.04.2021" {
+		t.Errorf("expected the milestone to be cloned to the start of the project, got %v", ms)
+	}
+	if c := mock.calendars[user+"/copy"]; !c.Template || c.Items.Milestones.Milestone[0].Duedate.Val != "31.03.2021" {
+		t.Errorf("template not cloned correctly: %v", c)
+	}
+}
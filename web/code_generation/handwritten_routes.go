@@ -0,0 +1,1043 @@
+package main
+
+// route describes a route that is registered by hand in registerRoutes. It is only used to document the route in
+// the OpenAPI specification, so keep this list in sync with web/routes.go.
+type route struct {
+	Path    string
+	Methods []string
+	Summary string
+	// Public routes are not mounted beneath the authed path prefix
+	Public bool
+	// Scope personal access tokens need to use the route; routes without one need a session
+	Scope     string
+	Query     []field
+	Form      []field
+	Responses []response
+}
+
+// field of a query or a form
+type field struct {
+	Name     string
+	Desc     string
+	Required bool
+}
+
+// response of a route. Content is the media type of the body, if the response has one
+type response struct {
+	Code    int
+	Desc    string
+	Content string
+}
+
+const (
+	mimeXML  = "application/xml"
+	mimeXSL  = "application/xslt+xml"
+	mimeJSON = "application/json"
+	mimeHTML = "text/html"
+)
+
+var (
+	// errorResponses are returned by nearly every authed route
+	errorResponses = []response{
+		{Code: 401, Desc: "not logged in", Content: mimeHTML},
+		{Code: 500, Desc: "internal server error", Content: mimeHTML},
+	}
+
+	// methodField tunnels the HTTP verb through HTML forms
+	methodField = field{Name: "_method", Desc: "HTTP verb to use instead of POST (PUT, PATCH or DELETE)"}
+
+	// formatField selects the representation of views
+	formatField = field{Name: "format", Desc: "json for JSON, XML otherwise; the Accept header is used if missing"}
+
+	redirect = response{Code: 303, Desc: "success, redirects to the main page"}
+
+	// renamed is returned for the former path of a renamed calendar within the grace period
+	renamed = response{Code: 307, Desc: "the calendar has been renamed, redirects to its new path"}
+
+	// throttled is returned after too many failed attempts, the Retry-After header tells how long to wait
+	throttled = response{Code: 429, Desc: "too many attempts, retry after the time in the Retry-After header",
+		Content: mimeHTML}
+
+	// busy is returned if passwords can not be hashed in time
+	busy = response{Code: 503, Desc: "server busy hashing passwords, try again later", Content: mimeHTML}
+)
+
+// withErrors appends the errorResponses to rs
+func withErrors(rs ...response) []response {
+	return append(rs, errorResponses...)
+}
+
+// itemFilterQuery are the query parameters filtering calendar items besides the time window
+var itemFilterQuery = []field{
+	{Name: "kind", Desc: "only items of these kinds: appointment, milestone, task; comma separated or repeated"},
+	{Name: "q", Desc: "only items containing this text in name or description"},
+	{Name: "assignee", Desc: "only items assigned to this user"},
+	{Name: "label", Desc: "only items carrying all of these labels; comma separated or repeated"},
+}
+
+// profileFields are the form fields updating the profile
+var profileFields = []field{
+	{Name: "display_name", Desc: "name shown instead of the username, at most 64 characters"},
+	{Name: "email", Desc: "address password reset links are sent to"},
+	{Name: "password", Desc: "current password, required to change the email address"},
+	{Name: "locale", Desc: "preferred language as BCP 47 tag, e.g. de-DE"},
+	{Name: "time_zone", Desc: "IANA time zone dates are shown in, e.g. Europe/Berlin"},
+	{Name: "week_start", Desc: "first day of the week, e.g. monday"},
+}
+
+// calendarViewQuery are the query parameters understood by the calendar views
+var calendarViewQuery = append([]field{
+	{Name: "mode", Desc: "view to render the calendar with: calendar (default), project or edit"},
+	{Name: "format", Desc: "ics to export the calendar as iCalendar, e.g. to subscribe to it, instead of rendering it"},
+	{Name: "from", Desc: "only items ending on or after this date (yyyy-mm-dd)"},
+	{Name: "to", Desc: "only items starting on or before this date (yyyy-mm-dd)"},
+}, itemFilterQuery...)
+
+// handwrittenRoutes are all routes of registerRoutes, which are not generated by generateEndpoints
+var handwrittenRoutes = []route{
+	{
+		Path:    "/c/{user_id}/{calendar_id}",
+		Methods: []string{"GET"},
+		Summary: "Render the calendar with the given owner and name",
+		Scope:   "read",
+		Query:   calendarViewQuery,
+		Responses: withErrors(
+			response{Code: 200, Desc: "the calendar with a stylesheet reference", Content: mimeXML},
+			renamed,
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+			response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/c/{calendar_id}",
+		Methods: []string{"GET"},
+		Summary: "Render a calendar of the logged in user",
+		Scope:   "read",
+		Query:   calendarViewQuery,
+		Responses: withErrors(
+			response{Code: 200, Desc: "the calendar with a stylesheet reference", Content: mimeXML},
+			renamed,
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/c",
+		Methods: []string{"GET"},
+		Summary: "Render the default calendar of the logged in user",
+		Scope:   "read",
+		Query:   calendarViewQuery,
+		Responses: withErrors(
+			response{Code: 200, Desc: "the calendar with a stylesheet reference", Content: mimeXML},
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+		),
+	},
+	{
+		Path:      "/calendar.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the calendar view, query parameters are injected as variables",
+		Scope:     "read",
+		Responses: withErrors(response{Code: 200, Desc: "the stylesheet", Content: mimeXSL}),
+	},
+	{
+		Path:      "/projectView.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the project view, query parameters are injected as variables",
+		Scope:     "read",
+		Responses: withErrors(response{Code: 200, Desc: "the stylesheet", Content: mimeXSL}),
+	},
+	{
+		Path:      "/editItem.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the edit view, query parameters are injected as variables",
+		Scope:     "read",
+		Responses: withErrors(response{Code: 200, Desc: "the stylesheet", Content: mimeXSL}),
+	},
+	{
+		Path:      "/showCalendars.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the calendar list, query parameters are injected as variables",
+		Scope:     "read",
+		Responses: withErrors(response{Code: 200, Desc: "the stylesheet", Content: mimeXSL}),
+	},
+	{
+		Path:    "/agenda",
+		Methods: []string{"GET"},
+		Summary: "Chronological timeline of the items of all calendars the logged in user can see",
+		Scope:   "read",
+		Query: append([]field{
+			{Name: "range", Desc: "length of the timeline: day, week (default) or month"},
+			{Name: "date", Desc: "a day within the timeline (yyyy-mm-dd), defaults to today"},
+			formatField,
+		}, itemFilterQuery...),
+		Responses: withErrors(
+			response{Code: 200, Desc: "the agenda, as XML with a stylesheet reference or as JSON", Content: mimeXML},
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+		),
+	},
+	{
+		Path:      "/agenda.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the agenda view, built in unless the frontend has one; query params injected",
+		Scope:     "read",
+		Responses: withErrors(response{Code: 200, Desc: "the stylesheet", Content: mimeXSL}),
+	},
+	{
+		Path:    "/search",
+		Methods: []string{"GET"},
+		Summary: "Full-text search in names and descriptions of all calendars the logged in user can view",
+		Scope:   "read",
+		Query: []field{
+			{Name: "q", Desc: "words to search for, matched as prefixes; all of them have to match", Required: true},
+			{Name: "kind", Desc: "only results of these kinds: calendar, appointment, milestone, task; " +
+				"comma separated or repeated. Facets are counted regardless"},
+			{Name: "limit", Desc: "maximum number of results, defaults to 50"},
+			formatField,
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "results with facets and snippets, as XML or JSON", Content: mimeXML},
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+			response{Code: 422, Desc: "nothing to search for", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars",
+		Methods: []string{"GET"},
+		Summary: "List the calendars of the logged in user",
+		Scope:   "read",
+		Responses: withErrors(
+			response{Code: 200, Desc: "the user with its calendar references", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/calendars",
+		Methods: []string{"POST"},
+		Summary: "Create a calendar owned by the logged in user",
+		Form: []field{
+			{Name: "name", Desc: "name of the calendar, may contain letters, digits, - and _", Required: true},
+			{Name: "desc", Desc: "description", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 409, Desc: "calendar already exists", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing or illegal name", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}",
+		Methods: []string{"GET"},
+		Summary: "Render the calendar with the given owner and name",
+		Scope:   "read",
+		Query:   calendarViewQuery,
+		Responses: withErrors(
+			response{Code: 200, Desc: "the calendar with a stylesheet reference", Content: mimeXML},
+			renamed,
+			response{Code: 400, Desc: "query not understood", Content: mimeHTML},
+			response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}",
+		Methods: []string{"PUT", "PATCH"},
+		Summary: "Update the description of the calendar",
+		Scope:   "edit",
+		Form: []field{
+			{Name: "desc", Desc: "description"},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "no permission to edit the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Delete the calendar into the trash of the owner, only the owner may do so",
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not the owner of the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 405, Desc: "the default calendar must not be deleted", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}",
+		Methods: []string{"POST"},
+		Summary: "Update or delete the calendar from an HTML form",
+		Scope:   "edit",
+		Form: []field{
+			methodField,
+			{Name: "desc", Desc: "description"},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "no permission to edit the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/transfer",
+		Methods: []string{"POST"},
+		Summary: "Hand the calendar over to another user, only the owner may do so",
+		Form: []field{
+			{Name: "owner", Desc: "name of the new owner", Required: true},
+			{Name: "name", Desc: "new name of the calendar, needed if the new owner has a calendar of the same name"},
+			{Name: "keep", Desc: "permission the previous owner keeps: view, edit or none (default)"},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not the owner of the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar or new owner not found", Content: mimeHTML},
+			response{Code: 405, Desc: "the default calendar must not be transferred", Content: mimeHTML},
+			response{Code: 409, Desc: "the new owner has a calendar of the same name", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing, illegal name or keep not understood",
+				Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/rename",
+		Methods: []string{"POST"},
+		Summary: "Rename the calendar, only the owner may do so. The former path redirects for a grace period",
+		Form: []field{
+			{Name: "name", Desc: "new name of the calendar, may contain letters, digits, - and _", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not the owner of the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 405, Desc: "the default calendar must not be renamed", Content: mimeHTML},
+			response{Code: 409, Desc: "a calendar of the same name exists already", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing or illegal name", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/clone",
+		Methods: []string{"POST"},
+		Summary: "Copy the calendar or template into a new calendar of the logged in user, with fresh item IDs",
+		Form: []field{
+			{Name: "name", Desc: "name of the new calendar, may contain letters, digits, - and _", Required: true},
+			{Name: "start", Desc: "day the project starts on (yyyy-mm-dd), all dates are shifted accordingly"},
+			{Name: "template", Desc: "on or true to save the copy as a template"},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 409, Desc: "calendar already exists", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing, illegal name, start or template not understood",
+				Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/audit",
+		Methods: []string{"GET"},
+		Summary: "List who changed the calendar and its items when, and how, the most recent first",
+		Query: []field{
+			formatField,
+			{Name: "user", Desc: "only changes made by this user"},
+			{Name: "item", Desc: "only changes of the item with this ID"},
+			{Name: "from", Desc: "only changes since this time (RFC 3339) or day (yyyy-mm-dd)"},
+			{Name: "to", Desc: "only changes before this time (RFC 3339) or until this day (yyyy-mm-dd)"},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the audit log, as XML or JSON", Content: mimeXML},
+			response{Code: 400, Desc: "from or to not understood", Content: mimeHTML},
+			response{Code: 403, Desc: "not owner of the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			renamed,
+		),
+	},
+	{
+		Path:    "/audit/deleted",
+		Methods: []string{"GET"},
+		Summary: "List the audit logs of the calendars of the logged in user which have been deleted for good, the " +
+			"most recently deleted first",
+		Query: []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the deleted audit logs, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/audit/deleted/{deleted_log_id}",
+		Methods: []string{"GET"},
+		Summary: "List who changed a calendar of the logged in user, which has been deleted for good, when, and how, " +
+			"the most recent first",
+		Query: []field{
+			formatField,
+			{Name: "user", Desc: "only changes made by this user"},
+			{Name: "item", Desc: "only changes of the item with this ID"},
+			{Name: "from", Desc: "only changes since this time (RFC 3339) or day (yyyy-mm-dd)"},
+			{Name: "to", Desc: "only changes before this time (RFC 3339) or until this day (yyyy-mm-dd)"},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the audit log, as XML or JSON", Content: mimeXML},
+			response{Code: 400, Desc: "from or to not understood", Content: mimeHTML},
+			response{Code: 404, Desc: "deleted audit log not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/revisions",
+		Methods: []string{"GET"},
+		Summary: "List the prior versions of the calendar without their items, the most recent first",
+		Scope:   "read",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the revisions, as XML or JSON", Content: mimeXML},
+			response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			renamed,
+		),
+	},
+	{
+		Path:    "/calendars/{user_id}/{calendar_id}/rollback",
+		Methods: []string{"POST"},
+		Summary: "Restore the items and the description the calendar had at a prior time",
+		Scope:   "edit",
+		Form: []field{
+			{Name: "time", Desc: "time to roll back to (RFC 3339), within the retention of revisions", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "no permission to edit the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 422, Desc: "time missing, not understood or beyond the retention", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/api/user",
+		Methods: []string{"DELETE"},
+		Summary: "Delete the logged in user along with its calendars, revoking its sessions at once. The user is " +
+			"kept for a delay, logging in again within it cancels the deletion",
+		Responses: withErrors(response{Code: 303, Desc: "success, redirects to the index page"}),
+	},
+	{
+		Path:    "/api/user",
+		Methods: []string{"POST"},
+		Summary: "Delete the logged in user from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			response{Code: 303, Desc: "success, redirects to the index page"},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/api/sharing",
+		Methods: []string{"POST"},
+		Summary: "Share a calendar of the logged in user with another user or a group of the user. Users who " +
+			"don't have the calendar yet are invited, also before they register",
+		Scope: "share",
+		Form: []field{
+			{Name: "calendarName", Desc: "name of the calendar to share", Required: true},
+			{Name: "userName", Desc: "user to invite or change the permission of, if no groupName is given"},
+			{Name: "groupName", Desc: "group to share the calendar with, whose members get the permission"},
+			{Name: "perm", Desc: "permission to grant: view, edit or none", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 400, Desc: "permission not understood", Content: mimeHTML},
+			response{Code: 403, Desc: "not the owner of the calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar or group not found", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing, illegal user name or the owner", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/trash",
+		Methods: []string{"GET"},
+		Summary: "List the deleted items and calendars of the calendars the logged in user owns, the most recent " +
+			"first. Deleted calendars are listed without their items",
+		Query: []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the trash, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/trash/{trash_id}/restore",
+		Methods: []string{"POST"},
+		Summary: "Restore a deleted item or calendar from the trash of the logged in user",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "entry not found or expired, or the calendar of the item deleted",
+				Content: mimeHTML},
+			response{Code: 409, Desc: "an item or calendar of the same ID exists already", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/invitations",
+		Methods: []string{"GET"},
+		Summary: "List the pending invitations to calendars the logged in user has received and sent",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the invitations, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/invitations/{invitation_id}/accept",
+		Methods: []string{"POST"},
+		Summary: "Accept an invitation of the logged in user, sharing the calendar with the user",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "invitation or calendar not found", Content: mimeHTML},
+			response{Code: 409, Desc: "calendar already shared with the user", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/invitations/{invitation_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Decline an invitation the logged in user has received, or revoke one the user has sent",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "invitation not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/invitations/{invitation_id}",
+		Methods: []string{"POST"},
+		Summary: "Decline or revoke an invitation from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "invitation not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/links",
+		Methods: []string{"GET"},
+		Summary: "List the public share links of the calendars of the logged in user, with their access counters",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the links without their tokens, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/links",
+		Methods: []string{"POST"},
+		Summary: "Create a public share link granting anyone who knows it read-only access to a calendar of the " +
+			"logged in user. The link is only shown in this response",
+		Query: []field{formatField},
+		Form: []field{
+			{Name: "calendarName", Desc: "name of the calendar to publish", Required: true},
+			{Name: "expires", Desc: "last day the link is valid on (yyyy-mm-dd), never expires if missing"},
+			{Name: "hide_descriptions", Desc: "true (or on) to hide the descriptions of the items"},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the link with its token and URL, as XML or JSON", Content: mimeXML},
+			response{Code: 404, Desc: "calendar not found", Content: mimeHTML},
+			response{Code: 422, Desc: "calendar name missing, expires or hide_descriptions not understood",
+				Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/links/{link_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Revoke a share link of the logged in user",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "link not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/links/{link_id}",
+		Methods: []string{"POST"},
+		Summary: "Revoke a share link from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "link not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/password",
+		Methods: []string{"PUT", "PATCH"},
+		Summary: "Change the password of the logged in user, revoking all sessions and starting a new one",
+		Form: []field{
+			{Name: "old_password", Desc: "current password", Required: true},
+			{Name: "new_password", Desc: "new password", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "old password incorrect", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/password",
+		Methods: []string{"POST"},
+		Summary: "Change the password from an HTML form",
+		Form: []field{
+			methodField,
+			{Name: "old_password", Desc: "current password", Required: true},
+			{Name: "new_password", Desc: "new password", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "old password incorrect", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/groups",
+		Methods: []string{"GET"},
+		Summary: "List the groups the logged in user is a member or an admin of",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the groups, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/groups",
+		Methods: []string{"POST"},
+		Summary: "Create a group administrated by the logged in user",
+		Form: []field{
+			{Name: "name", Desc: "name of the group, may contain letters, digits, - and _", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 409, Desc: "group already exists", Content: mimeHTML},
+			response{Code: 422, Desc: "name missing or illegal", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}",
+		Methods: []string{"GET"},
+		Summary: "Show a group of the logged in user with its members and the calendars shared with it",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the group, as XML or JSON", Content: mimeXML},
+			response{Code: 404, Desc: "group not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Delete a group administrated by the logged in user, revoking the permissions granted to it",
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not an admin of the group", Content: mimeHTML},
+			response{Code: 404, Desc: "group not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}",
+		Methods: []string{"POST"},
+		Summary: "Delete a group from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not an admin of the group", Content: mimeHTML},
+			response{Code: 404, Desc: "group not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}/members",
+		Methods: []string{"POST"},
+		Summary: "Add a member to a group administrated by the logged in user, or change the role of a member",
+		Form: []field{
+			{Name: "user", Desc: "user to add", Required: true},
+			{Name: "role", Desc: "member (default) or admin"},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not an admin of the group", Content: mimeHTML},
+			response{Code: 404, Desc: "group or user not found", Content: mimeHTML},
+			response{Code: 409, Desc: "the last admin can not be demoted", Content: mimeHTML},
+			response{Code: 422, Desc: "user missing or role not understood", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}/members/{member_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Remove a member from a group; admins may remove anyone, members only themselves",
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not an admin of the group", Content: mimeHTML},
+			response{Code: 404, Desc: "group or member not found", Content: mimeHTML},
+			response{Code: 409, Desc: "the last admin can not leave", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/groups/{group_id}/members/{member_id}",
+		Methods: []string{"POST"},
+		Summary: "Remove a member from a group from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "not an admin of the group", Content: mimeHTML},
+			response{Code: 404, Desc: "group or member not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+			response{Code: 409, Desc: "the last admin can not leave", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/profile",
+		Methods: []string{"GET"},
+		Summary: "Show the profile of the logged in user",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the profile and the email address, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/profile",
+		Methods: []string{"PUT", "PATCH"},
+		Summary: "Update the fields of the profile present in the form, empty values reset them to the defaults",
+		Form:    profileFields,
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "password incorrect", Content: mimeHTML},
+			response{Code: 422, Desc: "field not understood", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/profile",
+		Methods: []string{"POST"},
+		Summary: "Update the profile from an HTML form",
+		Form:    append([]field{methodField}, profileFields...),
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "password incorrect", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+			response{Code: 422, Desc: "field not understood", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/totp",
+		Methods: []string{"GET"},
+		Summary: "Show whether the logged in user has enabled two-factor authentication",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the state and the number of recovery codes left, as XML or JSON",
+				Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/totp",
+		Methods: []string{"POST"},
+		Summary: "Start the enrollment of a TOTP second factor, or disable it from an HTML form with _method=DELETE",
+		Query:   []field{formatField},
+		Form: []field{
+			methodField,
+			{Name: "password", Desc: "password of the user, required to disable"},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the secret and its otpauth:// provisioning URI, as XML or JSON",
+				Content: mimeXML},
+			response{Code: 303, Desc: "disabled, redirects to the main page"},
+			response{Code: 403, Desc: "password incorrect", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+			response{Code: 409, Desc: "two-factor authentication already enabled", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/totp",
+		Methods: []string{"DELETE"},
+		Summary: "Disable two-factor authentication of the logged in user, who has to confirm the password",
+		Form: []field{
+			{Name: "password", Desc: "password of the user", Required: true},
+		},
+		Responses: withErrors(
+			redirect,
+			response{Code: 403, Desc: "password incorrect", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+			busy,
+		),
+	},
+	{
+		Path:    "/totp/confirm",
+		Methods: []string{"POST"},
+		Summary: "Enable the enrolled second factor with a code of the authenticator app",
+		Query:   []field{formatField},
+		Form: []field{
+			{Name: "code", Desc: "current code of the authenticator app", Required: true},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the one-time recovery codes, shown only this once, as XML or JSON",
+				Content: mimeXML},
+			response{Code: 403, Desc: "code incorrect", Content: mimeHTML},
+			response{Code: 409, Desc: "no enrollment pending", Content: mimeHTML},
+			response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+		),
+	},
+	{
+		Path:    "/sessions",
+		Methods: []string{"GET"},
+		Summary: "List the sessions (devices logged in) of the logged in user, marking the current one",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the sessions with device and IP address, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:      "/sessions",
+		Methods:   []string{"DELETE"},
+		Summary:   "Revoke all sessions of the logged in user but the current one",
+		Responses: withErrors(redirect),
+	},
+	{
+		Path:    "/sessions",
+		Methods: []string{"POST"},
+		Summary: "Revoke all other sessions from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/sessions/{session_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Revoke a session of the logged in user; revoking the current one logs out",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "session not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/sessions/{session_id}",
+		Methods: []string{"POST"},
+		Summary: "Revoke a session from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "session not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/tokens",
+		Methods: []string{"GET"},
+		Summary: "List the personal access tokens of the logged in user, without the tokens themselves",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the tokens with scopes, calendars and expiry, as XML or JSON", Content: mimeXML},
+		),
+	},
+	{
+		Path:    "/tokens",
+		Methods: []string{"POST"},
+		Summary: "Create a personal access token for scripts, sent as Authorization: Bearer <token>",
+		Query:   []field{formatField},
+		Form: []field{
+			{Name: "name", Desc: "name telling the token apart from others", Required: true},
+			{Name: "scope", Desc: "read, edit (includes read) or share; comma separated or repeated", Required: true},
+			{Name: "calendar", Desc: "IDs (owner/name) of the only calendars the token may be used for, all if " +
+				"missing; comma separated or repeated"},
+			{Name: "expires", Desc: "last day the token is valid (yyyy-mm-dd), never expires if missing"},
+		},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the token, shown only this once, as XML or JSON", Content: mimeXML},
+			response{Code: 422, Desc: "required field missing, scope, calendar or expiry not understood",
+				Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/tokens/{access_token_id}",
+		Methods: []string{"DELETE"},
+		Summary: "Revoke a personal access token of the logged in user",
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "token not found", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/tokens/{access_token_id}",
+		Methods: []string{"POST"},
+		Summary: "Revoke a personal access token from an HTML form",
+		Form:    []field{methodField},
+		Responses: withErrors(
+			redirect,
+			response{Code: 404, Desc: "token not found", Content: mimeHTML},
+			response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+		),
+	},
+	{
+		Path:    "/admin/lockouts",
+		Methods: []string{"GET"},
+		Summary: "List the lockouts after too many failed logins or registrations, the most recent first",
+		Query:   []field{formatField},
+		Responses: withErrors(
+			response{Code: 200, Desc: "the lockouts, as XML or JSON", Content: mimeXML},
+			response{Code: 403, Desc: "not an admin", Content: mimeHTML},
+		),
+	},
+	{
+		Path:      "/logout",
+		Methods:   []string{"GET"},
+		Summary:   "Log out by revoking the session and deleting the authentication cookie",
+		Responses: withErrors(response{Code: 303, Desc: "success, redirects to the index page"}),
+	},
+	{
+		Path:    "/api/login",
+		Methods: []string{"POST"},
+		Summary: "Log in and receive the authentication cookie, or the password verified token if two-factor " +
+			"authentication is enabled",
+		Public: true,
+		Form: []field{
+			{Name: "username", Desc: "name of the user", Required: true},
+			{Name: "password", Desc: "password of the user", Required: true},
+		},
+		Responses: []response{
+			redirect,
+			{Code: 401, Desc: "username or password incorrect", Content: mimeHTML},
+			{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+			busy,
+		},
+	},
+	{
+		Path:    "/api/login/mfa",
+		Methods: []string{"POST"},
+		Summary: "Complete a login with the second factor, exchanging the password verified token for the " +
+			"authentication cookie",
+		Public: true,
+		Form: []field{
+			{Name: "code", Desc: "current code of the authenticator app or an unused recovery code", Required: true},
+		},
+		Responses: []response{
+			redirect,
+			{Code: 401, Desc: "code incorrect, or password not verified or verification expired", Content: mimeHTML},
+			{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+		},
+	},
+	{
+		Path:    "/api/register",
+		Methods: []string{"POST"},
+		Summary: "Register a new user and receive the authentication cookie",
+		Public:  true,
+		Form: []field{
+			{Name: "username", Desc: "name of the user, may contain letters, digits, - and _", Required: true},
+			{Name: "password", Desc: "password of the user", Required: true},
+			{Name: "email", Desc: "address password reset links are sent to"},
+		},
+		Responses: []response{
+			redirect,
+			{Code: 409, Desc: "username already exists", Content: mimeHTML},
+			{Code: 422, Desc: "required field missing, illegal name or illegal email address", Content: mimeHTML},
+			throttled,
+			busy,
+		},
+	},
+	{
+		Path:    "/api/password/forgot",
+		Methods: []string{"POST"},
+		Summary: "Mail a single-use password reset link to the address of the user, if it has one",
+		Public:  true,
+		Form: []field{
+			{Name: "username", Desc: "name of the user", Required: true},
+		},
+		Responses: []response{
+			{Code: 303, Desc: "redirects to the index page, whether the user exists or not"},
+			{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			throttled,
+		},
+	},
+	{
+		Path:    "/api/password/reset",
+		Methods: []string{"POST"},
+		Summary: "Set a new password with a reset token, revoking all sessions and logging in",
+		Public:  true,
+		Form: []field{
+			{Name: "token", Desc: "token of the reset link", Required: true},
+			{Name: "password", Desc: "new password", Required: true},
+		},
+		Responses: []response{
+			redirect,
+			{Code: 403, Desc: "token invalid, expired or already used", Content: mimeHTML},
+			{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			busy,
+			throttled,
+		},
+	},
+	{
+		Path:    "/s/{share_token}",
+		Methods: []string{"GET"},
+		Summary: "Render the calendar of a share link without logging in, or export it as iCalendar",
+		Public:  true,
+		Query: append([]field{
+			{Name: "mode", Desc: "view to render the calendar with: calendar (default) or project"},
+			{Name: "format", Desc: "ics for iCalendar, json for JSON, XML with a stylesheet reference otherwise"},
+			{Name: "from", Desc: "only items ending on or after this date (yyyy-mm-dd)"},
+			{Name: "to", Desc: "only items starting on or before this date (yyyy-mm-dd)"},
+		}, itemFilterQuery...),
+		Responses: []response{
+			{Code: 200, Desc: "the calendar without its permissions", Content: mimeXML},
+			{Code: 400, Desc: "query not understood", Content: mimeHTML},
+			{Code: 404, Desc: "share link invalid, expired or revoked", Content: mimeHTML},
+			{Code: 500, Desc: "internal server error", Content: mimeHTML},
+		},
+	},
+	{
+		Path:      "/s/{share_token}/calendar.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the calendar view of share links, query parameters are injected as variables",
+		Public:    true,
+		Responses: []response{{Code: 200, Desc: "the stylesheet", Content: mimeXSL}},
+	},
+	{
+		Path:      "/s/{share_token}/projectView.xsl",
+		Methods:   []string{"GET"},
+		Summary:   "Stylesheet of the project view of share links, query parameters are injected as variables",
+		Public:    true,
+		Responses: []response{{Code: 200, Desc: "the stylesheet", Content: mimeXSL}},
+	},
+	{
+		Path:    "/api/oidc/login",
+		Methods: []string{"GET"},
+		Summary: "Start a single sign-on, redirecting to the OpenID Connect provider",
+		Public:  true,
+		Responses: []response{
+			{Code: 302, Desc: "redirects to the provider"},
+			{Code: 404, Desc: "single sign-on not configured", Content: mimeHTML},
+			{Code: 502, Desc: "provider unavailable", Content: mimeHTML},
+		},
+	},
+	{
+		Path:    "/api/oidc/callback",
+		Methods: []string{"GET"},
+		Summary: "Complete a single sign-on, the provider redirects here. Logs in, or redirects to the second " +
+			"factor if enabled",
+		Public: true,
+		Query: []field{
+			{Name: "code", Desc: "authorization code issued by the provider", Required: true},
+			{Name: "state", Desc: "state of the single sign-on started by this browser", Required: true},
+			{Name: "error", Desc: "error sent by the provider instead of the code"},
+		},
+		Responses: []response{
+			redirect,
+			{Code: 401, Desc: "single sign-on not started in this browser, expired or refused", Content: mimeHTML},
+			{Code: 403, Desc: "no account for the identity and provisioning disabled", Content: mimeHTML},
+			{Code: 404, Desc: "single sign-on not configured", Content: mimeHTML},
+			{Code: 409, Desc: "account with this name not linked to the identity", Content: mimeHTML},
+			{Code: 422, Desc: "username of the identity not legal", Content: mimeHTML},
+			{Code: 502, Desc: "provider unavailable or sent an invalid token", Content: mimeHTML},
+		},
+	},
+	{
+		Path:      "/openapi.json",
+		Methods:   []string{"GET"},
+		Summary:   "This specification",
+		Public:    true,
+		Responses: []response{{Code: 200, Desc: "the OpenAPI specification", Content: mimeJSON}},
+	},
+}
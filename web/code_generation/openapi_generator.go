@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+// obj is a shorthand for the JSON objects of the specification
+type obj map[string]interface{}
+
+// defaultAuthedPathName is documented as the default of the server variable; the web server replaces it with the
+// configured path when serving the specification
+const defaultAuthedPathName = "/me"
+
+var pathParamRegex = regexp.MustCompile(`{([^}]+)}`)
+
+// generateOpenAPI writes the OpenAPI 3 specification of all routes, generated and handwritten, to openapi.json.
+// Paths of authed routes are relative to the authed path prefix, which is the default server. Public routes
+// override the server with the root.
+func generateOpenAPI() {
+	paths := obj{}
+	for _, rt := range append(handwrittenRoutes, itemRoutes()...) {
+		item, ok := paths[rt.Path].(obj)
+		if !ok {
+			item = pathItem(rt)
+			paths[rt.Path] = item
+		}
+
+		for _, m := range rt.Methods {
+			item[strings.ToLower(m)] = operation(rt, m)
+		}
+	}
+
+	doc := obj{
+		"openapi": "3.0.3",
+		"info": obj{
+			"title":       "Project Planner",
+			"description": "The backend for the project planner educational project",
+			"version":     "1.0.0",
+		},
+		"servers": []obj{
+			{
+				"url":         "{authedPathName}",
+				"description": "routes requiring authentication",
+				"variables": obj{
+					"authedPathName": obj{
+						"default":     defaultAuthedPathName,
+						"description": "path prefix of routes requiring authentication, see authed_path_name",
+					},
+				},
+			},
+		},
+		"security": []obj{{"cookieAuth": []string{}}},
+		"components": obj{
+			"securitySchemes": obj{
+				"cookieAuth": obj{
+					"type": "apiKey",
+					"in":   "cookie",
+					"name": "auth",
+					"description": "short-lived access token, refreshed transparently by the server " +
+						"as long as the refresh cookie of a valid session is sent along. Requests other than " +
+						"GET must send the CSRF token of the session in the X-CSRF-Token header or the form " +
+						"field csrf_token; every authed response carries it in the X-CSRF-Token header",
+				},
+				"bearerAuth": obj{
+					"type":   "http",
+					"scheme": "bearer",
+					"description": "personal access token for scripts, only accepted by routes listing its " +
+						"scope (read, edit or share; edit includes read)",
+				},
+			},
+		},
+		"paths": paths,
+	}
+
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := ioutil.WriteFile("../openapi.json", append(b, '\n'), 0644); err != nil {
+		panic(err)
+	}
+}
+
+// pathItem returns the path item for rt without any operations
+func pathItem(rt route) obj {
+	item := obj{}
+
+	var params []obj
+	for _, m := range pathParamRegex.FindAllStringSubmatch(rt.Path, -1) {
+		params = append(params, obj{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   obj{"type": "string"},
+		})
+	}
+	if len(params) > 0 {
+		item["parameters"] = params
+	}
+
+	if rt.Public {
+		item["servers"] = []obj{{"url": "/"}}
+	}
+
+	return item
+}
+
+// operation returns the operation of rt for the given HTTP method
+func operation(rt route, method string) obj {
+	op := obj{"summary": rt.Summary}
+
+	if rt.Public {
+		op["security"] = []obj{}
+	} else if rt.Scope != "" {
+		op["security"] = []obj{{"cookieAuth": []string{}}, {"bearerAuth": []string{rt.Scope}}}
+	}
+
+	if len(rt.Query) > 0 {
+		var params []obj
+		for _, f := range rt.Query {
+			params = append(params, obj{
+				"name":        f.Name,
+				"in":          "query",
+				"description": f.Desc,
+				"required":    f.Required,
+				"schema":      obj{"type": "string"},
+			})
+		}
+		op["parameters"] = params
+	}
+
+	if len(rt.Form) > 0 {
+		props := obj{}
+		var required []string
+		for _, f := range rt.Form {
+			props[f.Name] = obj{"type": "string", "description": f.Desc}
+			if f.Required {
+				required = append(required, f.Name)
+			}
+		}
+
+		schema := obj{"type": "object", "properties": props}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		op["requestBody"] = obj{
+			"required": true,
+			"content": obj{
+				"application/x-www-form-urlencoded": obj{"schema": schema},
+			},
+		}
+	}
+
+	responses := obj{}
+	for _, r := range rt.Responses {
+		resp := obj{"description": r.Desc}
+		if r.Content != "" {
+			resp["content"] = obj{r.Content: obj{}}
+		}
+		responses[fmt.Sprint(r.Code)] = resp
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// itemRoutes describes the routes written by generateEndpoints
+func itemRoutes() []route {
+	var rts []route
+	for _, i := range items {
+		name := strings.ToLower(i.Name)
+		path := fmt.Sprintf("/calendars/{user_id}/{calendar_id}/%s", lowerCasePlural(i.Name))
+		itemPath := path + "/{item_id}"
+
+		// fields are optional on updates, only sent fields are updated
+		var optional []field
+		for _, f := range i.Fields {
+			f.Required = false
+			optional = append(optional, f)
+		}
+
+		notFound := response{Code: 404, Desc: "calendar or " + name + " not found", Content: mimeHTML}
+		forbidden := response{Code: 403, Desc: "no permission to edit the calendar", Content: mimeHTML}
+
+		rts = append(rts, route{
+			Path:    path,
+			Methods: []string{"POST"},
+			Summary: "Create a " + name + " in the calendar",
+			Scope:   "edit",
+			Form:    i.Fields,
+			Responses: withErrors(
+				redirect, forbidden, notFound,
+				response{Code: 400, Desc: "could not parse sent data", Content: mimeHTML},
+				response{Code: 422, Desc: "required field missing", Content: mimeHTML},
+			),
+		})
+
+		for _, m := range methods {
+			rt := route{
+				Path:      itemPath,
+				Methods:   []string{m.Verb},
+				Scope:     "edit",
+				Responses: withErrors(redirect, forbidden, notFound),
+			}
+
+			switch m.Handler {
+			case "put":
+				rt.Summary = "Update the sent fields of the " + name
+				rt.Form = optional
+			case "delete":
+				rt.Summary = "Delete the " + name
+			}
+
+			rts = append(rts, rt)
+		}
+
+		rts = append(rts, route{
+			Path:    itemPath,
+			Methods: []string{"POST"},
+			Summary: "Update or delete the " + name + " from an HTML form",
+			Scope:   "edit",
+			Form:    append([]field{methodField}, optional...),
+			Responses: withErrors(
+				redirect, forbidden, notFound,
+				response{Code: 405, Desc: "method not allowed", Content: mimeHTML},
+			),
+		})
+
+		// the target calendar must be editable as well
+		target := []field{{Name: "calendar", Desc: "ID of the target calendar, formatted owner/name", Required: true}}
+		targetErrors := []response{
+			redirect,
+			response{Code: 403, Desc: "no permission to edit either calendar", Content: mimeHTML},
+			response{Code: 404, Desc: "calendar, target calendar or " + name + " not found", Content: mimeHTML},
+			response{Code: 422, Desc: "target calendar missing or not a different calendar", Content: mimeHTML},
+		}
+		conflict := response{Code: 409, Desc: "target calendar has an item of this ID already", Content: mimeHTML}
+
+		rts = append(rts, route{
+			Path:      itemPath + "/move",
+			Methods:   []string{"POST"},
+			Summary:   "Move the " + name + " to another calendar, keeping its ID",
+			Scope:     "edit",
+			Form:      target,
+			Responses: withErrors(append(targetErrors, conflict)...),
+		}, route{
+			Path:      itemPath + "/copy",
+			Methods:   []string{"POST"},
+			Summary:   "Copy the " + name + " to another calendar under a new ID",
+			Scope:     "edit",
+			Form:      target,
+			Responses: withErrors(targetErrors...),
+		})
+
+		revisionNotFound := response{Code: 404, Desc: "calendar, " + name + " or revision not found", Content: mimeHTML}
+		rts = append(rts, route{
+			Path:    itemPath + "/revisions",
+			Methods: []string{"GET"},
+			Summary: "List the prior versions of the " + name + ", the most recent first",
+			Scope:   "read",
+			Query:   []field{formatField},
+			Responses: withErrors(
+				response{Code: 200, Desc: "the revisions, as XML or JSON", Content: mimeXML},
+				response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+				notFound,
+			),
+		}, route{
+			Path:    itemPath + "/revisions/diff",
+			Methods: []string{"GET"},
+			Summary: "List the fields of the " + name + " changed between two revisions",
+			Scope:   "read",
+			Query: []field{
+				formatField,
+				{Name: "from", Desc: "ID of the earlier revision", Required: true},
+				{Name: "to", Desc: "ID of the later revision, the current version by default"},
+			},
+			Responses: withErrors(
+				response{Code: 200, Desc: "the changed fields, as XML or JSON", Content: mimeXML},
+				response{Code: 400, Desc: "from missing", Content: mimeHTML},
+				response{Code: 403, Desc: "no permission to view the calendar", Content: mimeHTML},
+				revisionNotFound,
+			),
+		}, route{
+			Path:      itemPath + "/revisions/{revision_id}/restore",
+			Methods:   []string{"POST"},
+			Summary:   "Restore the " + name + " as it has been in the revision, also if it has been deleted since",
+			Scope:     "edit",
+			Responses: withErrors(redirect, forbidden, revisionNotFound),
+		})
+	}
+
+	return rts
+}
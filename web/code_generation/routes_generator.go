@@ -1,4 +1,4 @@
-//go:generate go run routes_generator.go
+//go:generate go run .
 
 package main
 
@@ -15,74 +15,121 @@ package web
 
 import (
 	"fmt"
+	"github.com/Project-Planner/backend/model"
 	"github.com/gorilla/mux"
 )
 
+// attachEndpoints attaches the CRUD routes of all items, which personal access tokens can use with the edit scope
 func attachEndpoints(r *mux.Router) {
 {{- $methods := .Methods -}}
 {{- $items := .Items -}}
-{{- range $idxI, $item := $items}}
-	{{lowerCasePlural $item}}Router := r.PathPrefix("/api/{{lowerCasePlural $item}}").Subrouter()
-
-	{{lowerCasePlural $item}}Router.HandleFunc(fmt.Sprintf("/post/{%s}/{%s}", userIDStr, calendarIDStr), post{{$item}}Handler).Methods("POST")
-	{{lowerCasePlural $item}}Router.HandleFunc(fmt.Sprintf("/post/{%s}", calendarIDStr), post{{$item}}Handler).Methods("POST")
-	{{lowerCasePlural $item}}Router.HandleFunc("/post", post{{$item}}Handler).Methods("POST")
-
-	{{lowerCasePlural $item}}Router.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}/{%s}", userIDStr, calendarIDStr, itemIDStr), methodHandler(nil
-{{- range $idxM, $method := $methods -}}
-{{- if isPost $method -}}
-{{- else -}}
-	, {{lowerCase $method}}{{$item}}Handler
-{{- end -}}
-{{- end -}}
-	)).Methods("POST")	
-	{{lowerCasePlural $item}}Router.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}", calendarIDStr, itemIDStr), methodHandler(nil 
-{{- range $idxM, $method := $methods -}}
-{{- if isPost $method -}}
-{{- else -}}
-	, {{lowerCase $method}}{{$item}}Handler
-{{- end -}}
-{{- end -}}
-	)).Methods("POST")
-	{{lowerCasePlural $item}}Router.HandleFunc(fmt.Sprintf("/other/{%s}", itemIDStr), methodHandler(nil 
-{{- range $idxM, $method := $methods -}}
-{{- if isPost $method -}}
-{{- else -}}
-	, {{lowerCase $method}}{{$item}}Handler
-{{- end -}}
-{{- end -}}
-	)).Methods("POST")
+{{- range $idxI, $i := $items}}
+{{- $item := $i.Name}}
+	{{lowerCasePlural $item}}Path := fmt.Sprintf("/calendars/{%s}/{%s}/{{lowerCasePlural $item}}", userIDStr, calendarIDStr)
+	{{lowerCasePlural $item}}ItemPath := fmt.Sprintf("/calendars/{%s}/{%s}/{{lowerCasePlural $item}}/{%s}", userIDStr, calendarIDStr, itemIDStr)
 
+	scoped(model.ScopeEdit, r.HandleFunc({{lowerCasePlural $item}}Path, post{{$item}}Handler).Methods("POST"))
+{{range $idxM, $method := $methods}}
+	scoped(model.ScopeEdit, r.HandleFunc({{lowerCasePlural $item}}ItemPath, {{$method.Handler}}{{$item}}Handler).Methods("{{$method.Verb}}"))
+{{- end}}
+
+	// compatibility shim for HTML forms, which are only able to send GET and POST
+	scoped(model.ScopeEdit, r.HandleFunc({{lowerCasePlural $item}}ItemPath, methodHandler(nil, put{{$item}}Handler, delete{{$item}}Handler)).Methods("POST"))
+
+	scoped(model.ScopeEdit, r.HandleFunc({{lowerCasePlural $item}}ItemPath+"/move", moveItemHandler(model.Kind{{$item}})).Methods("POST"))
+	scoped(model.ScopeEdit, r.HandleFunc({{lowerCasePlural $item}}ItemPath+"/copy", copyItemHandler(model.Kind{{$item}})).Methods("POST"))
+
+	scoped(model.ScopeRead, r.HandleFunc({{lowerCasePlural $item}}ItemPath+"/revisions", getItemRevisionsHandler(model.Kind{{$item}})).Methods("GET"))
+	scoped(model.ScopeRead, r.HandleFunc({{lowerCasePlural $item}}ItemPath+"/revisions/diff", diffItemRevisionsHandler(model.Kind{{$item}})).Methods("GET"))
+	scoped(model.ScopeEdit, r.HandleFunc(fmt.Sprintf("%s/revisions/{%s}/restore", {{lowerCasePlural $item}}ItemPath, revisionIDStr), restoreItemRevisionHandler(model.Kind{{$item}})).Methods("POST"))
 {{ end }}
 }
 `
 
+// item is a calendar item with generated routes
+type item struct {
+	Name   string
+	Fields []field
+}
+
+// method maps an HTTP verb to the prefix of the handler function serving it
+type method struct {
+	Verb    string
+	Handler string
+}
+
+// items whose CRUD routes are generated, along with their form fields
+var items = []item{
+	{
+		Name: "Appointment",
+		Fields: []field{
+			{Name: "name", Desc: "name of the appointment", Required: true},
+			{Name: "startDate", Desc: "start date (yyyy-mm-dd)", Required: true},
+			{Name: "startTime", Desc: "start time (hh:mm)", Required: true},
+			{Name: "endDate", Desc: "end date (yyyy-mm-dd)", Required: true},
+			{Name: "endTime", Desc: "end time (hh:mm)", Required: true},
+			{Name: "assignee", Desc: "user the item is assigned to"},
+			{Name: "labels", Desc: "comma separated labels"},
+			{Name: "desc", Desc: "description", Required: true},
+		},
+	},
+	{
+		Name: "Milestone",
+		Fields: []field{
+			{Name: "name", Desc: "name of the milestone", Required: true},
+			{Name: "endDate", Desc: "due date (yyyy-mm-dd)", Required: true},
+			{Name: "endTime", Desc: "due time (hh:mm)", Required: true},
+			{Name: "assignee", Desc: "user the item is assigned to"},
+			{Name: "labels", Desc: "comma separated labels"},
+			{Name: "desc", Desc: "description", Required: true},
+		},
+	},
+	{
+		Name: "Task",
+		Fields: []field{
+			{Name: "name", Desc: "name of the task", Required: true},
+			{Name: "startDate", Desc: "start date (yyyy-mm-dd)", Required: true},
+			{Name: "startTime", Desc: "start time (hh:mm)", Required: true},
+			{Name: "endDate", Desc: "due date (yyyy-mm-dd)", Required: true},
+			{Name: "endTime", Desc: "due time (hh:mm)", Required: true},
+			{Name: "milestone-id", Desc: "id of the milestone this task belongs to"},
+			{Name: "assignee", Desc: "user the item is assigned to"},
+			{Name: "labels", Desc: "comma separated labels"},
+			{Name: "desc", Desc: "description", Required: true},
+		},
+	},
+}
+
+// methods of the item routes besides POST, which creates the item
+var methods = []method{
+	{Verb: "PUT", Handler: "put"},
+	{Verb: "PATCH", Handler: "put"},
+	{Verb: "DELETE", Handler: "delete"},
+}
+
 func main() {
+	generateEndpoints()
+	generateOpenAPI()
+}
+
+// generateEndpoints writes the router code for the item routes
+func generateEndpoints() {
 	f, err := os.Create("../endpoints.go")
 	if err != nil {
 		panic(err)
 	}
+	defer f.Close()
 
 	fm := template.FuncMap{
 		"lowerCasePlural": lowerCasePlural,
-		"isPost":          isPost,
-		"lowerCase":       lowerCase,
 	}
 
 	err = template.Must(template.New("").Funcs(fm).Parse(tmpl)).Execute(f, struct {
-		Items   []string
-		Methods []string
+		Items   []item
+		Methods []method
 	}{
-		Items: []string{
-			"Appointment",
-			"Milestone",
-			"Task",
-		},
-		Methods: []string{
-			"POST",
-			"PUT",
-			"DELETE",
-		},
+		Items:   items,
+		Methods: methods,
 	})
 	if err != nil {
 		panic(err)
@@ -92,11 +139,3 @@ func main() {
 func lowerCasePlural(s string) string {
 	return strings.ToLower(s) + "s"
 }
-
-func lowerCase(s string) string {
-	return strings.ToLower(s)
-}
-
-func isPost(s string) bool {
-	return s == "POST"
-}
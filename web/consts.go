@@ -8,7 +8,36 @@ const (
 	userIDStr     = "user_id"
 	calendarIDStr = "calendar_id"
 	itemIDStr     = "item_id"
-	expiryStr     = "expiry"
-	authStr       = "auth"
-	jwtDuration   = time.Hour * 365 * 24
+	groupIDStr    = "group_id"
+	memberIDStr   = "member_id"
+	sessionIDStr  = "session_id"
+	// accessTokenIDStr is the route variable of personal access tokens, accessTokenStr the context key of the token
+	// a request has been authenticated with
+	accessTokenIDStr = "access_token_id"
+	accessTokenStr   = "access_token"
+	invitationIDStr  = "invitation_id"
+	expiryStr        = "expiry"
+	authStr          = "auth"
+	refreshStr       = "refresh"
+	mfaStr           = "mfa"
+	oidcStr          = "oidc"
+	purposeStr       = "purpose"
+	profileStr       = "profile"
+	linkIDStr        = "link_id"
+	shareTokenStr    = "share_token"
+	revisionIDStr    = "revision_id"
+	trashIDStr       = "trash_id"
+	deletedLogIDStr  = "deleted_log_id"
+
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 30 * 24 * time.Hour
+	defaultKeyGracePeriod       = 24 * time.Hour
+	defaultResetTokenDuration   = time.Hour
+	defaultInvitationDuration   = 14 * 24 * time.Hour
+	defaultRedirectDuration     = 30 * 24 * time.Hour
+	defaultDeletionDelay        = 14 * 24 * time.Hour
+	defaultBcryptCost           = 12
+	// refreshGracePeriod is how long a rotated refresh token is still accepted, so that concurrent requests of the
+	// same browser racing the rotation are not mistaken for a reuse
+	refreshGracePeriod = 30 * time.Second
 )
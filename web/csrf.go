@@ -0,0 +1,59 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/Project-Planner/backend/model"
+	"net/http"
+)
+
+const (
+	// csrfHeader carries the CSRF token of requests sent by scripts, csrfStr the token of HTML forms
+	csrfHeader = "X-CSRF-Token"
+	csrfStr    = "csrf_token"
+)
+
+// ensureCSRFToken returns the session with a CSRF token, creating and storing one for sessions started before CSRF
+// tokens were introduced
+func ensureCSRFToken(s model.Session) (model.Session, error) {
+	if s.CSRFToken != "" {
+		return s, nil
+	}
+
+	t, err := randomToken()
+	if err != nil {
+		return s, err
+	}
+	s.CSRFToken = t
+	return s, db.SetSession(s)
+}
+
+// checkCSRF verifies that a state-changing request authenticated by the cookies of the session has been sent by our
+// own pages, which send the CSRF token of the session in the header X-CSRF-Token or the form field csrf_token. Other
+// sites are unable to read the token. If ok is false, an error has been written to w.
+func checkCSRF(w http.ResponseWriter, r *http.Request, s model.Session) (ok bool) {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+
+	// malformed forms are reported by the handlers, which parse them again
+	t := r.Header.Get(csrfHeader)
+	if t == "" {
+		t = r.PostFormValue(csrfStr)
+	}
+
+	if t == "" || subtle.ConstantTimeCompare([]byte(t), []byte(s.CSRFToken)) != 1 {
+		writeError(w, "CSRF token missing or invalid, please reload the page", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// csrfVars returns the CSRF token of the request as XSL variable csrf_token, which the pages put into their forms
+func csrfVars(r *http.Request) []varXLS {
+	t, ok := r.Context().Value(csrfStr).(string)
+	if !ok {
+		return nil
+	}
+	return []varXLS{{name: csrfStr, value: t}}
+}
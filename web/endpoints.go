@@ -5,41 +5,67 @@ package web
 
 import (
 	"fmt"
+	"github.com/Project-Planner/backend/model"
 	"github.com/gorilla/mux"
 )
 
+// attachEndpoints attaches the CRUD routes of all items, which personal access tokens can use with the edit scope
 func attachEndpoints(r *mux.Router) {
-	appointmentsRouter := r.PathPrefix("/api/appointments").Subrouter()
+	appointmentsPath := fmt.Sprintf("/calendars/{%s}/{%s}/appointments", userIDStr, calendarIDStr)
+	appointmentsItemPath := fmt.Sprintf("/calendars/{%s}/{%s}/appointments/{%s}", userIDStr, calendarIDStr, itemIDStr)
 
-	appointmentsRouter.HandleFunc(fmt.Sprintf("/post/{%s}/{%s}", userIDStr, calendarIDStr), postAppointmentHandler).Methods("POST")
-	appointmentsRouter.HandleFunc(fmt.Sprintf("/post/{%s}", calendarIDStr), postAppointmentHandler).Methods("POST")
-	appointmentsRouter.HandleFunc("/post", postAppointmentHandler).Methods("POST")
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsPath, postAppointmentHandler).Methods("POST"))
 
-	appointmentsRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}/{%s}", userIDStr, calendarIDStr, itemIDStr), methodHandler(nil, putAppointmentHandler, deleteAppointmentHandler)).Methods("POST")	
-	appointmentsRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}", calendarIDStr, itemIDStr), methodHandler(nil, putAppointmentHandler, deleteAppointmentHandler)).Methods("POST")
-	appointmentsRouter.HandleFunc(fmt.Sprintf("/other/{%s}", itemIDStr), methodHandler(nil, putAppointmentHandler, deleteAppointmentHandler)).Methods("POST")
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath, putAppointmentHandler).Methods("PUT"))
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath, putAppointmentHandler).Methods("PATCH"))
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath, deleteAppointmentHandler).Methods("DELETE"))
 
+	// compatibility shim for HTML forms, which are only able to send GET and POST
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath, methodHandler(nil, putAppointmentHandler, deleteAppointmentHandler)).Methods("POST"))
 
-	milestonesRouter := r.PathPrefix("/api/milestones").Subrouter()
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath+"/move", moveItemHandler(model.KindAppointment)).Methods("POST"))
+	scoped(model.ScopeEdit, r.HandleFunc(appointmentsItemPath+"/copy", copyItemHandler(model.KindAppointment)).Methods("POST"))
 
-	milestonesRouter.HandleFunc(fmt.Sprintf("/post/{%s}/{%s}", userIDStr, calendarIDStr), postMilestoneHandler).Methods("POST")
-	milestonesRouter.HandleFunc(fmt.Sprintf("/post/{%s}", calendarIDStr), postMilestoneHandler).Methods("POST")
-	milestonesRouter.HandleFunc("/post", postMilestoneHandler).Methods("POST")
+	scoped(model.ScopeRead, r.HandleFunc(appointmentsItemPath+"/revisions", getItemRevisionsHandler(model.KindAppointment)).Methods("GET"))
+	scoped(model.ScopeRead, r.HandleFunc(appointmentsItemPath+"/revisions/diff", diffItemRevisionsHandler(model.KindAppointment)).Methods("GET"))
+	scoped(model.ScopeEdit, r.HandleFunc(fmt.Sprintf("%s/revisions/{%s}/restore", appointmentsItemPath, revisionIDStr), restoreItemRevisionHandler(model.KindAppointment)).Methods("POST"))
 
-	milestonesRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}/{%s}", userIDStr, calendarIDStr, itemIDStr), methodHandler(nil, putMilestoneHandler, deleteMilestoneHandler)).Methods("POST")	
-	milestonesRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}", calendarIDStr, itemIDStr), methodHandler(nil, putMilestoneHandler, deleteMilestoneHandler)).Methods("POST")
-	milestonesRouter.HandleFunc(fmt.Sprintf("/other/{%s}", itemIDStr), methodHandler(nil, putMilestoneHandler, deleteMilestoneHandler)).Methods("POST")
+	milestonesPath := fmt.Sprintf("/calendars/{%s}/{%s}/milestones", userIDStr, calendarIDStr)
+	milestonesItemPath := fmt.Sprintf("/calendars/{%s}/{%s}/milestones/{%s}", userIDStr, calendarIDStr, itemIDStr)
 
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesPath, postMilestoneHandler).Methods("POST"))
 
-	tasksRouter := r.PathPrefix("/api/tasks").Subrouter()
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath, putMilestoneHandler).Methods("PUT"))
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath, putMilestoneHandler).Methods("PATCH"))
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath, deleteMilestoneHandler).Methods("DELETE"))
 
-	tasksRouter.HandleFunc(fmt.Sprintf("/post/{%s}/{%s}", userIDStr, calendarIDStr), postTaskHandler).Methods("POST")
-	tasksRouter.HandleFunc(fmt.Sprintf("/post/{%s}", calendarIDStr), postTaskHandler).Methods("POST")
-	tasksRouter.HandleFunc("/post", postTaskHandler).Methods("POST")
+	// compatibility shim for HTML forms, which are only able to send GET and POST
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath, methodHandler(nil, putMilestoneHandler, deleteMilestoneHandler)).Methods("POST"))
 
-	tasksRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}/{%s}", userIDStr, calendarIDStr, itemIDStr), methodHandler(nil, putTaskHandler, deleteTaskHandler)).Methods("POST")	
-	tasksRouter.HandleFunc(fmt.Sprintf("/other/{%s}/{%s}", calendarIDStr, itemIDStr), methodHandler(nil, putTaskHandler, deleteTaskHandler)).Methods("POST")
-	tasksRouter.HandleFunc(fmt.Sprintf("/other/{%s}", itemIDStr), methodHandler(nil, putTaskHandler, deleteTaskHandler)).Methods("POST")
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath+"/move", moveItemHandler(model.KindMilestone)).Methods("POST"))
+	scoped(model.ScopeEdit, r.HandleFunc(milestonesItemPath+"/copy", copyItemHandler(model.KindMilestone)).Methods("POST"))
 
+	scoped(model.ScopeRead, r.HandleFunc(milestonesItemPath+"/revisions", getItemRevisionsHandler(model.KindMilestone)).Methods("GET"))
+	scoped(model.ScopeRead, r.HandleFunc(milestonesItemPath+"/revisions/diff", diffItemRevisionsHandler(model.KindMilestone)).Methods("GET"))
+	scoped(model.ScopeEdit, r.HandleFunc(fmt.Sprintf("%s/revisions/{%s}/restore", milestonesItemPath, revisionIDStr), restoreItemRevisionHandler(model.KindMilestone)).Methods("POST"))
+
+	tasksPath := fmt.Sprintf("/calendars/{%s}/{%s}/tasks", userIDStr, calendarIDStr)
+	tasksItemPath := fmt.Sprintf("/calendars/{%s}/{%s}/tasks/{%s}", userIDStr, calendarIDStr, itemIDStr)
+
+	scoped(model.ScopeEdit, r.HandleFunc(tasksPath, postTaskHandler).Methods("POST"))
+
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath, putTaskHandler).Methods("PUT"))
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath, putTaskHandler).Methods("PATCH"))
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath, deleteTaskHandler).Methods("DELETE"))
+
+	// compatibility shim for HTML forms, which are only able to send GET and POST
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath, methodHandler(nil, putTaskHandler, deleteTaskHandler)).Methods("POST"))
+
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath+"/move", moveItemHandler(model.KindTask)).Methods("POST"))
+	scoped(model.ScopeEdit, r.HandleFunc(tasksItemPath+"/copy", copyItemHandler(model.KindTask)).Methods("POST"))
+
+	scoped(model.ScopeRead, r.HandleFunc(tasksItemPath+"/revisions", getItemRevisionsHandler(model.KindTask)).Methods("GET"))
+	scoped(model.ScopeRead, r.HandleFunc(tasksItemPath+"/revisions/diff", diffItemRevisionsHandler(model.KindTask)).Methods("GET"))
+	scoped(model.ScopeEdit, r.HandleFunc(fmt.Sprintf("%s/revisions/{%s}/restore", tasksItemPath, revisionIDStr), restoreItemRevisionHandler(model.KindTask)).Methods("POST"))
 
 }
@@ -17,11 +17,14 @@ func writeError(w http.ResponseWriter, msg string, code int) {
 		return
 	}
 
+	// Lang is the locale of the user's profile, if known
 	err := errTemplate.Execute(w, struct {
 		DetailedError string
 		StatusCode    string
 		Status        string
-	}{DetailedError: msg, StatusCode: fmt.Sprint(code), Status: http.StatusText(code)})
+		Lang          string
+	}{DetailedError: msg, StatusCode: fmt.Sprint(code), Status: http.StatusText(code),
+		Lang: w.Header().Get("Content-Language")})
 	if err != nil {
 		log.Println(err)
 	}
@@ -0,0 +1,272 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// userGroups returns the IDs of the groups the user is a member of
+func userGroups(userID string) ([]string, error) {
+	gs, err := db.GetGroups(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(gs))
+	for i, g := range gs {
+		ids[i] = g.ID
+	}
+	return ids, nil
+}
+
+// visibleCalendars returns the references of the calendars the user can find: the ones shared with the user and
+// the ones shared with the groups of the user. As membership is resolved on every request, joining or leaving a
+// group takes effect immediately. References may dangle or lack the permission, which the caller has to check.
+func visibleCalendars(u model.User) ([]model.CalendarReference, error) {
+	refs := append([]model.CalendarReference{}, u.Items.Calendars...)
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		seen[ref.Link] = true
+	}
+
+	gs, err := db.GetGroups(u.Name.Val)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range gs {
+		for _, ref := range g.Calendars {
+			if !seen[ref.Link] {
+				seen[ref.Link] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// getGroupsHandler lists the groups of the logged in user
+func getGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	gs, err := db.GetGroups(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.Groups{Group: gs}, "")
+}
+
+// postGroupHandler creates a group named after the form field name, administrated by the logged in user
+func postGroupHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		writeError(w, "name missing, html input must have name 'name'", http.StatusUnprocessableEntity)
+		return
+	} else if !legalName(name) {
+		writeError(w, "illegal name", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if err := db.AddGroup(model.NewGroup(name, userid)); err == model.ErrAlreadyExists {
+		writeError(w, "group already exists", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// getGroupHandler shows a group of the logged in user
+func getGroupHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := getGroupIfMember(w, r, false)
+	if !ok {
+		return
+	}
+
+	writeView(w, r, g, "")
+}
+
+// deleteGroupHandler deletes a group administrated by the logged in user. Its members lose the permissions granted
+// to it.
+func deleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := getGroupIfMember(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := db.DeleteGroup(g.ID); err != nil && err != model.ErrNotFound {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(groupAuditEntries(r, g, model.Group{})...)
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// postGroupMemberHandler adds the user of the form field user to a group administrated by the logged in user, or
+// changes the role of a member. The form field role is either member (default) or admin.
+func postGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	g, ok := getGroupIfMember(w, r, true)
+	if !ok {
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	member := r.Form.Get("user")
+	if member == "" {
+		writeError(w, "user missing, html input must have name 'user'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	role := model.RoleMember
+	if v := r.Form.Get("role"); v != "" {
+		var err error
+		if role, err = model.ParseGroupRole(v); err != nil {
+			writeError(w, "role not understood, must be one of member, admin", http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	if _, err := db.GetUser(member); err == model.ErrNotFound {
+		writeError(w, "specified user name not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	before := g
+	g.SetRole(member, role)
+	if len(g.Admins) == 0 {
+		writeError(w, "the last admin can not be demoted, make another member admin first", http.StatusConflict)
+		return
+	}
+
+	if err := db.SetGroup(g); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(groupAuditEntries(r, before, g)...)
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// deleteGroupMemberHandler removes a member from a group. Admins may remove anyone, members only themselves.
+func deleteGroupMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+	member := mux.Vars(r)[memberIDStr]
+
+	g, ok := getGroupIfMember(w, r, member != userid)
+	if !ok {
+		return
+	}
+
+	if !g.IsMember(member) {
+		writeError(w, "member not found", http.StatusNotFound)
+		return
+	}
+
+	before := g
+	g.Remove(member)
+	if len(g.Admins) == 0 {
+		writeError(w, "the last admin can not leave, make another member admin or delete the group",
+			http.StatusConflict)
+		return
+	}
+
+	if err := db.SetGroup(g); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(groupAuditEntries(r, before, g)...)
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// groupAuditEntries returns the entries recording that the logged in user changed the members of the group from before
+// to after, one for each calendar shared with the group, as the permissions of the members follow the group. The fields
+// changed are named after the group, e.g. group.team.member[0].
+func groupAuditEntries(r *http.Request, before, after model.Group) []model.AuditEntry {
+	members := func(g model.Group) interface{} {
+		return struct {
+			Admins  []string `xml:"admin"`
+			Members []string `xml:"member"`
+		}{g.Admins, g.Members}
+	}
+
+	var es []model.AuditEntry
+	for _, ref := range before.Calendars {
+		e := auditEntry(r, ref.Link, model.AuditShare, members(before), members(after))
+		if len(e.Changes) == 0 {
+			return nil
+		}
+		for i := range e.Changes {
+			e.Changes[i].Field = "group." + before.ID + "." + e.Changes[i].Field
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// getGroupIfMember returns the group of the request, if the logged in user is a member of it, or an admin, if admin
+// is set. Groups of others are treated as non-existent. If ok is false, an error has been written to w.
+func getGroupIfMember(w http.ResponseWriter, r *http.Request, admin bool) (g model.Group, ok bool) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return g, false
+	}
+
+	g, err := db.GetGroup(mux.Vars(r)[groupIDStr])
+	if err == model.ErrNotFound || (err == nil && !g.IsMember(userid)) {
+		writeError(w, "group not found", http.StatusNotFound)
+		return g, false
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return g, false
+	}
+
+	if admin && !g.IsAdmin(userid) {
+		writeError(w, "only admins of the group may do this", http.StatusForbidden)
+		return g, false
+	}
+	return g, true
+}
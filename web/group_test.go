@@ -0,0 +1,141 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	admin, member, other := "someadmin", "somemember", "someoneelse"
+	c := model.Calendar{Name: model.Attribute{Val: "cal"}, Owner: model.Attribute{Val: admin},
+		ID: model.Attribute{Val: admin + "/cal"}}
+	db = dbMock{
+		users: map[string]model.User{admin: model.NewUser(admin), member: model.NewUser(member),
+			other: model.NewUser(other)},
+		groups: map[string]model.Group{},
+		setCalendar: func(id string, cal model.Calendar) error {
+			c = cal
+			return nil
+		},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{},
+	}
+
+	do := func(h http.HandlerFunc, user string, vars map[string]string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		r = mux.SetURLVars(r, vars)
+		rr := httptest.NewRecorder()
+		h(rr, r)
+		return rr
+	}
+	team := map[string]string{groupIDStr: "team"}
+	memberOf := func(user string) map[string]string {
+		return map[string]string{groupIDStr: "team", memberIDStr: user}
+	}
+	share := func(group, perm string) *httptest.ResponseRecorder {
+		db.(dbMock).data["GetCalendar"] = struct {
+			d interface{}
+			e error
+		}{d: c}
+		return do(sharingHandler, admin, nil, url.Values{"calendarName": {"cal"}, "groupName": {group},
+			"perm": {perm}})
+	}
+	perm := func(user string) model.Permission {
+		r := httptest.NewRequest("GET", "/", nil)
+		return calendarPermission(r, c, user)
+	}
+
+	tt := []struct {
+		h    http.HandlerFunc
+		user string
+		vars map[string]string
+		form url.Values
+		code int
+	}{
+		{h: postGroupHandler, user: admin, form: url.Values{"name": {"team"}}, code: http.StatusSeeOther},
+		{h: postGroupHandler, user: other, form: url.Values{"name": {"team"}}, code: http.StatusConflict},
+		{h: postGroupHandler, user: admin, form: url.Values{"name": {"../team"}}, code: http.StatusUnprocessableEntity},
+		// only admins manage members, others don't see the group at all
+		{h: postGroupMemberHandler, user: admin, vars: team, form: url.Values{"user": {member}},
+			code: http.StatusSeeOther},
+		{h: postGroupMemberHandler, user: admin, vars: team, form: url.Values{"user": {"nobody"}},
+			code: http.StatusNotFound},
+		{h: postGroupMemberHandler, user: admin, vars: team, form: url.Values{"user": {member}, "role": {"owner"}},
+			code: http.StatusUnprocessableEntity},
+		{h: postGroupMemberHandler, user: member, vars: team, form: url.Values{"user": {other}},
+			code: http.StatusForbidden},
+		{h: postGroupMemberHandler, user: other, vars: team, form: url.Values{"user": {other}},
+			code: http.StatusNotFound},
+		{h: getGroupHandler, user: member, vars: team, code: http.StatusOK},
+		{h: getGroupHandler, user: other, vars: team, code: http.StatusNotFound},
+		// the last admin can neither be demoted nor leave
+		{h: postGroupMemberHandler, user: admin, vars: team, form: url.Values{"user": {admin}, "role": {"member"}},
+			code: http.StatusConflict},
+		{h: deleteGroupMemberHandler, user: admin, vars: memberOf(admin), code: http.StatusConflict},
+		{h: deleteGroupMemberHandler, user: member, vars: memberOf(admin), code: http.StatusForbidden},
+		{h: deleteGroupHandler, user: member, vars: team, code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		if rr := do(tc.h, tc.user, tc.vars, tc.form); rr.Code != tc.code {
+			t.Errorf("%d: wrong status code: got: %d want: %d \n%s", i, rr.Code, tc.code, rr.Body.String())
+		}
+	}
+
+	// members get the permission granted to the group and find the calendar
+	if rr := share("team", "edit"); rr.Code != http.StatusSeeOther {
+		t.Fatalf("calendar not shared: %d %s", rr.Code, rr.Body.String())
+	}
+	if p := perm(member); p != model.Edit {
+		t.Fatalf("wrong permission of member: got: %s want: %s", p, model.Edit)
+	}
+	if p := perm(other); p != model.None {
+		t.Fatalf("wrong permission of non-member: got: %s want: %s", p, model.None)
+	}
+	refs, err := visibleCalendars(db.(dbMock).users[member])
+	if err != nil || len(refs) != 1 || refs[0].Link != admin+"/cal" {
+		t.Fatalf("calendar not visible to member: %v %v", refs, err)
+	}
+
+	// only members may share with a group
+	db.(dbMock).groups["others"] = model.NewGroup("others", other)
+	for _, g := range []string{"unknown", "others"} {
+		if rr := share(g, "view"); rr.Code != http.StatusNotFound {
+			t.Fatalf("shared with group %s: %d", g, rr.Code)
+		}
+	}
+
+	// leaving takes effect immediately
+	if rr := do(deleteGroupMemberHandler, member, memberOf(member), nil); rr.Code != http.StatusSeeOther {
+		t.Fatalf("member couldn't leave: %d %s", rr.Code, rr.Body.String())
+	}
+	if p := perm(member); p != model.None {
+		t.Fatalf("permission not revoked on leaving: %s", p)
+	}
+	if refs, _ := visibleCalendars(db.(dbMock).users[member]); len(refs) != 0 {
+		t.Fatalf("calendar still visible after leaving: %v", refs)
+	}
+
+	// unsharing unlists the calendar
+	if rr := share("team", "none"); rr.Code != http.StatusSeeOther || len(c.Permissions.Edit.Group) != 0 ||
+		len(db.(dbMock).groups["team"].Calendars) != 0 {
+		t.Fatalf("calendar not unshared: %d %v %v", rr.Code, c.Permissions, db.(dbMock).groups["team"])
+	}
+
+	if rr := do(deleteGroupHandler, admin, team, nil); rr.Code != http.StatusSeeOther {
+		t.Fatalf("group not deleted: %d", rr.Code)
+	}
+	if _, ok := db.(dbMock).groups["team"]; ok {
+		t.Fatal("group not deleted")
+	}
+}
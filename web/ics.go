@@ -0,0 +1,21 @@
+package web
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"net/http"
+	"time"
+)
+
+// wantsICS returns whether the client asked for the calendar in the iCalendar format by the query parameter
+// format=ics, e.g. to subscribe to it with a calendar client.
+func wantsICS(r *http.Request) bool {
+	return r.URL.Query().Get("format") == "ics"
+}
+
+// writeICS writes the calendar in the iCalendar format
+func writeICS(w http.ResponseWriter, c model.Calendar) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s.ics"`, c.Name.Val))
+	w.Write([]byte(c.ICS(time.Now())))
+}
@@ -0,0 +1,175 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"time"
+)
+
+// inviteUser invites the user userName, who may not have registered yet, to the calendar c with permission perm,
+// replacing pending invitations of the user to the calendar. The calendar is only shared once the user accepts.
+func inviteUser(w http.ResponseWriter, r *http.Request, c model.Calendar, userName, perm string) {
+	if err := revokeInvitations(c.GetID(), userName); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	i := model.Invitation{ID: id.String(), Calendar: c.GetID(), From: c.Owner.Val, To: userName, Perm: perm,
+		Created: now, Expires: now.Add(conf.invitationDuration())}
+	if err := db.AddInvitation(i); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// revokeInvitations deletes the pending invitations of the user to the calendar with the given ID
+func revokeInvitations(calendarID, userName string) error {
+	is, err := db.GetInvitations(userName)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range is {
+		if i.Calendar != calendarID || i.To != userName {
+			continue
+		}
+		if err := db.DeleteInvitation(i.ID); err != nil && err != model.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// getInvitationsHandler lists the pending invitations the logged in user has received and sent
+func getInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	is, err := db.GetInvitations(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	var v model.Invitations
+	for _, i := range is {
+		if i.To == userid {
+			v.Received = append(v.Received, i)
+		} else {
+			v.Sent = append(v.Sent, i)
+		}
+	}
+
+	writeView(w, r, v, "")
+}
+
+// acceptInvitationHandler accepts an invitation the logged in user has received, sharing the calendar with the user
+func acceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	i, err := db.GetInvitation(mux.Vars(r)[invitationIDStr])
+	if err == model.ErrNotFound || (err == nil && i.To != userid) {
+		writeError(w, "invitation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// invitations are used once, also by concurrent requests
+	if err := db.DeleteInvitation(i.ID); err == model.ErrNotFound {
+		writeError(w, "invitation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the calendar may have been deleted or handed over since the invitation
+	c, err := db.GetCalendar(i.Calendar)
+	if err == model.ErrNotFound || (err == nil && c.Owner.Val != i.From) {
+		writeError(w, "calendar "+i.Calendar+" not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	u, err := db.GetUser(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.AssociateCalendar(u, c, i.Permission()); err == model.ErrAlreadyExists {
+		writeError(w, "calendar already shared with you", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the permissions of the calendar have been granted by the database
+	if after, err := db.GetCalendar(c.GetID()); err == nil {
+		recordAudit(auditEntry(r, c.GetID(), model.AuditShare, c.Metadata(), after.Metadata()))
+	} else {
+		log.Println(err)
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// deleteInvitationHandler declines an invitation the logged in user has received, or revokes one the user has sent
+func deleteInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	i, err := db.GetInvitation(mux.Vars(r)[invitationIDStr])
+	if err == model.ErrNotFound || (err == nil && i.To != userid && i.From != userid) {
+		writeError(w, "invitation not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteInvitation(i.ID); err != nil && err != model.ErrNotFound {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestInvitations(t *testing.T) {
+	owner, invitee, other := "someowner", "someinvitee", "someoneelse"
+	c := model.Calendar{Name: model.Attribute{Val: "cal"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/cal"}}
+	calendarSet := false
+	db = dbMock{
+		users: map[string]model.User{owner: model.NewUser(owner), invitee: model.NewUser(invitee),
+			other: model.NewUser(other)},
+		invitations: map[string]model.Invitation{},
+		setCalendar: func(string, model.Calendar) error {
+			calendarSet = true
+			return nil
+		},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{
+			"GetCalendar": {d: c},
+		},
+	}
+	invitations := db.(dbMock).invitations
+
+	do := func(h http.HandlerFunc, user, id string, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/?format=json", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		r = mux.SetURLVars(r, map[string]string{invitationIDStr: id})
+		rr := httptest.NewRecorder()
+		h(rr, r)
+		return rr
+	}
+	share := func(user, perm string) int {
+		return do(sharingHandler, owner, "", url.Values{"calendarName": {"cal"}, "userName": {user},
+			"perm": {perm}}).Code
+	}
+	pending := func(user string) []model.Invitation {
+		var res []model.Invitation
+		for _, i := range invitations {
+			if i.To == user {
+				res = append(res, i)
+			}
+		}
+		return res
+	}
+
+	tt := []struct {
+		user, perm string
+		code       int
+	}{
+		{user: invitee, perm: "view", code: http.StatusSeeOther},
+		// replaces the pending invitation
+		{user: invitee, perm: "edit", code: http.StatusSeeOther},
+		// held until registration
+		{user: "notregistered", perm: "view", code: http.StatusSeeOther},
+		{user: other, perm: "view", code: http.StatusSeeOther},
+		{user: "../illegal", perm: "view", code: http.StatusUnprocessableEntity},
+		{user: owner, perm: "view", code: http.StatusUnprocessableEntity},
+		{user: invitee, perm: "owner", code: http.StatusBadRequest},
+	}
+	for _, tc := range tt {
+		if code := share(tc.user, tc.perm); code != tc.code {
+			t.Errorf("sharing with %s (%s): wrong status code: got: %d want: %d", tc.user, tc.perm, code, tc.code)
+		}
+	}
+
+	// nothing is shared before accepting
+	is := pending(invitee)
+	if len(invitations) != 3 || len(is) != 1 || is[0].Perm != "edit" || is[0].From != owner || calendarSet ||
+		len(db.(dbMock).users[invitee].Items.Calendars) != 0 {
+		t.Fatalf("invitations not created correctly: %v %v", invitations, db.(dbMock).users[invitee])
+	}
+
+	rr := do(getInvitationsHandler, owner, "", nil)
+	var v model.Invitations
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil || len(v.Sent) != 3 || len(v.Received) != 0 {
+		t.Fatalf("invitations of the owner not listed correctly: %s %v", rr.Body.String(), err)
+	}
+
+	// only the invitee may accept, and only once
+	if rr := do(acceptInvitationHandler, other, is[0].ID, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("invitation accepted by another user: %d", rr.Code)
+	}
+	if rr := do(acceptInvitationHandler, invitee, is[0].ID, nil); rr.Code != http.StatusSeeOther {
+		t.Fatalf("invitation not accepted: %d %s", rr.Code, rr.Body.String())
+	}
+	refs := db.(dbMock).users[invitee].Items.Calendars
+	if len(refs) != 1 || refs[0].Link != owner+"/cal" || refs[0].Perm != "edit" || len(pending(invitee)) != 0 {
+		t.Fatalf("calendar not shared on accepting: %v %v", refs, invitations)
+	}
+	if rr := do(acceptInvitationHandler, invitee, is[0].ID, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("invitation accepted twice: %d", rr.Code)
+	}
+
+	// users who have the calendar get the new permission directly
+	if code := share(invitee, "view"); code != http.StatusSeeOther || !calendarSet || len(pending(invitee)) != 0 {
+		t.Fatalf("permission not changed directly: %d %v", code, invitations)
+	}
+
+	// invitees decline, owners revoke, others can't do either
+	declined, revoked := pending(other)[0], pending("notregistered")[0]
+	if rr := do(deleteInvitationHandler, invitee, declined.ID, nil); rr.Code != http.StatusNotFound {
+		t.Fatalf("invitation of another user deleted: %d", rr.Code)
+	}
+	if rr := do(deleteInvitationHandler, other, declined.ID, nil); rr.Code != http.StatusSeeOther {
+		t.Fatalf("invitation not declined: %d", rr.Code)
+	}
+	if rr := do(deleteInvitationHandler, owner, revoked.ID, nil); rr.Code != http.StatusSeeOther {
+		t.Fatalf("invitation not revoked: %d", rr.Code)
+	}
+	if len(invitations) != 0 {
+		t.Fatalf("invitations not deleted: %v", invitations)
+	}
+}
@@ -1,39 +1,24 @@
 package web
 
 import (
-	"errors"
+	"github.com/Project-Planner/backend/model"
 	"github.com/dgrijalva/jwt-go"
-	"github.com/google/uuid"
 	"time"
 )
 
-// createToken takes the userid and generates a JWT authentication token for it. The token is returned as model.JWT
-// and signed string ready for transmission to the client's browser, and an error, if one occurs
-func createToken(userid string) (string, error) {
-	id, err := uuid.NewRandom()
-	if err != nil {
-		return "", err
-	}
-	tkID := id.String()
-	exp := time.Now().Add(jwtDuration).Unix()
-
+// createToken generates a short-lived JWT access token for the given session. The token is identified by the session
+// ID, so that it is only accepted as long as the session exists. The token is returned as signed string ready for
+// transmission to the client's browser, and an error, if one occurs
+func createToken(s model.Session) (string, error) {
 	c := jwt.MapClaims{}
 	c[authorizedStr] = true
-	c[tokenIDStr] = tkID
-	c[userIDStr] = userid
-	c[expiryStr] = exp
+	c[tokenIDStr] = s.ID
+	c[userIDStr] = s.User
+	c[expiryStr] = time.Now().Add(conf.accessTokenDuration()).Unix()
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-	return t.SignedString([]byte(conf.JWTSecret))
+	return keys.sign(c)
 }
 
 func parseTokenAndVerifySignature(tokenString string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("jwt: signing method not correct")
-		}
-
-		return []byte(conf.JWTSecret), nil
-	})
-	return token, err
+	return jwt.Parse(tokenString, keys.keyFunc)
 }
@@ -0,0 +1,276 @@
+package web
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"time"
+)
+
+// minSecretLength is the minimum length in bytes of HMAC secrets, see RFC 7518, section 3.2
+const minSecretLength = 32
+
+// defaultKeyID is the ID of the key built from ServerConfig.JWTSecret. Tokens without kid header, which have been
+// issued before key rotation was introduced, are verified with the key of this ID.
+const defaultKeyID = "default"
+
+// keys is the key ring JWTs are signed and verified with, set up by ListenAndServe
+var keys keyRing
+
+// KeyConfig configures a key of the JWT key ring
+type KeyConfig struct {
+	// ID of the key, sent as kid header of the tokens signed with it
+	ID string `yaml:"id"`
+	// Algorithm is one of HS256, HS384, HS512, ES256, ES384, ES512 and EdDSA. If empty, it is derived from the key
+	Algorithm string `yaml:"algorithm"`
+	// Secret of HMAC keys
+	Secret string `yaml:"secret"`
+	// KeyFile is the path of a PEM file with the private key of ECDSA or Ed25519 keys (PKCS #8 or SEC 1)
+	KeyFile string `yaml:"key_file"`
+	// Retired is the date (yyyy-mm-dd or RFC 3339) the key has been replaced. Retired keys do not sign anymore, but
+	// still verify tokens for the grace period after retirement. Empty for active keys
+	Retired string `yaml:"retired"`
+}
+
+// signingKey is a key of the key ring
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+	retired time.Time
+}
+
+// keyRing holds all keys tokens are verified with. The first active key signs new tokens.
+type keyRing struct {
+	keys  []signingKey
+	grace time.Duration
+}
+
+// newKeyRing builds the key ring of the config. If no keys are configured, the JWTSecret is used as the only key.
+// Returns an error if a key can not be loaded, a secret is weak, or there is no active key to sign with.
+func newKeyRing(c ServerConfig) (keyRing, error) {
+	kcs := c.JWTKeys
+	if len(kcs) == 0 {
+		kcs = []KeyConfig{{ID: defaultKeyID, Algorithm: jwt.SigningMethodHS256.Alg(), Secret: c.JWTSecret}}
+	}
+
+	kr := keyRing{grace: c.jwtKeyGracePeriod()}
+	ids := make(map[string]bool)
+	active := false
+	for _, kc := range kcs {
+		if kc.ID == "" {
+			return keyRing{}, errors.New("jwt: key without id")
+		}
+		if ids[kc.ID] {
+			return keyRing{}, fmt.Errorf("jwt: duplicate key id %q", kc.ID)
+		}
+		ids[kc.ID] = true
+
+		k, err := loadKey(kc)
+		if err != nil {
+			return keyRing{}, fmt.Errorf("jwt: key %q: %w", kc.ID, err)
+		}
+
+		// the active key signing new tokens goes first
+		if k.retired.IsZero() && !active {
+			kr.keys = append([]signingKey{k}, kr.keys...)
+			active = true
+		} else {
+			kr.keys = append(kr.keys, k)
+		}
+	}
+
+	if !active {
+		return keyRing{}, errors.New("jwt: no active key to sign tokens with, all keys are retired")
+	}
+
+	return kr, nil
+}
+
+// loadKey loads the key of the config
+func loadKey(kc KeyConfig) (signingKey, error) {
+	k := signingKey{id: kc.ID}
+
+	if kc.Retired != "" {
+		t, err := time.Parse(time.RFC3339, kc.Retired)
+		if err != nil {
+			if t, err = time.Parse("2006-01-02", kc.Retired); err != nil {
+				return k, errors.New("retirement date not understood: " + kc.Retired)
+			}
+		}
+		k.retired = t
+	}
+
+	// HMAC keys
+	if kc.KeyFile == "" {
+		if err := checkSecret(kc.Secret); err != nil {
+			return k, err
+		}
+
+		switch kc.Algorithm {
+		case "", "HS256":
+			k.method = jwt.SigningMethodHS256
+		case "HS384":
+			k.method = jwt.SigningMethodHS384
+		case "HS512":
+			k.method = jwt.SigningMethodHS512
+		default:
+			return k, errors.New("algorithm " + kc.Algorithm + " requires a key file")
+		}
+		k.private, k.public = []byte(kc.Secret), []byte(kc.Secret)
+		return k, nil
+	}
+
+	// asymmetric keys
+	b, err := ioutil.ReadFile(kc.KeyFile)
+	if err != nil {
+		return k, err
+	}
+	priv, err := parsePrivateKey(b)
+	if err != nil {
+		return k, err
+	}
+
+	switch key := priv.(type) {
+	case ed25519.PrivateKey:
+		k.method = signingMethodEdDSA
+	case *ecdsa.PrivateKey:
+		switch key.Curve {
+		case elliptic.P256():
+			k.method = jwt.SigningMethodES256
+		case elliptic.P384():
+			k.method = jwt.SigningMethodES384
+		case elliptic.P521():
+			k.method = jwt.SigningMethodES512
+		default:
+			return k, errors.New("unsupported elliptic curve " + key.Curve.Params().Name)
+		}
+	default:
+		return k, fmt.Errorf("unsupported key type %T", priv)
+	}
+
+	if kc.Algorithm != "" && kc.Algorithm != k.method.Alg() {
+		return k, errors.New("key file does not match algorithm " + kc.Algorithm + ", it is a " + k.method.Alg() +
+			" key")
+	}
+
+	k.private, k.public = priv, priv.(crypto.Signer).Public()
+	return k, nil
+}
+
+// parsePrivateKey parses the first PEM block of b as PKCS #8 or SEC 1 private key
+func parsePrivateKey(b []byte) (interface{}, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// checkSecret returns an error if the HMAC secret is too weak to sign tokens with: too short or made up of only a
+// few different characters.
+func checkSecret(secret string) error {
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("secret too weak, it must be at least %d bytes long", minSecretLength)
+	}
+
+	distinct := make(map[rune]bool)
+	for _, r := range secret {
+		distinct[r] = true
+	}
+	if len(distinct) < minSecretLength/4 {
+		return errors.New("secret too weak, it consists of too few different characters")
+	}
+
+	return nil
+}
+
+// sign signs the claims with the active key and sets its ID as kid header
+func (kr keyRing) sign(claims jwt.Claims) (string, error) {
+	if len(kr.keys) == 0 {
+		return "", errors.New("jwt: no key to sign with")
+	}
+
+	k := kr.keys[0]
+	t := jwt.NewWithClaims(k.method, claims)
+	t.Header["kid"] = k.id
+	return t.SignedString(k.private)
+}
+
+// keyFunc returns the key to verify the token with, selected by its kid header. The algorithm of the token has to
+// match the one of the key, and retired keys are only used during the grace period.
+func (kr keyRing) keyFunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = defaultKeyID
+	}
+
+	for _, k := range kr.keys {
+		if k.id != kid {
+			continue
+		}
+
+		if t.Method.Alg() != k.method.Alg() {
+			return nil, errors.New("jwt: signing method not correct")
+		}
+		if !k.retired.IsZero() && time.Now().After(k.retired.Add(kr.grace)) {
+			return nil, fmt.Errorf("jwt: key %q has been retired", kid)
+		}
+		return k.public, nil
+	}
+
+	return nil, fmt.Errorf("jwt: unknown key %q", kid)
+}
+
+// signingMethodEdDSA implements the EdDSA signing method with Ed25519 keys (RFC 8037), which jwt-go lacks
+var signingMethodEdDSA = &signingMethodEd25519{}
+
+type signingMethodEd25519 struct{}
+
+func init() {
+	jwt.RegisterSigningMethod(signingMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return signingMethodEdDSA
+	})
+}
+
+func (m *signingMethodEd25519) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEd25519) Verify(signingString, signature string, key interface{}) error {
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(pub, []byte(signingString), sig) {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
+
+func (m *signingMethodEd25519) Sign(signingString string, key interface{}) (string, error) {
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(priv, []byte(signingString))), nil
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewKeyRing(t *testing.T) {
+	dir := t.TempDir()
+	ecFile := writeKey(t, dir, "ec.pem", ecKey(t))
+
+	tt := []struct {
+		name string
+		c    ServerConfig
+		ok   bool
+	}{
+		{name: "secret", c: ServerConfig{JWTSecret: testSecret}, ok: true},
+		{name: "sample secret", c: ServerConfig{JWTSecret: "abc"}},
+		{name: "no secret", c: ServerConfig{}},
+		{name: "monotonous secret", c: ServerConfig{JWTSecret: strings.Repeat("ab", 20)}},
+		{name: "ecdsa", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", KeyFile: ecFile}}}, ok: true},
+		{name: "ecdsa alg", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", KeyFile: ecFile, Algorithm: "ES256"}}},
+			ok: true},
+		{name: "wrong alg", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", KeyFile: ecFile, Algorithm: "ES384"}}}},
+		{name: "hmac alg with file", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", Algorithm: "ES256",
+			Secret: testSecret}}}},
+		{name: "missing file", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", KeyFile: dir + "/missing.pem"}}}},
+		{name: "missing id", c: ServerConfig{JWTKeys: []KeyConfig{{Secret: testSecret}}}},
+		{name: "duplicate id", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", Secret: testSecret},
+			{ID: "1", KeyFile: ecFile}}}},
+		{name: "all retired", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", Secret: testSecret,
+			Retired: "2021-07-01"}}}},
+		{name: "bad retirement", c: ServerConfig{JWTKeys: []KeyConfig{{ID: "1", Secret: testSecret},
+			{ID: "2", KeyFile: ecFile, Retired: "yesterday"}}}},
+	}
+
+	for _, tc := range tt {
+		_, err := newKeyRing(tc.c)
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: got error %v, want ok: %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	ecFile := writeKey(t, dir, "ec.pem", ecKey(t))
+	_, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edFile := writeKey(t, dir, "ed.pem", edPriv)
+
+	claims := jwt.MapClaims{userIDStr: "someusername"}
+	kid := func(token string) string {
+		tk, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+		if err != nil {
+			return ""
+		}
+		s, _ := tk.Header["kid"].(string)
+		return s
+	}
+
+	// tokens signed before rotation
+	old, err := newKeyRing(ServerConfig{JWTSecret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := old.sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid(oldToken) != defaultKeyID {
+		t.Fatalf("wrong kid: got: %s want: %s", kid(oldToken), defaultKeyID)
+	}
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, alg := range []struct {
+		file string
+		alg  string
+	}{{ecFile, "ES256"}, {edFile, "EdDSA"}} {
+		rotated := func(retired time.Time) keyRing {
+			kr, err := newKeyRing(ServerConfig{
+				JWTKeys: []KeyConfig{
+					{ID: defaultKeyID, Secret: testSecret, Retired: retired.Format(time.RFC3339)},
+					{ID: "new", KeyFile: alg.file},
+				},
+				JWTKeyGracePeriod: time.Hour,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return kr
+		}
+
+		// the new key signs, old tokens verify within the grace period
+		kr := rotated(time.Now().Add(-time.Minute))
+		newToken, err := kr.sign(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk, err := jwt.Parse(newToken, kr.keyFunc)
+		if err != nil || !tk.Valid || kid(newToken) != "new" || tk.Method.Alg() != alg.alg {
+			t.Fatalf("%s: token of new key not verified: %v", alg.alg, err)
+		}
+		for _, token := range []string{oldToken, legacyToken} {
+			if _, err := jwt.Parse(token, kr.keyFunc); err != nil {
+				t.Errorf("%s: token of retired key not verified within grace period: %v", alg.alg, err)
+			}
+		}
+
+		// but not anymore after it
+		kr = rotated(time.Now().Add(-2 * time.Hour))
+		if _, err := jwt.Parse(oldToken, kr.keyFunc); err == nil {
+			t.Errorf("%s: token of retired key verified after grace period", alg.alg)
+		}
+
+		// the old ring does not know the new key
+		if _, err := jwt.Parse(newToken, old.keyFunc); err == nil {
+			t.Errorf("%s: token of unknown key verified", alg.alg)
+		}
+	}
+
+	// tokens claiming the algorithm of another key are rejected
+	kr, err := newKeyRing(ServerConfig{JWTKeys: []KeyConfig{{ID: "ec", KeyFile: ecFile}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	forged.Header["kid"] = "ec"
+	pub, err := x509.MarshalPKIXPublicKey(kr.keys[0].public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedToken, err := forged.SignedString(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(forgedToken, kr.keyFunc); err == nil {
+		t.Error("token with algorithm of another key verified")
+	}
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// writeKey writes the private key as PKCS #8 PEM file into dir and returns its path
+func writeKey(t *testing.T, dir, name string, key interface{}) string {
+	b, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: b}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -1,15 +1,23 @@
 package web
 
 import (
+	_ "embed"
 	"html/template"
 	"io/ioutil"
+	"os"
 )
 
+// defaultAgenda is the agenda view used if the frontend doesn't ship its own
+//
+//go:embed agenda.xsl
+var defaultAgenda string
+
 var loaded struct {
 	calendar string
 	project  string
 	editItem string
 	showCalendars string
+	agenda        string
 }
 
 func load() {
@@ -18,6 +26,7 @@ func load() {
 		project  string
 		editItem string
 		showCalendars string
+		agenda        string
 	}{}
 
 	// loading the xsl into memory as they are queried very often
@@ -45,6 +54,15 @@ func load() {
 	}
 	loaded.showCalendars = string(c)
 
+	// older frontends don't ship an agenda view, so the default one is used
+	c, err = ioutil.ReadFile(conf.FrontendDir + "/data/agenda.xsl")
+	if os.IsNotExist(err) {
+		c = []byte(defaultAgenda)
+	} else if err != nil {
+		panic(err)
+	}
+	loaded.agenda = string(c)
+
 	// Loading the template for fancy error reporting
 	tmpl, err := ioutil.ReadFile(conf.FrontendDir + "/html/error.html")
 	if err != nil {
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MailSender sends plain text mails, e.g. with password reset links
+type MailSender interface {
+	Send(to, subject, body string) error
+}
+
+// SMTPConfig of the server mails are sent with
+type SMTPConfig struct {
+	// Host of the SMTP server. Mails are only logged if empty
+	Host string `yaml:"host"`
+	// Port of the SMTP server, 587 if not set
+	Port int `yaml:"port"`
+	// Username and Password to authenticate with (PLAIN), no authentication if empty
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// From is the sender address of all mails
+	From string `yaml:"from"`
+	// LogBodies logs the bodies of the mails as well, if no host is configured. They hold live password reset links,
+	// so it is meant for development only
+	LogBodies bool `yaml:"log_bodies"`
+}
+
+// mailer sends all mails, set up by ListenAndServe
+var mailer MailSender = logMailSender{}
+
+// newMailSender returns an SMTP mail sender if a host is configured, else one that only logs the mails
+func newMailSender(c SMTPConfig) (MailSender, error) {
+	if c.Host == "" {
+		return logMailSender{bodies: c.LogBodies}, nil
+	}
+
+	if c.From == "" {
+		return nil, errors.New("smtp: no sender address (from) configured")
+	}
+
+	port := c.Port
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+
+	return smtpMailSender{addr: net.JoinHostPort(c.Host, strconv.Itoa(port)), from: c.From, auth: auth}, nil
+}
+
+// smtpMailSender sends mails via SMTP, using STARTTLS if the server supports it
+type smtpMailSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func (s smtpMailSender) Send(to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return errors.New("smtp: illegal recipient " + to)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{to}, []byte(msg.String()))
+}
+
+// logMailSender only logs the recipients and subjects of the mails, and their bodies if enabled, which is useful for
+// development
+type logMailSender struct {
+	bodies bool
+}
+
+func (l logMailSender) Send(to, subject, body string) error {
+	if !l.bodies {
+		log.Printf("mail to %s: %s", to, subject)
+		return nil
+	}
+	log.Printf("mail to %s: %s\n%s", to, subject, body)
+	return nil
+}
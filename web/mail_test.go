@@ -0,0 +1,144 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer is a minimal SMTP server accepting a single mail, as a stand-in for a real one. It sends the
+// commands and the data received to the channel.
+func fakeSMTPServer(t *testing.T) (SMTPConfig, <-chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		defer func() { received <- lines }()
+
+		rd := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		reply("220 localhost ESMTP fake")
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				reply("235 2.7.0 Authentication successful")
+			case "MAIL", "RCPT":
+				reply("250 2.1.0 Ok")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				for {
+					line, err := rd.ReadString('\n')
+					if err != nil {
+						return
+					}
+					line = strings.TrimRight(line, "\r\n")
+					if line == "." {
+						break
+					}
+					lines = append(lines, line)
+				}
+				reply("250 2.0.0 Ok: queued")
+			case "QUIT":
+				reply("221 2.0.0 Bye")
+				return
+			default:
+				reply("502 5.5.2 Error: command not recognized")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return SMTPConfig{Host: host, Port: p, Username: "plannet", Password: "secret", From: "noreply@example.com"},
+		received
+}
+
+func TestSMTPMailSender(t *testing.T) {
+	c, received := fakeSMTPServer(t)
+
+	m, err := newMailSender(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Send("someone@example.com", "Reset your password", "Hello,\nfollow this link"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Join(<-received, "\n")
+	auth := base64.StdEncoding.EncodeToString([]byte("\x00plannet\x00secret"))
+	for _, want := range []string{
+		"AUTH PLAIN " + auth,
+		"MAIL FROM:<noreply@example.com>",
+		"RCPT TO:<someone@example.com>",
+		"From: noreply@example.com",
+		"To: someone@example.com",
+		"Subject: Reset your password",
+		"Content-Type: text/plain; charset=utf-8",
+		"Hello,\nfollow this link",
+		"QUIT",
+	} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("%q not received by server:\n%s", want, lines)
+		}
+	}
+
+	if err := m.Send("someone@example.com\r\nBcc: other@example.com", "Subject", "Body"); err == nil {
+		t.Error("header injection not rejected")
+	}
+}
+
+func TestNewMailSender(t *testing.T) {
+	if m, err := newMailSender(SMTPConfig{}); err != nil || m != (logMailSender{}) {
+		t.Errorf("mails are not logged without SMTP host: %v %v", m, err)
+	}
+	if _, err := newMailSender(SMTPConfig{Host: "localhost"}); err == nil {
+		t.Error("SMTP sender without sender address accepted")
+	}
+}
+
+func TestLogMailSender(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	for _, bodies := range []bool{false, true} {
+		buf.Reset()
+		if err := (logMailSender{bodies: bodies}).Send("someone@example.com", "Reset your password",
+			"secret link"); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "someone@example.com: Reset your password") ||
+			strings.Contains(buf.String(), "secret link") != bodies {
+			t.Errorf("wrong log with bodies %v: %s", bodies, buf.String())
+		}
+	}
+}
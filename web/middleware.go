@@ -3,6 +3,7 @@ package web
 import (
 	"context"
 	"fmt"
+	"github.com/Project-Planner/backend/model"
 	"github.com/dgrijalva/jwt-go"
 	"log"
 	"net/http"
@@ -10,52 +11,140 @@ import (
 	"time"
 )
 
-// auth authenticates and authorizes a user for accessing a requested resource
+// auth authenticates and authorizes a user for accessing a requested resource. An expired access token is refreshed
+// transparently, if the refresh token of a still valid session is presented. Scripts authenticate with a personal
+// access token in the Authorization header instead of cookies, which need no CSRF token, as browsers never send the
+// header on their own.
 func auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
-			c, err := r.Cookie(authStr)
-			if err != nil {
-				writeError(w, "no authentication token (jwt) provided, please log in.\n"+err.Error(),
-					http.StatusUnauthorized)
+			if r.Header.Get("Authorization") != "" {
+				t, ok := verifyBearerToken(w, r)
+				if !ok {
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), userIDStr, t.User)
+				ctx = context.WithValue(ctx, accessTokenStr, t)
+				ctx = withProfile(ctx, w, t.User)
+				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
-			t, err := parseTokenAndVerifySignature(c.Value)
-			if err != nil {
-				log.Println(err)
-				writeError(w, "untrusted signature, please log in again.", http.StatusUnauthorized)
+			userID, tokenID, expired, ok := verifyAccessToken(w, r)
+			if !ok {
 				return
 			}
 
-			claims, ok := t.Claims.(jwt.MapClaims)
-			if !ok || !t.Valid {
-				writeError(w, "token invalid, please log in again", http.StatusUnauthorized)
-				return
+			if expired {
+				rc, err := r.Cookie(refreshStr)
+				if err != nil {
+					deleteAuthCookies(w)
+					writeError(w, "your session has expired, please log in again", http.StatusUnauthorized)
+					return
+				}
+
+				s, err := refreshSession(w, r, rc.Value)
+				if err == errRefreshFailed {
+					deleteAuthCookies(w)
+					writeError(w, "your session has expired or has been revoked, please log in again",
+						http.StatusUnauthorized)
+					return
+				} else if err != nil {
+					log.Println(err)
+					writeError(w, "", http.StatusInternalServerError)
+					return
+				}
+				userID, tokenID = s.User, s.ID
 			}
 
-			uid, ok := claims[userIDStr]
-			userID, cast := uid.(string)
-			if !ok || !cast {
-				writeError(w, "user_id missing", http.StatusUnauthorized)
+			// the token is only valid as long as its session has not been revoked
+			s, err := db.GetSession(tokenID)
+			if err == model.ErrNotFound || (err == nil && s.User != userID) {
+				deleteAuthCookies(w)
+				writeError(w, "your session has been revoked, please log in again", http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				log.Println(err)
+				writeError(w, "", http.StatusInternalServerError)
 				return
 			}
-			exp, ok := claims[expiryStr]
-			expiry, err := strconv.ParseInt(fmt.Sprintf("%.f", exp), 10, 64)
-			if !ok || err != nil {
-				writeError(w, "expiry date missing", http.StatusUnauthorized)
+
+			// cookies are sent along with requests of other sites, which must not change anything
+			if s, err = ensureCSRFToken(s); err != nil {
+				log.Println(err)
+				writeError(w, "", http.StatusInternalServerError)
 				return
 			}
-			if time.Now().Unix() > expiry {
-				deleteCookie(w, c)
-				writeError(w, "your session has expired, please log in again", http.StatusUnauthorized)
+			if !checkCSRF(w, r, s) {
 				return
 			}
+			w.Header().Set(csrfHeader, s.CSRFToken)
 
 			// Sets the verified user context, this user is authenticated
 			ctx := context.WithValue(r.Context(), userIDStr, userID)
+			ctx = context.WithValue(ctx, tokenIDStr, tokenID)
+			ctx = context.WithValue(ctx, csrfStr, s.CSRFToken)
+			ctx = withProfile(ctx, w, userID)
 
 			// executes the next function in the chain. Do not remove this.
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 }
+
+// verifyAccessToken verifies the access token of the request and returns the user and session it was issued for.
+// Missing or expired access tokens are reported as expired, if a refresh token is present, so that the caller can
+// refresh them. If ok is false, an error has been written to w.
+func verifyAccessToken(w http.ResponseWriter, r *http.Request) (userID, tokenID string, expired, ok bool) {
+	_, refreshErr := r.Cookie(refreshStr)
+
+	c, err := r.Cookie(authStr)
+	if err != nil {
+		if refreshErr == nil {
+			return "", "", true, true
+		}
+		writeError(w, "no authentication token (jwt) provided, please log in.\n"+err.Error(),
+			http.StatusUnauthorized)
+		return
+	}
+
+	t, err := parseTokenAndVerifySignature(c.Value)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "untrusted signature, please log in again.", http.StatusUnauthorized)
+		return
+	}
+
+	claims, cast := t.Claims.(jwt.MapClaims)
+	if !cast || !t.Valid {
+		writeError(w, "token invalid, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	// tokens for other purposes, e.g. the password verified token of a two-step login, are no access tokens
+	if _, found := claims[purposeStr]; found {
+		writeError(w, "token invalid, please log in again", http.StatusUnauthorized)
+		return
+	}
+
+	uid, found := claims[userIDStr]
+	userID, cast = uid.(string)
+	if !found || !cast {
+		writeError(w, "user_id missing", http.StatusUnauthorized)
+		return
+	}
+	tid, found := claims[tokenIDStr]
+	tokenID, cast = tid.(string)
+	if !found || !cast {
+		writeError(w, "token_id missing", http.StatusUnauthorized)
+		return
+	}
+	exp, found := claims[expiryStr]
+	expiry, err := strconv.ParseInt(fmt.Sprintf("%.f", exp), 10, 64)
+	if !found || err != nil {
+		writeError(w, "expiry date missing", http.StatusUnauthorized)
+		return
+	}
+
+	return userID, tokenID, time.Now().Unix() > expiry, true
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"github.com/Project-Planner/backend/model"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
 	"net/http"
@@ -10,26 +11,10 @@ import (
 )
 
 func TestAuth(t *testing.T) {
-	theConfig := ServerConfig{JWTSecret: "some%secret"}
+	theConfig := ServerConfig{JWTSecret: testSecret}
 
-	conf = theConfig
-
-	createExpiredToken := func(username string) string {
-		exp := time.Now().Add(-jwtDuration).Unix()
-
-		c := jwt.MapClaims{}
-		c[authorizedStr] = true
-		c[tokenIDStr] = uuid.New()
-		c[userIDStr] = username
-		c[expiryStr] = exp
-
-		t := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
-		tStr, err := t.SignedString([]byte(conf.JWTSecret))
-		if err != nil {
-			panic(err)
-		}
-		return tStr
-	}
+	useConfig(t, theConfig)
+	db = dbMock{sessions: map[string]model.Session{}}
 
 	un := "someusername"
 	okCookie, err := createCookie(un)
@@ -37,11 +22,21 @@ func TestAuth(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	revokedCookie, err := createCookie(un)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteSessions(un, tokenID(t, okCookie)); err != nil {
+		t.Fatal(err)
+	}
+
 	tt := []struct {
 		un               string
 		c                http.Cookie
+		method           string
 		jwtMissing       bool
 		invalidSignature bool
+		csrf             string
 		code             int
 	}{
 		// kosher case
@@ -50,6 +45,25 @@ func TestAuth(t *testing.T) {
 			c:    okCookie,
 			code: http.StatusOK,
 		},
+		// safe methods need no CSRF token
+		{
+			un:     un,
+			c:      okCookie,
+			method: "GET",
+			csrf:   "-",
+			code:   http.StatusOK,
+		},
+		// CSRF token missing or wrong
+		{
+			c:    okCookie,
+			csrf: "-",
+			code: http.StatusForbidden,
+		},
+		{
+			c:    okCookie,
+			csrf: "wrong",
+			code: http.StatusForbidden,
+		},
 		// cookie missing
 		{
 			jwtMissing: true,
@@ -76,28 +90,46 @@ func TestAuth(t *testing.T) {
 			un: un,
 			c: http.Cookie{
 				Name:  authStr,
-				Value: createExpiredToken(un),
+				Value: createExpiredToken(uuid.New().String(), un),
 			},
 			code: http.StatusUnauthorized,
 		},
+		// revoked session
+		{
+			un:   un,
+			c:    revokedCookie,
+			code: http.StatusUnauthorized,
+		},
 	}
 
 	for _, tc := range tt {
-		conf = theConfig
+		useConfig(t, theConfig)
 
-		r, err := http.NewRequest("POST", "/authorize", nil)
+		if tc.method == "" {
+			tc.method = "POST"
+		}
+		r, err := http.NewRequest(tc.method, "/authorize", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		// the CSRF token of the valid session is sent, unless the test case sends another one or none ("-")
+		switch tc.csrf {
+		case "":
+			r.Header.Set(csrfHeader, db.(dbMock).sessions[tokenID(t, okCookie)].CSRFToken)
+		case "-":
+		default:
+			r.Header.Set(csrfHeader, tc.csrf)
+		}
+
 		// special test cases
 		if !tc.jwtMissing {
 			r.AddCookie(&tc.c)
 		}
 		if tc.invalidSignature {
-			conf = ServerConfig{
-				JWTSecret: "otherSecret",
-			}
+			useConfig(t, ServerConfig{
+				JWTSecret: "other%secret-that-is-long-enough-for-hs256",
+			})
 		}
 
 		// tests whether the context
@@ -109,6 +141,12 @@ func TestAuth(t *testing.T) {
 			if n != tc.un {
 				t.Errorf("username incorrect, want: %s got: %s", tc.un, n)
 			}
+			if id, ok := r.Context().Value(tokenIDStr).(string); !ok || id != tokenID(t, tc.c) {
+				t.Errorf("%s not in request context: got %q", tokenIDStr, id)
+			}
+			if vs := csrfVars(r); len(vs) != 1 || vs[0].value != db.(dbMock).sessions[tokenID(t, tc.c)].CSRFToken {
+				t.Errorf("CSRF token not passed on to the XSL: got %v", vs)
+			}
 
 			w.WriteHeader(http.StatusOK)
 		})
@@ -125,8 +163,134 @@ func TestAuth(t *testing.T) {
 
 }
 
+func TestRefresh(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret})
+	sessions := map[string]model.Session{}
+	db = dbMock{sessions: sessions}
+
+	un := "someusername"
+
+	// log in
+	rr := httptest.NewRecorder()
+	if err := setAuthCookie(rr, httptest.NewRequest("POST", "/api/login", nil), un); err != nil {
+		t.Fatal(err)
+	}
+	cookies := cookieMap(rr)
+	first := cookies[refreshStr]
+	if first == nil || cookies[authStr] == nil {
+		t.Fatalf("cookies not set: %v", rr.Result().Cookies())
+	}
+	sid := tokenID(t, *cookies[authStr])
+	expired := &http.Cookie{Name: authStr, Value: createExpiredToken(sid, un)}
+
+	serve := func(cs ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/c", nil)
+		for _, c := range cs {
+			r.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if n, _ := r.Context().Value(userIDStr).(string); n != un {
+				t.Errorf("username incorrect, want: %s got: %s", un, n)
+			}
+			w.WriteHeader(http.StatusOK)
+		})).ServeHTTP(rr, r)
+		return rr
+	}
+
+	// expired access token is refreshed and the refresh token rotated
+	rr = serve(expired, first)
+	cookies = cookieMap(rr)
+	if rr.Code != http.StatusOK || cookies[authStr] == nil || cookies[refreshStr] == nil {
+		t.Fatalf("not refreshed: %d %v", rr.Code, rr.Result().Cookies())
+	}
+	second := cookies[refreshStr]
+	if second.Value == first.Value {
+		t.Fatal("refresh token not rotated")
+	}
+	if rr := serve(cookies[authStr]); rr.Code != http.StatusOK {
+		t.Fatalf("refreshed access token not accepted: %d", rr.Code)
+	}
+
+	// missing access token is refreshed as well
+	rr = serve(second)
+	cookies = cookieMap(rr)
+	if rr.Code != http.StatusOK || cookies[refreshStr] == nil {
+		t.Fatalf("not refreshed: %d %v", rr.Code, rr.Result().Cookies())
+	}
+	third := cookies[refreshStr]
+
+	// the previous refresh token is accepted within the grace period, but not rotated
+	rr = serve(expired, second)
+	cookies = cookieMap(rr)
+	if rr.Code != http.StatusOK || cookies[authStr] == nil || cookies[refreshStr] != nil {
+		t.Fatalf("previous refresh token not accepted within grace period: %d %v", rr.Code, rr.Result().Cookies())
+	}
+
+	// malformed refresh token
+	if rr := serve(expired, &http.Cookie{Name: refreshStr, Value: "garbage"}); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("malformed refresh token accepted: %d", rr.Code)
+	}
+
+	// reuse of an older refresh token revokes the session
+	if rr := serve(expired, first); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("reused refresh token accepted: %d", rr.Code)
+	}
+	if _, ok := sessions[sid]; ok {
+		t.Fatal("session not revoked after reuse")
+	}
+	if rr := serve(expired, third); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("refresh token of revoked session accepted: %d", rr.Code)
+	}
+}
+
+// testSecret is a secret strong enough for the key ring
+const testSecret = "some%secret-that-is-long-enough-for-hs256"
+
+// useConfig sets the config and builds its key ring
+func useConfig(t *testing.T, c ServerConfig) {
+	var err error
+	conf = c
+	if keys, err = newKeyRing(c); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// createExpiredToken returns an access token of the session that has expired
+func createExpiredToken(sessionID, username string) string {
+	c := jwt.MapClaims{}
+	c[authorizedStr] = true
+	c[tokenIDStr] = sessionID
+	c[userIDStr] = username
+	c[expiryStr] = time.Now().Add(-conf.accessTokenDuration()).Unix()
+
+	tStr, err := keys.sign(c)
+	if err != nil {
+		panic(err)
+	}
+	return tStr
+}
+
+// cookieMap returns the cookies set by the response by name
+func cookieMap(rr *httptest.ResponseRecorder) map[string]*http.Cookie {
+	m := map[string]*http.Cookie{}
+	for _, c := range rr.Result().Cookies() {
+		m[c.Name] = c
+	}
+	return m
+}
+
+// createCookie starts a session of the user in db and returns the cookie with its token
 func createCookie(username string) (http.Cookie, error) {
-	t, err := createToken(username)
+	s, _, err := newSession(httptest.NewRequest("POST", "/api/login", nil), username)
+	if err != nil {
+		return http.Cookie{}, err
+	}
+	if err := db.AddSession(s); err != nil {
+		return http.Cookie{}, err
+	}
+
+	t, err := createToken(s)
 	if err != nil {
 		return http.Cookie{}, err
 	}
@@ -134,9 +298,18 @@ func createCookie(username string) (http.Cookie, error) {
 	c := http.Cookie{
 		Name:     authStr,
 		Value:    t,
-		Expires:  time.Now().Add(jwtDuration),
+		Expires:  s.Expires,
 		HttpOnly: true,
 	}
 
 	return c, nil
 }
+
+// tokenID returns the token ID of the session the cookie belongs to
+func tokenID(t *testing.T, c http.Cookie) string {
+	tk, err := parseTokenAndVerifySignature(c.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tk.Claims.(jwt.MapClaims)[tokenIDStr].(string)
+}
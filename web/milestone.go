@@ -14,7 +14,7 @@ func postMilestoneHandler(w http.ResponseWriter, r *http.Request) {
 
 	c.Items.Milestones.Milestone = append(c.Items.Milestones.Milestone, i)
 
-	finishItem(w, r, c)
+	finishItem(w, r, c, itemAuditEntry(r, c.GetID(), model.KindMilestone, i.ID, model.AuditCreate, nil, i))
 }
 
 func putMilestoneHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,31 +37,13 @@ func putMilestoneHandler(w http.ResponseWriter, r *http.Request) {
 		return // err reporting already done by method call
 	}
 
+	before := items[idx]
 	items[idx].Update(a)
 
-	finishItem(w, r, c)
+	e := itemAuditEntry(r, c.GetID(), model.KindMilestone, before.ID, model.AuditUpdate, before, items[idx])
+	finishItem(w, r, c, e)
 }
 
 func deleteMilestoneHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := getCalendarIfPermission(w, r, model.Edit)
-	if err != nil {
-		// err reporting already done by method call
-		return
-	}
-
-	items := c.Items.Milestones.Milestone
-
-	ids := make([]model.Identifier, len(items))
-	for i, v := range items {
-		ids[i] = v
-	}
-	idx, err := itemIdx(w, r, ids...)
-	if err != nil {
-		return // err reporting already done by method call
-	}
-
-	items[idx] = items[len(items)-1]
-	c.Items.Milestones.Milestone = items[:len(items)-1]
-
-	finishItem(w, r, c)
+	trashItem(w, r, model.KindMilestone)
 }
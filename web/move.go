@@ -0,0 +1,108 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// moveItemHandler returns a handler moving the item of the given kind to the calendar named by the form field calendar
+// (owner/name), keeping its ID. The logged in user must be able to edit both calendars.
+func moveItemHandler(kind model.ItemKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		relocateItem(w, r, kind, false)
+	}
+}
+
+// copyItemHandler returns a handler copying the item of the given kind to the calendar named by the form field
+// calendar (owner/name) under a new ID. The logged in user must be able to edit both calendars.
+func copyItemHandler(kind model.ItemKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		relocateItem(w, r, kind, true)
+	}
+}
+
+// relocateItem moves or, if duplicate is set, copies the item of the request to the target calendar of the form
+func relocateItem(w http.ResponseWriter, r *http.Request, kind model.ItemKind, duplicate bool) {
+	src, err := getCalendarIfPermission(w, r, model.Edit)
+	if err != nil {
+		return
+	}
+
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	target := strings.TrimSpace(r.Form.Get("calendar"))
+	if target == "" {
+		writeError(w, "calendar missing, html input must have name 'calendar'", http.StatusUnprocessableEntity)
+		return
+	} else if target == src.GetID() {
+		writeError(w, "item is in this calendar already", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// calendars the user cannot view are reported as not found, so that their existence is not revealed
+	dst, err := db.GetCalendar(target)
+	if err == model.ErrNotFound {
+		writeError(w, "target calendar not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	if perm := calendarPermission(r, dst, userid); perm < model.Read {
+		writeError(w, "target calendar not found", http.StatusNotFound)
+		return
+	} else if perm < model.Edit {
+		writeError(w, "no permissions to edit the target calendar", http.StatusForbidden)
+		return
+	}
+
+	itemID, newID := mux.Vars(r)[itemIDStr], ""
+	if duplicate {
+		newID, err = db.CopyItem(src.GetID(), dst.GetID(), kind, itemID)
+	} else {
+		newID, err = itemID, db.MoveItem(src.GetID(), dst.GetID(), kind, itemID)
+	}
+
+	if err == model.ErrNotFound {
+		writeError(w, "item not found", http.StatusNotFound)
+		return
+	} else if err == model.ErrAlreadyExists {
+		writeError(w, "target calendar has an item of this ID already", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the item is recorded as the target calendar has it, as it may have lost its milestone
+	before, _ := src.Item(kind, itemID)
+	var after interface{}
+	if c, err := db.GetCalendar(dst.GetID()); err == nil {
+		after, _ = c.Item(kind, newID)
+	} else {
+		log.Println(err)
+	}
+	if duplicate {
+		recordAudit(itemAuditEntry(r, dst.GetID(), kind, newID, model.AuditCreate, nil, after))
+	} else {
+		recordAudit(itemAuditEntry(r, src.GetID(), kind, itemID, model.AuditMove, before, nil),
+			itemAuditEntry(r, dst.GetID(), kind, itemID, model.AuditMove, nil, after))
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
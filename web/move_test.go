@@ -0,0 +1,82 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRelocateItem(t *testing.T) {
+	owner, editor, viewer := "someowner", "someeditor", "someviewer"
+	newCalendar := func(name string) model.Calendar {
+		c := model.Calendar{Name: model.Attribute{Val: name}, Owner: model.Attribute{Val: owner},
+			ID: model.Attribute{Val: owner + "/" + name}}
+		c.Permissions.Edit.User = []model.Attribute{{Val: editor}}
+		c.Permissions.View.User = []model.Attribute{{Val: viewer}}
+		return c
+	}
+
+	src, dst, readonly := newCalendar("src"), newCalendar("dst"), newCalendar("readonly")
+	src.Items.Milestones.Milestone = []model.Milestone{{ID: "m1"}}
+	src.Items.Tasks.Task = []model.Task{{ID: "t1"}, {ID: "t2"}}
+	src.Items.Tasks.Task[0].Milestone.ID = "m1"
+	dst.Items.Tasks.Task = []model.Task{{ID: "t2"}}
+	readonly.Permissions.Edit.User = nil
+	readonly.Permissions.View.User = []model.Attribute{{Val: editor}}
+
+	mock := dbMock{
+		calendars: map[string]model.Calendar{src.GetID(): src, dst.GetID(): dst, readonly.GetID(): readonly},
+		groups:    map[string]model.Group{},
+	}
+	db = mock
+
+	tt := []struct {
+		user   string
+		kind   model.ItemKind
+		item   string
+		target string
+		copy   bool
+		code   int
+	}{
+		{user: editor, kind: model.KindTask, item: "t1", target: owner + "/dst", code: http.StatusSeeOther},
+		{user: editor, kind: model.KindTask, item: "t1", target: owner + "/dst", code: http.StatusNotFound},
+		{user: editor, kind: model.KindTask, item: "t2", target: owner + "/dst", code: http.StatusConflict},
+		{user: editor, kind: model.KindTask, item: "t2", target: owner + "/dst", copy: true, code: http.StatusSeeOther},
+		{user: editor, kind: model.KindMilestone, item: "m1", target: owner + "/readonly", code: http.StatusForbidden},
+		{user: editor, kind: model.KindMilestone, item: "m1", target: "someoneelse/dst", code: http.StatusNotFound},
+		{user: editor, kind: model.KindMilestone, item: "m1", target: owner + "/src", code: http.StatusUnprocessableEntity},
+		{user: editor, kind: model.KindMilestone, item: "m1", target: "", code: http.StatusUnprocessableEntity},
+		// both calendars must be editable
+		{user: viewer, kind: model.KindTask, item: "t2", target: owner + "/dst", code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"calendar": {tc.target}}.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "src", itemIDStr: tc.item})
+		rr := httptest.NewRecorder()
+		if tc.copy {
+			copyItemHandler(tc.kind)(rr, r)
+		} else {
+			moveItemHandler(tc.kind)(rr, r)
+		}
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+
+	moved, copied := mock.calendars[owner+"/dst"].Items.Tasks.Task, mock.calendars[owner+"/src"].Items.Tasks.Task
+	if len(moved) != 3 || moved[1].ID != "t1" || moved[1].Milestone.ID != "" || moved[2].ID == "t2" {
+		t.Errorf("expected t1 to be moved and t2 to be copied, got %v", moved)
+	}
+	if len(copied) != 1 || copied[0].ID != "t2" {
+		t.Errorf("expected only t2 to be left in the source, got %v", copied)
+	}
+}
@@ -0,0 +1,610 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"github.com/dgrijalva/jwt-go"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OIDCConfig configures single sign-on with an OpenID Connect provider. The password login keeps working.
+type OIDCConfig struct {
+	// Issuer is the URL of the provider, its configuration is discovered below /.well-known/openid-configuration.
+	// Single sign-on is disabled if not set
+	Issuer   string `yaml:"issuer"`
+	ClientID string `yaml:"client_id"`
+	// ClientSecret is sent with client_secret_basic, if set; public clients rely on PKCE alone
+	ClientSecret string `yaml:"client_secret"`
+	// Scopes to request, openid, profile and email if not set
+	Scopes []string `yaml:"scopes"`
+	// UsernameClaim is the claim of the ID token holding the username, preferred_username if not set
+	UsernameClaim string `yaml:"username_claim"`
+	// AutoProvision registers users logging in for the first time
+	AutoProvision bool `yaml:"auto_provision"`
+	// LinkExisting links existing accounts with the same username to the identity on its first login. Only enable
+	// this if the provider controls all usernames, as it hands over the accounts.
+	LinkExisting bool `yaml:"link_existing"`
+}
+
+// enabled returns whether single sign-on is configured
+func (c OIDCConfig) enabled() bool {
+	return c.Issuer != ""
+}
+
+// scopes returns the configured Scopes or the default, if none are configured
+func (c OIDCConfig) scopes() []string {
+	if len(c.Scopes) > 0 {
+		return c.Scopes
+	}
+	return []string{"openid", "profile", "email"}
+}
+
+// usernameClaim returns the configured UsernameClaim or the default, if none is configured
+func (c OIDCConfig) usernameClaim() string {
+	if c.UsernameClaim != "" {
+		return c.UsernameClaim
+	}
+	return "preferred_username"
+}
+
+const (
+	// oidcFlowDuration is how long the user has to log in at the provider
+	oidcFlowDuration = 10 * time.Minute
+	// jwksRefreshInterval is how often the keys of the provider are fetched at most, if a token references an
+	// unknown key
+	jwksRefreshInterval = time.Minute
+	// oidcPath is the path of the single sign-on routes, where the flow cookie is sent to
+	oidcPath = "/api/oidc"
+)
+
+var (
+	errOIDCFlow        = errors.New("single sign-on expired or not started here, please try again")
+	errOIDCNotLinked   = errors.New("an account with this name exists, but is not linked to single sign-on")
+	errOIDCNoAccount   = errors.New("there is no account for you yet, please ask an admin")
+	errOIDCIllegalName = errors.New("the username of the identity provider is no legal name")
+)
+
+// sso is the configured OpenID Connect provider, nil if single sign-on is disabled
+var sso *oidcProvider
+
+// oidcProvider talks to the OpenID Connect provider. Its configuration and keys are fetched lazily and cached, so
+// that the server starts even if the provider is unavailable.
+type oidcProvider struct {
+	config OIDCConfig
+	client *http.Client
+
+	mutex     sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+	fetched   time.Time
+}
+
+// oidcDiscovery is the part of the provider configuration in use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jwk is a public key of the provider, RSA or EC (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newOIDCProvider(c OIDCConfig) *oidcProvider {
+	return &oidcProvider{config: c, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover returns the configuration of the provider
+func (p *oidcProvider) discover() (oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return *p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", &d); err != nil {
+		return d, err
+	}
+	if d.Issuer != p.config.Issuer {
+		return d, fmt.Errorf("oidc: discovered issuer %s does not match the configured %s", d.Issuer, p.config.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return d, errors.New("oidc: provider configuration incomplete")
+	}
+
+	p.discovery = &d
+	return d, nil
+}
+
+// key returns the public key with the given ID, fetching the keys of the provider if it is unknown
+func (p *oidcProvider) key(kid string) (interface{}, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(p.fetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("oidc: unknown key %q", kid)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := p.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	p.fetched = time.Now()
+
+	p.keys = make(map[string]interface{})
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			log.Printf("oidc: skipping key %q: %v", k.Kid, err)
+			continue
+		}
+		p.keys[k.Kid] = pub
+	}
+
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("oidc: unknown key %q", kid)
+}
+
+// publicKey decodes the RSA or EC public key
+func (k jwk) publicKey() (interface{}, error) {
+	num := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil, errors.New("malformed key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := num(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := num(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() < 3 {
+			return nil, errors.New("malformed exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := num(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := num(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// authURL returns where to send the user to log in at the provider
+func (p *oidcProvider) authURL(state, nonce, verifier string) (string, error) {
+	d, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", oidcRedirectURI())
+	q.Set("scope", strings.Join(p.config.scopes(), " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(verifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// exchange redeems the authorization code for the ID token
+func (p *oidcProvider) exchange(code, verifier string) (string, error) {
+	d, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidcRedirectURI()},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequest("POST", d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc: token response: %v", err)
+	}
+	if res.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc: token request failed: %d %s %s", res.StatusCode, body.Error,
+			body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc: no id_token in token response")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken verifies the signature, issuer, audience, expiry and nonce of the ID token and returns its claims
+func (p *oidcProvider) verifyIDToken(raw, nonce string) (jwt.MapClaims, error) {
+	d, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := p.key(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// the algorithm must match the key, so that a public key is never used as HMAC secret
+		switch t.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			if _, ok := k.(*rsa.PublicKey); ok {
+				return k, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := k.(*ecdsa.PublicKey); ok {
+				return k, nil
+			}
+		}
+		return nil, fmt.Errorf("oidc: algorithm %s does not match key %q", t.Method.Alg(), kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok || !t.Valid {
+		return nil, errors.New("oidc: id token invalid")
+	}
+
+	if iss, _ := claims["iss"].(string); iss != d.Issuer {
+		return nil, fmt.Errorf("oidc: wrong issuer %q", iss)
+	}
+	if !audienceContains(claims["aud"], p.config.ClientID) {
+		return nil, errors.New("oidc: id token not issued for this client")
+	}
+	if _, ok := claims["exp"].(float64); !ok {
+		return nil, errors.New("oidc: id token without expiry")
+	}
+	if n, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(n), []byte(nonce)) != 1 {
+		return nil, errors.New("oidc: wrong nonce")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("oidc: id token without subject")
+	}
+
+	return claims, nil
+}
+
+// getJSON fetches the JSON document at u into v
+func (p *oidcProvider) getJSON(u string, v interface{}) error {
+	res, err := p.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, res.Body)
+		return fmt.Errorf("oidc: GET %s: %s", u, res.Status)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
+
+// audienceContains returns whether the aud claim, a string or an array of them, contains the client ID
+func audienceContains(aud interface{}, clientID string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == clientID
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pkceChallenge derives the S256 code challenge from the verifier (RFC 7636)
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// oidcRedirectURI is where the provider sends the user back to
+func oidcRedirectURI() string {
+	return strings.TrimSuffix(conf.PublicURL, "/") + oidcPath + "/callback"
+}
+
+// oidcCookie is the cookie holding the state of a single sign-on flow, only sent back to the single sign-on routes.
+// It has to be sent along with the top-level navigation back from the provider, hence SameSite Lax.
+func oidcCookie() http.Cookie {
+	return http.Cookie{Name: oidcStr, Path: oidcPath, HttpOnly: true, Secure: !conf.InsecureCookies,
+		SameSite: http.SameSiteLaxMode}
+}
+
+// oidcLoginHandler starts a single sign-on by sending the user to the provider. State, nonce and PKCE verifier are
+// kept in a signed cookie until the user returns.
+func oidcLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if sso == nil {
+		writeError(w, "single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	var secrets [3]string
+	for i := range secrets {
+		var err error
+		if secrets[i], err = randomToken(); err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+	}
+	state, nonce, verifier := secrets[0], secrets[1], secrets[2]
+
+	target, err := sso.authURL(state, nonce, verifier)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "identity provider unavailable, please try again later", http.StatusBadGateway)
+		return
+	}
+
+	c := jwt.MapClaims{}
+	c[purposeStr] = oidcStr
+	c["state"], c["nonce"], c["verifier"] = state, nonce, verifier
+	c[expiryStr] = time.Now().Add(oidcFlowDuration).Unix()
+	t, err := keys.sign(c)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	cookie := oidcCookie()
+	cookie.Value, cookie.Expires = t, time.Now().Add(oidcFlowDuration)
+	http.SetCookie(w, &cookie)
+
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// oidcCallbackHandler completes a single sign-on: the authorization code is exchanged for the ID token, which maps
+// to a user, who is logged in
+func oidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	if sso == nil {
+		writeError(w, "single sign-on is not configured", http.StatusNotFound)
+		return
+	}
+
+	flow, err := verifyOIDCFlow(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	c := oidcCookie()
+	deleteCookie(w, &c)
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		writeError(w, "single sign-on failed: "+e+" "+q.Get("error_description"), http.StatusUnauthorized)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(flow["state"])) != 1 || q.Get("code") == "" {
+		writeError(w, errOIDCFlow.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	raw, err := sso.exchange(q.Get("code"), flow["verifier"])
+	if err != nil {
+		log.Println(err)
+		writeError(w, "identity provider refused the login, please try again", http.StatusBadGateway)
+		return
+	}
+
+	claims, err := sso.verifyIDToken(raw, flow["nonce"])
+	if err != nil {
+		log.Println(err)
+		writeError(w, "identity provider sent an invalid token", http.StatusBadGateway)
+		return
+	}
+
+	l, err := oidcLogin(claims)
+	switch err {
+	case nil:
+	case errOIDCNotLinked:
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	case errOIDCNoAccount:
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	case errOIDCIllegalName:
+		writeError(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	default:
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	location, err := startLogin(w, r, l)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
+// verifyOIDCFlow returns the state, nonce and verifier of the single sign-on flow cookie of the request
+func verifyOIDCFlow(r *http.Request) (map[string]string, error) {
+	c, err := r.Cookie(oidcStr)
+	if err != nil {
+		return nil, errOIDCFlow
+	}
+
+	t, err := parseTokenAndVerifySignature(c.Value)
+	if err != nil {
+		return nil, errOIDCFlow
+	}
+
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok || !t.Valid || claims[purposeStr] != oidcStr {
+		return nil, errOIDCFlow
+	}
+	if exp, ok := claims[expiryStr].(float64); !ok || time.Now().Unix() > int64(exp) {
+		return nil, errOIDCFlow
+	}
+
+	flow := map[string]string{}
+	for _, k := range []string{"state", "nonce", "verifier"} {
+		v, ok := claims[k].(string)
+		if !ok || v == "" {
+			return nil, errOIDCFlow
+		}
+		flow[k] = v
+	}
+	return flow, nil
+}
+
+// oidcLogin returns the login of the identity the claims are about. Users without an account are provisioned and
+// unlinked accounts with the same name linked, if configured.
+func oidcLogin(claims jwt.MapClaims) (model.Login, error) {
+	id := model.OIDCIdentity{Issuer: claims["iss"].(string), Subject: claims["sub"].(string)}
+
+	username, _ := claims[sso.config.usernameClaim()].(string)
+	if !legalName(username) {
+		return model.Login{}, errOIDCIllegalName
+	}
+
+	l, err := db.GetLogin(username)
+	if err == model.ErrNotFound {
+		if !sso.config.AutoProvision {
+			return model.Login{}, errOIDCNoAccount
+		}
+
+		// without a password hash, the account can only log in via single sign-on, until a password is reset
+		if err := db.AddUser(username, ""); err != nil {
+			return model.Login{}, err
+		}
+		if l, err = db.GetLogin(username); err != nil {
+			return model.Login{}, err
+		}
+		log.Printf("oidc: provisioned user %s for subject %s", username, id.Subject)
+	} else if err != nil {
+		return model.Login{}, err
+	} else if l.OIDC == nil && !sso.config.LinkExisting {
+		return model.Login{}, errOIDCNotLinked
+	}
+
+	if l.OIDC != nil {
+		if *l.OIDC != id {
+			return model.Login{}, errOIDCNotLinked
+		}
+		return l, nil
+	}
+
+	err = db.UpdateLogin(username, func(u *model.Login) error {
+		// another login may have linked the account in the meantime
+		if u.OIDC != nil && *u.OIDC != id {
+			return errOIDCNotLinked
+		}
+		u.OIDC = &id
+		if email, _ := claims["email"].(string); email != "" && claims["email_verified"] == true && u.Email.Val == "" {
+			u.Email.Val = email
+		}
+		l = *u
+		return nil
+	})
+	return l, err
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/dgrijalva/jwt-go"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockProvider is an OpenID Connect provider issuing ID tokens for the codes registered with issue
+type mockProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mutex sync.Mutex
+	// codes map authorization codes to the PKCE challenge they were issued for and the ID token claims
+	codes map[string]struct {
+		challenge string
+		claims    jwt.MapClaims
+	}
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &mockProvider{key: key, codes: map[string]struct {
+		challenge string
+		claims    jwt.MapClaims
+	}{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		p.mutex.Lock()
+		c, ok := p.codes[r.FormValue("code")]
+		delete(p.codes, r.FormValue("code"))
+		p.mutex.Unlock()
+
+		if !ok || r.FormValue("grant_type") != "authorization_code" ||
+			pkceChallenge(r.FormValue("code_verifier")) != c.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, c.claims)
+		token.Header["kid"] = "k1"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "a", "token_type": "Bearer", "id_token": signed})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+// issue registers an authorization code for the challenge, redeemed for an ID token with the claims
+func (p *mockProvider) issue(code, challenge string, claims jwt.MapClaims) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.codes[code] = struct {
+		challenge string
+		claims    jwt.MapClaims
+	}{challenge, claims}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockProvider(t)
+	useConfig(t, ServerConfig{JWTSecret: testSecret, PublicURL: "https://plannet.example.com",
+		OIDC: OIDCConfig{Issuer: provider.URL, ClientID: "plannet", AutoProvision: true}})
+	sso = newOIDCProvider(conf.OIDC)
+	t.Cleanup(func() { sso = nil })
+
+	db = dbMock{
+		logins:   map[string]model.Login{"existing": model.NewLogin("existing", "hash")},
+		sessions: map[string]model.Session{},
+	}
+
+	// login runs a single sign-on, in which the provider issues an ID token with the claims altered by alter. The
+	// callback is sent with the state altered by alterState.
+	login := func(username string, alter func(jwt.MapClaims), alterState func(string) string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		oidcLoginHandler(rr, httptest.NewRequest("GET", "/api/oidc/login", nil))
+		if rr.Code != http.StatusFound {
+			t.Fatalf("login not started: %d %s", rr.Code, rr.Body.String())
+		}
+
+		loc, err := url.Parse(rr.Header().Get("Location"))
+		if err != nil || !strings.HasPrefix(loc.String(), provider.URL+"/authorize?") {
+			t.Fatalf("not redirected to the provider: %s", loc)
+		}
+		q := loc.Query()
+		if q.Get("client_id") != "plannet" || q.Get("code_challenge_method") != "S256" ||
+			q.Get("redirect_uri") != "https://plannet.example.com/api/oidc/callback" {
+			t.Fatalf("wrong authorization request: %s", loc)
+		}
+
+		claims := jwt.MapClaims{
+			"iss":                provider.URL,
+			"aud":                "plannet",
+			"sub":                "subject-" + username,
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"iat":                time.Now().Unix(),
+			"nonce":              q.Get("nonce"),
+			"preferred_username": username,
+			"email":              username + "@example.com",
+			"email_verified":     true,
+		}
+		if alter != nil {
+			alter(claims)
+		}
+		provider.issue("code-"+username, q.Get("code_challenge"), claims)
+
+		state := q.Get("state")
+		if alterState != nil {
+			state = alterState(state)
+		}
+		r := httptest.NewRequest("GET", "/api/oidc/callback?"+url.Values{"code": {"code-" + username},
+			"state": {state}}.Encode(), nil)
+		for _, c := range rr.Result().Cookies() {
+			r.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		oidcCallbackHandler(rr, r)
+		return rr
+	}
+
+	loggedIn := func(rr *httptest.ResponseRecorder) bool {
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == authStr && c.Value != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	// provisioned on the first login, linked on every further one
+	for i := 0; i < 2; i++ {
+		rr := login("newuser", nil, nil)
+		if rr.Code != http.StatusSeeOther || !loggedIn(rr) {
+			t.Fatalf("login %d failed: %d %s", i, rr.Code, rr.Body.String())
+		}
+	}
+	l := db.(dbMock).logins["newuser"]
+	if l.OIDC == nil || l.OIDC.Issuer != provider.URL || l.OIDC.Subject != "subject-newuser" ||
+		l.Email.Val != "newuser@example.com" || l.Hash.Val != "" {
+		t.Fatalf("user not provisioned correctly: %v", l)
+	}
+
+	tt := []struct {
+		name       string
+		username   string
+		alter      func(jwt.MapClaims)
+		alterState func(string) string
+		code       int
+	}{
+		{name: "state mismatch", username: "newuser", alterState: func(s string) string { return s + "x" },
+			code: http.StatusUnauthorized},
+		{name: "wrong nonce", username: "newuser", alter: func(c jwt.MapClaims) { c["nonce"] = "other" },
+			code: http.StatusBadGateway},
+		{name: "wrong audience", username: "newuser", alter: func(c jwt.MapClaims) { c["aud"] = []string{"other"} },
+			code: http.StatusBadGateway},
+		{name: "wrong issuer", username: "newuser", alter: func(c jwt.MapClaims) { c["iss"] = "https://evil" },
+			code: http.StatusBadGateway},
+		{name: "expired", username: "newuser",
+			alter: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+			code:  http.StatusBadGateway},
+		{name: "other subject", username: "newuser", alter: func(c jwt.MapClaims) { c["sub"] = "impostor" },
+			code: http.StatusConflict},
+		{name: "existing account not linked", username: "existing", code: http.StatusConflict},
+		{name: "illegal name", username: "new user", code: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range tt {
+		rr := login(tc.username, tc.alter, tc.alterState)
+		if rr.Code != tc.code || loggedIn(rr) {
+			t.Errorf("%s: wrong status code: got: %d want: %d \n%s", tc.name, rr.Code, tc.code, rr.Body.String())
+		}
+	}
+
+	// without provisioning, unknown users are refused
+	sso.config.AutoProvision = false
+	if rr := login("another", nil, nil); rr.Code != http.StatusForbidden {
+		t.Errorf("unknown user not refused: %d", rr.Code)
+	}
+
+	// a callback without a flow started by this browser is refused
+	rr := httptest.NewRecorder()
+	oidcCallbackHandler(rr, httptest.NewRequest("GET", "/api/oidc/callback?code=c&state=s", nil))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("callback without flow accepted: %d", rr.Code)
+	}
+}
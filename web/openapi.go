@@ -0,0 +1,42 @@
+package web
+
+import (
+	_ "embed" // embeds the OpenAPI specification
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+//go:embed openapi.json
+var openAPISpec []byte // generated alongside the endpoints by code_generation, do not edit it by hand
+
+// openAPIHandler serves the OpenAPI specification with the configured authed path prefix as default server
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	var spec map[string]interface{}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// servers[0].variables.authedPathName.default = conf.AuthedPathName
+	if servers, ok := spec["servers"].([]interface{}); ok && len(servers) > 0 {
+		if s, ok := servers[0].(map[string]interface{}); ok {
+			if vars, ok := s["variables"].(map[string]interface{}); ok {
+				if v, ok := vars["authedPathName"].(map[string]interface{}); ok {
+					v["default"] = conf.AuthedPathName
+				}
+			}
+		}
+	}
+
+	b, err := json.Marshal(spec)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type openAPIDoc struct {
+	Servers []struct {
+		Variables map[string]struct {
+			Default string `json:"default"`
+		} `json:"variables"`
+	} `json:"servers"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// TestOpenAPICoversRoutes fails if a registered route is missing from the specification. Regenerate the
+// specification with go generate in code_generation after adding a route to code_generation/handwritten_routes.go.
+func TestOpenAPICoversRoutes(t *testing.T) {
+	conf = ServerConfig{AuthedPathName: "/me"}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter().StrictSlash(true)
+	registerRoutes(r)
+
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // path prefixes and the static file server
+		}
+
+		public := true
+		if strings.HasPrefix(path, conf.AuthedPathName) {
+			path = strings.TrimPrefix(path, conf.AuthedPathName)
+			public = false
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			t.Errorf("path %s missing in openapi.json", path)
+			return nil
+		}
+
+		if _, overridden := item["servers"]; overridden != public {
+			t.Errorf("path %s: public route must override the server, authed routes must not", path)
+		}
+
+		for _, m := range methods {
+			if _, ok := item[strings.ToLower(m)]; !ok {
+				t.Errorf("operation %s %s missing in openapi.json", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	conf = ServerConfig{AuthedPathName: "/authed"}
+
+	r, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(openAPIHandler).ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("wrong status code: got: %d want: %d", rr.Code, http.StatusOK)
+	}
+
+	var doc openAPIDoc
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := doc.Servers[0].Variables["authedPathName"].Default; got != conf.AuthedPathName {
+		t.Errorf("authed path not set as default server: got: %s want: %s", got, conf.AuthedPathName)
+	}
+}
@@ -0,0 +1,244 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// changePasswordHandler sets a new password for the logged in user, who has to confirm the old one. All sessions
+// are revoked and a new one is started for the current device.
+func changePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	old, pw := r.Form.Get("old_password"), r.Form.Get("new_password")
+	if old == "" || pw == "" {
+		writeError(w, "old or new password missing, html inputs must have names 'old_password' and "+
+			"'new_password'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// guessing the old password is throttled like logins
+	if wait := loginWait(r, userid); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
+	l, err := db.GetLogin(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := comparePassword(r.Context(), l.Hash.Val, old); err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		loginFailed(r, userid)
+		writeError(w, "old password incorrect", http.StatusForbidden)
+		return
+	}
+
+	hashed, err := hashPassword(r.Context(), pw)
+	if err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := setPassword(userid, hashed); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := setAuthCookie(w, r, userid); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// pendingResets tracks the reset links being mailed in the background
+var pendingResets sync.WaitGroup
+
+// forgotPasswordHandler mails a password reset link to the address of the user. The response is the same whether
+// the user exists or not, so that it does not reveal the existence of accounts: requests are throttled for unknown
+// users as well, and the link is mailed in the background.
+func forgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	username := r.Form.Get("username")
+	if username == "" {
+		writeError(w, "username missing, html input must have name 'username'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// requests are delayed exponentially per account and IP address, so that nobody is flooded with mails
+	c := conf.LoginThrottle.withDefaults()
+	if wait := logins.wait(c, string(model.LockoutReset)+":"+username,
+		string(model.LockoutResetIP)+":"+clientIP(r)); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+	recordFailure(r, model.LockoutReset, username, c.LockoutThreshold)
+	recordFailure(r, model.LockoutResetIP, clientIP(r), c.IPLockoutThreshold)
+
+	pendingResets.Add(1)
+	go func() {
+		defer pendingResets.Done()
+		if err := sendResetToken(username); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// sendResetToken creates a reset token for the user and mails it, if the user exists and has an email address
+func sendResetToken(username string) error {
+	l, err := db.GetLogin(username)
+	if err == model.ErrNotFound || (err == nil && l.Email.Val == "") {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	t := model.ResetToken{
+		Hash:    hashToken(token),
+		User:    username,
+		Expires: time.Now().Add(conf.resetTokenDuration()),
+	}
+	if err := db.AddResetToken(t); err != nil {
+		return err
+	}
+
+	link := conf.PublicURL + "/html/resetPassword.html?" + url.Values{"token": {token}}.Encode()
+	body := "Hello " + username + ",\n\n" +
+		"someone, hopefully you, asked to reset your password. Follow this link to choose a new one:\n\n" +
+		link + "\n\n" +
+		"The link is valid until " + t.Expires.Format(time.RFC1123) + " and can only be used once. " +
+		"If you did not ask for it, just ignore this mail.\n"
+
+	return mailer.Send(l.Email.Val, "Reset your password", body)
+}
+
+// resetPasswordHandler sets a new password for the user of a reset token. All sessions of the user are revoked and
+// the user is logged in, which still requires the second factor, if enabled.
+func resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	token, pw := r.Form.Get("token"), r.Form.Get("password")
+	if token == "" || pw == "" {
+		writeError(w, "token or password missing, html inputs must have names 'token' and 'password'",
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	// guessing tokens is throttled per IP address, and only valid tokens are worth hashing the password
+	c := conf.LoginThrottle.withDefaults()
+	if wait := logins.wait(c, string(model.LockoutResetToken)+":"+clientIP(r)); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+	if _, err := db.GetResetToken(hashToken(token)); err == model.ErrNotFound {
+		recordFailure(r, model.LockoutResetToken, clientIP(r), c.IPLockoutThreshold)
+		writeError(w, "reset link invalid, expired or already used", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// hash before consuming the token, so that it is not lost if the server is busy
+	hashed, err := hashPassword(r.Context(), pw)
+	if err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	t, err := db.ConsumeResetToken(hashToken(token))
+	if err == model.ErrNotFound {
+		writeError(w, "reset link invalid, expired or already used", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	l, err := db.GetLogin(t.User)
+	if err == model.ErrNotFound {
+		writeError(w, "reset link invalid, expired or already used", http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := setPassword(t.User, hashed); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the reset link only replaces the password, not the second factor
+	location, err := startLogin(w, r, l)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
+// setPassword stores the hash of the new password in the login and revokes all sessions of the user
+func setPassword(userid, hashed string) error {
+	if err := db.UpdateLogin(userid, func(l *model.Login) error {
+		l.Hash.Val = hashed
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return db.DeleteSessions(userid, "")
+}
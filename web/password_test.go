@@ -0,0 +1,179 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// mailMock records all sent mails
+type mailMock struct {
+	mails *[]sentMail
+}
+
+type sentMail struct {
+	to, subject, body string
+}
+
+func (m mailMock) Send(to, subject, body string) error {
+	*m.mails = append(*m.mails, sentMail{to: to, subject: subject, body: body})
+	return nil
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret})
+
+	un := "someusername"
+	oldPW := "supersafepassword%&$"
+	hashed, err := bcrypt.GenerateFromPassword([]byte(oldPW), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		form url.Values
+		code int
+	}{
+		// kosher case
+		{form: url.Values{"old_password": {oldPW}, "new_password": {"new%password"}}, code: http.StatusSeeOther},
+		// wrong old password
+		{form: url.Values{"old_password": {"wrong"}, "new_password": {"new%password"}}, code: http.StatusForbidden},
+		// new password missing
+		{form: url.Values{"old_password": {oldPW}}, code: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range tt {
+		sessions := map[string]model.Session{"other": {ID: "other", User: un}}
+		db = dbMock{
+			sessions: sessions,
+			logins:   map[string]model.Login{un: model.NewLogin(un, string(hashed))},
+		}
+
+		r := httptest.NewRequest("PUT", "/password", strings.NewReader(tc.form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+
+		rr := httptest.NewRecorder()
+		changePasswordHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Fatalf("wrong status code: got: %d want: %d \n%s\n%v", rr.Code, tc.code, rr.Body.String(), tc)
+		}
+
+		l, _ := db.GetLogin(un)
+		changed := bcrypt.CompareHashAndPassword([]byte(l.Hash.Val), []byte("new%password")) == nil
+		_, kept := sessions["other"]
+		revoked := !kept
+		if changed != (tc.code == http.StatusSeeOther) || revoked != changed {
+			t.Errorf("password changed: %v, sessions revoked: %v, want: %v", changed, revoked,
+				tc.code == http.StatusSeeOther)
+		}
+		if changed && (len(sessions) != 1 || cookieMap(rr)[authStr] == nil) {
+			t.Error("no new session started for the current device")
+		}
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret, PublicURL: "https://plannet.example.com"})
+	logins = newThrottle()
+	t.Cleanup(func() { logins = newThrottle() })
+
+	var mails []sentMail
+	mailer = mailMock{mails: &mails}
+	t.Cleanup(func() { mailer = logMailSender{} })
+
+	withEmail := model.NewLogin("withemail", "hash")
+	withEmail.Email.Val = "someone@example.com"
+	sessions := map[string]model.Session{"s": {ID: "s", User: "withemail"}}
+	db = dbMock{
+		sessions: sessions,
+		logins: map[string]model.Login{
+			"withemail":    withEmail,
+			"withoutemail": model.NewLogin("withoutemail", "hash"),
+		},
+		resets: map[string]model.ResetToken{},
+	}
+
+	post := func(handler http.HandlerFunc, form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/password", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		return rr
+	}
+
+	// users without email and unknown users get the same response, but no mail
+	for _, un := range []string{"withoutemail", "unknown", "withemail"} {
+		if rr := post(forgotPasswordHandler, url.Values{"username": {un}}); rr.Code != http.StatusSeeOther {
+			t.Fatalf("wrong status code for %s: got: %d want: %d", un, rr.Code, http.StatusSeeOther)
+		}
+	}
+	if rr := post(forgotPasswordHandler, url.Values{}); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("wrong status code without username: got: %d want: %d", rr.Code, http.StatusUnprocessableEntity)
+	}
+	// further requests from the same IP address are throttled
+	if rr := post(forgotPasswordHandler, url.Values{"username": {"withemail"}}); rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("wrong status code for throttled request: got: %d want: %d", rr.Code, http.StatusTooManyRequests)
+	}
+	pendingResets.Wait()
+
+	if len(mails) != 1 || mails[0].to != "someone@example.com" {
+		t.Fatalf("wrong mails sent: %v", mails)
+	}
+	m := regexp.MustCompile(`https://plannet\.example\.com/html/resetPassword\.html\?token=(\S+)`).
+		FindStringSubmatch(mails[0].body)
+	if m == nil {
+		t.Fatalf("no reset link in mail: %s", mails[0].body)
+	}
+	token, _ := url.QueryUnescape(m[1])
+
+	// only the hash is stored
+	if _, ok := db.(dbMock).resets[hashToken(token)]; !ok {
+		t.Fatal("hash of reset token not stored")
+	}
+
+	// wrong tokens are rejected before hashing, so they can not keep the bcrypt slots busy
+	for i := 0; i < cap(bcryptSlots); i++ {
+		bcryptSlots <- struct{}{}
+	}
+	rr := post(resetPasswordHandler, url.Values{"token": {"wrong"}, "password": {"new%password"}})
+	for i := 0; i < cap(bcryptSlots); i++ {
+		<-bcryptSlots
+	}
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("wrong status code for wrong token: got: %d want: %d", rr.Code, http.StatusForbidden)
+	}
+
+	// kosher case
+	rr = post(resetPasswordHandler, url.Values{"token": {token}, "password": {"new%password"}})
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("wrong status code: got: %d want: %d \n%s", rr.Code, http.StatusSeeOther, rr.Body.String())
+	}
+	l, _ := db.GetLogin("withemail")
+	if bcrypt.CompareHashAndPassword([]byte(l.Hash.Val), []byte("new%password")) != nil {
+		t.Error("password not changed")
+	}
+	if _, ok := sessions["s"]; ok || len(sessions) != 1 || cookieMap(rr)[authStr] == nil {
+		t.Error("sessions not revoked or no new session started")
+	}
+
+	// tokens are single-use
+	if rr := post(resetPasswordHandler, url.Values{"token": {token}, "password": {"other"}}); rr.Code !=
+		http.StatusForbidden {
+		t.Fatalf("wrong status code for used token: got: %d want: %d", rr.Code, http.StatusForbidden)
+	}
+
+	// guessing tokens is throttled per IP address
+	post(resetPasswordHandler, url.Values{"token": {"wrong"}, "password": {"new%password"}})
+	if rr := post(resetPasswordHandler, url.Values{"token": {"wrong"}, "password": {"new%password"}}); rr.Code !=
+		http.StatusTooManyRequests {
+		t.Fatalf("wrong status code for guessed token: got: %d want: %d", rr.Code, http.StatusTooManyRequests)
+	}
+}
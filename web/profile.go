@@ -0,0 +1,199 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxDisplayNameLength is the maximum number of characters of display names
+const maxDisplayNameLength = 64
+
+// withProfile adds the profile of the user to the context and announces its locale, so that views and error pages
+// are rendered for the user. Users without a profile get the defaults.
+func withProfile(ctx context.Context, w http.ResponseWriter, userID string) context.Context {
+	u, err := db.GetUser(userID)
+	if err != nil {
+		if err != model.ErrNotFound {
+			log.Println(err)
+		}
+		return ctx
+	}
+
+	if u.Profile.Locale != "" {
+		w.Header().Set("Content-Language", u.Profile.Locale)
+	}
+	return context.WithValue(ctx, profileStr, u.Profile)
+}
+
+// profile returns the profile of the user the request has been authenticated for
+func profile(r *http.Request) model.Profile {
+	p, _ := r.Context().Value(profileStr).(model.Profile)
+	return p
+}
+
+// profileVars returns the profile of the request as XSL variables, so that the pages render dates for the user
+func profileVars(r *http.Request) []varXLS {
+	p := profile(r)
+	return []varXLS{
+		{name: "display_name", value: p.DisplayName},
+		{name: "locale", value: p.Locale},
+		{name: "time_zone", value: p.TimeZone},
+		{name: "week_start", value: strings.ToLower(p.FirstWeekday().String())},
+	}
+}
+
+// getProfileHandler shows the profile of the logged in user
+func getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	u, err := db.GetUser(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	l, err := db.GetLogin(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.ProfileView{Name: userid, Email: l.Email.Val, Profile: u.Profile}, "")
+}
+
+// putProfileHandler updates the fields of the profile of the logged in user that are present in the form, an empty
+// value resets the field to its default. Changing the email address, where password reset links are sent to,
+// requires the password of the user.
+func putProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	u, err := db.GetUser(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	p := u.Profile
+
+	if vs, ok := r.Form["display_name"]; ok {
+		n := strings.TrimSpace(vs[0])
+		if utf8.RuneCountInString(n) > maxDisplayNameLength || strings.IndexFunc(n, unicode.IsControl) >= 0 {
+			writeError(w, "illegal display name, it must have at most 64 characters", http.StatusUnprocessableEntity)
+			return
+		}
+		p.DisplayName = n
+	}
+
+	if vs, ok := r.Form["locale"]; ok {
+		if vs[0] != "" && !model.ValidLocale(vs[0]) {
+			writeError(w, "locale not understood, must be a language tag like de-DE", http.StatusUnprocessableEntity)
+			return
+		}
+		p.Locale = vs[0]
+	}
+
+	if vs, ok := r.Form["time_zone"]; ok {
+		if _, err := model.ParseTimeZone(vs[0]); vs[0] != "" && err != nil {
+			writeError(w, "time zone not understood, must be a name like Europe/Berlin",
+				http.StatusUnprocessableEntity)
+			return
+		}
+		p.TimeZone = vs[0]
+	}
+
+	if vs, ok := r.Form["week_start"]; ok {
+		d, err := model.ParseWeekday(vs[0])
+		if vs[0] != "" && err != nil {
+			writeError(w, "week start not understood, must be a weekday like monday", http.StatusUnprocessableEntity)
+			return
+		}
+		p.WeekStart = ""
+		if vs[0] != "" {
+			p.WeekStart = strings.ToLower(d.String())
+		}
+	}
+
+	if vs, ok := r.Form["email"]; ok {
+		if !updateEmail(w, r, userid, vs[0]) {
+			return
+		}
+	}
+
+	if err := db.SetProfile(userid, p); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// updateEmail sets the email address of the user, if it changes and the form holds the password of the user. Users
+// without a password, who log in via single sign-on only, need none. If ok is false, an error has been written to w.
+func updateEmail(w http.ResponseWriter, r *http.Request, userid, email string) (ok bool) {
+	if email != "" {
+		if a, err := mail.ParseAddress(email); err != nil || a.Address != email {
+			writeError(w, "illegal email address", http.StatusUnprocessableEntity)
+			return false
+		}
+	}
+
+	l, err := db.GetLogin(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return false
+	}
+	if l.Email.Val == email {
+		return true
+	}
+
+	if l.Hash.Val != "" {
+		// guessing the password is throttled like logins
+		if wait := loginWait(r, userid); wait > 0 {
+			writeThrottled(w, wait)
+			return false
+		}
+
+		if err := comparePassword(r.Context(), l.Hash.Val, r.Form.Get("password")); err == errBusy {
+			writeError(w, err.Error(), http.StatusServiceUnavailable)
+			return false
+		} else if err != nil {
+			loginFailed(r, userid)
+			writeError(w, "password incorrect, it is required to change the email address", http.StatusForbidden)
+			return false
+		}
+	}
+
+	if err := db.UpdateLogin(userid, func(l *model.Login) error {
+		l.Email.Val = email
+		return nil
+	}); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret})
+	logins = newThrottle()
+	t.Cleanup(func() { logins = newThrottle() })
+
+	un, pw := "someusername", "supersafepassword%&$"
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = dbMock{
+		users:  map[string]model.User{un: model.NewUser(un)},
+		logins: map[string]model.Login{un: model.NewLogin(un, string(hashed))},
+	}
+
+	put := func(form url.Values) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("PUT", "/profile", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+		rr := httptest.NewRecorder()
+		putProfileHandler(rr, r)
+		return rr
+	}
+
+	tt := []struct {
+		form url.Values
+		code int
+	}{
+		// kosher cases
+		{form: url.Values{"display_name": {" Jörg 'JJ' <Test> "}, "locale": {"de-DE"},
+			"time_zone": {"Europe/Berlin"}, "week_start": {"Sunday"}}, code: http.StatusSeeOther},
+		{form: url.Values{"email": {"jj@example.com"}, "password": {pw}}, code: http.StatusSeeOther},
+		// fields not understood
+		{form: url.Values{"display_name": {strings.Repeat("a", 65)}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"display_name": {"new\nline"}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"locale": {"de_DE!"}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"time_zone": {"Mars/Olympus"}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"time_zone": {"Local"}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"week_start": {"someday"}}, code: http.StatusUnprocessableEntity},
+		{form: url.Values{"email": {"no address"}, "password": {pw}}, code: http.StatusUnprocessableEntity},
+		// changing the email address needs the password
+		{form: url.Values{"email": {"evil@example.com"}}, code: http.StatusForbidden},
+		{form: url.Values{"email": {"evil@example.com"}, "password": {"wrong"}}, code: http.StatusForbidden},
+		// unchanged email address needs none
+		{form: url.Values{"email": {"jj@example.com"}}, code: http.StatusSeeOther},
+	}
+
+	for _, tc := range tt {
+		if rr := put(tc.form); rr.Code != tc.code {
+			t.Errorf("%v: wrong status code: got: %d want: %d \n%s", tc.form, rr.Code, tc.code, rr.Body.String())
+		}
+	}
+
+	want := model.Profile{DisplayName: "Jörg 'JJ' <Test>", Locale: "de-DE", TimeZone: "Europe/Berlin",
+		WeekStart: "sunday"}
+	if p := db.(dbMock).users[un].Profile; p != want {
+		t.Fatalf("profile not stored correctly: got: %+v want: %+v", p, want)
+	}
+
+	// shown along with the email address
+	r := httptest.NewRequest("GET", "/profile?format=json", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+	rr := httptest.NewRecorder()
+	getProfileHandler(rr, r)
+	var v model.ProfileView
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil || v.Name != un || v.Email != "jj@example.com" ||
+		v.Profile != want {
+		t.Fatalf("profile not shown correctly: %s %v", rr.Body.String(), err)
+	}
+
+	// passed on to the pages, which the query can not override
+	r = httptest.NewRequest("GET", "/calendar.xsl?week_start=monday", nil)
+	rr = httptest.NewRecorder()
+	r = r.WithContext(withProfile(r.Context(), rr, un))
+	sendXSL(rr, r, xlsTruncated)
+	for _, s := range []string{
+		`<xsl:variable name="display_name" select="&quot;Jörg 'JJ' &lt;Test&gt;&quot;"/>`,
+		`<xsl:variable name="time_zone" select="'Europe/Berlin'"/>`,
+		`<xsl:variable name="week_start" select="'sunday'"/>`,
+	} {
+		if !strings.Contains(rr.Body.String(), s) {
+			t.Errorf("%s missing in %s", s, rr.Body.String())
+		}
+	}
+	if strings.Contains(rr.Body.String(), "monday") || rr.Header().Get("Content-Language") != "de-DE" {
+		t.Errorf("profile not passed on correctly: %s %v", rr.Body.String(), rr.Header())
+	}
+
+	// empty values reset to the defaults
+	if rr := put(url.Values{"locale": {""}, "time_zone": {""}, "week_start": {""}}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("profile not reset: %d", rr.Code)
+	}
+	if p := db.(dbMock).users[un].Profile; p != (model.Profile{DisplayName: want.DisplayName}) {
+		t.Fatalf("profile not reset: %+v", p)
+	}
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// renameCalendarHandler renames a calendar of the logged in user to the form field name. Its former path keeps
+// redirecting to the new one for the configured grace period, so that bookmarks and feed URLs don't break.
+func renameCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := getCalendarIfPermission(w, r, model.Owner)
+	if err != nil {
+		return
+	}
+
+	if c.Name.Val == c.Owner.Val {
+		writeError(w, "you must not rename default calendar", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		writeError(w, "name missing, html input must have name 'name'", http.StatusUnprocessableEntity)
+		return
+	} else if !legalName(name) {
+		writeError(w, "illegal name", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = db.RenameCalendar(c.GetID(), name, time.Now().Add(conf.renameRedirectDuration()))
+	if err == model.ErrNotFound {
+		writeError(w, "calendar not found", http.StatusNotFound)
+		return
+	} else if err == model.ErrAlreadyExists {
+		writeError(w, fmt.Sprintf("a calendar named %s exists already", name), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the audit log has been renamed along with the calendar
+	if after, err := db.GetCalendar(c.Owner.Val + "/" + name); err == nil {
+		recordAudit(auditEntry(r, after.GetID(), model.AuditRename, c.Metadata(), after.Metadata()))
+	} else {
+		log.Println(err)
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// redirectAlias redirects a request to the former ID calID of a renamed calendar to the same route of its current ID,
+// if the grace period is not over yet and the user has at least minPerm for the calendar, so that the new name is not
+// revealed to others. It reports whether it has redirected. The redirect is temporary, as the former name may be
+// taken by another calendar later on.
+func redirectAlias(w http.ResponseWriter, r *http.Request, calID, userID string, minPerm model.Permission) bool {
+	a, err := db.GetCalendarAlias(calID)
+	if err != nil {
+		if err != model.ErrNotFound {
+			log.Println(err)
+		}
+		return false
+	}
+
+	c, err := db.GetCalendar(a.To)
+	if err != nil {
+		if err != model.ErrNotFound {
+			log.Println(err)
+		}
+		return false
+	} else if calendarPermission(r, c, userID) < minPerm {
+		return false
+	}
+
+	route := mux.CurrentRoute(r)
+	parts := strings.SplitN(a.To, "/", 2)
+	if route == nil || len(parts) != 2 {
+		return false
+	}
+
+	// routes without the owner in their path refer to the calendars of the logged in user
+	vars := mux.Vars(r)
+	if _, ok := vars[userIDStr]; !ok && !strings.HasPrefix(calID, parts[0]+"/") {
+		return false
+	}
+
+	var pairs []string
+	for k, v := range vars {
+		switch k {
+		case userIDStr:
+			v = parts[0]
+		case calendarIDStr:
+			v = parts[1]
+		}
+		pairs = append(pairs, k, v)
+	}
+
+	u, err := route.URLPath(pairs...)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	u.RawQuery = r.URL.RawQuery
+
+	http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+	return true
+}
@@ -0,0 +1,127 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenameCalendar(t *testing.T) {
+	owner, other := "someowner", "someoneelse"
+	c := model.Calendar{Name: model.Attribute{Val: "typo"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/typo"}}
+	c.Permissions.Edit.User = []model.Attribute{{Val: other}}
+	def := model.Calendar{Name: model.Attribute{Val: owner}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/" + owner}}
+
+	var renamed []string
+	var until time.Time
+	db = dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c, def.GetID(): def},
+		renameCalendar: func(calID, newName string, redirectUntil time.Time) error {
+			if newName == "taken" {
+				return model.ErrAlreadyExists
+			}
+			renamed = append(renamed, calID+" "+newName)
+			until = redirectUntil
+			return nil
+		},
+	}
+
+	tt := []struct {
+		user string
+		cal  string
+		name string
+		code int
+	}{
+		{user: owner, cal: "typo", name: "fixed", code: http.StatusSeeOther},
+		{user: owner, cal: "typo", name: "taken", code: http.StatusConflict},
+		{user: owner, cal: "typo", name: "", code: http.StatusUnprocessableEntity},
+		{user: owner, cal: "typo", name: "../fixed", code: http.StatusUnprocessableEntity},
+		{user: owner, cal: owner, name: "fixed", code: http.StatusMethodNotAllowed},
+		{user: owner, cal: "unknown", name: "fixed", code: http.StatusNotFound},
+		// editors may not rename the calendar
+		{user: other, cal: "typo", name: "fixed", code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"name": {tc.name}}.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: tc.cal})
+		rr := httptest.NewRecorder()
+		renameCalendarHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+
+	if len(renamed) != 1 || renamed[0] != owner+"/typo fixed" {
+		t.Errorf("expected one rename of %s/typo to fixed, got %v", owner, renamed)
+	}
+	if d := time.Until(until); d < defaultRedirectDuration-time.Minute || d > defaultRedirectDuration {
+		t.Errorf("expected the former ID to redirect for %v, got %v", defaultRedirectDuration, d)
+	}
+}
+
+func TestRedirectAlias(t *testing.T) {
+	owner, viewer, other := "someowner", "someviewer", "someoneelse"
+	c := model.Calendar{Name: model.Attribute{Val: "fixed"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/fixed"}}
+	c.Permissions.View.User = []model.Attribute{{Val: viewer}}
+	db = dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		aliases: map[string]model.CalendarAlias{
+			owner + "/typo": {From: owner + "/typo", To: c.GetID(), Expires: time.Now().Add(time.Hour)},
+		},
+	}
+
+	// routes as registered beneath the authed prefix, the user is logged in by a stub of the auth middleware
+	router := mux.NewRouter()
+	authed := router.PathPrefix("/me").Subrouter()
+	authed.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userIDStr, r.Header.Get("X-User"))))
+		})
+	})
+	authed.HandleFunc("/calendars/{user_id}/{calendar_id}", getCalendarHandler).Methods("GET")
+	authed.HandleFunc("/c/{calendar_id}", getCalendarHandler).Methods("GET")
+
+	tt := []struct {
+		user     string
+		path     string
+		code     int
+		location string
+	}{
+		{user: viewer, path: "/me/calendars/someowner/typo?format=ics", code: http.StatusTemporaryRedirect,
+			location: "/me/calendars/someowner/fixed?format=ics"},
+		{user: owner, path: "/me/c/typo", code: http.StatusTemporaryRedirect, location: "/me/c/fixed"},
+		// the former ID of the owner means nothing to others
+		{user: viewer, path: "/me/c/typo", code: http.StatusNotFound},
+		{user: viewer, path: "/me/calendars/someowner/unknown", code: http.StatusNotFound},
+		// the new name is not revealed to those without access
+		{user: other, path: "/me/calendars/someowner/typo", code: http.StatusNotFound},
+		{user: viewer, path: "/me/calendars/someowner/fixed?format=ics", code: http.StatusOK},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest("GET", tc.path, nil)
+		r.Header.Set("X-User", tc.user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+		if loc := rr.Header().Get("Location"); loc != tc.location {
+			t.Errorf("%d: expected location %q, got %q", i, tc.location, loc)
+		}
+	}
+}
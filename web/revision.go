@@ -0,0 +1,188 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"time"
+)
+
+// currentRevision names the current version of an item, when diffing revisions
+const currentRevision = "current"
+
+// getRevisionsHandler lists the retained prior versions of the calendar, the most recent first. Only the metadata of
+// the versions is listed, the items are listed per item.
+func getRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := getCalendarIfPermission(w, r, model.Read)
+	if err != nil {
+		return
+	}
+
+	revs, err := db.GetRevisions(c.GetID())
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range revs {
+		revs[i].Calendar = revs[i].Calendar.Metadata()
+	}
+	writeView(w, r, model.Revisions{Revision: revs}, "")
+}
+
+// rollbackCalendarHandler restores the items and the description the calendar had at the time of the form field time
+// (RFC 3339). The version replaced is kept as a revision, so that the rollback can be undone.
+func rollbackCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := getCalendarIfPermission(w, r, model.Edit)
+	if err != nil {
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, r.Form.Get("time"))
+	if err != nil {
+		writeError(w, "time missing or not understood, must be formatted as RFC 3339", http.StatusUnprocessableEntity)
+		return
+	}
+
+	err = db.RollbackCalendar(c.GetID(), t)
+	if err == model.ErrNotFound {
+		writeError(w, "calendar not found", http.StatusNotFound)
+		return
+	} else if err == model.ErrExpired {
+		writeError(w, "no revisions retained for this time", http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if after, err := db.GetCalendar(c.GetID()); err == nil {
+		recordAudit(auditEntry(r, c.GetID(), model.AuditRollback, c, after))
+	} else {
+		log.Println(err)
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// getItemRevisionsHandler returns a handler listing the retained prior versions of the item of the given kind, the
+// most recent first
+func getItemRevisionsHandler(kind model.ItemKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := getCalendarIfPermission(w, r, model.Read)
+		if err != nil {
+			return
+		}
+
+		revs, err := db.GetRevisions(c.GetID())
+		if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		// deleted items are found by their revisions
+		itemID := mux.Vars(r)[itemIDStr]
+		h := c.ItemHistory(revs, kind, itemID)
+		if _, ok := c.Item(kind, itemID); !ok && len(h) == 0 {
+			writeError(w, "item not found", http.StatusNotFound)
+			return
+		}
+
+		writeView(w, r, model.ItemRevisions{Revision: h}, "")
+	}
+}
+
+// diffItemRevisionsHandler returns a handler listing the fields of the item of the given kind changed from the
+// revision of the query parameter from to the one of to, which defaults to the current version
+func diffItemRevisionsHandler(kind model.ItemKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := getCalendarIfPermission(w, r, model.Read)
+		if err != nil {
+			return
+		}
+
+		v := r.URL.Query()
+		d := model.ItemDiff{From: v.Get("from"), To: v.Get("to")}
+		if d.From == "" {
+			writeError(w, "from missing, must be the ID of a revision", http.StatusBadRequest)
+			return
+		}
+		if d.To == "" {
+			d.To = currentRevision
+		}
+
+		// items are missing from revisions before their creation or after their deletion
+		itemID := mux.Vars(r)[itemIDStr]
+		version := func(revID string) (interface{}, error) {
+			cal := c
+			if revID != currentRevision {
+				rev, err := db.GetRevision(c.GetID(), revID)
+				if err != nil {
+					return nil, err
+				}
+				cal = rev.Calendar
+			}
+			item, _ := cal.Item(kind, itemID)
+			return item, nil
+		}
+
+		before, err := version(d.From)
+		var after interface{}
+		if err == nil {
+			after, err = version(d.To)
+		}
+		if err == model.ErrNotFound || (err == nil && before == nil && after == nil) {
+			writeError(w, "revision or item not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		d.Changes = model.Diff(before, after)
+		writeView(w, r, d, "")
+	}
+}
+
+// restoreItemRevisionHandler returns a handler restoring the item of the given kind as it has been in the revision
+// of the request. Deleted items are added again.
+func restoreItemRevisionHandler(kind model.ItemKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := getCalendarIfPermission(w, r, model.Edit)
+		if err != nil {
+			return
+		}
+
+		vars := mux.Vars(r)
+		rev, err := db.GetRevision(c.GetID(), vars[revisionIDStr])
+		if err == model.ErrNotFound {
+			writeError(w, "revision not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		itemID := vars[itemIDStr]
+		before, _ := c.Item(kind, itemID)
+		if err := c.RestoreItem(rev.Calendar, kind, itemID); err != nil {
+			writeError(w, "item not found in revision", http.StatusNotFound)
+			return
+		}
+		after, _ := c.Item(kind, itemID)
+
+		finishItem(w, r, c, itemAuditEntry(r, c.GetID(), kind, itemID, model.AuditRestore, before, after))
+	}
+}
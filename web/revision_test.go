@@ -0,0 +1,214 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestItemRevisions(t *testing.T) {
+	owner, viewer := "someowner", "someviewer"
+	c := model.Calendar{Name: model.Attribute{Val: "project"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/project"}}
+	c.Permissions.View.User = []model.Attribute{{Val: viewer}}
+	old := c
+	old.Items.Tasks.Task = []model.Task{{ID: "t1", Name: model.Attribute{Val: "draft"}}, {ID: "t2"}}
+	c.Items.Tasks.Task = []model.Task{{ID: "t1", Name: model.Attribute{Val: "final"}}}
+
+	var entries []model.AuditEntry
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		groups:    map[string]model.Group{},
+		revisions: map[string][]model.Revision{c.GetID(): {{ID: "r1", Until: time.Now(), Calendar: old}}},
+		audit:     &entries,
+	}
+	mock.setCalendar = func(id string, c model.Calendar) error {
+		mock.calendars[id] = c
+		return nil
+	}
+	db = mock
+
+	request := func(method, user, item, rev, query string) *http.Request {
+		r := httptest.NewRequest(method, "/?"+query, nil)
+		r.Header.Set("Accept", "application/json")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		return mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "project", itemIDStr: item,
+			revisionIDStr: rev})
+	}
+
+	// listing
+	rr := httptest.NewRecorder()
+	getItemRevisionsHandler(model.KindTask)(rr, request("GET", viewer, "t1", "", ""))
+	var revs model.ItemRevisions
+	if err := json.Unmarshal(rr.Body.Bytes(), &revs); err != nil {
+		t.Fatalf("%v: %s", err, rr.Body.String())
+	}
+	if len(revs.Revision) != 1 || revs.Revision[0].ID != "r1" || revs.Revision[0].Task.Name.Val != "draft" {
+		t.Errorf("expected the draft of t1, got %v", revs)
+	}
+	rr = httptest.NewRecorder()
+	getItemRevisionsHandler(model.KindTask)(rr, request("GET", viewer, "unknown", "", ""))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected code %d for an unknown item, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	// diffing
+	tt := []struct {
+		query   string
+		code    int
+		changes []model.Change
+	}{
+		{query: "from=r1", code: http.StatusOK,
+			changes: []model.Change{{Field: "name", Before: "draft", After: "final"}}},
+		{query: "from=current&to=r1", code: http.StatusOK,
+			changes: []model.Change{{Field: "name", Before: "final", After: "draft"}}},
+		{query: "from=r0", code: http.StatusNotFound},
+		{query: "", code: http.StatusBadRequest},
+	}
+	for i, tc := range tt {
+		rr := httptest.NewRecorder()
+		diffItemRevisionsHandler(model.KindTask)(rr, request("GET", viewer, "t1", "", tc.query))
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+			continue
+		} else if rr.Code != http.StatusOK {
+			continue
+		}
+
+		var d model.ItemDiff
+		if err := json.Unmarshal(rr.Body.Bytes(), &d); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Changes) != len(tc.changes) || d.Changes[0] != tc.changes[0] {
+			t.Errorf("%d: expected changes %v, got %v", i, tc.changes, d.Changes)
+		}
+	}
+
+	// restoring, also the deleted task
+	for i, tc := range []struct {
+		user string
+		item string
+		rev  string
+		code int
+	}{
+		{user: viewer, item: "t1", rev: "r1", code: http.StatusForbidden},
+		{user: owner, item: "t1", rev: "r0", code: http.StatusNotFound},
+		{user: owner, item: "t3", rev: "r1", code: http.StatusNotFound},
+		{user: owner, item: "t1", rev: "r1", code: http.StatusSeeOther},
+		{user: owner, item: "t2", rev: "r1", code: http.StatusSeeOther},
+	} {
+		rr := httptest.NewRecorder()
+		restoreItemRevisionHandler(model.KindTask)(rr, request("POST", tc.user, tc.item, tc.rev, ""))
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+
+	ts := mock.calendars[c.GetID()].Items.Tasks.Task
+	if len(ts) != 2 || ts[0].Name.Val != "draft" || ts[1].ID != "t2" {
+		t.Errorf("expected both tasks to be restored, got %v", ts)
+	}
+	if len(entries) != 2 || entries[0].Action != model.AuditRestore || entries[1].Item != "t2" {
+		t.Errorf("expected the restores to be audited, got %v", entries)
+	}
+}
+
+func TestPutItemRevision(t *testing.T) {
+	owner := "someowner"
+	c := model.Calendar{Name: model.Attribute{Val: "project"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/project"}}
+	c.Items.Appointments.Appointment = []model.Appointment{{ID: "i1", Name: model.Attribute{Val: "draft"}}}
+	c.Items.Milestones.Milestone = []model.Milestone{{ID: "i1", Name: model.Attribute{Val: "draft"}}}
+	c.Items.Tasks.Task = []model.Task{{ID: "i1", Name: model.Attribute{Val: "draft"}}}
+
+	// the stored calendar is handed out as is and kept as a revision, if it has been changed, like the database does
+	var entries []model.AuditEntry
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		groups:    map[string]model.Group{},
+		revisions: map[string][]model.Revision{},
+		audit:     &entries,
+	}
+	mock.setCalendar = func(id string, c model.Calendar) error {
+		if old := mock.calendars[id]; old.String() != c.String() {
+			mock.revisions[id] = append(mock.revisions[id], model.Revision{ID: "r", Calendar: old})
+		}
+		mock.calendars[id] = c
+		return nil
+	}
+	db = mock
+
+	for i, h := range []http.HandlerFunc{putAppointmentHandler, putMilestoneHandler, putTaskHandler} {
+		r := httptest.NewRequest("PUT", "/", strings.NewReader(url.Values{"name": {"final"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, owner))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "project", itemIDStr: "i1"})
+
+		rr := httptest.NewRecorder()
+		h(rr, r)
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("%d: expected code %d, got %d: %s", i, http.StatusSeeOther, rr.Code, rr.Body.String())
+		}
+		if revs := mock.revisions[c.GetID()]; len(revs) != i+1 {
+			t.Errorf("%d: expected the edit to be kept as revision, got %d revisions", i, len(revs))
+		}
+	}
+
+	revs := mock.revisions[c.GetID()]
+	if len(revs) != 3 || revs[0].Calendar.Items.Appointments.Appointment[0].Name.Val != "draft" ||
+		revs[2].Calendar.Items.Tasks.Task[0].Name.Val != "draft" {
+		t.Errorf("expected the drafts to be kept, got %v", revs)
+	}
+}
+
+func TestRollbackCalendar(t *testing.T) {
+	owner, editor := "someowner", "someeditor"
+	c := model.Calendar{Name: model.Attribute{Val: "project"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/project"}, Desc: "now"}
+	c.Permissions.Edit.User = []model.Attribute{{Val: editor}}
+	old := c
+	old.Desc = "before"
+
+	now := time.Now()
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		groups:    map[string]model.Group{},
+		revisions: map[string][]model.Revision{c.GetID(): {{ID: "r1", Until: now.Add(-time.Hour), Calendar: old}}},
+	}
+	db = mock
+
+	tt := []struct {
+		user string
+		time string
+		code int
+	}{
+		{user: editor, time: "yesterday", code: http.StatusUnprocessableEntity},
+		{user: editor, time: now.AddDate(0, -2, 0).Format(time.RFC3339), code: http.StatusUnprocessableEntity},
+		{user: "someoneelse", time: now.Add(-2 * time.Hour).Format(time.RFC3339), code: http.StatusForbidden},
+		{user: editor, time: now.Add(-2 * time.Hour).Format(time.RFC3339), code: http.StatusSeeOther},
+	}
+
+	for i, tc := range tt {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"time": {tc.time}}.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: "project"})
+		rr := httptest.NewRecorder()
+		rollbackCalendarHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+
+	if d := mock.calendars[c.GetID()].Desc; d != "before" {
+		t.Errorf("expected the calendar to be rolled back, got description %q", d)
+	}
+}
@@ -18,9 +18,32 @@ func ListenAndServe(database model.Database, configuration ServerConfig) {
 	db = database
 	conf = configuration
 
+	// refuses to start with weak or missing keys
+	var err error
+	if keys, err = newKeyRing(conf); err != nil {
+		log.Fatal(err)
+	}
+
+	if mailer, err = newMailSender(conf.SMTP); err != nil {
+		log.Fatal(err)
+	}
+	if conf.SMTP.Host != "" && conf.PublicURL == "" {
+		log.Fatal("public_url has to be configured for the links in mails")
+	}
+
+	if conf.OIDC.enabled() {
+		if conf.PublicURL == "" || conf.OIDC.ClientID == "" {
+			log.Fatal("public_url and oidc.client_id have to be configured for single sign-on")
+		}
+		sso = newOIDCProvider(conf.OIDC)
+	}
+
 	// loads templates
 	load()
 
+	// destroys expired trash entries and deleted accounts in the background
+	go purgePeriodically()
+
 	// create a new router to attach routes to. Redirect to proper routes without trailing slash
 	r := mux.NewRouter().StrictSlash(true)
 
@@ -35,32 +58,122 @@ func registerRoutes(r *mux.Router) {
 	// authed router that enforces authentication
 	authed := r.PathPrefix(conf.AuthedPathName).Subrouter()
 
-	// attach middleware for all routes
+	// attach middleware for all routes. Personal access tokens can only use the routes marked with scoped.
 	authed.Use(auth)
 
 	//Get Calendar
-	authed.HandleFunc(fmt.Sprintf("/c/{%s}/{%s}", userIDStr, calendarIDStr), getCalendarHandler).Methods("GET")
-	authed.HandleFunc(fmt.Sprintf("/c/{%s}", calendarIDStr), getCalendarHandler).Methods("GET")
-	authed.HandleFunc("/c", getCalendarHandler).Methods("GET")
-	authed.Handle("/calendar.xsl", loadedXSLHandler(loaded.calendar)).Methods("GET")
-	authed.Handle("/projectView.xsl", loadedXSLHandler(loaded.project)).Methods("GET")
-	authed.Handle("/editItem.xsl", loadedXSLHandler(loaded.editItem)).Methods("GET")
+	scoped(model.ScopeRead,
+		authed.HandleFunc(fmt.Sprintf("/c/{%s}/{%s}", userIDStr, calendarIDStr), getCalendarHandler).Methods("GET"),
+		authed.HandleFunc(fmt.Sprintf("/c/{%s}", calendarIDStr), getCalendarHandler).Methods("GET"),
+		authed.HandleFunc("/c", getCalendarHandler).Methods("GET"),
+		authed.Handle("/calendar.xsl", loadedXSLHandler(loaded.calendar)).Methods("GET"),
+		authed.Handle("/projectView.xsl", loadedXSLHandler(loaded.project)).Methods("GET"),
+		authed.Handle("/editItem.xsl", loadedXSLHandler(loaded.editItem)).Methods("GET"),
+	)
 
 	//Get all Calendars of User
-	authed.HandleFunc("/calendars", getUserCalendarsHandler).Methods("GET")
-	authed.Handle("/showCalendars.xsl", loadedXSLHandler(loaded.showCalendars)).Methods("GET")
-
-	// Modify Calendar
-	authed.HandleFunc("/c", methodHandler(postCalendarHandler, putCalendarHandler, nil)).Methods("POST")
-	authed.HandleFunc(fmt.Sprintf("/c/{%s}/{%s}", userIDStr, calendarIDStr),
-		methodHandler(nil, putCalendarHandler, deleteCalendarHandler)).Methods("POST")
-	authed.HandleFunc(fmt.Sprintf("/c/{%s}", calendarIDStr),
-		methodHandler(nil, putCalendarHandler, deleteCalendarHandler)).Methods("POST")
-
-	// Delete User
+	scoped(model.ScopeRead,
+		authed.HandleFunc("/calendars", getUserCalendarsHandler).Methods("GET"),
+		authed.Handle("/showCalendars.xsl", loadedXSLHandler(loaded.showCalendars)).Methods("GET"),
+	)
+
+	// Agenda across all calendars of the user
+	scoped(model.ScopeRead,
+		authed.HandleFunc("/agenda", getAgendaHandler).Methods("GET"),
+		authed.Handle("/agenda.xsl", loadedXSLHandler(loaded.agenda)).Methods("GET"),
+	)
+
+	// Full-text search across all calendars of the user
+	scoped(model.ScopeRead, authed.HandleFunc("/search", searchHandler).Methods("GET"))
+
+	// Calendar resources. Deleting, transferring and renaming calendars needs the owner, which personal access tokens
+	// never act as. Former paths of renamed calendars redirect to the new ones for a grace period.
+	calendarPath := fmt.Sprintf("/calendars/{%s}/{%s}", userIDStr, calendarIDStr)
+	authed.HandleFunc("/calendars", postCalendarHandler).Methods("POST")
+	scoped(model.ScopeRead, authed.HandleFunc(calendarPath, getCalendarHandler).Methods("GET"))
+	scoped(model.ScopeEdit, authed.HandleFunc(calendarPath, putCalendarHandler).Methods("PUT", "PATCH"))
+	authed.HandleFunc(calendarPath, deleteCalendarHandler).Methods("DELETE")
+	// compatibility shim for HTML forms, which are only able to send GET and POST
+	scoped(model.ScopeEdit,
+		authed.HandleFunc(calendarPath, methodHandler(nil, putCalendarHandler, deleteCalendarHandler)).Methods("POST"))
+	authed.HandleFunc(calendarPath+"/transfer", transferCalendarHandler).Methods("POST")
+	authed.HandleFunc(calendarPath+"/rename", renameCalendarHandler).Methods("POST")
+	authed.HandleFunc(calendarPath+"/clone", cloneCalendarHandler).Methods("POST")
+	authed.HandleFunc(calendarPath+"/audit", getAuditLogHandler).Methods("GET")
+	// audit logs of the calendars of the user deleted for good
+	authed.HandleFunc("/audit/deleted", getDeletedAuditLogsHandler).Methods("GET")
+	authed.HandleFunc(fmt.Sprintf("/audit/deleted/{%s}", deletedLogIDStr), getDeletedAuditLogHandler).Methods("GET")
+	scoped(model.ScopeRead, authed.HandleFunc(calendarPath+"/revisions", getRevisionsHandler).Methods("GET"))
+	scoped(model.ScopeEdit, authed.HandleFunc(calendarPath+"/rollback", rollbackCalendarHandler).Methods("POST"))
+
+	// Delete User, kept for a delay before the account is deleted for good
+	authed.HandleFunc("/api/user", deleteUserHandler).Methods("DELETE")
 	authed.HandleFunc("/api/user", methodHandler(nil, nil, deleteUserHandler)).Methods("POST")
 
-	authed.HandleFunc("/api/sharing", sharingHandler).Methods("POST")
+	scoped(model.ScopeShare, authed.HandleFunc("/api/sharing", sharingHandler).Methods("POST"))
+
+	// Trash of the user with the deleted items and calendars, only the owner of the calendars may restore them
+	authed.HandleFunc("/trash", getTrashHandler).Methods("GET")
+	authed.HandleFunc(fmt.Sprintf("/trash/{%s}/restore", trashIDStr), restoreTrashHandler).Methods("POST")
+
+	// Invitations to calendars, only the invitee may accept them
+	invitationPath := fmt.Sprintf("/invitations/{%s}", invitationIDStr)
+	authed.HandleFunc("/invitations", getInvitationsHandler).Methods("GET")
+	authed.HandleFunc(invitationPath+"/accept", acceptInvitationHandler).Methods("POST")
+	authed.HandleFunc(invitationPath, deleteInvitationHandler).Methods("DELETE")
+	authed.HandleFunc(invitationPath, methodHandler(nil, nil, deleteInvitationHandler)).Methods("POST")
+
+	// Public share links of calendars, only manageable with a session
+	linkPath := fmt.Sprintf("/links/{%s}", linkIDStr)
+	authed.HandleFunc("/links", getShareLinksHandler).Methods("GET")
+	authed.HandleFunc("/links", postShareLinkHandler).Methods("POST")
+	authed.HandleFunc(linkPath, deleteShareLinkHandler).Methods("DELETE")
+	authed.HandleFunc(linkPath, methodHandler(nil, nil, deleteShareLinkHandler)).Methods("POST")
+
+	// Change password
+	authed.HandleFunc("/password", changePasswordHandler).Methods("PUT", "PATCH")
+	authed.HandleFunc("/password", methodHandler(nil, changePasswordHandler, nil)).Methods("POST")
+
+	// Groups of the user, which calendars can be shared with
+	groupPath := fmt.Sprintf("/groups/{%s}", groupIDStr)
+	memberPath := fmt.Sprintf("%s/members/{%s}", groupPath, memberIDStr)
+	authed.HandleFunc("/groups", getGroupsHandler).Methods("GET")
+	authed.HandleFunc("/groups", postGroupHandler).Methods("POST")
+	authed.HandleFunc(groupPath, getGroupHandler).Methods("GET")
+	authed.HandleFunc(groupPath, deleteGroupHandler).Methods("DELETE")
+	authed.HandleFunc(groupPath, methodHandler(nil, nil, deleteGroupHandler)).Methods("POST")
+	authed.HandleFunc(groupPath+"/members", postGroupMemberHandler).Methods("POST")
+	authed.HandleFunc(memberPath, deleteGroupMemberHandler).Methods("DELETE")
+	authed.HandleFunc(memberPath, methodHandler(nil, nil, deleteGroupMemberHandler)).Methods("POST")
+
+	// Profile of the user
+	authed.HandleFunc("/profile", getProfileHandler).Methods("GET")
+	authed.HandleFunc("/profile", putProfileHandler).Methods("PUT", "PATCH")
+	authed.HandleFunc("/profile", methodHandler(nil, putProfileHandler, nil)).Methods("POST")
+
+	// Second factor of the user
+	authed.HandleFunc("/totp", getTOTPHandler).Methods("GET")
+	authed.HandleFunc("/totp", disableTOTPHandler).Methods("DELETE")
+	authed.HandleFunc("/totp", methodHandler(enrollTOTPHandler, nil, disableTOTPHandler)).Methods("POST")
+	authed.HandleFunc("/totp/confirm", confirmTOTPHandler).Methods("POST")
+
+	// Sessions of the user, i.e. devices logged in
+	sessionPath := fmt.Sprintf("/sessions/{%s}", sessionIDStr)
+	authed.HandleFunc("/sessions", getSessionsHandler).Methods("GET")
+	authed.HandleFunc("/sessions", deleteOtherSessionsHandler).Methods("DELETE")
+	authed.HandleFunc("/sessions", methodHandler(nil, nil, deleteOtherSessionsHandler)).Methods("POST")
+	authed.HandleFunc(sessionPath, deleteSessionHandler).Methods("DELETE")
+	authed.HandleFunc(sessionPath, methodHandler(nil, nil, deleteSessionHandler)).Methods("POST")
+
+	// Personal access tokens of the user, only manageable with a session
+	accessTokenPath := fmt.Sprintf("/tokens/{%s}", accessTokenIDStr)
+	authed.HandleFunc("/tokens", getAccessTokensHandler).Methods("GET")
+	authed.HandleFunc("/tokens", postAccessTokenHandler).Methods("POST")
+	authed.HandleFunc(accessTokenPath, deleteAccessTokenHandler).Methods("DELETE")
+	authed.HandleFunc(accessTokenPath, methodHandler(nil, nil, deleteAccessTokenHandler)).Methods("POST")
+
+	// Review of lockouts after too many failed logins, only for admins
+	authed.HandleFunc("/admin/lockouts", getLockoutsHandler).Methods("GET")
 
 	// attach auto generated endpoint routes
 	attachEndpoints(authed)
@@ -68,12 +181,29 @@ func registerRoutes(r *mux.Router) {
 	authed.HandleFunc("/logout", logoutHandler).Methods("GET")
 
 	r.HandleFunc("/api/login", loginHandler).Methods("POST")
+	r.HandleFunc("/api/login/mfa", loginMFAHandler).Methods("POST")
 	r.HandleFunc("/api/register", registerHandler).Methods("POST")
+	r.HandleFunc("/api/password/forgot", forgotPasswordHandler).Methods("POST")
+	r.HandleFunc("/api/password/reset", resetPasswordHandler).Methods("POST")
+	r.HandleFunc("/api/oidc/login", oidcLoginHandler).Methods("GET")
+	r.HandleFunc("/api/oidc/callback", oidcCallbackHandler).Methods("GET")
+
+	// Calendars published by share links, viewable without an account
+	sharedPath := fmt.Sprintf("%s{%s}", sharePath, shareTokenStr)
+	r.HandleFunc(sharedPath, sharedCalendarHandler).Methods("GET")
+	r.Handle(sharedPath+"/calendar.xsl", loadedXSLHandler(loaded.calendar)).Methods("GET")
+	r.Handle(sharedPath+"/projectView.xsl", loadedXSLHandler(loaded.project)).Methods("GET")
+
+	// machine-readable description of all routes above, keep code_generation/handwritten_routes.go up to date
+	r.HandleFunc("/openapi.json", openAPIHandler).Methods("GET")
 
 	// serve static files (index, impressum, login, register ...). Note that this has to be registered last.
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir(conf.FrontendDir)))
 }
 
+// methodHandler is a compatibility shim for HTML forms, which are only able to send GET and POST. The verb is
+// tunnelled through the form field '_method'; POST is assumed if it is missing. Verbs without a handler (nil) are
+// answered with 405 Method Not Allowed. PATCH is served by the put handler, as updates are partial anyway.
 func methodHandler(post, put, delete http.HandlerFunc) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Parse HTML form from body
@@ -82,25 +212,26 @@ func methodHandler(post, put, delete http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		vs, ok := r.Form["_method"]
-		if !ok || len(vs) != 1 {
-			post(w, r) // default
-			return
+		method := "POST" // default
+		if vs, ok := r.Form["_method"]; ok && len(vs) == 1 {
+			method = strings.ToUpper(vs[0])
 		}
 
-		method := strings.ToUpper(vs[0])
+		var h http.HandlerFunc
 		switch method {
-		case "PUT":
-			put(w,r)
-			return
+		case "PUT", "PATCH":
+			h = put
 		case "DELETE":
-			delete(w,r)
-			return
+			h = delete
 		case "POST":
-			fallthrough
-		default:
-			post(w,r)
+			h = post
+		}
+
+		if h == nil {
+			writeError(w, "method "+method+" not allowed on this resource", http.StatusMethodNotAllowed)
 			return
 		}
+
+		h(w, r)
 	})
 }
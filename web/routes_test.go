@@ -0,0 +1,108 @@
+package web
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMethodHandler(t *testing.T) {
+	var called string
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			called = name
+		}
+	}
+
+	tt := []struct {
+		method string // value of the _method form field, empty means not sent
+		noPost bool
+		want   string
+		code   int
+	}{
+		// default is post
+		{want: "post", code: http.StatusOK},
+		{method: "post", want: "post", code: http.StatusOK},
+		{method: "PUT", want: "put", code: http.StatusOK},
+		// patch is served by put
+		{method: "patch", want: "put", code: http.StatusOK},
+		{method: "DELETE", want: "delete", code: http.StatusOK},
+		// missing handler must not panic
+		{noPost: true, code: http.StatusMethodNotAllowed},
+		{method: "TRACE", code: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tt {
+		called = ""
+
+		data := url.Values{}
+		if tc.method != "" {
+			data.Set("_method", tc.method)
+		}
+
+		r, err := http.NewRequest("POST", "/", strings.NewReader(data.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+		post := handler("post")
+		if tc.noPost {
+			post = nil
+		}
+
+		rr := httptest.NewRecorder()
+		methodHandler(post, handler("put"), handler("delete")).ServeHTTP(rr, r)
+
+		if rr.Code != tc.code || called != tc.want {
+			t.Errorf("got code: %d handler: %q want code: %d handler: %q", rr.Code, called, tc.code, tc.want)
+		}
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	conf = ServerConfig{AuthedPathName: "/me"}
+
+	r := mux.NewRouter().StrictSlash(true)
+	registerRoutes(r)
+
+	tt := []struct {
+		method string
+		path   string
+	}{
+		{"GET", "/me/calendars"},
+		{"POST", "/me/calendars"},
+		{"GET", "/me/calendars/lambda/work"},
+		{"PUT", "/me/calendars/lambda/work"},
+		{"PATCH", "/me/calendars/lambda/work"},
+		{"DELETE", "/me/calendars/lambda/work"},
+		{"POST", "/me/calendars/lambda/work"},
+		{"POST", "/me/calendars/lambda/work/appointments"},
+		{"PUT", "/me/calendars/lambda/work/appointments/1234"},
+		{"PATCH", "/me/calendars/lambda/work/tasks/1234"},
+		{"DELETE", "/me/calendars/lambda/work/milestones/1234"},
+		{"POST", "/me/calendars/lambda/work/milestones/1234"},
+		{"DELETE", "/me/api/user"},
+	}
+
+	for _, tc := range tt {
+		req, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m mux.RouteMatch
+		if !r.Match(req, &m) || m.MatchErr != nil {
+			t.Errorf("no route for %s %s: %v", tc.method, tc.path, m.MatchErr)
+			continue
+		}
+
+		// the static file server matches everything, so make sure we did not end up there
+		if tmpl, _ := m.Route.GetPathTemplate(); !strings.HasPrefix(tmpl, conf.AuthedPathName) {
+			t.Errorf("%s %s matched %q instead of an api route", tc.method, tc.path, tmpl)
+		}
+	}
+}
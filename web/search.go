@@ -0,0 +1,67 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+)
+
+// searchHandler searches the names and descriptions of all calendars the user can at least view, and of their
+// items. The query parameter q holds the words to search for, which are matched as prefixes; kind restricts the
+// results to some kinds and limit their number.
+func searchHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	q, err := model.NewSearchQuery(r)
+	if err == model.ErrReqFieldMissing {
+		writeError(w, "nothing to search for, query parameter q must contain words", http.StatusUnprocessableEntity)
+		return
+	} else if err != nil {
+		writeError(w, "query not understood: kind must be one of calendar, appointment, milestone, task "+
+			"and limit a positive number", http.StatusBadRequest)
+		return
+	}
+
+	u, err := db.GetUser(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	refs, err := visibleCalendars(u)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// only search in calendars the user may view
+	for _, ref := range refs {
+		c, err := db.GetCalendar(ref.Link)
+		if err == model.ErrNotFound {
+			continue // dangling reference, nothing to search
+		} else if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		if calendarPermission(r, c, userid) >= model.Read {
+			q.Calendars = append(q.Calendars, c.GetID())
+		}
+	}
+
+	res, err := db.Search(q)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, res, "")
+}
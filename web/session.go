@@ -0,0 +1,260 @@
+package web
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errRefreshFailed is returned if a session could not be refreshed, because the refresh token is malformed, its
+// session has been revoked or it has been reused.
+var errRefreshFailed = errors.New("refresh token invalid")
+
+// refreshMutex serializes the rotation of refresh tokens, so that a token can not be rotated twice concurrently
+var refreshMutex sync.Mutex
+
+// newSession creates a new session of the user for the device the request comes from, along with its first refresh
+// token. The session is not stored.
+func newSession(r *http.Request, userid string) (model.Session, string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return model.Session{}, "", err
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		return model.Session{}, "", err
+	}
+
+	csrf, err := randomToken()
+	if err != nil {
+		return model.Session{}, "", err
+	}
+
+	now := time.Now()
+	s := model.Session{
+		ID:          id.String(),
+		User:        userid,
+		Created:     now,
+		Expires:     now.Add(conf.refreshTokenDuration()),
+		Device:      r.UserAgent(),
+		IP:          clientIP(r),
+		RefreshHash: hashToken(secret),
+		Rotated:     now,
+		CSRFToken:   csrf,
+	}
+	return s, s.ID + "." + secret, nil
+}
+
+// setAuthCookie starts a new session of the user and sets the cookies with its access and refresh token. The caller
+// is responsible for writing an error to w, if one is returned.
+func setAuthCookie(w http.ResponseWriter, r *http.Request, userid string) error {
+	s, refresh, err := newSession(r, userid)
+	if err != nil {
+		return err
+	}
+
+	if err := db.AddSession(s); err != nil {
+		return err
+	}
+
+	return setTokenCookies(w, s, refresh)
+}
+
+// setTokenCookies sets the cookie with a new access token of the session and, if refresh is not empty, the cookie
+// with the refresh token. Both cookies live as long as the session, so that an expired access token can be
+// refreshed by the auth middleware.
+func setTokenCookies(w http.ResponseWriter, s model.Session, refresh string) error {
+	t, err := createToken(s)
+	if err != nil {
+		return err
+	}
+
+	c := authCookie(authStr)
+	c.Value, c.Expires = t, s.Expires
+	http.SetCookie(w, &c)
+
+	if refresh != "" {
+		c.Name, c.Value = refreshStr, refresh
+		http.SetCookie(w, &c)
+	}
+
+	return nil
+}
+
+// deleteAuthCookies deletes the cookies with the access and the refresh token
+func deleteAuthCookies(w http.ResponseWriter) {
+	for _, name := range []string{authStr, refreshStr} {
+		c := authCookie(name)
+		deleteCookie(w, &c)
+	}
+}
+
+// authCookie is a cookie with the access or the refresh token, only sent to the authed routes. SameSite keeps other
+// sites from sending state-changing requests with it in most browsers, the CSRF token in all others.
+func authCookie(name string) http.Cookie {
+	return http.Cookie{Name: name, Path: conf.AuthedPathName, HttpOnly: true, Secure: !conf.InsecureCookies,
+		SameSite: http.SameSiteLaxMode}
+}
+
+// refreshSession issues a new access token for the session of the given refresh token and sets it as cookie. The
+// refresh token is rotated and the session extended, unless the previous refresh token is presented within the
+// grace period, which happens if concurrent requests race the rotation. Presenting any other former refresh token
+// revokes the session, as the token must have been stolen.
+func refreshSession(w http.ResponseWriter, r *http.Request, token string) (model.Session, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return model.Session{}, errRefreshFailed
+	}
+
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+
+	s, err := db.GetSession(parts[0])
+	if err == model.ErrNotFound {
+		return model.Session{}, errRefreshFailed
+	} else if err != nil {
+		return model.Session{}, err
+	}
+
+	now := time.Now()
+	h := hashToken(parts[1])
+	switch {
+	case subtle.ConstantTimeCompare([]byte(h), []byte(s.RefreshHash)) == 1:
+		secret, err := randomToken()
+		if err != nil {
+			return model.Session{}, err
+		}
+
+		s.PreviousHash, s.RefreshHash, s.Rotated = s.RefreshHash, hashToken(secret), now
+		s.Expires = now.Add(conf.refreshTokenDuration())
+		s.IP = clientIP(r)
+		if err := db.SetSession(s); err != nil {
+			return model.Session{}, err
+		}
+
+		return s, setTokenCookies(w, s, s.ID+"."+secret)
+	case subtle.ConstantTimeCompare([]byte(h), []byte(s.PreviousHash)) == 1 && now.Sub(s.Rotated) < refreshGracePeriod:
+		return s, setTokenCookies(w, s, "")
+	default:
+		log.Printf("refresh token of session %s of user %s reused, revoking the session", s.ID, s.User)
+		if err := db.DeleteSession(s.ID); err != nil && err != model.ErrNotFound {
+			return model.Session{}, err
+		}
+		return model.Session{}, errRefreshFailed
+	}
+}
+
+// randomToken returns a random, URL safe token with 256 bits of entropy
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken hashes a random token for storage. A fast hash suffices, as the token has full entropy.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// clientIP returns the IP address of the client without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+	current, _ := r.Context().Value(tokenIDStr).(string)
+
+	ss, err := db.GetSessions(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range ss {
+		ss[i].Current = ss[i].ID == current
+	}
+
+	writeView(w, r, model.Sessions{Session: ss}, "")
+}
+
+// deleteSessionHandler revokes a single session of the logged in user. If it is the current one, the user is logged
+// out.
+func deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+	current, _ := r.Context().Value(tokenIDStr).(string)
+
+	id := mux.Vars(r)[sessionIDStr]
+
+	// sessions of other users are treated as non-existent
+	s, err := db.GetSession(id)
+	if err == model.ErrNotFound || (err == nil && s.User != userid) {
+		writeError(w, "session not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteSession(id); err != nil && err != model.ErrNotFound {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if id == current {
+		deleteAuthCookies(w)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// deleteOtherSessionsHandler revokes all sessions of the logged in user but the current one
+func deleteOtherSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+	current, _ := r.Context().Value(tokenIDStr).(string)
+
+	if err := db.DeleteSessions(userid, current); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
@@ -0,0 +1,251 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/xml"
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sharePath is the path of the public view of a share link, followed by the token of the link
+const sharePath = "/s/"
+
+// getShareLinksHandler lists the share links of the calendars of the logged in user, without their tokens
+func getShareLinksHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	ls, err := db.GetShareLinks(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.ShareLinks{Link: ls}, "")
+}
+
+// postShareLinkHandler creates a share link granting anyone who knows it read-only access to a calendar of the
+// logged in user. The form holds the name of the calendar and optionally the date the link expires on and whether
+// the descriptions of the items are hidden. The link is shown only this once.
+func postShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := r.Form.Get("calendarName")
+	if name == "" {
+		writeError(w, "calendar name missing, html input must have name 'calendarName'",
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	// only owners can publish calendars
+	id := userid + "/" + name
+	if _, err := db.GetCalendar(id); err == model.ErrNotFound {
+		writeError(w, "calendar "+id+" not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// checkboxes send on
+	hide, err := formFlag(r, "hide_descriptions")
+	if err != nil {
+		writeError(w, "hide_descriptions not understood, must be true or false", http.StatusUnprocessableEntity)
+		return
+	}
+
+	now := time.Now()
+	expires, ok := parseExpires(w, r, now)
+	if !ok {
+		return
+	}
+
+	linkID, err := uuid.NewRandom()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	l := model.ShareLink{
+		ID:               linkID.String(),
+		Calendar:         id,
+		Owner:            userid,
+		Created:          now,
+		Expires:          expires,
+		HideDescriptions: hide,
+		Hash:             hashToken(secret),
+	}
+	if err := db.AddShareLink(l); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	l.Token = l.ID + "." + secret
+	l.URL = strings.TrimSuffix(conf.PublicURL, "/") + sharePath + l.Token
+	writeView(w, r, l, "")
+}
+
+// deleteShareLinkHandler revokes a share link of the logged in user
+func deleteShareLinkHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	id := mux.Vars(r)[linkIDStr]
+
+	// links of other users are treated as non-existent
+	l, err := db.GetShareLink(id)
+	if err == model.ErrNotFound || (err == nil && l.Owner != userid) {
+		writeError(w, "link not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteShareLink(id); err != nil && err != model.ErrNotFound {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// sharedCalendarHandler shows the calendar of a share link to anyone who knows the link, without logging in. The
+// calendar is rendered by the same views as for users, or exported as iCalendar with format=ics. Each request is
+// counted.
+func sharedCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	// the token must not leak to other sites or into search engines
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	token := mux.Vars(r)[shareTokenStr]
+	l, ok := verifyShareLink(w, token)
+	if !ok {
+		return
+	}
+
+	// the calendar may have been deleted or handed over since
+	c, err := db.GetCalendar(l.Calendar)
+	if err == model.ErrNotFound || (err == nil && c.Owner.Val != l.Owner) {
+		writeError(w, "share link invalid, expired or revoked", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	q, err := model.NewCalendarQuery(r)
+	if err != nil {
+		writeError(w, "query not understood: from and to must be dates, kind one of appointment, milestone, task",
+			http.StatusBadRequest)
+		return
+	}
+	if !q.IsZero() {
+		c, err = db.QueryCalendar(c.GetID(), q)
+		if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := db.CountShareLinkAccess(l.ID, time.Now()); err != nil {
+		log.Println(err) // the calendar is shown anyway
+	}
+
+	c = l.Public(c)
+	if wantsICS(r) {
+		writeICS(w, c)
+		return
+	} else if wantsJSON(r) {
+		writeView(w, r, c, "")
+		return
+	}
+
+	xslLink := "/calendar.xsl?"
+	if r.URL.Query().Get("mode") == "project" {
+		xslLink = "/projectView.xsl?"
+	}
+
+	xmlRaw, _ := xml.Marshal(c)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(addStylesheet(string(xmlRaw), sharePath+token+xslLink+r.URL.RawQuery)))
+}
+
+// verifyShareLink returns the unexpired share link of the token (ID and secret separated by a dot). If ok is false,
+// an error has been written to w.
+func verifyShareLink(w http.ResponseWriter, token string) (l model.ShareLink, ok bool) {
+	invalid := func() {
+		writeError(w, "share link invalid, expired or revoked", http.StatusNotFound)
+	}
+
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		invalid()
+		return
+	}
+
+	l, err := db.GetShareLink(parts[0])
+	if err == model.ErrNotFound {
+		invalid()
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(parts[1])), []byte(l.Hash)) != 1 || l.Expired(time.Now()) {
+		invalid()
+		return
+	}
+	return l, true
+}
+
+// formFlag returns the value of the checkbox or boolean form field name, false if it is missing
+func formFlag(r *http.Request, name string) (bool, error) {
+	switch v := r.Form.Get(name); v {
+	case "":
+		return false, nil
+	case "on":
+		return true, nil
+	default:
+		return strconv.ParseBool(v)
+	}
+}
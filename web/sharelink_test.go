@@ -0,0 +1,130 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareLinks(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret, AuthedPathName: "/me", PublicURL: "https://plannet.example.com/"})
+
+	un := "someusername"
+	c := model.Calendar{Name: model.Attribute{Val: "cal"}, Owner: model.Attribute{Val: un},
+		ID: model.Attribute{Val: un + "/cal"}}
+	c.Permissions.View.User = []model.Attribute{{Val: "someoneelse"}}
+	c.Items.Milestones.Milestone = []model.Milestone{{ID: "m1", Name: model.Attribute{Val: "Release"},
+		Duedate: model.Attribute{Val: "31.03.2021"}, Desc: "internal notes"}}
+	db = dbMock{
+		links: map[string]model.ShareLink{},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{
+			"GetCalendar": {d: c},
+		},
+	}
+	links := db.(dbMock).links
+
+	old := loaded
+	loaded.project = xlsTruncated
+	t.Cleanup(func() { loaded = old })
+
+	router := mux.NewRouter().StrictSlash(true)
+	registerRoutes(router)
+
+	create := func(form url.Values) (model.ShareLink, int) {
+		r := httptest.NewRequest("POST", "/me/links?format=json", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+		rr := httptest.NewRecorder()
+		postShareLinkHandler(rr, r)
+
+		var res model.ShareLink
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return res, rr.Code
+	}
+	open := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	for _, form := range []url.Values{
+		{},
+		{"calendarName": {"cal"}, "hide_descriptions": {"maybe"}},
+		{"calendarName": {"cal"}, "expires": {"2000-01-01"}},
+	} {
+		if _, code := create(form); code != http.StatusUnprocessableEntity {
+			t.Errorf("wrong status code for %v: got: %d want: %d", form, code, http.StatusUnprocessableEntity)
+		}
+	}
+
+	l, _ := create(url.Values{"calendarName": {"cal"}})
+	hidden, _ := create(url.Values{"calendarName": {"cal"}, "hide_descriptions": {"on"}, "expires": {"2999-12-31"}})
+	if l.Token == "" || l.URL != "https://plannet.example.com/s/"+l.Token || links[l.ID].Token != "" ||
+		links[l.ID].Hash != hashToken(strings.SplitN(l.Token, ".", 2)[1]) || !links[hidden.ID].HideDescriptions {
+		t.Fatalf("link not created, shown or stored correctly: %v %v", l, links)
+	}
+
+	// viewable without logging in, through the same views, but without the permissions
+	rr := open("/s/" + l.Token + "?mode=project")
+	xsl := `href="/s/` + l.Token + `/projectView.xsl?mode=project"`
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), xsl) ||
+		!strings.Contains(rr.Body.String(), "internal notes") || strings.Contains(rr.Body.String(), "someoneelse") ||
+		rr.Header().Get("Referrer-Policy") != "no-referrer" {
+		t.Fatalf("calendar not shown correctly: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := open("/s/" + l.Token + "/projectView.xsl"); rr.Code != http.StatusOK {
+		t.Fatalf("stylesheet not served: %d", rr.Code)
+	}
+
+	// exported as iCalendar, with descriptions hidden if wanted
+	rr = open("/s/" + hidden.Token + "?format=ics")
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/calendar") ||
+		!strings.Contains(rr.Body.String(), "SUMMARY:Release") || strings.Contains(rr.Body.String(), "internal notes") {
+		t.Fatalf("calendar not exported correctly: %d %s", rr.Code, rr.Body.String())
+	}
+
+	if links[l.ID].Accesses != 1 || links[l.ID].LastAccess == nil || links[hidden.ID].Accesses != 1 {
+		t.Fatalf("accesses not counted: %v", links)
+	}
+
+	// guessed, expired and revoked links are not found
+	expired := links[hidden.ID]
+	past := time.Now().Add(-time.Minute)
+	expired.Expires = &past
+	links[hidden.ID] = expired
+	for _, token := range []string{l.ID, l.ID + ".guessed", hidden.Token} {
+		if rr := open("/s/" + token); rr.Code != http.StatusNotFound {
+			t.Errorf("link %s: wrong status code: got: %d want: %d", token, rr.Code, http.StatusNotFound)
+		}
+	}
+
+	r := httptest.NewRequest("DELETE", "/me/links/"+l.ID, nil)
+	r = mux.SetURLVars(r, map[string]string{linkIDStr: l.ID})
+	rr = httptest.NewRecorder()
+	deleteShareLinkHandler(rr, r.WithContext(context.WithValue(r.Context(), userIDStr, "someoneelse")))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("link of another user revoked: %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	deleteShareLinkHandler(rr, r.WithContext(context.WithValue(r.Context(), userIDStr, un)))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("link not revoked: %d", rr.Code)
+	}
+	if rr := open("/s/" + l.Token); rr.Code != http.StatusNotFound {
+		t.Fatalf("revoked link still works: %d", rr.Code)
+	}
+}
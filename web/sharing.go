@@ -7,7 +7,9 @@ import (
 	"net/http"
 )
 
-// sharingHandler handles the request to share a calendar with another user
+// sharingHandler handles the request to share a calendar with another user or a group. Users who don't have the
+// calendar yet are invited and only get it once they accept, the permissions of users who have it are changed
+// directly.
 func sharingHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse HTML form from body
 	if err := r.ParseForm(); err != nil {
@@ -23,12 +25,16 @@ func sharingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	calendarName := vs[0]
 
-	vs, ok = r.Form["userName"]
-	if !ok || len(vs) != 1 {
-		writeError(w, "calendar name missing", http.StatusUnprocessableEntity)
+	// calendars are shared either with a user or with a group
+	var userName, groupName string
+	if vs, ok = r.Form["groupName"]; ok && len(vs) == 1 {
+		groupName = vs[0]
+	} else if vs, ok = r.Form["userName"]; ok && len(vs) == 1 {
+		userName = vs[0]
+	} else {
+		writeError(w, "user or group name missing", http.StatusUnprocessableEntity)
 		return
 	}
-	userName := vs[0]
 
 	vs, ok = r.Form["perm"]
 	if !ok || len(vs) != 1 {
@@ -36,6 +42,10 @@ func sharingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	perm := vs[0]
+	if perm != "view" && perm != "edit" && perm != "none" {
+		writeError(w, "permission not understood", http.StatusBadRequest)
+		return
+	}
 
 	// get person initiating the share
 	owner, ok := r.Context().Value(userIDStr).(string)
@@ -58,52 +68,72 @@ func sharingHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// only owners can share calendars
-	if c.Owner.Val != owner {
+	if c.Owner.Val != owner || !allowsCalendar(r, id) {
 		writeError(w, "not owner of the calendar", http.StatusForbidden)
 		return
 	}
 
-	user, err := db.GetUser(userName)
-	if err == model.ErrNotFound {
-		writeError(w, "specified user name not found", http.StatusNotFound)
+	if groupName != "" {
+		shareWithGroup(w, r, c, groupName, perm)
 		return
-	} else if err != nil {
+	}
+
+	if userName == owner {
+		writeError(w, "calendars can not be shared with their owner", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// users may be invited before they register
+	user, err := db.GetUser(userName)
+	if err != nil && err != model.ErrNotFound {
 		log.Println(err)
 		writeError(w, "", http.StatusInternalServerError)
 		return
 	}
+	registered := err == nil
+	if !registered && !legalName(userName) {
+		writeError(w, "illegal user name", http.StatusUnprocessableEntity)
+		return
+	}
 
-	deleteFrom := func(us []model.Attribute, toDel model.Attribute) []model.Attribute {
-		var idxs []int
-		for i, v := range us {
-			if v.Val == toDel.Val {
-				idxs = append(idxs, i)
-			}
-		}
-		if len(idxs) == 0 {
-			return us
-		}
-
-		for i := len(idxs) - 1; i >= 0; i-- {
-			us[idxs[i]] = us[len(us) - 1]
-			us = us[:len(us) - 1]
+	// check whether the user has already accepted the calendar, otherwise the user is invited
+	found := false
+	for _, v := range user.Items.Calendars {
+		if v.Link == id {
+			found = true
+			break
 		}
+	}
+	if !found && perm != "none" {
+		inviteUser(w, r, c, userName, perm)
+		return
+	}
 
-		return us
+	// pending invitations are superseded by the new permission
+	if err := revokeInvitations(id, userName); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	if !registered {
+		http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+		return
 	}
 
+	// the permissions are not changed in place, so that they can be compared for the audit log
+	before := c.Metadata()
 	addUserReq := false
 
 	// give user the permission to either view or edit
 	userAttr := model.Attribute{Val: userName}
 	if perm == "view" {
-		c.Permissions.Edit.User = deleteFrom(c.Permissions.Edit.User, userAttr)
+		c.Permissions.Edit.User = removeAttribute(c.Permissions.Edit.User, userAttr)
 		c.Permissions.View.User = append(c.Permissions.View.User, userAttr)
 	} else if perm == "edit" {
 		c.Permissions.Edit.User = append(c.Permissions.Edit.User, userAttr)
 	} else if perm == "none" {
-		c.Permissions.Edit.User = deleteFrom(c.Permissions.Edit.User, userAttr)
-		c.Permissions.View.User = deleteFrom(c.Permissions.View.User, userAttr)
+		c.Permissions.Edit.User = removeAttribute(c.Permissions.Edit.User, userAttr)
+		c.Permissions.View.User = removeAttribute(c.Permissions.View.User, userAttr)
 		// Deletes the calendar from the user file
 		idx := -1
 		for i, v := range user.Items.Calendars {
@@ -116,23 +146,6 @@ func sharingHandler(w http.ResponseWriter, r *http.Request) {
 			user.Items.Calendars = append(user.Items.Calendars[:idx], user.Items.Calendars[idx+1:]...)
 		}
 		addUserReq = true
-	} else {
-		writeError(w, "permission not understood", http.StatusBadRequest)
-		return
-	}
-
-	// check whether the user has already access to the calendar (view), and based on that add the calendar
-	found := false
-	for _, v := range user.Items.Calendars {
-		if v.Link == id {
-			found = true
-			break
-		}
-	}
-	// add the calendar if it isn't already added and if it hasn't been removed by permissions none before
-	if !found && perm != "none" {
-		user.Items.Calendars = append(user.Items.Calendars, model.CalendarReference{Link: id, Perm: perm})
-		addUserReq = true
 	}
 
 	if addUserReq {
@@ -148,6 +161,73 @@ func sharingHandler(w http.ResponseWriter, r *http.Request) {
 		writeError(w, "", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(auditEntry(r, id, model.AuditShare, before, c.Metadata()))
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// shareWithGroup grants the group the permission perm (view, edit or none) for the calendar c. The calendar is
+// listed in the group, so that its members find it. Only members of the group may share with it.
+func shareWithGroup(w http.ResponseWriter, r *http.Request, c model.Calendar, groupName, perm string) {
+	userid, _ := r.Context().Value(userIDStr).(string)
+
+	g, err := db.GetGroup(groupName)
+	if err == model.ErrNotFound || (err == nil && !g.IsMember(userid)) {
+		writeError(w, "specified group name not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	var p model.Permission
+	switch perm {
+	case "view":
+		p = model.Read
+	case "edit":
+		p = model.Edit
+	case "none":
+		p = model.None
+	default:
+		writeError(w, "permission not understood", http.StatusBadRequest)
+		return
+	}
+
+	before := c.Metadata()
+	groupAttr := model.Attribute{Val: groupName}
+	c.Permissions.View.Group = removeAttribute(c.Permissions.View.Group, groupAttr)
+	c.Permissions.Edit.Group = removeAttribute(c.Permissions.Edit.Group, groupAttr)
+	if p == model.Read {
+		c.Permissions.View.Group = append(c.Permissions.View.Group, groupAttr)
+	} else if p == model.Edit {
+		c.Permissions.Edit.Group = append(c.Permissions.Edit.Group, groupAttr)
+	}
+
+	g.ShareCalendar(c.GetID(), p)
+	if err := db.SetGroup(g); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.SetCalendar(c.GetID(), c); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(auditEntry(r, c.GetID(), model.AuditShare, before, c.Metadata()))
 
 	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
 }
+
+// removeAttribute returns as without the attributes of the same value as a
+func removeAttribute(as []model.Attribute, a model.Attribute) []model.Attribute {
+	var res []model.Attribute
+	for _, v := range as {
+		if v.Val != a.Val {
+			res = append(res, v)
+		}
+	}
+	return res
+}
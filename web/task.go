@@ -14,7 +14,7 @@ func postTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	c.Items.Tasks.Task = append(c.Items.Tasks.Task, i)
 
-	finishItem(w, r, c)
+	finishItem(w, r, c, itemAuditEntry(r, c.GetID(), model.KindTask, i.ID, model.AuditCreate, nil, i))
 }
 
 func putTaskHandler(w http.ResponseWriter, r *http.Request) {
@@ -37,31 +37,13 @@ func putTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return // err reporting already done by method call
 	}
 
+	before := items[idx]
 	items[idx].Update(a)
 
-	finishItem(w, r, c)
+	e := itemAuditEntry(r, c.GetID(), model.KindTask, before.ID, model.AuditUpdate, before, items[idx])
+	finishItem(w, r, c, e)
 }
 
 func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
-	c, err := getCalendarIfPermission(w, r, model.Edit)
-	if err != nil {
-		// err reporting already done by method call
-		return
-	}
-
-	items := c.Items.Tasks.Task
-
-	ids := make([]model.Identifier, len(items))
-	for i, v := range items {
-		ids[i] = v
-	}
-	idx, err := itemIdx(w, r, ids...)
-	if err != nil {
-		return // err reporting already done by method call
-	}
-
-	items[idx] = items[len(items)-1]
-	c.Items.Tasks.Task = items[:len(items)-1]
-
-	finishItem(w, r, c)
+	trashItem(w, r, model.KindTask)
 }
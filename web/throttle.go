@@ -0,0 +1,252 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"github.com/Project-Planner/backend/model"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig configures the throttling of failed logins, of registrations and of password reset requests
+type ThrottleConfig struct {
+	// FreeAttempts is the number of failures before attempts are delayed, 3 if not set
+	FreeAttempts int `yaml:"free_attempts"`
+	// BaseDelay is the delay after the first failure beyond the free attempts, doubling with each further failure;
+	// 1s if not set
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// LockoutThreshold is the number of failures after which an account is locked out, 10 if not set
+	LockoutThreshold int `yaml:"lockout_threshold"`
+	// IPLockoutThreshold is the number of failures or registrations after which an IP address is locked out, 50 if
+	// not set
+	IPLockoutThreshold int `yaml:"ip_lockout_threshold"`
+	// LockoutDuration is how long lockouts last and the longest delay. Failures are forgotten after this long
+	// without any; 15m if not set
+	LockoutDuration time.Duration `yaml:"lockout_duration"`
+}
+
+// withDefaults returns the config with defaults for all fields not set
+func (c ThrottleConfig) withDefaults() ThrottleConfig {
+	if c.FreeAttempts <= 0 {
+		c.FreeAttempts = 3
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = time.Second
+	}
+	if c.LockoutThreshold <= 0 {
+		c.LockoutThreshold = 10
+	}
+	if c.IPLockoutThreshold <= 0 {
+		c.IPLockoutThreshold = 50
+	}
+	if c.LockoutDuration <= 0 {
+		c.LockoutDuration = 15 * time.Minute
+	}
+	return c
+}
+
+// logins throttles failed logins and password reset requests per account and IP address as well as registrations
+// per IP address
+var logins = newThrottle()
+
+// throttle tracks failed attempts per key, e.g. an account or an IP address. It is kept in memory only, so it is
+// reset on restart.
+type throttle struct {
+	mutex    sync.Mutex
+	attempts map[string]attempts
+	now      func() time.Time
+}
+
+type attempts struct {
+	failures int
+	last     time.Time
+	locked   time.Time
+}
+
+// maxThrottleEntries is the size of the throttle beyond which forgotten entries are pruned
+const maxThrottleEntries = 10000
+
+func newThrottle() *throttle {
+	return &throttle{attempts: make(map[string]attempts), now: time.Now}
+}
+
+// wait returns how long to wait before the next attempt is allowed for all of the keys
+func (t *throttle) wait(c ThrottleConfig, keys ...string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	var max time.Duration
+	for _, key := range keys {
+		a := t.current(c, key, now)
+
+		var until time.Time
+		if a.locked.After(now) {
+			until = a.locked
+		} else if a.failures >= c.FreeAttempts {
+			until = a.last.Add(delay(c, a.failures))
+		}
+
+		if d := until.Sub(now); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// fail records a failed attempt for the key. If the failures reach the threshold, the key is locked out and true is
+// returned along with the number of failures and the end of the lockout.
+func (t *throttle) fail(c ThrottleConfig, key string, threshold int) (bool, int, time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	a := t.current(c, key, now)
+	a.failures++
+	a.last = now
+
+	locked := false
+	if a.failures >= threshold && !a.locked.After(now) {
+		a.locked, locked = now.Add(c.LockoutDuration), true
+	}
+
+	if len(t.attempts) >= maxThrottleEntries {
+		t.prune(c, now)
+	}
+	t.attempts[key] = a
+	return locked, a.failures, a.locked
+}
+
+// succeed forgets the failures of the key
+func (t *throttle) succeed(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.attempts, key)
+}
+
+// current returns the attempts of the key, forgetting them if the lockout has ended or there has not been any
+// failure for the lockout duration. The caller must hold the lock.
+func (t *throttle) current(c ThrottleConfig, key string, now time.Time) attempts {
+	a := t.attempts[key]
+	if (!a.locked.IsZero() && !a.locked.After(now)) || now.Sub(a.last) > c.LockoutDuration {
+		return attempts{}
+	}
+	return a
+}
+
+// prune drops all forgotten attempts. The caller must hold the lock.
+func (t *throttle) prune(c ThrottleConfig, now time.Time) {
+	for key := range t.attempts {
+		if t.current(c, key, now).failures == 0 {
+			delete(t.attempts, key)
+		}
+	}
+}
+
+// delay returns the delay after the given number of failures, doubling with each failure beyond the free attempts
+func delay(c ThrottleConfig, failures int) time.Duration {
+	d := c.BaseDelay
+	for i := c.FreeAttempts; i < failures && d < c.LockoutDuration; i++ {
+		d *= 2
+	}
+	if d > c.LockoutDuration {
+		d = c.LockoutDuration
+	}
+	return d
+}
+
+// writeThrottled writes 429 Too Many Requests with a Retry-After header
+func writeThrottled(w http.ResponseWriter, wait time.Duration) {
+	secs := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	writeError(w, "too many attempts, please try again in "+strconv.Itoa(secs)+" seconds",
+		http.StatusTooManyRequests)
+}
+
+// recordFailure records a failed attempt for the key and records a lockout in the database, if it triggered one
+func recordFailure(r *http.Request, kind model.LockoutKind, subject string, threshold int) {
+	c := conf.LoginThrottle.withDefaults()
+	locked, failures, until := logins.fail(c, string(kind)+":"+subject, threshold)
+	if !locked {
+		return
+	}
+
+	log.Printf("%s %s locked out until %s after %d failures", kind, subject, until.Format(time.RFC3339), failures)
+	err := db.AddLockout(model.Lockout{
+		Time:     logins.now(),
+		Kind:     kind,
+		Subject:  subject,
+		IP:       clientIP(r),
+		Failures: failures,
+		Until:    until,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// loginWait returns how long the user has to wait before trying to log in again from the IP address of the request
+func loginWait(r *http.Request, username string) time.Duration {
+	return logins.wait(conf.LoginThrottle.withDefaults(), string(model.LockoutAccount)+":"+username,
+		string(model.LockoutIP)+":"+clientIP(r))
+}
+
+// loginFailed records a failed login of the user from the IP address of the request
+func loginFailed(r *http.Request, username string) {
+	c := conf.LoginThrottle.withDefaults()
+	recordFailure(r, model.LockoutAccount, username, c.LockoutThreshold)
+	recordFailure(r, model.LockoutIP, clientIP(r), c.IPLockoutThreshold)
+}
+
+// loginSucceeded forgets the failed logins of the user, but not those of the IP address
+func loginSucceeded(username string) {
+	logins.succeed(string(model.LockoutAccount) + ":" + username)
+}
+
+// errBusy is returned if no bcrypt slot could be acquired in time
+var errBusy = errors.New("server busy, please try again later")
+
+// bcryptSlots limits the number of concurrent bcrypt operations, so that they can not exhaust the CPU
+var bcryptSlots = make(chan struct{}, runtime.NumCPU())
+
+// bcryptTimeout is how long to wait for a bcrypt slot
+const bcryptTimeout = 10 * time.Second
+
+// withBcrypt runs f as soon as a bcrypt slot is free. Returns errBusy if none becomes free in time or the request is
+// cancelled.
+func withBcrypt(ctx context.Context, f func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, bcryptTimeout)
+	defer cancel()
+
+	select {
+	case bcryptSlots <- struct{}{}:
+		defer func() { <-bcryptSlots }()
+		return f()
+	case <-ctx.Done():
+		return errBusy
+	}
+}
+
+// hashPassword hashes the password for storage in a model.Login with the configured cost
+func hashPassword(ctx context.Context, pw string) (string, error) {
+	var hashed []byte
+	err := withBcrypt(ctx, func() (err error) {
+		hashed, err = bcrypt.GenerateFromPassword([]byte(pw), conf.bcryptCost())
+		return
+	})
+	return string(hashed), err
+}
+
+// comparePassword returns nil if the password matches the hash, bcrypt.ErrMismatchedHashAndPassword if not, or
+// errBusy
+func comparePassword(ctx context.Context, hash, pw string) error {
+	return withBcrypt(ctx, func() error {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(pw))
+	})
+}
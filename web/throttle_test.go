@@ -0,0 +1,157 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	c := ThrottleConfig{FreeAttempts: 2, BaseDelay: time.Second, LockoutThreshold: 5,
+		LockoutDuration: time.Minute}.withDefaults()
+
+	now := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	th := newThrottle()
+	th.now = func() time.Time { return now }
+
+	tt := []struct {
+		locked bool
+		wait   time.Duration
+	}{
+		// free attempts
+		{wait: 0},
+		{wait: time.Second},
+		// exponential back-off
+		{wait: 2 * time.Second},
+		{wait: 4 * time.Second},
+		// lockout
+		{locked: true, wait: time.Minute},
+	}
+
+	for i, tc := range tt {
+		locked, failures, _ := th.fail(c, "account:someusername", c.LockoutThreshold)
+		if locked != tc.locked || failures != i+1 {
+			t.Errorf("failure %d: locked: %v failures: %d, want locked: %v", i+1, locked, failures, tc.locked)
+		}
+		if wait := th.wait(c, "account:someusername", "ip:192.0.2.1"); wait != tc.wait {
+			t.Errorf("failure %d: wait: got: %s want: %s", i+1, wait, tc.wait)
+		}
+	}
+
+	// other keys are not affected
+	if wait := th.wait(c, "account:other"); wait != 0 {
+		t.Errorf("other account has to wait %s", wait)
+	}
+
+	// lockouts end and failures are forgotten
+	now = now.Add(time.Minute)
+	if wait := th.wait(c, "account:someusername"); wait != 0 {
+		t.Errorf("still locked after lockout: %s", wait)
+	}
+	if _, failures, _ := th.fail(c, "account:someusername", c.LockoutThreshold); failures != 1 {
+		t.Errorf("failures not forgotten after lockout: %d", failures)
+	}
+
+	// success forgets the failures
+	th.fail(c, "account:someusername", c.LockoutThreshold)
+	th.succeed("account:someusername")
+	if wait := th.wait(c, "account:someusername"); wait != 0 {
+		t.Errorf("failures not forgotten after success: %s", wait)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	useConfig(t, ServerConfig{
+		JWTSecret:     testSecret,
+		LoginThrottle: ThrottleConfig{FreeAttempts: 2, BaseDelay: time.Millisecond, LockoutThreshold: 3},
+		Admins:        []string{"admin"},
+	})
+	logins = newThrottle()
+	t.Cleanup(func() { logins = newThrottle() })
+
+	un, pw := "someusername", "supersafepassword%&$"
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lockouts []model.Lockout
+	db = dbMock{
+		logins:   map[string]model.Login{un: model.NewLogin(un, string(hashed))},
+		sessions: map[string]model.Session{},
+		lockouts: &lockouts,
+	}
+
+	login := func(pw string) *httptest.ResponseRecorder {
+		data := url.Values{"username": {un}, "password": {pw}}
+		r := httptest.NewRequest("POST", "/api/login", strings.NewReader(data.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		loginHandler(rr, r)
+		return rr
+	}
+
+	for i := 0; i < 3; i++ {
+		if rr := login("wrong"); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: wrong status code: got: %d want: %d", i+1, rr.Code, http.StatusUnauthorized)
+		}
+		time.Sleep(10 * time.Millisecond) // back-off
+	}
+
+	// locked out, even with the correct password
+	rr := login(pw)
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("not locked out: %d %v", rr.Code, rr.Header())
+	}
+	if len(lockouts) != 1 || lockouts[0].Kind != model.LockoutAccount || lockouts[0].Subject != un ||
+		lockouts[0].Failures != 3 {
+		t.Fatalf("lockout not recorded: %v", lockouts)
+	}
+
+	// review by admins only
+	for _, tc := range []struct {
+		user string
+		code int
+	}{{"admin", http.StatusOK}, {un, http.StatusForbidden}} {
+		r := httptest.NewRequest("GET", "/admin/lockouts?format=json", nil)
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		rr := httptest.NewRecorder()
+		getLockoutsHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Fatalf("wrong status code for %s: got: %d want: %d", tc.user, rr.Code, tc.code)
+		}
+		if tc.code != http.StatusOK {
+			continue
+		}
+
+		var res model.Lockouts
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil || len(res.Lockout) != 1 {
+			t.Errorf("lockouts not listed: %s %v", rr.Body.String(), err)
+		}
+	}
+}
+
+func TestWithBcrypt(t *testing.T) {
+	// occupy all slots
+	for i := 0; i < cap(bcryptSlots); i++ {
+		bcryptSlots <- struct{}{}
+	}
+	defer func() {
+		for i := 0; i < cap(bcryptSlots); i++ {
+			<-bcryptSlots
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := withBcrypt(ctx, func() error { return nil }); err != errBusy {
+		t.Errorf("got: %v want: %v", err, errBusy)
+	}
+}
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"crypto/subtle"
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// tokenRoutes are the scopes personal access tokens need for the routes they may use. All other routes need a
+// session, e.g. managing the tokens themselves.
+var tokenRoutes = map[*mux.Route]model.TokenScope{}
+
+// scoped allows personal access tokens with the scope to use the routes
+func scoped(scope model.TokenScope, routes ...*mux.Route) {
+	for _, r := range routes {
+		tokenRoutes[r] = scope
+	}
+}
+
+// verifyBearerToken verifies the personal access token in the Authorization header of the request and checks that
+// it may be used for the route. If ok is false, an error has been written to w.
+func verifyBearerToken(w http.ResponseWriter, r *http.Request) (t model.AccessToken, ok bool) {
+	unauthorized := func() {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeError(w, "personal access token invalid, expired or revoked", http.StatusUnauthorized)
+	}
+
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, "authorization header must hold a personal access token: Bearer <token>",
+			http.StatusUnauthorized)
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(h[7:]), ".", 2)
+	if len(parts) != 2 {
+		unauthorized()
+		return
+	}
+
+	t, err := db.GetAccessToken(parts[0])
+	if err == model.ErrNotFound {
+		unauthorized()
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(parts[1])), []byte(t.Hash)) != 1 || t.Expired(time.Now()) {
+		unauthorized()
+		return
+	}
+
+	scope, found := tokenRoutes[mux.CurrentRoute(r)]
+	if !found {
+		writeError(w, "personal access tokens can not be used here, please log in", http.StatusForbidden)
+		return
+	} else if !t.HasScope(scope) {
+		writeError(w, "personal access token lacks the scope "+string(scope), http.StatusForbidden)
+		return
+	}
+
+	return t, true
+}
+
+// accessToken returns the personal access token the request has been authenticated with, if it has not been
+// authenticated with a session
+func accessToken(r *http.Request) (model.AccessToken, bool) {
+	t, ok := r.Context().Value(accessTokenStr).(model.AccessToken)
+	return t, ok
+}
+
+// allowsCalendar returns whether the request may access the calendar. Only personal access tokens can be
+// restricted to some calendars.
+func allowsCalendar(r *http.Request, calendarID string) bool {
+	t, ok := accessToken(r)
+	return !ok || t.AllowsCalendar(calendarID)
+}
+
+// getAccessTokensHandler lists the personal access tokens of the logged in user, without the tokens themselves
+func getAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	ts, err := db.GetAccessTokens(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.AccessTokens{Token: ts}, "")
+}
+
+// postAccessTokenHandler creates a personal access token of the logged in user. The form holds its name, its scopes
+// and optionally the calendars it is restricted to and the date it expires on. The token is shown only this once.
+func postAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		writeError(w, "name missing, html input must have name 'name'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	var scopes []model.TokenScope
+	for _, s := range model.SplitAll(r.Form["scope"]) {
+		scope, err := model.ParseTokenScope(s)
+		if err != nil {
+			writeError(w, "scope not understood, must be one of read, edit, share", http.StatusUnprocessableEntity)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		writeError(w, "scope missing, html input must have name 'scope'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// tokens can only be restricted to calendars the user can see
+	calendars := model.SplitAll(r.Form["calendar"])
+	for _, id := range calendars {
+		c, err := db.GetCalendar(id)
+		if err == model.ErrNotFound || (err == nil && calendarPermission(r, c, userid) < model.Read) {
+			writeError(w, "calendar "+id+" not found", http.StatusUnprocessableEntity)
+			return
+		} else if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	now := time.Now()
+	expires, ok := parseExpires(w, r, now)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	secret, err := randomToken()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	t := model.AccessToken{
+		ID:        id.String(),
+		User:      userid,
+		Name:      name,
+		Created:   now,
+		Expires:   expires,
+		Scopes:    scopes,
+		Calendars: calendars,
+		Hash:      hashToken(secret),
+	}
+	if err := db.AddAccessToken(t); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	t.Token = t.ID + "." + secret
+	writeView(w, r, t, "")
+}
+
+// parseExpires parses the optional form field expires (yyyy-mm-dd), the day until the end of which a token or link
+// is valid. It returns nil if the field is missing. If ok is false, an error has been written to w.
+func parseExpires(w http.ResponseWriter, r *http.Request, now time.Time) (expires *time.Time, ok bool) {
+	e := r.Form.Get("expires")
+	if e == "" {
+		return nil, true
+	}
+
+	day, err := time.Parse("2006-01-02", e)
+	if err != nil {
+		writeError(w, "expires not understood, must be formatted yyyy-mm-dd", http.StatusUnprocessableEntity)
+		return nil, false
+	}
+
+	end := day.AddDate(0, 0, 1)
+	if !end.After(now) {
+		writeError(w, "expires must not be in the past", http.StatusUnprocessableEntity)
+		return nil, false
+	}
+	return &end, true
+}
+
+// deleteAccessTokenHandler revokes a personal access token of the logged in user
+func deleteAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	id := mux.Vars(r)[accessTokenIDStr]
+
+	// tokens of other users are treated as non-existent
+	t, err := db.GetAccessToken(id)
+	if err == model.ErrNotFound || (err == nil && t.User != userid) {
+		writeError(w, "token not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteAccessToken(id); err != nil && err != model.ErrNotFound {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
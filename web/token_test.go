@@ -0,0 +1,148 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAccessTokens(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret, AuthedPathName: "/me"})
+
+	un := "someusername"
+	c := model.Calendar{Name: model.Attribute{Val: "cal"}, Owner: model.Attribute{Val: un},
+		ID: model.Attribute{Val: un + "/cal"}}
+	db = dbMock{
+		sessions: map[string]model.Session{},
+		tokens:   map[string]model.AccessToken{},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{
+			"GetCalendar": {d: c},
+			"GetUser":     {d: model.User{}},
+		},
+	}
+
+	router := mux.NewRouter().StrictSlash(true)
+	registerRoutes(router)
+
+	create := func(form url.Values) (model.AccessToken, int) {
+		r := httptest.NewRequest("POST", "/me/tokens?format=json", strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+		rr := httptest.NewRecorder()
+		postAccessTokenHandler(rr, r)
+
+		var res model.AccessToken
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return res, rr.Code
+	}
+
+	for _, form := range []url.Values{
+		{"scope": {"read"}},
+		{"name": {"backup"}},
+		{"name": {"backup"}, "scope": {"write"}},
+		{"name": {"backup"}, "scope": {"read"}, "expires": {"2000-01-01"}},
+	} {
+		if _, code := create(form); code != http.StatusUnprocessableEntity {
+			t.Errorf("wrong status code for %v: got: %d want: %d", form, code, http.StatusUnprocessableEntity)
+		}
+	}
+
+	read, _ := create(url.Values{"name": {"backup"}, "scope": {"read"}, "calendar": {un + "/cal"},
+		"expires": {"2999-12-31"}})
+	edit, _ := create(url.Values{"name": {"sync"}, "scope": {"edit,share"}})
+	restricted, _ := create(url.Values{"name": {"other"}, "scope": {"edit"}, "calendar": {un + "/other"}})
+	if read.Token == "" || read.Expires == nil || db.(dbMock).tokens[read.ID].Token != "" ||
+		db.(dbMock).tokens[read.ID].Hash != hashToken(strings.SplitN(read.Token, ".", 2)[1]) {
+		t.Fatalf("token not created, shown or stored correctly: %v", read)
+	}
+
+	tt := []struct {
+		method, path string
+		token        string
+		form         url.Values
+		code         int
+	}{
+		// kosher cases
+		{method: "GET", path: "/me/calendars/someusername/cal", token: read.Token, code: http.StatusOK},
+		{method: "PUT", path: "/me/calendars/someusername/cal", token: edit.Token, form: url.Values{"desc": {"d"}},
+			code: http.StatusSeeOther},
+		// no such token or wrong secret
+		{method: "GET", path: "/me/calendars/someusername/cal", token: "unknown.secret",
+			code: http.StatusUnauthorized},
+		{method: "GET", path: "/me/calendars/someusername/cal", token: read.ID + ".wrong",
+			code: http.StatusUnauthorized},
+		// scope missing
+		{method: "PUT", path: "/me/calendars/someusername/cal", token: read.Token, form: url.Values{"desc": {"d"}},
+			code: http.StatusForbidden},
+		{method: "POST", path: "/me/api/sharing", token: read.Token, code: http.StatusForbidden},
+		// restricted to other calendars
+		{method: "GET", path: "/me/calendars/someusername/cal", token: restricted.Token, code: http.StatusForbidden},
+		// tokens never act as owner
+		{method: "DELETE", path: "/me/calendars/someusername/cal", token: edit.Token, code: http.StatusForbidden},
+		{method: "POST", path: "/me/calendars/someusername/cal", token: edit.Token,
+			form: url.Values{"_method": {"DELETE"}}, code: http.StatusForbidden},
+		// sessions only
+		{method: "GET", path: "/me/tokens", token: edit.Token, code: http.StatusForbidden},
+		{method: "GET", path: "/me/sessions", token: edit.Token, code: http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Authorization", "Bearer "+tc.token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%s %s with %s: wrong status code: got: %d want: %d \n%s", tc.method, tc.path, tc.token,
+				rr.Code, tc.code, rr.Body.String())
+		}
+	}
+
+	// listed without the tokens themselves
+	r := httptest.NewRequest("GET", "/me/tokens?format=json", nil)
+	r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+	rr := httptest.NewRecorder()
+	getAccessTokensHandler(rr, r)
+	var ts model.AccessTokens
+	if err := json.Unmarshal(rr.Body.Bytes(), &ts); err != nil || len(ts.Token) != 3 || ts.Token[0].Token != "" {
+		t.Fatalf("tokens not listed correctly: %s %v", rr.Body.String(), err)
+	}
+
+	// revocation by the owner only
+	for _, tc := range []struct {
+		user string
+		code int
+	}{{"other", http.StatusNotFound}, {un, http.StatusSeeOther}, {un, http.StatusNotFound}} {
+		r := httptest.NewRequest("DELETE", "/me/tokens/"+read.ID, nil)
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{accessTokenIDStr: read.ID})
+		rr := httptest.NewRecorder()
+		deleteAccessTokenHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Fatalf("revoking as %s: wrong status code: got: %d want: %d", tc.user, rr.Code, tc.code)
+		}
+	}
+
+	r = httptest.NewRequest("GET", "/me/calendars/someusername/cal", nil)
+	r.Header.Set("Authorization", "Bearer "+read.Token)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, r)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token accepted: %d", rr.Code)
+	}
+}
@@ -0,0 +1,451 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"github.com/dgrijalva/jwt-go"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// totpIssuer is shown by authenticator apps along with the username
+	totpIssuer = "Plannet"
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is the number of time steps a code may be off, to allow for clocks drifting apart
+	totpSkew = 1
+	// recoveryCodeCount is the number of recovery codes generated on enrollment
+	recoveryCodeCount = 10
+	// mfaTokenDuration is how long the password verified token is valid, i.e. how long the user has to enter the code
+	mfaTokenDuration = 5 * time.Minute
+	// mfaPage is where the user enters the code after the password has been verified
+	mfaPage = "/html/mfa.html"
+)
+
+// totpEncoding encodes the secrets as expected by authenticator apps
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// errMFAToken is returned if the password verified token is missing, invalid or expired
+var errMFAToken = errors.New("password not verified or verification expired, please log in again")
+
+// errCodeIncorrect is returned if the code of a second factor is wrong or has been used before
+var errCodeIncorrect = errors.New("code incorrect")
+
+// errNoEnrollment is returned if a code is confirmed without a pending enrollment
+var errNoEnrollment = errors.New("no enrollment pending")
+
+// errTOTPEnabled is returned if a second factor is enrolled while one is enabled already
+var errTOTPEnabled = errors.New("two-factor authentication already enabled, disable it first")
+
+// totpCode computes the code of the key for the time step as specified by RFC 4226 and RFC 6238
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0xf
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, bin%mod)
+}
+
+// checkTOTP checks the code against the secret at the given time. Only codes of time steps after lastStep are
+// accepted, so that a code can not be replayed. Returns the time step of the code and whether it is correct.
+func checkTOTP(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(totpPeriod/time.Second)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step > lastStep && subtle.ConstantTimeCompare([]byte(code), []byte(totpCode(key, step))) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newTOTPSecret generates a new random secret of 160 bits, as recommended by RFC 4226
+func newTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// provisioningURI returns the otpauth:// URI authenticator apps are set up with, usually scanned as QR code
+func provisioningURI(secret, username string) string {
+	q := url.Values{
+		"secret":    {secret},
+		"issuer":    {totpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {strconv.Itoa(totpDigits)},
+		"period":    {strconv.Itoa(int(totpPeriod / time.Second))},
+	}
+	return "otpauth://totp/" + url.PathEscape(totpIssuer+":"+username) + "?" + q.Encode()
+}
+
+// newRecoveryCodes generates the one-time recovery codes, which are shown to the user once, and their hashes, which
+// are stored
+func newRecoveryCodes() ([]string, []model.Attribute, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]model.Attribute, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+		codes[i] = c[:5] + "-" + c[5:]
+		hashes[i] = model.Attribute{Val: hashToken(c)}
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode removes the formatting users may have added or dropped when typing a recovery code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// checkSecondFactor checks the code, which may be a TOTP code or a recovery code, against the second factor of the
+// login. If it is correct, the login is updated so that the code can not be used again; the caller has to store it
+// within the same db.UpdateLogin, so that a code can not be used twice concurrently.
+func checkSecondFactor(l *model.Login, code string, now time.Time) bool {
+	if step, ok := checkTOTP(l.TOTP.Secret, code, now, l.TOTP.LastStep); ok {
+		l.TOTP.LastStep = step
+		return true
+	}
+
+	hash := hashToken(normalizeRecoveryCode(code))
+	for i, rc := range l.TOTP.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(rc.Val), []byte(hash)) == 1 {
+			l.TOTP.RecoveryCodes = append(l.TOTP.RecoveryCodes[:i], l.TOTP.RecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// createMFAToken generates the short-lived token stating that the password of the user has been verified. It is
+// exchanged for a session once the second factor has been checked and is never accepted by the auth middleware.
+func createMFAToken(userid string) (string, error) {
+	c := jwt.MapClaims{}
+	c[purposeStr] = mfaStr
+	c[userIDStr] = userid
+	c[expiryStr] = time.Now().Add(mfaTokenDuration).Unix()
+
+	return keys.sign(c)
+}
+
+// verifyMFAToken returns the user of the password verified token of the request, or errMFAToken
+func verifyMFAToken(r *http.Request) (string, error) {
+	c, err := r.Cookie(mfaStr)
+	if err != nil {
+		return "", errMFAToken
+	}
+
+	t, err := parseTokenAndVerifySignature(c.Value)
+	if err != nil {
+		return "", errMFAToken
+	}
+
+	claims, cast := t.Claims.(jwt.MapClaims)
+	if !cast || !t.Valid || claims[purposeStr] != mfaStr {
+		return "", errMFAToken
+	}
+
+	userid, cast := claims[userIDStr].(string)
+	expiry, found := claims[expiryStr].(float64)
+	if !cast || !found || time.Now().Unix() > int64(expiry) {
+		return "", errMFAToken
+	}
+
+	return userid, nil
+}
+
+// mfaCookie is the cookie with the password verified token, only sent along with the code
+func mfaCookie() http.Cookie {
+	return http.Cookie{Name: mfaStr, Path: "/api/login", HttpOnly: true, Secure: !conf.InsecureCookies,
+		SameSite: http.SameSiteStrictMode}
+}
+
+// startLogin logs in the user, whose password has been verified. If the user has enabled a second factor, only the
+// password verified token is set and the user is sent to enter the code; otherwise a scheduled deletion of the
+// account is cancelled and a new session is started. Returns where to redirect the user to. The caller is
+// responsible for writing an error to w, if one is returned.
+func startLogin(w http.ResponseWriter, r *http.Request, l model.Login) (string, error) {
+	if !l.TOTP.Enabled {
+		if l.Deletion != nil {
+			if err := db.UpdateLogin(l.Name.Val, cancelDeletion); err != nil {
+				return "", err
+			}
+		}
+		return "/html/mainPage.html", setAuthCookie(w, r, l.Name.Val)
+	}
+
+	t, err := createMFAToken(l.Name.Val)
+	if err != nil {
+		return "", err
+	}
+
+	c := mfaCookie()
+	c.Value, c.Expires = t, time.Now().Add(mfaTokenDuration)
+	http.SetCookie(w, &c)
+
+	return mfaPage, nil
+}
+
+// loginMFAHandler completes a login by checking the second factor of the user, whose password has been verified
+// before, and starts a new session
+func loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	code := strings.TrimSpace(r.Form.Get("code"))
+	if code == "" {
+		writeError(w, "code missing, html input must have name 'code'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	userid, err := verifyMFAToken(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// guessing codes is throttled like logins
+	if wait := loginWait(r, userid); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
+	err = db.UpdateLogin(userid, func(l *model.Login) error {
+		if !l.TOTP.Enabled {
+			return errMFAToken
+		}
+		if !checkSecondFactor(l, code, time.Now()) {
+			return errCodeIncorrect
+		}
+		return cancelDeletion(l)
+	})
+	if err == model.ErrNotFound || err == errMFAToken {
+		writeError(w, errMFAToken.Error(), http.StatusUnauthorized)
+		return
+	} else if err == errCodeIncorrect {
+		loginFailed(r, userid)
+		writeError(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	loginSucceeded(userid)
+
+	c := mfaCookie()
+	deleteCookie(w, &c)
+
+	if err := setAuthCookie(w, r, userid); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// getTOTPHandler shows whether the logged in user has enabled a second factor and how many recovery codes are left
+func getTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	l, err := db.GetLogin(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.TOTPView{Enabled: l.TOTP.Enabled, RecoveryCodesLeft: len(l.TOTP.RecoveryCodes)}, "")
+}
+
+// enrollTOTPHandler generates a new secret for the logged in user and shows it along with its provisioning URI. The
+// second factor is only enabled once a code has been confirmed with confirmTOTPHandler.
+func enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	err = db.UpdateLogin(userid, func(l *model.Login) error {
+		if l.TOTP.Enabled {
+			return errTOTPEnabled
+		}
+		l.TOTP = model.TOTP{Secret: secret}
+		return nil
+	})
+	if err == errTOTPEnabled {
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.TOTPView{Secret: secret, URI: provisioningURI(secret, userid)}, "")
+}
+
+// confirmTOTPHandler enables the second factor of the logged in user, if the code matches the secret of the
+// enrollment, and shows the recovery codes. They are shown only this once.
+func confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	code := strings.TrimSpace(r.Form.Get("code"))
+	if code == "" {
+		writeError(w, "code missing, html input must have name 'code'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if wait := loginWait(r, userid); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	err = db.UpdateLogin(userid, func(l *model.Login) error {
+		if l.TOTP.Enabled || l.TOTP.Secret == "" {
+			return errNoEnrollment
+		}
+
+		step, ok := checkTOTP(l.TOTP.Secret, code, time.Now(), l.TOTP.LastStep)
+		if !ok {
+			return errCodeIncorrect
+		}
+
+		l.TOTP.Enabled, l.TOTP.LastStep, l.TOTP.RecoveryCodes = true, step, hashes
+		return nil
+	})
+	if err == errNoEnrollment {
+		writeError(w, err.Error(), http.StatusConflict)
+		return
+	} else if err == errCodeIncorrect {
+		loginFailed(r, userid)
+		writeError(w, err.Error(), http.StatusForbidden)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	writeView(w, r, model.TOTPView{Enabled: true, RecoveryCodesLeft: len(codes), RecoveryCodes: codes}, "")
+}
+
+// disableTOTPHandler removes the second factor of the logged in user, who has to confirm the password
+func disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	pw := r.Form.Get("password")
+	if pw == "" {
+		writeError(w, "password missing, html input must have name 'password'", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// guessing the password is throttled like logins
+	if wait := loginWait(r, userid); wait > 0 {
+		writeThrottled(w, wait)
+		return
+	}
+
+	l, err := db.GetLogin(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the password is compared before updating the login, which would lock it for as long as hashing takes
+	if err := comparePassword(r.Context(), l.Hash.Val, pw); err == errBusy {
+		writeError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		loginFailed(r, userid)
+		writeError(w, "password incorrect", http.StatusForbidden)
+		return
+	}
+
+	if err := db.UpdateLogin(userid, func(l *model.Login) error {
+		l.TOTP = model.TOTP{}
+		return nil
+	}); err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
@@ -0,0 +1,167 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors of RFC 6238 for SHA1, which has 8 digits where we use the last 6
+	key := []byte("12345678901234567890")
+	tt := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range tt {
+		if code := totpCode(key, tc.time/30); code != tc.code {
+			t.Errorf("time %d: got: %s want: %s", tc.time, code, tc.code)
+		}
+	}
+
+	secret := totpEncoding.EncodeToString(key)
+	now := time.Unix(59, 0)
+	if _, ok := checkTOTP(secret, "287082", now.Add(30*time.Second), 0); !ok {
+		t.Error("code of the previous time step not accepted")
+	}
+	if _, ok := checkTOTP(secret, "287082", now.Add(90*time.Second), 0); ok {
+		t.Error("code two time steps old accepted")
+	}
+	if _, ok := checkTOTP(secret, "287082", now, 1); ok {
+		t.Error("replayed code accepted")
+	}
+}
+
+func TestTOTPLogin(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret})
+	logins = newThrottle()
+	t.Cleanup(func() { logins = newThrottle() })
+
+	un, pw := "someusername", "supersafepassword%&$"
+	hashed, err := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = dbMock{
+		logins:   map[string]model.Login{un: model.NewLogin(un, string(hashed))},
+		sessions: map[string]model.Session{},
+	}
+
+	do := func(handler http.HandlerFunc, target string, form url.Values, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", target, strings.NewReader(form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, un))
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, r)
+		return rr
+	}
+
+	// enrollment
+	rr := do(enrollTOTPHandler, "/totp?format=json", nil)
+	var enrolled model.TOTPView
+	if err := json.Unmarshal(rr.Body.Bytes(), &enrolled); err != nil || enrolled.Secret == "" {
+		t.Fatalf("no secret: %s %v", rr.Body.String(), err)
+	}
+	if !strings.HasPrefix(enrolled.URI, "otpauth://totp/Plannet:someusername?") ||
+		!strings.Contains(enrolled.URI, "secret="+enrolled.Secret) {
+		t.Errorf("wrong provisioning uri: %s", enrolled.URI)
+	}
+	key, _ := totpEncoding.DecodeString(enrolled.Secret)
+	step := time.Now().Unix() / 30
+
+	if rr := do(confirmTOTPHandler, "/totp/confirm", url.Values{"code": {"abcdef"}}); rr.Code != http.StatusForbidden {
+		t.Fatalf("wrong code confirmed: %d", rr.Code)
+	}
+	rr = do(confirmTOTPHandler, "/totp/confirm?format=json", url.Values{"code": {totpCode(key, step)}})
+	var confirmed model.TOTPView
+	if err := json.Unmarshal(rr.Body.Bytes(), &confirmed); err != nil || !confirmed.Enabled ||
+		len(confirmed.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("not enabled: %s %v", rr.Body.String(), err)
+	}
+	if l, _ := db.GetLogin(un); l.TOTP.RecoveryCodes[0].Val == confirmed.RecoveryCodes[0] {
+		t.Error("recovery codes stored in plain text")
+	}
+	if rr := do(enrollTOTPHandler, "/totp", nil); rr.Code != http.StatusConflict {
+		t.Errorf("enrolled twice: %d", rr.Code)
+	}
+
+	// the password only gets the password verified token
+	login := func() *http.Cookie {
+		rr := do(loginHandler, "/api/login", url.Values{"username": {un}, "password": {pw}})
+		cs := cookieMap(rr)
+		if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != mfaPage || cs[authStr] != nil ||
+			cs[mfaStr] == nil {
+			t.Fatalf("no two-step login: %d %v", rr.Code, rr.Header())
+		}
+		return cs[mfaStr]
+	}
+	mfa := login()
+
+	// which is no access token
+	r := httptest.NewRequest("GET", "/calendars", nil)
+	r.AddCookie(&http.Cookie{Name: authStr, Value: mfa.Value})
+	if _, _, _, ok := verifyAccessToken(httptest.NewRecorder(), r); ok {
+		t.Error("password verified token accepted as access token")
+	}
+
+	tt := []struct {
+		code    string
+		cookies []*http.Cookie
+		want    int
+	}{
+		// password not verified
+		{code: confirmed.RecoveryCodes[0], want: http.StatusUnauthorized},
+		// wrong code
+		{code: "notacode", cookies: []*http.Cookie{mfa}, want: http.StatusUnauthorized},
+		// recovery code, typed sloppily
+		{code: strings.ToUpper(strings.Replace(confirmed.RecoveryCodes[0], "-", " ", 1)),
+			cookies: []*http.Cookie{mfa}, want: http.StatusSeeOther},
+		// recovery codes are single-use
+		{code: confirmed.RecoveryCodes[0], cookies: []*http.Cookie{mfa}, want: http.StatusUnauthorized},
+		// code of the confirmation can not be replayed
+		{code: totpCode(key, step), cookies: []*http.Cookie{mfa}, want: http.StatusUnauthorized},
+		// kosher case
+		{code: totpCode(key, step+1), cookies: []*http.Cookie{mfa}, want: http.StatusSeeOther},
+	}
+
+	for i, tc := range tt {
+		logins = newThrottle()
+		rr := do(loginMFAHandler, "/api/login/mfa", url.Values{"code": {tc.code}}, tc.cookies...)
+		if rr.Code != tc.want {
+			t.Fatalf("case %d: wrong status code: got: %d want: %d \n%s", i, rr.Code, tc.want, rr.Body.String())
+		}
+		if authed := cookieMap(rr)[authStr] != nil; authed != (tc.want == http.StatusSeeOther) {
+			t.Errorf("case %d: auth cookie set: %v", i, authed)
+		}
+	}
+
+	// disabling requires the password
+	if rr := do(disableTOTPHandler, "/totp", url.Values{"password": {"wrong"}}); rr.Code != http.StatusForbidden {
+		t.Fatalf("disabled with wrong password: %d", rr.Code)
+	}
+	if rr := do(disableTOTPHandler, "/totp", url.Values{"password": {pw}}); rr.Code != http.StatusSeeOther {
+		t.Fatalf("not disabled: %d %s", rr.Code, rr.Body.String())
+	}
+	rr = do(loginHandler, "/api/login", url.Values{"username": {un}, "password": {pw}})
+	if cookieMap(rr)[authStr] == nil {
+		t.Error("still two-step login after disabling")
+	}
+}
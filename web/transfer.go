@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// transferCalendarHandler hands a calendar of the logged in user over to the user of the form field owner, e.g. when
+// a colleague leaves. The form field name renames the calendar, which is needed if the new owner has a calendar of
+// the same name already. keep is the permission the previous owner keeps: view, edit or none (default).
+func transferCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	c, err := getCalendarIfPermission(w, r, model.Owner)
+	if err != nil {
+		return
+	}
+
+	if c.Name.Val == c.Owner.Val {
+		writeError(w, "you must not transfer default calendar", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse HTML form from body
+	if err := r.ParseForm(); err != nil {
+		writeError(w, "couldn't parse form", http.StatusBadRequest)
+		return
+	}
+
+	newOwner := strings.TrimSpace(r.Form.Get("owner"))
+	if newOwner == "" {
+		writeError(w, "owner missing, html input must have name 'owner'", http.StatusUnprocessableEntity)
+		return
+	} else if newOwner == c.Owner.Val {
+		writeError(w, "calendar is owned by this user already", http.StatusUnprocessableEntity)
+		return
+	}
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	if name == "" {
+		name = c.Name.Val
+	} else if !legalName(name) {
+		writeError(w, "illegal name", http.StatusUnprocessableEntity)
+		return
+	}
+
+	keep := model.None
+	switch r.Form.Get("keep") {
+	case "", "none":
+	case "view":
+		keep = model.Read
+	case "edit":
+		keep = model.Edit
+	default:
+		writeError(w, "keep not understood, must be one of view, edit, none", http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, err := db.GetUser(newOwner); err == model.ErrNotFound {
+		writeError(w, "specified user name not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	err = db.TransferCalendar(c.GetID(), newOwner, name, keep)
+	if err == model.ErrNotFound {
+		writeError(w, "calendar not found", http.StatusNotFound)
+		return
+	} else if err == model.ErrAlreadyExists {
+		writeError(w, fmt.Sprintf("%s already has a calendar named %s, choose another name", newOwner, name),
+			http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	// the audit log has been handed over along with the calendar
+	if after, err := db.GetCalendar(newOwner + "/" + name); err == nil {
+		recordAudit(auditEntry(r, after.GetID(), model.AuditTransfer, c.Metadata(), after.Metadata()))
+	} else {
+		log.Println(err)
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"context"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTransferCalendar(t *testing.T) {
+	owner, heir, other := "someowner", "someheir", "someoneelse"
+	c := model.Calendar{Name: model.Attribute{Val: "cal"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/cal"}}
+	c.Permissions.Edit.User = []model.Attribute{{Val: other}}
+
+	type transfer struct {
+		calID, newOwner, newName string
+		keep                     model.Permission
+	}
+	var got *transfer
+	db = dbMock{
+		users: map[string]model.User{owner: model.NewUser(owner), heir: model.NewUser(heir),
+			other: model.NewUser(other)},
+		transferCalendar: func(calID, newOwner, newName string, keep model.Permission) error {
+			if newName == "taken" {
+				return model.ErrAlreadyExists
+			}
+			got = &transfer{calID, newOwner, newName, keep}
+			return nil
+		},
+		data: map[string]struct {
+			d interface{}
+			e error
+		}{"GetCalendar": {d: c}},
+	}
+
+	tt := []struct {
+		user string
+		cal  string
+		form url.Values
+		code int
+		want *transfer
+	}{
+		{user: owner, cal: "cal", form: url.Values{"owner": {heir}}, code: http.StatusSeeOther,
+			want: &transfer{owner + "/cal", heir, "cal", model.None}},
+		{user: owner, cal: "cal", form: url.Values{"owner": {heir}, "name": {"renamed"}, "keep": {"view"}},
+			code: http.StatusSeeOther, want: &transfer{owner + "/cal", heir, "renamed", model.Read}},
+		{user: owner, cal: "cal", form: url.Values{"owner": {heir}, "name": {"taken"}}, code: http.StatusConflict},
+		{user: owner, cal: "cal", form: url.Values{"owner": {"nobody"}}, code: http.StatusNotFound},
+		{user: owner, cal: "cal", form: url.Values{"owner": {owner}}, code: http.StatusUnprocessableEntity},
+		{user: owner, cal: "cal", form: url.Values{}, code: http.StatusUnprocessableEntity},
+		{user: owner, cal: "cal", form: url.Values{"owner": {heir}, "name": {"../cal"}},
+			code: http.StatusUnprocessableEntity},
+		{user: owner, cal: "cal", form: url.Values{"owner": {heir}, "keep": {"owner"}},
+			code: http.StatusUnprocessableEntity},
+		// editors may not give the calendar away
+		{user: other, cal: "cal", form: url.Values{"owner": {other}}, code: http.StatusForbidden},
+	}
+
+	for i, tc := range tt {
+		got = nil
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tc.form.Encode()))
+		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, tc.user))
+		r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: tc.cal})
+		rr := httptest.NewRecorder()
+		transferCalendarHandler(rr, r)
+
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+		if (tc.want == nil) != (got == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("%d: expected transfer %v, got %v", i, tc.want, got)
+		}
+	}
+
+	// the default calendar stays with its owner
+	def := c
+	def.Name.Val = owner
+	db.(dbMock).data["GetCalendar"] = struct {
+		d interface{}
+		e error
+	}{d: def}
+	r := httptest.NewRequest("POST", "/", strings.NewReader(url.Values{"owner": {heir}}.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r = r.WithContext(context.WithValue(r.Context(), userIDStr, owner))
+	r = mux.SetURLVars(r, map[string]string{userIDStr: owner, calendarIDStr: owner})
+	rr := httptest.NewRecorder()
+	transferCalendarHandler(rr, r)
+	if rr.Code != http.StatusMethodNotAllowed || got != nil {
+		t.Errorf("default calendar: expected code %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
@@ -0,0 +1,205 @@
+package web
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// purgeInterval is how often expired trash entries and accounts due to be deleted are destroyed
+const purgeInterval = time.Hour
+
+// trashItem moves the item of the given kind of the request into the trash of the owner of its calendar, where it
+// can be restored until the retention is over
+func trashItem(w http.ResponseWriter, r *http.Request, kind model.ItemKind) {
+	c, err := getCalendarIfPermission(w, r, model.Edit)
+	if err != nil {
+		// err reporting already done by method call
+		return
+	}
+
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	itemID := mux.Vars(r)[itemIDStr]
+	before, ok := c.Item(kind, itemID)
+	if !ok {
+		writeError(w, "item with given id not found", http.StatusNotFound)
+		return
+	}
+
+	err = db.TrashItem(c.GetID(), kind, itemID, userid)
+	if err == model.ErrNotFound {
+		writeError(w, "item with given id not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(itemAuditEntry(r, c.GetID(), kind, itemID, model.AuditDelete, before, nil))
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// getTrashHandler lists the deleted items and calendars the logged in user can restore, the most recent first. Only
+// the metadata of deleted calendars is listed.
+func getTrashHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	es, err := db.GetTrash(userid)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range es {
+		if es[i].Content != nil {
+			c := es[i].Content.Metadata()
+			es[i].Content = &c
+		}
+	}
+	writeView(w, r, model.Trash{Entry: es}, "")
+}
+
+// restoreTrashHandler restores the deleted item or calendar of the request from the trash of the logged in user
+func restoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	userid, ok := r.Context().Value(userIDStr).(string)
+	if !ok {
+		writeError(w, "", http.StatusUnauthorized)
+		return
+	}
+
+	e, err := db.RestoreTrash(userid, mux.Vars(r)[trashIDStr])
+	if err == model.ErrNotFound {
+		writeError(w, "entry not found, or the calendar of the item has been deleted", http.StatusNotFound)
+		return
+	} else if err == model.ErrAlreadyExists {
+		writeError(w, "an item or calendar of the same ID exists already", http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	if e.Content != nil {
+		recordAudit(auditEntry(r, e.Calendar, model.AuditRestore, nil, e.Content.Metadata()))
+	} else if c, err := db.GetCalendar(e.Calendar); err == nil {
+		after, _ := c.Item(e.Kind, e.Item)
+		recordAudit(itemAuditEntry(r, e.Calendar, e.Kind, e.Item, model.AuditRestore, nil, after))
+	} else {
+		log.Println(err)
+	}
+
+	http.Redirect(w, r, "/html/mainPage.html", http.StatusSeeOther)
+}
+
+// scheduleDeletion deletes the account of the user for good after the configured delay and revokes all of its
+// sessions and personal access tokens, so that nobody changes the calendars about to be deleted. The user is notified,
+// if an email address is known.
+func scheduleDeletion(userid string) error {
+	t := time.Now().Add(conf.accountDeletionDelay())
+	var l model.Login
+	if err := db.UpdateLogin(userid, func(u *model.Login) error {
+		u.Deletion = &t
+		l = *u
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := db.DeleteSessions(userid, ""); err != nil {
+		return err
+	}
+
+	ts, err := db.GetAccessTokens(userid)
+	if err != nil {
+		return err
+	}
+	for _, tok := range ts {
+		if err := db.DeleteAccessToken(tok.ID); err != nil && err != model.ErrNotFound {
+			return err
+		}
+	}
+
+	if l.Email.Val == "" {
+		return nil
+	}
+	body := "Hello " + userid + ",\n\n" +
+		"your account has been deleted. It is kept until " + t.Format(time.RFC1123) + ", when it is deleted for " +
+		"good along with all of your calendars. Log in again before to keep your account. Your personal access " +
+		"tokens have been revoked.\n"
+	if err := mailer.Send(l.Email.Val, "Your account has been deleted", body); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+// cancelDeletion keeps the account of the login, if its deletion has been scheduled. Every login does so, within the
+// db.UpdateLogin of the login.
+func cancelDeletion(l *model.Login) error {
+	l.Deletion = nil
+	return nil
+}
+
+// deleteAccount deletes the user for good. The calendars shared with the user lose a member, which their owners find
+// in the audit logs. The user is gone already, if deleting its login failed before.
+func deleteAccount(userid string) error {
+	u, err := db.GetUser(userid)
+	if err != nil && err != model.ErrNotFound {
+		return err
+	}
+
+	if err := db.DeleteUser(userid); err != nil {
+		return err
+	}
+
+	for _, ref := range u.Items.Calendars {
+		if !strings.HasPrefix(ref.Link, userid+"/") {
+			recordAudit(model.AuditEntry{Time: time.Now(), User: userid, Calendar: ref.Link,
+				Action: model.AuditDeleteUser})
+		}
+	}
+	return nil
+}
+
+// purge destroys the trash entries and sessions expired until now and the accounts due to be deleted. Failures are
+// only logged, they are retried with the next purge.
+func purge(now time.Time) {
+	if err := db.PurgeTrash(now); err != nil {
+		log.Println(err)
+	}
+	if err := db.PurgeSessions(now); err != nil {
+		log.Println(err)
+	}
+
+	users, err := db.GetScheduledDeletions(now)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, userid := range users {
+		if err := deleteAccount(userid); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// purgePeriodically purges every purgeInterval, starting right away. It never returns.
+func purgePeriodically() {
+	for now := time.Now(); ; now = <-time.After(purgeInterval) {
+		purge(now)
+	}
+}
@@ -0,0 +1,203 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Project-Planner/backend/model"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrash(t *testing.T) {
+	owner, editor := "someowner", "someeditor"
+	c := model.Calendar{Name: model.Attribute{Val: "project"}, Owner: model.Attribute{Val: owner},
+		ID: model.Attribute{Val: owner + "/project"}}
+	c.Permissions.Edit.User = []model.Attribute{{Val: editor}}
+	c.Items.Tasks.Task = []model.Task{{ID: "t1", Name: model.Attribute{Val: "Write docs"}}}
+
+	var entries []model.AuditEntry
+	mock := dbMock{
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		groups:    map[string]model.Group{},
+		trash:     map[string]model.TrashEntry{},
+		audit:     &entries,
+	}
+	db = mock
+
+	request := func(method, user string, vars map[string]string) *http.Request {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("Accept", "application/json")
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, user))
+		return mux.SetURLVars(r, vars)
+	}
+	itemVars := map[string]string{userIDStr: owner, calendarIDStr: "project", itemIDStr: "t1"}
+	trash := func(user string) model.Trash {
+		rr := httptest.NewRecorder()
+		getTrashHandler(rr, request("GET", user, nil))
+		var tr model.Trash
+		if err := json.Unmarshal(rr.Body.Bytes(), &tr); err != nil {
+			t.Fatalf("%v: %s", err, rr.Body.String())
+		}
+		return tr
+	}
+
+	// the editor deletes the task into the trash of the owner
+	rr := httptest.NewRecorder()
+	deleteTaskHandler(rr, request("DELETE", editor, itemVars))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+	}
+	if mock.calendars[c.GetID()].HasItem("t1") {
+		t.Error("expected the task to be deleted")
+	}
+	rr = httptest.NewRecorder()
+	deleteTaskHandler(rr, request("DELETE", editor, itemVars))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("expected code %d for a deleted task, got %d", http.StatusNotFound, rr.Code)
+	}
+
+	if tr := trash(editor); len(tr.Entry) != 0 {
+		t.Errorf("expected the trash of the editor to be empty, got %v", tr)
+	}
+	tr := trash(owner)
+	if len(tr.Entry) != 1 || tr.Entry[0].Task == nil || tr.Entry[0].DeletedBy != editor {
+		t.Fatalf("expected the task in the trash of the owner, got %v", tr)
+	}
+
+	// only the owner restores it
+	for i, tc := range []struct {
+		user string
+		code int
+	}{
+		{user: editor, code: http.StatusNotFound},
+		{user: owner, code: http.StatusSeeOther},
+		{user: owner, code: http.StatusNotFound},
+	} {
+		rr := httptest.NewRecorder()
+		restoreTrashHandler(rr, request("POST", tc.user, map[string]string{trashIDStr: tr.Entry[0].ID}))
+		if rr.Code != tc.code {
+			t.Errorf("%d: expected code %d, got %d: %s", i, tc.code, rr.Code, rr.Body.String())
+		}
+	}
+	if !mock.calendars[c.GetID()].HasItem("t1") {
+		t.Error("expected the task to be restored")
+	}
+	if len(entries) != 2 || entries[0].Action != model.AuditDelete || entries[1].Action != model.AuditRestore ||
+		entries[1].Item != "t1" {
+		t.Errorf("expected the deletion and the restore to be audited, got %v", entries)
+	}
+
+	// deleted calendars are listed without their items
+	rr = httptest.NewRecorder()
+	deleteCalendarHandler(rr, request("DELETE", owner, map[string]string{userIDStr: owner, calendarIDStr: "project"}))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+	}
+	tr = trash(owner)
+	if len(tr.Entry) != 1 || tr.Entry[0].Content == nil || tr.Entry[0].Content.HasItem("t1") {
+		t.Fatalf("expected the calendar without its items in the trash, got %v", tr)
+	}
+
+	rr = httptest.NewRecorder()
+	restoreTrashHandler(rr, request("POST", owner, map[string]string{trashIDStr: tr.Entry[0].ID}))
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+	}
+	if !mock.calendars[c.GetID()].HasItem("t1") {
+		t.Error("expected the calendar to be restored with its items")
+	}
+}
+
+func TestAccountDeletion(t *testing.T) {
+	useConfig(t, ServerConfig{JWTSecret: testSecret, AuthedPathName: "/me", AccountDeletionDelay: time.Hour})
+
+	var mails []sentMail
+	mailer = mailMock{mails: &mails}
+	t.Cleanup(func() { mailer = logMailSender{} })
+
+	user := model.NewUser("someone")
+	user.Items.Calendars = []model.CalendarReference{{Link: "someone/someone"}, {Link: "someoneelse/project"}}
+	l := model.NewLogin("someone", "hash")
+	l.Email.Val = "someone@example.com"
+	c := model.Calendar{Name: model.Attribute{Val: "someone"}, Owner: model.Attribute{Val: "someone"},
+		ID: model.Attribute{Val: "someone/someone"}}
+
+	var entries []model.AuditEntry
+	mock := dbMock{
+		users:     map[string]model.User{"someone": user},
+		logins:    map[string]model.Login{"someone": l},
+		calendars: map[string]model.Calendar{c.GetID(): c},
+		sessions:  map[string]model.Session{"s": {ID: "s", User: "someone"}},
+		tokens: map[string]model.AccessToken{"tok": {ID: "tok", User: "someone", Hash: hashToken("secret"),
+			Scopes: []model.TokenScope{model.ScopeEdit}}},
+		trash: map[string]model.TrashEntry{},
+		audit: &entries,
+	}
+	db = mock
+
+	router := mux.NewRouter().StrictSlash(true)
+	registerRoutes(router)
+	readWithToken := func() int {
+		r := httptest.NewRequest("GET", "/me/calendars/someone/someone", nil)
+		r.Header.Set("Authorization", "Bearer tok.secret")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, r)
+		return rr.Code
+	}
+	if code := readWithToken(); code != http.StatusOK {
+		t.Fatalf("expected code %d with the token, got %d", http.StatusOK, code)
+	}
+
+	requestDeletion := func() {
+		r := httptest.NewRequest("DELETE", "/", nil)
+		r = r.WithContext(context.WithValue(r.Context(), userIDStr, "someone"))
+		rr := httptest.NewRecorder()
+		deleteUserHandler(rr, r)
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("expected code %d, got %d: %s", http.StatusSeeOther, rr.Code, rr.Body.String())
+		}
+	}
+
+	// the account is kept for the delay, logging in again keeps it
+	requestDeletion()
+	if d := mock.logins["someone"].Deletion; d == nil || d.Sub(time.Now()) > time.Hour {
+		t.Fatalf("expected the deletion to be scheduled in an hour, got %v", d)
+	}
+	if len(mock.sessions) != 0 || len(mails) != 1 || mails[0].to != "someone@example.com" {
+		t.Errorf("expected the sessions to be revoked and the user to be notified, got %v and %v", mock.sessions,
+			mails)
+	}
+	if code := readWithToken(); code != http.StatusUnauthorized {
+		t.Errorf("expected code %d with the token of the deleted account, got %d", http.StatusUnauthorized, code)
+	}
+	purge(time.Now())
+	if _, ok := mock.users["someone"]; !ok {
+		t.Fatal("expected the user to be kept within the delay")
+	}
+
+	if _, err := startLogin(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil),
+		mock.logins["someone"]); err != nil {
+		t.Fatal(err)
+	}
+	if d := mock.logins["someone"].Deletion; d != nil {
+		t.Errorf("expected the login to cancel the deletion, got %v", d)
+	}
+	purge(time.Now().Add(2 * time.Hour))
+	if _, ok := mock.users["someone"]; !ok {
+		t.Fatal("expected the user to be kept after cancelling the deletion")
+	}
+
+	// after the delay, the user is deleted for good
+	requestDeletion()
+	purge(time.Now().Add(2 * time.Hour))
+	if _, ok := mock.users["someone"]; ok {
+		t.Error("expected the user to be deleted after the delay")
+	}
+	if len(entries) != 1 || entries[0].Calendar != "someoneelse/project" ||
+		entries[0].Action != model.AuditDeleteUser {
+		t.Errorf("expected the deletion to be audited in the shared calendar, got %v", entries)
+	}
+}
@@ -3,6 +3,7 @@ package web
 import (
 	"fmt"
 	"net/url"
+	"strings"
 )
 
 type varXLS struct {
@@ -11,7 +12,23 @@ type varXLS struct {
 }
 
 func (v varXLS) String() string {
-	return fmt.Sprintf(`<xsl:variable name="%s" select="'%s'"/>`, v.name, v.value) + "\n"
+	return fmt.Sprintf(`<xsl:variable name="%s" select="%s"/>`, v.name, attrEscaper.Replace(xpathLiteral(v.value))) +
+		"\n"
+}
+
+// attrEscaper escapes the characters not allowed in double quoted XML attributes
+var attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// xpathLiteral quotes s as XPath string literal. XPath has no escapes, so strings with both kinds of quotes are
+// concatenated from parts.
+func xpathLiteral(s string) string {
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	return `concat('` + strings.ReplaceAll(s, "'", `', "'", '`) + `')`
 }
 
 func allFromURL(params url.Values) []varXLS {
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// wantsJSON returns whether the client asked for JSON instead of XML, either by the query parameter format=json or
+// by the Accept header.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// writeView writes v as JSON if the client wants it (see wantsJSON), else as XML. The XML references the given
+// stylesheet (path below the authed path) with the query params of the request, if xsl is not empty.
+func writeView(w http.ResponseWriter, r *http.Request, v interface{}, xsl string) {
+	if wantsJSON(r) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			log.Println(err)
+			writeError(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+		return
+	}
+
+	b, err := xml.Marshal(v)
+	if err != nil {
+		log.Println(err)
+		writeError(w, "", http.StatusInternalServerError)
+		return
+	}
+
+	xmlStr := string(b)
+	if xsl != "" {
+		xmlStr = addStylesheet(xmlStr, conf.AuthedPathName+xsl+"?"+r.URL.RawQuery)
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write([]byte(xmlStr))
+}
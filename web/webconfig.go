@@ -1,5 +1,7 @@
 package web
 
+import "time"
+
 // ServerConfig of the web web
 type ServerConfig struct {
 	// Here go the config fields that are relevant to the webserver
@@ -9,6 +11,112 @@ type ServerConfig struct {
 	FrontendDir string `yaml:"frontend_dir"`
 	// AuthedPathName - Path prefix of routes requiring authentication
 	AuthedPathName string `yaml:"authed_path_name"`
-	// JWTSecret contains the private, server-sided secret to sign JWTs
+	// JWTSecret contains the private, server-sided secret to sign JWTs, if no JWTKeys are configured. It has to be at
+	// least 32 bytes long
 	JWTSecret string `yaml:"jwt_secret"`
+	// JWTKeys is the key ring to sign and verify JWTs with. Rotate keys by adding a new one and retiring the old one
+	JWTKeys []KeyConfig `yaml:"jwt_keys"`
+	// InsecureCookies sends the cookies over plain http too. Only enable this for development without TLS
+	InsecureCookies bool `yaml:"insecure_cookies"`
+	// PublicURL is the URL the server is reachable at, used for links in mails, e.g. https://plannet.example.com
+	PublicURL string `yaml:"public_url"`
+	// SMTP server to send mails with, mails are only logged if not configured
+	SMTP SMTPConfig `yaml:"smtp"`
+	// ResetTokenDuration is how long password reset links are valid, e.g. 1h
+	ResetTokenDuration time.Duration `yaml:"reset_token_duration"`
+	// InvitationDuration is how long invitations to calendars wait to be accepted, e.g. 336h
+	InvitationDuration time.Duration `yaml:"invitation_duration"`
+	// RenameRedirectDuration is how long the former path of a renamed calendar redirects to the new one, e.g. 720h
+	RenameRedirectDuration time.Duration `yaml:"rename_redirect_duration"`
+	// AccountDeletionDelay is how long deleted accounts are kept before they are deleted for good, e.g. 336h. Logging
+	// in again within this time cancels the deletion
+	AccountDeletionDelay time.Duration `yaml:"account_deletion_delay"`
+	// LoginThrottle configures the throttling of failed logins and of registrations
+	LoginThrottle ThrottleConfig `yaml:"login_throttle"`
+	// BcryptCost is the cost new passwords are hashed with, 12 if not set
+	BcryptCost int `yaml:"bcrypt_cost"`
+	// Admins are the users allowed to review lockouts
+	Admins []string `yaml:"admins"`
+	// JWTKeyGracePeriod is how long retired keys still verify tokens after their retirement, e.g. 24h
+	JWTKeyGracePeriod time.Duration `yaml:"jwt_key_grace_period"`
+	// AccessTokenDuration is the lifetime of the JWT authenticating a request, e.g. 15m
+	AccessTokenDuration time.Duration `yaml:"access_token_duration"`
+	// RefreshTokenDuration is the lifetime of a session since the last refresh of its access token, e.g. 720h
+	RefreshTokenDuration time.Duration `yaml:"refresh_token_duration"`
+	// OIDC configures single sign-on with an OpenID Connect provider, disabled if not set
+	OIDC OIDCConfig `yaml:"oidc"`
+}
+
+// accessTokenDuration returns the configured AccessTokenDuration or the default, if none is configured
+func (c ServerConfig) accessTokenDuration() time.Duration {
+	if c.AccessTokenDuration > 0 {
+		return c.AccessTokenDuration
+	}
+	return defaultAccessTokenDuration
+}
+
+// refreshTokenDuration returns the configured RefreshTokenDuration or the default, if none is configured
+func (c ServerConfig) refreshTokenDuration() time.Duration {
+	if c.RefreshTokenDuration > 0 {
+		return c.RefreshTokenDuration
+	}
+	return defaultRefreshTokenDuration
+}
+
+// jwtKeyGracePeriod returns the configured JWTKeyGracePeriod or the default, if none is configured
+func (c ServerConfig) jwtKeyGracePeriod() time.Duration {
+	if c.JWTKeyGracePeriod > 0 {
+		return c.JWTKeyGracePeriod
+	}
+	return defaultKeyGracePeriod
+}
+
+// resetTokenDuration returns the configured ResetTokenDuration or the default, if none is configured
+func (c ServerConfig) resetTokenDuration() time.Duration {
+	if c.ResetTokenDuration > 0 {
+		return c.ResetTokenDuration
+	}
+	return defaultResetTokenDuration
+}
+
+// invitationDuration returns the configured InvitationDuration or the default, if none is configured
+func (c ServerConfig) invitationDuration() time.Duration {
+	if c.InvitationDuration > 0 {
+		return c.InvitationDuration
+	}
+	return defaultInvitationDuration
+}
+
+// renameRedirectDuration returns the configured RenameRedirectDuration or the default, if none is configured
+func (c ServerConfig) renameRedirectDuration() time.Duration {
+	if c.RenameRedirectDuration > 0 {
+		return c.RenameRedirectDuration
+	}
+	return defaultRedirectDuration
+}
+
+// accountDeletionDelay returns the configured AccountDeletionDelay or the default, if none is configured
+func (c ServerConfig) accountDeletionDelay() time.Duration {
+	if c.AccountDeletionDelay > 0 {
+		return c.AccountDeletionDelay
+	}
+	return defaultDeletionDelay
+}
+
+// bcryptCost returns the configured BcryptCost or the default, if none is configured
+func (c ServerConfig) bcryptCost() int {
+	if c.BcryptCost > 0 {
+		return c.BcryptCost
+	}
+	return defaultBcryptCost
+}
+
+// isAdmin returns whether the user is an admin
+func (c ServerConfig) isAdmin(userid string) bool {
+	for _, a := range c.Admins {
+		if a == userid {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,161 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//aliasStore holds the former IDs of renamed calendars. Each alias
+//is stored in its own file, placed like the file of the calendar
+//has been before the rename: <owner>/<former name>.xml
+type aliasStore struct {
+	mutex   sync.RWMutex
+	aliases map[string]model.CalendarAlias
+}
+
+//loadAliases parses all alias files in @dir, dropping the ones
+//whose grace period is over in the meantime.
+func loadAliases(dir string) (*aliasStore, error) {
+	var store = &aliasStore{aliases: make(map[string]model.CalendarAlias)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var alias model.CalendarAlias
+		if err := parse(file, &alias); err != nil {
+			return err
+		}
+		if alias.Expired(now) {
+			return os.Remove(file)
+		}
+		store.aliases[alias.From] = alias
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//RenameCalendar renames the calendar to a given @calID to @newName,
+//keeping its owner. The former ID redirects to the new one until
+//@redirectUntil.
+//If the calendar doesn't exist, an error is thrown, as well as if
+//the owner already has a calendar named @newName.
+func (db database) RenameCalendar(calID, newName string, redirectUntil time.Time) error {
+	var owner = strings.Split(calID, "/")[0]
+	return db.rekeyCalendar(calID, owner, newName, model.None, redirectUntil)
+}
+
+//GetCalendarAlias retrieves the alias of a given former @calID. If
+//there is none or its grace period is over, an error is thrown.
+func (db database) GetCalendarAlias(calID string) (model.CalendarAlias, error) {
+	db.aliases.mutex.RLock()
+	defer db.aliases.mutex.RUnlock()
+
+	var val, ok = db.aliases.aliases[calID]
+	if !ok || val.Expired(time.Now()) {
+		return model.CalendarAlias{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//rekeyAliases lets the aliases of @calID redirect to @newID, so
+//that renaming a calendar twice doesn't build chains, and drops
+//the alias of @newID, which is taken by the calendar now. If
+//@redirectUntil is set, @calID becomes an alias of @newID.
+func (db database) rekeyAliases(calID, newID string, redirectUntil time.Time, undo *undoLog) error {
+	db.aliases.mutex.Lock()
+	defer db.aliases.mutex.Unlock()
+
+	for from, a := range db.aliases.aliases {
+		if a.To != calID && from != newID {
+			continue
+		}
+
+		var original = a
+		var err error
+		if from == newID {
+			err = db.deleteAlias(from)
+		} else {
+			a.To = newID
+			err = db.setAlias(a)
+		}
+		if err != nil {
+			return err
+		}
+		undo.add(func() {
+			db.aliases.mutex.Lock()
+			db.setAlias(original)
+			db.aliases.mutex.Unlock()
+		})
+	}
+
+	if redirectUntil.IsZero() {
+		return nil
+	}
+
+	var original, existed = db.aliases.aliases[calID]
+	if err := db.setAlias(model.CalendarAlias{From: calID, To: newID, Expires: redirectUntil}); err != nil {
+		return err
+	}
+	undo.add(func() {
+		db.aliases.mutex.Lock()
+		if existed {
+			db.setAlias(original)
+		} else {
+			db.deleteAlias(calID)
+		}
+		db.aliases.mutex.Unlock()
+	})
+	return nil
+}
+
+//deleteAliases deletes all aliases @match returns true
+//for. The caller must hold the write lock.
+func (db database) deleteAliases(match func(model.CalendarAlias) bool) error {
+	for from, a := range db.aliases.aliases {
+		if !match(a) {
+			continue
+		}
+		if err := db.deleteAlias(from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setAlias writes the alias @a to disk and to the collection.
+//The caller must hold the write lock.
+func (db database) setAlias(a model.CalendarAlias) error {
+	var path = db.aliasPath(a.From)
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if err := write(path, a.String()); err != nil {
+		return err
+	}
+	db.aliases.aliases[a.From] = a
+	return nil
+}
+
+//deleteAlias removes the alias file of the former ID @from and
+//the collection entry. The caller must hold the write lock.
+func (db database) deleteAlias(from string) error {
+	delete(db.aliases.aliases, from)
+	if err := os.Remove(db.aliasPath(from)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//aliasPath returns the path of the alias file of the former ID @from.
+func (db database) aliasPath(from string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.AliasDir, from)
+}
@@ -0,0 +1,244 @@
+package xmldb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//auditStore guards the audit logs of the calendars. Each calendar
+//has its own log file, placed like the calendar file itself:
+//<owner>/<name>.xml. Entries are only ever appended to it, one XML
+//element each, so that logs are neither kept in memory nor
+//rewritten, however long they grow.
+//Once a calendar is deleted for good, its log is kept for the owner
+//as <owner>/.deleted/<name>.<time of deletion>.xml. Calendar names
+//never start with a dot, so it doesn't clash with calendar IDs.
+type auditStore struct {
+	mutex sync.Mutex
+}
+
+//AddAuditEntry appends the entry @e to the audit log of its
+//calendar. If the calendar doesn't exist, an error is thrown.
+func (db database) AddAuditEntry(e model.AuditEntry) error {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	if _, ok := db.mutexes[e.Calendar]; !ok {
+		return model.ErrNotFound
+	}
+
+	return db.appendAuditEntry(db.auditPath(e.Calendar), e)
+}
+
+//addAuditEntry appends the entry @e like AddAuditEntry does, as
+//part of an operation which is rolled back with @undo. Rolling back
+//drops the entry again.
+func (db database) addAuditEntry(e model.AuditEntry, undo *undoLog) error {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	var path = db.auditPath(e.Calendar)
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := db.appendAuditEntry(path, e); err != nil {
+		return err
+	}
+	undo.add(func() {
+		db.audits.mutex.Lock()
+		if size == 0 {
+			os.Remove(path)
+		} else {
+			os.Truncate(path, size)
+		}
+		db.audits.mutex.Unlock()
+	})
+	return nil
+}
+
+//appendAuditEntry appends the entry @e to the audit log at @path.
+//The caller must hold the lock.
+func (db database) appendAuditEntry(path string, e model.AuditEntry) error {
+	if err := ensureDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return appendLine(path, e.String())
+}
+
+//GetAuditLog retrieves the entries of the audit log of @calID which
+//pass the query @q, the most recent first. If the calendar doesn't
+//exist, an error is thrown.
+func (db database) GetAuditLog(calID string, q model.AuditQuery) ([]model.AuditEntry, error) {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	if _, ok := db.mutexes[calID]; !ok {
+		return nil, model.ErrNotFound
+	}
+
+	return db.readAuditLog(db.auditPath(calID), q)
+}
+
+//GetDeletedAuditLogs retrieves the audit logs of the calendars of
+//@userID which have been deleted for good, the most recently
+//deleted first.
+func (db database) GetDeletedAuditLogs(userID string) ([]model.DeletedAuditLog, error) {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	var files, err = os.ReadDir(db.deletedAuditDir(userID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var res []model.DeletedAuditLog
+	for _, file := range files {
+		var id = strings.TrimSuffix(file.Name(), ".xml")
+		var index = strings.LastIndex(id, ".")
+		if index < 0 {
+			continue
+		}
+		var nanos, err = strconv.ParseInt(id[index+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		res = append(res, model.DeletedAuditLog{
+			ID:       id,
+			Calendar: fmt.Sprintf("%s/%s", userID, id[:index]),
+			Deleted:  time.Unix(0, nanos),
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Deleted.After(res[j].Deleted) })
+	return res, nil
+}
+
+//GetDeletedAuditLog retrieves the entries of the deleted audit log
+//@logID of @userID which pass the query @q, the most recent first.
+//If the log doesn't exist, an error is thrown.
+func (db database) GetDeletedAuditLog(userID, logID string, q model.AuditQuery) ([]model.AuditEntry, error) {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	if logID == "" || strings.ContainsAny(logID, "/\\") || strings.HasPrefix(logID, ".") {
+		return nil, model.ErrNotFound
+	}
+	var path = fmt.Sprintf("%s/%s.xml", db.deletedAuditDir(userID), logID)
+	if !exists(path) {
+		return nil, model.ErrNotFound
+	}
+	return db.readAuditLog(path, q)
+}
+
+//readAuditLog parses the entries of the audit log at @path which
+//pass the query @q, the most recent first. The caller must hold the
+//lock.
+func (db database) readAuditLog(path string, q model.AuditQuery) ([]model.AuditEntry, error) {
+	var file, err = os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var res []model.AuditEntry
+	var decoder = xml.NewDecoder(file)
+	for {
+		var e model.AuditEntry
+		if err := decoder.Decode(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if q.Matches(e) {
+			res = append(res, e)
+		}
+	}
+
+	for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+		res[i], res[j] = res[j], res[i]
+	}
+	return res, nil
+}
+
+//rekeyAuditLog moves the audit log of @calID to @newID, so that
+//the history of a calendar survives its rename or transfer.
+func (db database) rekeyAuditLog(calID, newID string, undo *undoLog) error {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	var path, newPath = db.auditPath(calID), db.auditPath(newID)
+	if !exists(path) {
+		return nil
+	}
+	if err := ensureDir(filepath.Dir(newPath)); err != nil {
+		return err
+	}
+	if err := os.Rename(path, newPath); err != nil {
+		return err
+	}
+	undo.add(func() {
+		db.audits.mutex.Lock()
+		os.Rename(newPath, path)
+		db.audits.mutex.Unlock()
+	})
+	return nil
+}
+
+//archiveAuditLog keeps the audit log of @calID, which is found
+//under @id, e.g. the ID of the calendar in the trash, as a deleted
+//log of its owner, once the calendar has been deleted for good at
+//@deleted. So it is kept, but doesn't pass on to a calendar of the
+//same name created later on.
+func (db database) archiveAuditLog(calID, id string, deleted time.Time) error {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	var path = db.auditPath(id)
+	if !exists(path) {
+		return nil
+	}
+
+	var parts = strings.SplitN(calID, "/", 2)
+	var dir = db.deletedAuditDir(parts[0])
+	if err := ensureDir(dir); err != nil {
+		return err
+	}
+	return os.Rename(path, fmt.Sprintf("%s/%s.%d.xml", dir, parts[len(parts)-1], deleted.UnixNano()))
+}
+
+//deleteAuditLogs removes all audit logs of the calendars of
+//@userID, including the deleted ones, along with the user.
+func (db database) deleteAuditLogs(userID string) error {
+	db.audits.mutex.Lock()
+	defer db.audits.mutex.Unlock()
+
+	return os.RemoveAll(fmt.Sprintf("%s/%s", db.config.AuditDir, userID))
+}
+
+//auditPath returns the path of the audit log of @calID.
+func (db database) auditPath(calID string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.AuditDir, calID)
+}
+
+//deletedAuditDir returns the directory of the deleted audit logs
+//of @userID.
+func (db database) deletedAuditDir(userID string) string {
+	return fmt.Sprintf("%s/%s/.deleted", db.config.AuditDir, userID)
+}
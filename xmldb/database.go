@@ -6,8 +6,10 @@ import (
 	"github.com/Project-Planner/backend/model"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 //The struct implementing the web.Database interface
@@ -15,8 +17,24 @@ type database struct {
 	config    DBConfig
 	mutexes   map[string]*sync.Mutex
 	logins    map[string]model.Login
+	//loginLock guards the logins collection itself, the
+	//logins are locked by the mutexes of their users
+	loginLock *sync.RWMutex
 	users     map[string]model.User
 	calendars map[string]model.Calendar
+	indexes   map[string]dateIndex
+	search    *searchIndex
+	sessions  *sessionStore
+	resets    *resetStore
+	tokens    *tokenStore
+	groups    *groupStore
+	invites   *invitationStore
+	links     *linkStore
+	aliases   *aliasStore
+	audits    *auditStore
+	revisions *revisionStore
+	trash     *trashStore
+	lockouts  *lockoutLog
 }
 
 //New configures and parses a new database struct.
@@ -31,6 +49,16 @@ func New(config DBConfig) (database, error) {
 	config.AuthRelDir = "/auth"
 	config.UserRelDir = "/users"
 	config.CalendarRelDir = "/calendars"
+	config.SessionRelDir = "/sessions"
+	config.ResetRelDir = "/resets"
+	config.TokenRelDir = "/tokens"
+	config.GroupRelDir = "/groups"
+	config.InvitationRelDir = "/invitations"
+	config.LinkRelDir = "/links"
+	config.AliasRelDir = "/aliases"
+	config.AuditRelDir = "/audit"
+	config.RevisionRelDir = "/revisions"
+	config.TrashRelDir = "/trash"
 
 	//1. Step: Expanding configuration file (e.g. constructing absolute paths
 	//		   from relative paths)
@@ -39,9 +67,30 @@ func New(config DBConfig) (database, error) {
 	config.AuthDir = fmt.Sprintf("%s%s", config.DBDir, config.AuthRelDir)
 	config.UserDir = fmt.Sprintf("%s%s", config.DBDir, config.UserRelDir)
 	config.CalendarDir = fmt.Sprintf("%s%s", config.DBDir, config.CalendarRelDir)
+	config.SessionDir = fmt.Sprintf("%s%s", config.DBDir, config.SessionRelDir)
+	config.ResetDir = fmt.Sprintf("%s%s", config.DBDir, config.ResetRelDir)
+	config.TokenDir = fmt.Sprintf("%s%s", config.DBDir, config.TokenRelDir)
+	config.GroupDir = fmt.Sprintf("%s%s", config.DBDir, config.GroupRelDir)
+	config.InvitationDir = fmt.Sprintf("%s%s", config.DBDir, config.InvitationRelDir)
+	config.LinkDir = fmt.Sprintf("%s%s", config.DBDir, config.LinkRelDir)
+	config.AliasDir = fmt.Sprintf("%s%s", config.DBDir, config.AliasRelDir)
+	config.AuditDir = fmt.Sprintf("%s%s", config.DBDir, config.AuditRelDir)
+	config.RevisionDir = fmt.Sprintf("%s%s", config.DBDir, config.RevisionRelDir)
+	config.TrashDir = fmt.Sprintf("%s%s", config.DBDir, config.TrashRelDir)
+	if config.RevisionRetention == 0 {
+		config.RevisionRetention = defaultRevisionRetention
+	}
+	if config.RevisionLimit == 0 {
+		config.RevisionLimit = defaultRevisionLimit
+	}
+	if config.TrashRetention == 0 {
+		config.TrashRetention = defaultTrashRetention
+	}
 
-	//2. Step: Ensure that parent folders (auth, user, calendars) exist.
-	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	//2. Step: Ensure that parent folders (auth, user, calendars, sessions,
+	//		   resets, tokens, groups, invitations, links,
+	//		   aliases, audit, revisions, trash) exist.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
 	if err := ensureDir(config.DBDir); err != nil {
 		return database{}, err
 	}
@@ -58,6 +107,46 @@ func New(config DBConfig) (database, error) {
 		return database{}, err
 	}
 
+	if err := ensureDir(config.SessionDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.ResetDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.TokenDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.GroupDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.InvitationDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.LinkDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.AliasDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.AuditDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.RevisionDir); err != nil {
+		return database{}, err
+	}
+
+	if err := ensureDir(config.TrashDir); err != nil {
+		return database{}, err
+	}
+
 	//3. Step: Parse source files into corresponding structures or collections.
 	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
 	// Authentication: Each user has his own authentication file containing his
@@ -103,6 +192,8 @@ func New(config DBConfig) (database, error) {
 	// Calendars: Each calendar has an owner. Hence, it is placed into a folder
 	//			  named after its owner, along with other calendars.
 	calendars := make(map[string]model.Calendar)
+	indexes := make(map[string]dateIndex)
+	search := newSearchIndex()
 	if err := filepath.Walk(config.CalendarDir, func(folder string, folderInfo os.FileInfo, err error) error {
 		if folderInfo.IsDir() && folder != config.CalendarDir {
 			if err := filepath.Walk(folder, func(file string, fileInfo os.FileInfo, err error) error {
@@ -119,6 +210,8 @@ func New(config DBConfig) (database, error) {
 						var key = fmt.Sprintf("%s/%s", calendar.Owner.Val, name[:index])
 						var lock sync.Mutex
 						calendars[key] = calendar
+						indexes[key] = newDateIndex(calendar)
+						search.index(key, calendar)
 						mutexes[key] = &lock
 					}
 				}
@@ -134,7 +227,82 @@ func New(config DBConfig) (database, error) {
 		return database{}, err
 	}
 
-	return database{config, mutexes, logins, users, calendars}, nil
+	// Sessions: Each issued token has its own session file named after
+	//			 the token ID. Expired sessions are dropped.
+	sessions, err := loadSessions(config.SessionDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Reset tokens: Each password reset token has its own file named
+	//				 after its hash. Expired tokens are dropped.
+	resets, err := loadResetTokens(config.ResetDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Access tokens: Each personal access token has its own file named
+	//				  after its ID. Expired tokens are dropped.
+	tokens, err := loadAccessTokens(config.TokenDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Groups: Each group has its own file named after its ID.
+	groups, err := loadGroups(config.GroupDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Invitations: Each invitation has its own file named after its
+	//				ID. Expired invitations are dropped.
+	invites, err := loadInvitations(config.InvitationDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Share links: Each share link has its own file named after its
+	//				ID. Expired links are dropped.
+	links, err := loadShareLinks(config.LinkDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Aliases: Each former ID of a renamed calendar has its own file,
+	//			placed like the calendar file has been. Expired aliases
+	//			are dropped.
+	aliases, err := loadAliases(config.AliasDir)
+	if err != nil {
+		return database{}, err
+	}
+
+	// Lockouts: All lockouts are recorded in a single file.
+	lockouts, err := loadLockouts(fmt.Sprintf("%s/lockouts.xml", config.DBDir))
+	if err != nil {
+		return database{}, err
+	}
+
+	return database{
+		config:    config,
+		mutexes:   mutexes,
+		logins:    logins,
+		loginLock: &sync.RWMutex{},
+		users:     users,
+		calendars: calendars,
+		indexes:   indexes,
+		search:    search,
+		sessions:  sessions,
+		resets:    resets,
+		tokens:    tokens,
+		groups:    groups,
+		invites:   invites,
+		links:     links,
+		aliases:   aliases,
+		audits:    &auditStore{},
+		revisions: &revisionStore{parsed: make(map[string][]model.Revision)},
+		trash:     &trashStore{},
+		lockouts:  lockouts,
+	}, nil
 }
 
 //GetUser retrieves the user to a given @userID.
@@ -168,6 +336,26 @@ func (db database) SetUser(userID string, user model.User) error {
 	return db.setUser(userID, user)
 }
 
+//SetProfile overwrites the profile of the user with
+//the given @userID under the lock of the user, so that
+//concurrent changes of its calendars are not lost.
+func (db database) SetProfile(userID string, p model.Profile) error {
+	var mutex, ok = db.mutexes[userID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var user, err = db.GetUser(userID)
+	if err != nil {
+		return err
+	}
+	user.Profile = p
+	return db.setUser(userID, user)
+}
+
 //setUser writes the given user data for the user
 //with the given @userID. This function overwrites any
 //existing user file or creates a new one; on the disk
@@ -225,7 +413,9 @@ func (db database) AddUser(userID, hash string) error {
 	if err := write(path, login.String()); err != nil {
 		return err
 	}
+	db.loginLock.Lock()
 	db.logins[userID] = login
+	db.loginLock.Unlock()
 
 	//5. Step: Make user file itself and
 	//		   register resource in collection.
@@ -249,6 +439,9 @@ func (db database) AddUser(userID, hash string) error {
 //calendars. Since calendars can be referenced by multiple other users,
 //these must be found in order to remove their references to the calendars
 //to be deleted.
+//Every step can be repeated, and the login is deleted last. If a step
+//fails, the user is thus still found and deleting it again finishes
+//the deletion.
 func (db database) DeleteUser(userID string) error {
 	//1. Step: Check whether user actually exists,
 	//		   before deleting the resource and
@@ -260,28 +453,74 @@ func (db database) DeleteUser(userID string) error {
 	}
 	mutex.Lock()
 	defer mutex.Unlock()
-	defer delete(db.mutexes, userID)
 
-	//3. Step: Delete authentication file from disk and
-	//         from the authentication collection.
+	//2. Step: Revoke all sessions, reset tokens, access
+	//         tokens, invitations, share links and the
+	//         trash of the user.
 	//――――――――――――――――――――――――――――――――――――――――――――――――――
-	delete(db.logins, userID)
-	var path = fmt.Sprintf("%s/%s.xml", db.config.AuthDir, userID)
-	if err := os.Remove(path); err != nil {
+	if err := db.DeleteSessions(userID, ""); err != nil {
+		return err
+	}
+
+	db.resets.mutex.Lock()
+	var err = db.deleteResetTokens(userID)
+	db.resets.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.tokens.mutex.Lock()
+	err = db.deleteAccessTokens(userID)
+	db.tokens.mutex.Unlock()
+	if err != nil {
 		return err
 	}
 
-	//4. Step: Delete the user's calendars and remove
+	db.invites.mutex.Lock()
+	err = db.deleteInvitations(func(i model.Invitation) bool {
+		return i.From == userID || i.To == userID
+	})
+	db.invites.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.links.mutex.Lock()
+	err = db.deleteShareLinks(func(l model.ShareLink) bool {
+		return l.Owner == userID
+	})
+	db.links.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.trash.mutex.Lock()
+	err = db.deleteTrash(userID)
+	db.trash.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.groups.mutex.Lock()
+	deleted, err := db.leaveGroups(userID)
+	db.groups.mutex.Unlock()
+	for _, group := range deleted {
+		if err == nil {
+			err = db.revokeGroup(group)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	//3. Step: Delete the user's calendars and remove
 	//		   their references in the other users' files.
 	//――――――――――――――――――――――――――――――――――――――――――――――――――――――――――――
 	// The user to be deleted has some calendars referenced.
 	// These are checked whether the user is their owner before
-	// also deleting them.
-	owner, ok := db.users[userID]
-	if !ok {
-		return model.ErrNotFound
-	}
-
+	// also deleting them. The user file is already gone, if a
+	// previous deletion failed at deleting the login.
+	var owner = db.users[userID]
 	for _, reference := range owner.Items.Calendars {
 		var calID = reference.Link
 		var cal, ok = db.calendars[calID]
@@ -293,27 +532,45 @@ func (db database) DeleteUser(userID string) error {
 		//from referenced users.
 		var calLock = db.mutexes[calID]
 		calLock.Lock()
-		if err := db.deleteCalendar(calID); err != nil {
+		err = db.deleteCalendar(calID)
+		calLock.Unlock()
+		if err != nil {
 			return err
 		}
-		calLock.Unlock()
 	}
 
-	//5. Step: Delete calendars folder of user to be deleted.
+	//4. Step: Delete calendars folder and audit logs of
+	//		   user to be deleted.
 	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――――
-	path = fmt.Sprintf("%s/%s", db.config.CalendarDir, userID)
+	var path = fmt.Sprintf("%s/%s", db.config.CalendarDir, userID)
 	if err := os.RemoveAll(path); err != nil {
 		return err
 	}
 
-	//2. Step: Delete user file itself from disk and
+	if err := db.deleteAuditLogs(userID); err != nil {
+		return err
+	}
+
+	//5. Step: Delete user file itself from disk and
 	//		   from the user collection.
 	//――――――――――――――――――――――――――――――――――――――――――――――――
-	delete(db.users, userID)
 	path = fmt.Sprintf("%s/%s.xml", db.config.UserDir, userID)
-	if err := os.Remove(path); err != nil {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	delete(db.users, userID)
+
+	//6. Step: Delete authentication file from disk and
+	//         from the authentication collection.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	path = fmt.Sprintf("%s/%s.xml", db.config.AuthDir, userID)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db.loginLock.Lock()
+	delete(db.logins, userID)
+	db.loginLock.Unlock()
+	delete(db.mutexes, userID)
 
 	return nil
 
@@ -322,6 +579,8 @@ func (db database) DeleteUser(userID string) error {
 //GetLogin retrieves the login to a given @userID.
 //If the user doesn't exist, an error is thrown.
 func (db database) GetLogin(userID string) (model.Login, error) {
+	db.loginLock.RLock()
+	defer db.loginLock.RUnlock()
 	var val, ok = db.logins[userID]
 	if !ok {
 		return model.Login{}, model.ErrNotFound
@@ -329,6 +588,63 @@ func (db database) GetLogin(userID string) (model.Login, error) {
 	return val, nil
 }
 
+//SetLogin overwrites the login of the user with the given @userID,
+//on disk as well as in the collection, if the user yet exists.
+func (db database) SetLogin(userID string, login model.Login) error {
+	return db.UpdateLogin(userID, func(l *model.Login) error {
+		*l = login
+		return nil
+	})
+}
+
+//UpdateLogin changes the login of the user with the given @userID
+//by calling @update while the user is locked, so that concurrent
+//updates of the login are not lost. The login is only stored if
+//@update succeeds.
+func (db database) UpdateLogin(userID string, update func(l *model.Login) error) error {
+	//Obtain mutex and lock resource
+	var mutex, ok = db.mutexes[userID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	//Update only if the login yet exists
+	var login, err = db.GetLogin(userID)
+	if err != nil {
+		return err
+	}
+	if err := update(&login); err != nil {
+		return err
+	}
+
+	var path = fmt.Sprintf("%s/%s.xml", db.config.AuthDir, userID)
+	if err := write(path, login.String()); err != nil {
+		return err
+	}
+	db.loginLock.Lock()
+	db.logins[userID] = login
+	db.loginLock.Unlock()
+	return nil
+}
+
+//GetScheduledDeletions retrieves the IDs of the users whose
+//accounts are due to be deleted until @t.
+func (db database) GetScheduledDeletions(t time.Time) ([]string, error) {
+	db.loginLock.RLock()
+	defer db.loginLock.RUnlock()
+	var res []string
+	for userID, login := range db.logins {
+		if login.Deletion != nil && !login.Deletion.After(t) {
+			res = append(res, userID)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
 //GetCalendar retrieves the calendar to a given @calID.
 //If the calendar doesn't exist, an error is thrown.
 //Note: IDs of calendars are made of several parts.
@@ -342,6 +658,58 @@ func (db database) GetCalendar(calID string) (model.Calendar, error) {
 	return val, nil
 }
 
+//QueryCalendar retrieves the calendar to a given @calID, but only with
+//the items passing the query @q, sorted by their end date. The date
+//index of the calendar is used to find items within the queried window,
+//so only these have to be looked at.
+//If the calendar doesn't exist, an error is thrown.
+func (db database) QueryCalendar(calID string, q model.CalendarQuery) (model.Calendar, error) {
+	var cal, ok = db.calendars[calID]
+	if !ok {
+		return model.Calendar{}, model.ErrNotFound
+	}
+
+	//The result shares all fields but the items with
+	//the stored calendar.
+	var res = cal
+	res.Items.Appointments.Appointment = nil
+	res.Items.Milestones.Milestone = nil
+	res.Items.Tasks.Task = nil
+
+	for _, e := range db.indexes[calID].window(q) {
+		if !q.HasKind(e.kind) {
+			continue
+		}
+
+		switch e.kind {
+		case model.KindAppointment:
+			var a = cal.Items.Appointments.Appointment[e.idx]
+			if q.Matches(a.Name.Val, a.Desc, a.Assignee, a.Labels) {
+				res.Items.Appointments.Appointment = append(res.Items.Appointments.Appointment, a)
+			}
+		case model.KindMilestone:
+			var m = cal.Items.Milestones.Milestone[e.idx]
+			if q.Matches(m.Name.Val, m.Desc, m.Assignee, m.Labels) {
+				res.Items.Milestones.Milestone = append(res.Items.Milestones.Milestone, m)
+			}
+		case model.KindTask:
+			var t = cal.Items.Tasks.Task[e.idx]
+			if q.Matches(t.Name.Val, t.Desc, t.Assignee, t.Labels) {
+				res.Items.Tasks.Task = append(res.Items.Tasks.Task, t)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+//Search looks up the calendars and items matching the query @q in
+//the full-text index. Only the calendars listed in the query are
+//searched, so the caller is responsible for checking permissions.
+func (db database) Search(q model.SearchQuery) (model.SearchResults, error) {
+	return db.search.search(q), nil
+}
+
 //AddCalendar is the synchronized version of addCalendar
 //used for concurrent modification.
 func (db database) AddCalendar(ownerID, calName string) error {
@@ -425,11 +793,20 @@ func (db database) SetCalendar(calID string, cal model.Calendar) error {
 
 //setCalendar sets the given calendar to the given @calID.
 //This overrides any existing calendar or creates a new one,
-//on the disk as well as in the collection.
+//on the disk as well as in the collection. The version it
+//overrides is kept as a revision, unless nothing changed.
 func (db database) setCalendar(calID string, cal model.Calendar) error {
+	if old, ok := db.calendars[calID]; ok && old.String() != cal.String() {
+		if err := db.addRevision(calID, old); err != nil {
+			return err
+		}
+	}
+
 	var path = fmt.Sprintf("%s/%s.xml", db.config.CalendarDir, calID)
 	var err = write(path, cal.String())
 	db.calendars[calID] = cal
+	db.indexes[calID] = newDateIndex(cal)
+	db.search.index(calID, cal)
 	return err
 }
 
@@ -487,10 +864,11 @@ func (db database) deleteCalendar(calID string) error {
 			//calling following unsafe functions
 			var userMutex = db.mutexes[userID.Val]
 			userMutex.Lock()
-			if err := db.disassociateCalendar(user, cal); err != nil {
+			var err = db.disassociateCalendar(user, cal)
+			userMutex.Unlock()
+			if err != nil {
 				return err
 			}
-			userMutex.Unlock()
 		}
 	}
 
@@ -503,7 +881,48 @@ func (db database) deleteCalendar(calID string) error {
 		return err
 	}
 
+	if err := db.unshareFromGroups(cal, calID); err != nil {
+		return err
+	}
+
+	db.invites.mutex.Lock()
+	var err = db.deleteInvitations(func(i model.Invitation) bool {
+		return i.Calendar == calID
+	})
+	db.invites.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.links.mutex.Lock()
+	err = db.deleteShareLinks(func(l model.ShareLink) bool {
+		return l.Calendar == calID
+	})
+	db.links.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	db.aliases.mutex.Lock()
+	err = db.deleteAliases(func(a model.CalendarAlias) bool {
+		return a.To == calID
+	})
+	db.aliases.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := db.archiveAuditLog(calID, calID, time.Now()); err != nil {
+		return err
+	}
+
+	if err := db.deleteRevisions(calID); err != nil {
+		return err
+	}
+
 	delete(db.calendars, calID)
+	delete(db.indexes, calID)
+	db.search.delete(calID)
 	delete(db.mutexes, calID)
 
 	return nil
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"github.com/Project-Planner/backend/model"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 //DONE
@@ -522,6 +525,24 @@ func TestDeleteUser(t *testing.T) {
 			t.Fatal(fmt.Sprintf("Reference to calendar '%s' can still be found at user '%s'.", calID, userID2))
 		}
 	}
+
+	//5. Step: A deletion having failed after deleting the user
+	//		   file is finished by deleting the user again.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	delete(db.users, userID2)
+	if err := os.Remove(fmt.Sprintf("%s/%s.xml", db.config.UserDir, userID2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteUser(userID2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetLogin(userID2); err != model.ErrNotFound {
+		t.Fatal(fmt.Sprintf("Login of user '%s' still exists.", userID2))
+	}
+	path = fmt.Sprintf("%s/%s.xml", db.config.AuthDir, userID2)
+	if _, err := os.Stat(path); err == nil {
+		t.Fatal(fmt.Sprintf("Authentication file for user '%s' still exists.", userID2))
+	}
 }
 
 //DONE
@@ -587,6 +608,1661 @@ func TestDeleteCalendar(t *testing.T) {
 	}
 }
 
+//DONE
+func TestQueryCalendar(t *testing.T) {
+	//1. Step: Construct a database.
+	//――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	//2. Step: Add user and fill his initial
+	//		   calendar with items.
+	//―――――――――――――――――――――――――――――――――――――
+	var userID = "a"
+	var hash = "hash"
+	if err := db.AddUser(userID, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID = fmt.Sprintf("%s/%s", userID, userID)
+	var cal, _ = db.GetCalendar(calID)
+	cal.Items.Appointments.Appointment = []model.Appointment{
+		{ID: "june", StartDate: model.Attribute{Val: "01.06.2021"}, EndDate: model.Attribute{Val: "02.06.2021"}},
+		{ID: "july", StartDate: model.Attribute{Val: "01.07.2021"}, EndDate: model.Attribute{Val: "01.07.2021"},
+			Labels: model.NewLabels("urgent")},
+	}
+	cal.Items.Milestones.Milestone = []model.Milestone{
+		{ID: "release", Duedate: model.Attribute{Val: "15.06.2021"}},
+		{ID: "undated"},
+	}
+	cal.Items.Tasks.Task = []model.Task{
+		{ID: "long", StartDate: model.Attribute{Val: "01.05.2021"}, Duedate: model.Attribute{Val: "01.08.2021"},
+			Labels: model.NewLabels("urgent")},
+	}
+	if err := db.SetCalendar(calID, cal); err != nil {
+		t.Fatal(err)
+	}
+
+	//3. Step: Query the calendar and compare the
+	//		   IDs of the returned items.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var june = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	var tt = []struct {
+		q    model.CalendarQuery
+		want string
+	}{
+		{q: model.CalendarQuery{}, want: "june july undated release long"},
+		{q: model.CalendarQuery{From: june, To: june.AddDate(0, 0, 29)}, want: "june release long"},
+		{q: model.CalendarQuery{From: june.AddDate(0, 1, 0)}, want: "july long"},
+		{q: model.CalendarQuery{To: june}, want: "june long"},
+		{q: model.CalendarQuery{Kinds: []model.ItemKind{model.KindMilestone}}, want: "undated release"},
+		{q: model.CalendarQuery{From: june, Labels: []string{"urgent"}}, want: "july long"},
+	}
+
+	for _, tc := range tt {
+		var res, err = db.QueryCalendar(calID, tc.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for _, a := range res.Items.Appointments.Appointment {
+			ids = append(ids, a.ID)
+		}
+		for _, m := range res.Items.Milestones.Milestone {
+			ids = append(ids, m.ID)
+		}
+		for _, t := range res.Items.Tasks.Task {
+			ids = append(ids, t.ID)
+		}
+
+		if got := strings.Join(ids, " "); got != tc.want {
+			t.Errorf("Query %+v returned '%s', want '%s'.", tc.q, got, tc.want)
+		}
+	}
+
+	//4. Step: Check that the stored calendar
+	//		   has not been modified.
+	//――――――――――――――――――――――――――――――――――――――――
+	cal, _ = db.GetCalendar(calID)
+	if len(cal.Items.Appointments.Appointment) != 2 || len(cal.Items.Milestones.Milestone) != 2 {
+		t.Fatal(fmt.Sprintf("Calendar with id '%s' has been modified by querying it.", calID))
+	}
+
+	if _, err := db.QueryCalendar(fmt.Sprintf("%s/test", userID), model.CalendarQuery{}); err != model.ErrNotFound {
+		t.Fatal("No error thrown for querying a non-existent calendar.")
+	}
+}
+
+//DONE
+func TestSearch(t *testing.T) {
+	//1. Step: Construct a database.
+	//――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	//2. Step: Add two users with items in
+	//		   their initial calendars.
+	//―――――――――――――――――――――――――――――――――――――
+	var userID1 = "a"
+	var userID2 = "b"
+	var hash = "hash"
+	if err := db.AddUser(userID1, hash); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddUser(userID2, hash); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID1 = fmt.Sprintf("%s/%s", userID1, userID1)
+	var cal, _ = db.GetCalendar(calID1)
+	cal.Desc = "Contracts and more"
+	cal.Items.Tasks.Task = []model.Task{
+		{ID: "vendor", Name: model.Attribute{Val: "Vendor contract"}, Desc: "Negotiate the contract with ACME"},
+		{ID: "other", Name: model.Attribute{Val: "Lunch"}, Desc: "with the vendor"},
+	}
+	cal.Items.Milestones.Milestone = []model.Milestone{
+		{ID: "signed", Name: model.Attribute{Val: "Contract signed"}, Desc: " "},
+	}
+	if err := db.SetCalendar(calID1, cal); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID2 = fmt.Sprintf("%s/%s", userID2, userID2)
+	cal, _ = db.GetCalendar(calID2)
+	cal.Items.Tasks.Task = []model.Task{
+		{ID: "secret", Name: model.Attribute{Val: "Vendor contract"}, Desc: "of user b"},
+	}
+	if err := db.SetCalendar(calID2, cal); err != nil {
+		t.Fatal(err)
+	}
+
+	//3. Step: Search, also in a database parsed
+	//		   from disk, which has to rebuild the index.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tt = []struct {
+		q      model.SearchQuery
+		want   string
+		facets int
+	}{
+		{q: model.SearchQuery{Terms: []string{"contr"}, Calendars: []string{calID1}}, want: "vendor signed ", facets: 3},
+		{q: model.SearchQuery{Terms: []string{"vendor", "contract"}, Calendars: []string{calID1}}, want: "vendor", facets: 1},
+		{q: model.SearchQuery{Terms: []string{"vendor"}, Calendars: []string{calID1, calID2}}, want: "vendor secret other", facets: 1},
+		{q: model.SearchQuery{Terms: []string{"contract"}, Calendars: []string{calID1},
+			Kinds: []model.ItemKind{model.KindMilestone}}, want: "signed", facets: 3},
+		{q: model.SearchQuery{Terms: []string{"vendor"}}, want: ""},
+		{q: model.SearchQuery{Terms: []string{"nothing"}, Calendars: []string{calID1}}, want: ""},
+	}
+
+	for _, database := range []database{db, reloaded} {
+		for _, tc := range tt {
+			var res, err = database.Search(tc.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []string
+			for _, r := range res.Results {
+				ids = append(ids, r.ID)
+			}
+			if got := strings.Join(ids, " "); got != tc.want || len(res.Facets) != tc.facets {
+				t.Errorf("Search for %v returned '%s' with %d facets, want '%s' with %d.",
+					tc.q.Terms, got, len(res.Facets), tc.want, tc.facets)
+			}
+		}
+	}
+
+	//4. Step: Delete the calendar and check that
+	//		   it has been removed from the index.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteUser(userID1); err != nil {
+		t.Fatal(err)
+	}
+	if res, _ := db.Search(tt[0].q); res.Total != 0 {
+		t.Fatal(fmt.Sprintf("Calendar with id '%s' can still be found after deletion.", calID1))
+	}
+}
+
+//DONE
+func TestSessions(t *testing.T) {
+	//1. Step: Construct a database with a user
+	//		   and some sessions of it.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "f5932068"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var now = time.Now().Round(time.Second)
+	var sessions = []model.Session{
+		{ID: "s1", User: userID, Created: now.Add(-time.Hour), Expires: now.Add(time.Hour), Device: "firefox", IP: "::1"},
+		{ID: "s2", User: userID, Created: now, Expires: now.Add(time.Hour), Device: "curl", IP: "127.0.0.1"},
+		{ID: "s3", User: userID, Created: now, Expires: now.Add(-time.Minute)},
+		{ID: "s4", User: "other", Created: now, Expires: now.Add(time.Hour)},
+	}
+	for _, s := range sessions {
+		if err := db.AddSession(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddSession(sessions[0]); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Session with id '%s' could be added twice.", sessions[0].ID))
+	}
+
+	sessions[0].RefreshHash = "rotated"
+	if err := db.SetSession(sessions[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetSession(model.Session{ID: "unknown"}); err != model.ErrNotFound {
+		t.Fatal("Session with id 'unknown' could be set without being added.")
+	}
+
+	//2. Step: Check that the sessions can be retrieved,
+	//		   also after reloading the database, but
+	//		   expired ones can not.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		if s, err := database.GetSession("s1"); err != nil || s.Device != "firefox" || s.RefreshHash != "rotated" || !s.Expires.Equal(now.Add(time.Hour)) {
+			t.Fatal(fmt.Sprintf("Session 's1' not retrieved correctly: %v, %v", s, err))
+		}
+		if _, err := database.GetSession("s3"); err != model.ErrNotFound {
+			t.Fatal("Expired session 's3' has been retrieved.")
+		}
+
+		var ss, err = database.GetSessions(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ss) != 2 || ss[0].ID != "s2" || ss[1].ID != "s1" {
+			t.Fatal(fmt.Sprintf("Wrong sessions of user '%s': %v", userID, ss))
+		}
+	}
+
+	//3. Step: Revoke and purge sessions and check that
+	//		   they are gone, but the ones of other users
+	//		   and unexpired ones are kept.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteSession("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteSession("s1"); err != model.ErrNotFound {
+		t.Fatal("Session 's1' could be deleted twice.")
+	}
+	if _, err := os.Stat(db.sessionPath("s1")); !os.IsNotExist(err) {
+		t.Fatal("Session file of 's1' still exists.")
+	}
+
+	if err := db.AddSession(sessions[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteSessions(userID, "s2"); err != nil {
+		t.Fatal(err)
+	}
+	if ss, _ := db.GetSessions(userID); len(ss) != 1 || ss[0].ID != "s2" {
+		t.Fatal(fmt.Sprintf("Only session 's2' should be left, got: %v", ss))
+	}
+
+	if err := db.AddSession(model.Session{ID: "s5", User: userID, Created: now, Expires: now.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PurgeSessions(now.Add(2 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"s3", "s5"} {
+		if _, ok := db.sessions.sessions[id]; ok || exists(db.sessionPath(id)) {
+			t.Fatal(fmt.Sprintf("Expired session '%s' has not been purged.", id))
+		}
+	}
+	if _, err := db.GetSession("s2"); err != nil {
+		t.Fatal("Unexpired session 's2' has been purged.")
+	}
+
+	//4. Step: Delete the user and check that
+	//		   all of its sessions are revoked.
+	//――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetSession("s2"); err != model.ErrNotFound {
+		t.Fatal("Session 's2' still exists after deleting its user.")
+	}
+	if _, err := db.GetSession("s4"); err != nil {
+		t.Fatal("Session 's4' of another user has been revoked.")
+	}
+}
+
+//DONE
+func TestSetLogin(t *testing.T) {
+	//1. Step: Construct a database with a user.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "f5932068"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	//2. Step: Change the login and check whether
+	//		   the change persists.
+	//―――――――――――――――――――――――――――――――――――――――――――――
+	var login = model.NewLogin(userID, "newHash")
+	login.Email.Val = "someone@example.com"
+	if err := db.SetLogin(userID, login); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetLogin("unknown", login); err != model.ErrNotFound {
+		t.Fatal("Login of unknown user could be set.")
+	}
+
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, database := range []database{db, reloaded} {
+		var l, err = database.GetLogin(userID)
+		if err != nil || l.Hash.Val != "newHash" || l.Email.Val != "someone@example.com" {
+			t.Fatal(fmt.Sprintf("Login of user '%s' not set correctly: %v, %v", userID, l, err))
+		}
+	}
+}
+
+//DONE
+func TestUpdateLogin(t *testing.T) {
+	//1. Step: Construct a database with a user.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "f5932068"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	//2. Step: Update different fields concurrently
+	//		   and check that no update is lost.
+	//―――――――――――――――――――――――――――――――――――――――――――――――
+	var wg sync.WaitGroup
+	for _, update := range []func(l *model.Login){
+		func(l *model.Login) { l.Hash.Val = "newHash" },
+		func(l *model.Login) { l.Email.Val = "someone@example.com" },
+		func(l *model.Login) { l.TOTP.LastStep = 1 },
+	} {
+		wg.Add(1)
+		go func(update func(l *model.Login)) {
+			defer wg.Done()
+			if err := db.UpdateLogin(userID, func(l *model.Login) error {
+				update(l)
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}(update)
+	}
+	wg.Wait()
+
+	//3. Step: Failed updates are not stored.
+	//―――――――――――――――――――――――――――――――――――――――――
+	if err := db.UpdateLogin(userID, func(l *model.Login) error {
+		l.Hash.Val = "failed"
+		return model.ErrAlreadyExists
+	}); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Unexpected error of failed update: %v", err))
+	}
+	if err := db.UpdateLogin("unknown", func(*model.Login) error { return nil }); err != model.ErrNotFound {
+		t.Fatal("Login of unknown user could be updated.")
+	}
+
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, database := range []database{db, reloaded} {
+		var l, err = database.GetLogin(userID)
+		if err != nil || l.Hash.Val != "newHash" || l.Email.Val != "someone@example.com" || l.TOTP.LastStep != 1 {
+			t.Fatal(fmt.Sprintf("Login of user '%s' not updated correctly: %v, %v", userID, l, err))
+		}
+	}
+}
+
+//DONE
+func TestResetTokens(t *testing.T) {
+	//1. Step: Construct a database with a user
+	//		   and some reset tokens of it.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "f5932068"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var now = time.Now()
+	for _, token := range []model.ResetToken{
+		{Hash: "h1", User: userID, Expires: now.Add(time.Hour)},
+		{Hash: "h2", User: userID, Expires: now.Add(time.Hour)},
+		{Hash: "h3", User: userID, Expires: now.Add(-time.Minute)},
+		{Hash: "h4", User: "other", Expires: now.Add(time.Hour)},
+		{Hash: "h5", User: userID, Expires: now.Add(time.Hour)},
+	} {
+		if err := db.AddResetToken(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//2. Step: Check that tokens can be retrieved and
+	//		   consumed once, also after reloading,
+	//		   consuming all other tokens of the user, but
+	//		   no expired ones.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reloaded.ConsumeResetToken("h3"); err != model.ErrNotFound {
+		t.Fatal("Expired reset token 'h3' could be consumed.")
+	}
+	if _, err := reloaded.GetResetToken("h3"); err != model.ErrNotFound {
+		t.Fatal("Expired reset token 'h3' could be retrieved.")
+	}
+	if token, err := reloaded.GetResetToken("h1"); err != nil || token.User != userID {
+		t.Fatal(fmt.Sprintf("Reset token 'h1' not retrieved correctly: %v, %v", token, err))
+	}
+
+	if token, err := reloaded.ConsumeResetToken("h1"); err != nil || token.User != userID {
+		t.Fatal(fmt.Sprintf("Reset token 'h1' not consumed correctly: %v, %v", token, err))
+	}
+	for _, hash := range []string{"h1", "h2"} {
+		if _, err := reloaded.ConsumeResetToken(hash); err != model.ErrNotFound {
+			t.Fatal(fmt.Sprintf("Reset token '%s' could be consumed after 'h1'.", hash))
+		}
+	}
+	if _, err := reloaded.ConsumeResetToken("h4"); err != nil {
+		t.Fatal("Reset token 'h4' of another user has been consumed as well.")
+	}
+
+	//3. Step: Delete the user and check that
+	//		   its reset tokens are deleted.
+	//――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ConsumeResetToken("h5"); err != model.ErrNotFound {
+		t.Fatal("Reset token 'h5' still exists after deleting its user.")
+	}
+
+	//4. Step: Check that only the latest reset
+	//		   tokens of a user are kept.
+	//――――――――――――――――――――――――――――――――――――――――――
+	for i, hash := range []string{"h6", "h7", "h8"} {
+		var token = model.ResetToken{Hash: hash, User: "other", Expires: now.Add(time.Duration(i+2) * time.Hour)}
+		if err := db.AddResetToken(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ConsumeResetToken("h4"); err != model.ErrNotFound {
+		t.Fatal("Reset token 'h4' has been kept beyond the latest tokens.")
+	}
+	if _, err := db.ConsumeResetToken("h8"); err != nil {
+		t.Fatal(fmt.Sprintf("Reset token 'h8' not kept: %v", err))
+	}
+}
+
+//DONE
+func TestLockouts(t *testing.T) {
+	//1. Step: Construct a database and
+	//		   record some lockouts.
+	//――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var now = time.Now().Round(time.Second)
+	for i, subject := range []string{"f5932068", "192.0.2.1"} {
+		var lockout = model.Lockout{
+			Time:     now.Add(time.Duration(i) * time.Minute),
+			Kind:     model.LockoutAccount,
+			Subject:  subject,
+			Failures: 10,
+			Until:    now.Add(time.Hour),
+		}
+		if err := db.AddLockout(lockout); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//2. Step: Check that the lockouts are listed,
+	//		   the most recent first, also after
+	//		   reloading the database.
+	//――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, database := range []database{db, reloaded} {
+		var lockouts, err = database.GetLockouts()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lockouts) != 2 || lockouts[0].Subject != "192.0.2.1" || !lockouts[1].Time.Equal(now) {
+			t.Fatal(fmt.Sprintf("Lockouts not listed correctly: %v", lockouts))
+		}
+	}
+
+	//3. Step: Check that lockouts are appended to
+	//		   files which wrap them in one element.
+	//――――――――――――――――――――――――――――――――――――――――――――――
+	var wrapped = model.Lockouts{Lockout: []model.Lockout{{Time: now, Kind: model.LockoutIP, Subject: "192.0.2.2"}}}
+	if err := write(db.lockoutPath(), wrapped.String()); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err = New(db.config); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.AddLockout(model.Lockout{Time: now, Kind: model.LockoutIP, Subject: "192.0.2.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err = New(db.config); err != nil {
+		t.Fatal(err)
+	}
+	if lockouts, _ := reloaded.GetLockouts(); len(lockouts) != 2 || lockouts[0].Subject != "192.0.2.3" ||
+		lockouts[1].Subject != "192.0.2.2" {
+		t.Fatal(fmt.Sprintf("Lockouts not appended correctly: %v", lockouts))
+	}
+}
+
+//DONE
+func TestAccessTokens(t *testing.T) {
+	//1. Step: Construct a database with a user
+	//		   and some access tokens of it.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "f5932068"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var now = time.Now().Round(time.Second)
+	var future, past = now.Add(time.Hour), now.Add(-time.Minute)
+	var tokens = []model.AccessToken{
+		{ID: "t1", User: userID, Name: "backup", Created: now.Add(-time.Hour), Hash: "h1",
+			Scopes: []model.TokenScope{model.ScopeRead}, Calendars: []string{userID + "/" + userID}, Token: "secret"},
+		{ID: "t2", User: userID, Name: "sync", Created: now, Expires: &future, Hash: "h2",
+			Scopes: []model.TokenScope{model.ScopeEdit, model.ScopeShare}},
+		{ID: "t3", User: userID, Created: now, Expires: &past},
+		{ID: "t4", User: "other", Created: now},
+	}
+	for _, token := range tokens {
+		if err := db.AddAccessToken(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddAccessToken(tokens[0]); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Access token with id '%s' could be added twice.", tokens[0].ID))
+	}
+
+	//2. Step: Check that the tokens can be retrieved,
+	//		   also after reloading the database, but
+	//		   expired ones can not and the plain token
+	//		   is not stored.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		if token, err := database.GetAccessToken("t1"); err != nil || token.Hash != "h1" || token.Token != "" ||
+			token.Expires != nil || len(token.Calendars) != 1 || !token.HasScope(model.ScopeRead) {
+			t.Fatal(fmt.Sprintf("Access token 't1' not retrieved correctly: %v, %v", token, err))
+		}
+		if token, err := database.GetAccessToken("t2"); err != nil || !token.Expires.Equal(future) ||
+			len(token.Scopes) != 2 {
+			t.Fatal(fmt.Sprintf("Access token 't2' not retrieved correctly: %v, %v", token, err))
+		}
+		if _, err := database.GetAccessToken("t3"); err != model.ErrNotFound {
+			t.Fatal("Expired access token 't3' has been retrieved.")
+		}
+
+		var ts, _ = database.GetAccessTokens(userID)
+		if len(ts) != 2 || ts[0].ID != "t2" || ts[1].ID != "t1" {
+			t.Fatal(fmt.Sprintf("Wrong access tokens of user '%s': %v", userID, ts))
+		}
+	}
+
+	//3. Step: Revoke a token and delete the user and
+	//		   check that all of its tokens are gone,
+	//		   but the ones of other users are kept.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteAccessToken("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteAccessToken("t1"); err != model.ErrNotFound {
+		t.Fatal("Access token 't1' could be deleted twice.")
+	}
+	if _, err := os.Stat(db.tokenPath("t1")); !os.IsNotExist(err) {
+		t.Fatal("Access token file of 't1' still exists.")
+	}
+
+	if err := db.DeleteUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetAccessToken("t2"); err != model.ErrNotFound {
+		t.Fatal("Access token 't2' still exists after deleting its user.")
+	}
+	if _, err := db.GetAccessToken("t4"); err != nil {
+		t.Fatal("Access token 't4' of another user has been revoked.")
+	}
+}
+
+//DONE
+func TestSetProfile(t *testing.T) {
+	//1. Step: Construct a database with a user
+	//		   and set its profile.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "7c1e52d0"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var profile = model.Profile{DisplayName: "Jörg <Test>", Locale: "de-DE", TimeZone: "Europe/Berlin",
+		WeekStart: "sunday"}
+	if err := db.SetProfile(userID, profile); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetProfile("unknown", profile); err != model.ErrNotFound {
+		t.Fatal("Profile of unknown user could be set.")
+	}
+
+	//2. Step: Check that the profile is stored, also
+	//		   after reloading the database, and that the
+	//		   calendars of the user are kept.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		var user, err = database.GetUser(userID)
+		if err != nil || user.Profile != profile || len(user.Items.Calendars) != 1 {
+			t.Fatal(fmt.Sprintf("Profile of user '%s' not stored correctly: %v, %v", userID, user, err))
+		}
+	}
+}
+
+//DONE
+func TestGroups(t *testing.T) {
+	//1. Step: Construct a database with three users
+	//		   and a group sharing the initial calendar
+	//		   of the first user.
+	//――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var admin, member, other = "a", "b", "c"
+	for _, userID := range []string{admin, member, other} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var group = model.NewGroup("team", admin)
+	group.SetRole(member, model.RoleMember)
+	if err := db.AddGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddGroup(group); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Group with id '%s' could be added twice.", group.ID))
+	}
+	if err := db.AddGroup(model.NewGroup("others", other)); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID = fmt.Sprintf("%s/%s", admin, admin)
+	var cal, _ = db.GetCalendar(calID)
+	cal.Permissions.View.Group = []model.Attribute{{Val: group.ID}}
+	if err := db.SetCalendar(calID, cal); err != nil {
+		t.Fatal(err)
+	}
+	group.ShareCalendar(calID, model.Read)
+	if err := db.SetGroup(group); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetGroup(model.NewGroup("unknown", admin)); err != model.ErrNotFound {
+		t.Fatal("Unknown group could be set.")
+	}
+
+	//2. Step: Check that the groups can be retrieved,
+	//		   also after reloading the database.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		var g, err = database.GetGroup(group.ID)
+		if err != nil || !g.IsAdmin(admin) || !g.IsMember(member) || len(g.Calendars) != 1 ||
+			g.Calendars[0].Link != calID {
+			t.Fatal(fmt.Sprintf("Group '%s' not retrieved correctly: %v, %v", group.ID, g, err))
+		}
+
+		var gs, _ = database.GetGroups(member)
+		if len(gs) != 1 || gs[0].ID != group.ID {
+			t.Fatal(fmt.Sprintf("Wrong groups of user '%s': %v", member, gs))
+		}
+	}
+
+	//3. Step: Delete the group and check that the
+	//		   calendar doesn't grant it anything anymore.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteGroup(group.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteGroup(group.ID); err != model.ErrNotFound {
+		t.Fatal(fmt.Sprintf("Group '%s' could be deleted twice.", group.ID))
+	}
+	if _, err := os.Stat(db.groupPath(group.ID)); !os.IsNotExist(err) {
+		t.Fatal(fmt.Sprintf("Group file of '%s' still exists.", group.ID))
+	}
+	if cal, _ = db.GetCalendar(calID); len(cal.Permissions.View.Group) != 0 {
+		t.Fatal(fmt.Sprintf("Calendar '%s' still grants the deleted group: %v", calID, cal.Permissions))
+	}
+
+	//4. Step: Delete the admin of a group and check that
+	//		   the member is promoted, and that a group
+	//		   without members is deleted.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	group = model.NewGroup("team", admin)
+	group.SetRole(member, model.RoleMember)
+	if err := db.AddGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.DeleteUser(admin); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := db.GetGroup(group.ID); err != nil || g.IsMember(admin) || !g.IsAdmin(member) {
+		t.Fatal(fmt.Sprintf("Group '%s' not handed over: %v, %v", group.ID, g, err))
+	}
+
+	if err := db.DeleteUser(other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetGroup("others"); err != model.ErrNotFound {
+		t.Fatal("Group without members still exists.")
+	}
+}
+
+//DONE
+func TestInvitations(t *testing.T) {
+	//1. Step: Construct a database with two users and
+	//		   invitations to the calendar of the first
+	//		   one, also to a user not registered yet.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner, invitee = "a", "b"
+	for _, userID := range []string{owner, invitee} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calID = fmt.Sprintf("%s/%s", owner, owner)
+	var now = time.Now().Round(time.Second)
+	var invitations = []model.Invitation{
+		{ID: "i1", Calendar: calID, From: owner, To: invitee, Perm: "edit", Created: now.Add(-time.Hour),
+			Expires: now.Add(time.Hour)},
+		{ID: "i2", Calendar: calID, From: owner, To: "c", Perm: "view", Created: now, Expires: now.Add(time.Hour)},
+		{ID: "i3", Calendar: calID, From: owner, To: invitee, Created: now, Expires: now.Add(-time.Minute)},
+	}
+	for _, invitation := range invitations {
+		if err := db.AddInvitation(invitation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddInvitation(invitations[0]); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Invitation with id '%s' could be added twice.", invitations[0].ID))
+	}
+
+	//2. Step: Check that the invitations can be retrieved,
+	//		   also after reloading the database, but
+	//		   expired ones can not.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		if invitation, err := database.GetInvitation("i1"); err != nil || invitation.To != invitee ||
+			invitation.Permission() != model.Edit || !invitation.Expires.Equal(now.Add(time.Hour)) {
+			t.Fatal(fmt.Sprintf("Invitation 'i1' not retrieved correctly: %v, %v", invitation, err))
+		}
+		if _, err := database.GetInvitation("i3"); err != model.ErrNotFound {
+			t.Fatal("Expired invitation 'i3' has been retrieved.")
+		}
+
+		var is, _ = database.GetInvitations(owner)
+		if len(is) != 2 || is[0].ID != "i2" || is[1].ID != "i1" {
+			t.Fatal(fmt.Sprintf("Wrong invitations of user '%s': %v", owner, is))
+		}
+		is, _ = database.GetInvitations(invitee)
+		if len(is) != 1 || is[0].ID != "i1" {
+			t.Fatal(fmt.Sprintf("Wrong invitations of user '%s': %v", invitee, is))
+		}
+	}
+
+	//3. Step: Delete an invitation, the invitee and the
+	//		   calendar and check that all invitations
+	//		   are gone.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteInvitation("i1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteInvitation("i1"); err != model.ErrNotFound {
+		t.Fatal("Invitation 'i1' could be deleted twice.")
+	}
+	if _, err := os.Stat(db.invitationPath("i1")); !os.IsNotExist(err) {
+		t.Fatal("Invitation file of 'i1' still exists.")
+	}
+
+	if err := db.AddInvitation(invitations[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteUser(invitee); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetInvitation("i1"); err != model.ErrNotFound {
+		t.Fatal("Invitation 'i1' still exists after deleting its invitee.")
+	}
+
+	if err := db.DeleteCalendar(calID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetInvitation("i2"); err != model.ErrNotFound {
+		t.Fatal("Invitation 'i2' still exists after deleting its calendar.")
+	}
+}
+
+//DONE
+func TestShareLinks(t *testing.T) {
+	//1. Step: Construct a database with a user and
+	//		   share links of its initial calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var userID = "d4a7c9e1"
+	if err := db.AddUser(userID, "hash"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID = fmt.Sprintf("%s/%s", userID, userID)
+	var now = time.Now().Round(time.Second)
+	var past = now.Add(-time.Minute)
+	var links = []model.ShareLink{
+		{ID: "l1", Calendar: calID, Owner: userID, Created: now.Add(-time.Hour), Hash: "h1", Token: "secret",
+			URL: "https://example.com/s/secret"},
+		{ID: "l2", Calendar: calID, Owner: userID, Created: now, HideDescriptions: true, Hash: "h2"},
+		{ID: "l3", Calendar: calID, Owner: userID, Created: now, Expires: &past},
+	}
+	for _, link := range links {
+		if err := db.AddShareLink(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddShareLink(links[0]); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Share link with id '%s' could be added twice.", links[0].ID))
+	}
+
+	//2. Step: Count accesses and check that they are
+	//		   stored, also after reloading the database,
+	//		   but the plain token is not and expired
+	//		   links can not be retrieved.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	for i := 0; i < 2; i++ {
+		if err := db.CountShareLinkAccess("l1", now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.CountShareLinkAccess("unknown", now); err != model.ErrNotFound {
+		t.Fatal("Access of unknown share link has been counted.")
+	}
+
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		if link, err := database.GetShareLink("l1"); err != nil || link.Hash != "h1" || link.Token != "" ||
+			link.URL != "" || link.Accesses != 2 || !link.LastAccess.Equal(now) {
+			t.Fatal(fmt.Sprintf("Share link 'l1' not retrieved correctly: %v, %v", link, err))
+		}
+		if _, err := database.GetShareLink("l3"); err != model.ErrNotFound {
+			t.Fatal("Expired share link 'l3' has been retrieved.")
+		}
+
+		var ls, _ = database.GetShareLinks(userID)
+		if len(ls) != 2 || ls[0].ID != "l2" || !ls[0].HideDescriptions || ls[1].ID != "l1" {
+			t.Fatal(fmt.Sprintf("Wrong share links of user '%s': %v", userID, ls))
+		}
+	}
+
+	//3. Step: Revoke a link and delete the user and
+	//		   check that all of its links are gone.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteShareLink("l1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteShareLink("l1"); err != model.ErrNotFound {
+		t.Fatal("Share link 'l1' could be deleted twice.")
+	}
+	if _, err := os.Stat(db.linkPath("l1")); !os.IsNotExist(err) {
+		t.Fatal("Share link file of 'l1' still exists.")
+	}
+
+	if err := db.DeleteUser(userID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetShareLink("l2"); err != model.ErrNotFound {
+		t.Fatal("Share link 'l2' still exists after deleting its user.")
+	}
+}
+
+//DONE
+func TestTransferCalendar(t *testing.T) {
+	//1. Step: Construct a database with a calendar of
+	//		   the first user, shared with the third one,
+	//		   a group and referenced by an invitation,
+	//		   a share link and an access token.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner, heir, viewer = "a", "b", "c"
+	for _, userID := range []string{owner, heir, viewer} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, userID := range []string{owner, heir} {
+		if err := db.AddCalendar(userID, "proj"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calID, newID = owner + "/proj", heir + "/renamed"
+	var cal, _ = db.GetCalendar(calID)
+	if err := db.AssociateCalendar(db.users[viewer], cal, model.Read); err != nil {
+		t.Fatal(err)
+	}
+
+	var group = model.NewGroup("team", owner)
+	group.ShareCalendar(calID, model.Edit)
+	if err := db.AddGroup(group); err != nil {
+		t.Fatal(err)
+	}
+
+	var now = time.Now().Round(time.Second)
+	for _, invitation := range []model.Invitation{
+		{ID: "i1", Calendar: calID, From: owner, To: heir, Perm: "view", Created: now, Expires: now.Add(time.Hour)},
+		{ID: "i2", Calendar: calID, From: owner, To: "d", Perm: "edit", Created: now, Expires: now.Add(time.Hour)},
+	} {
+		if err := db.AddInvitation(invitation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddShareLink(model.ShareLink{ID: "l1", Calendar: calID, Owner: owner, Created: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddAccessToken(model.AccessToken{ID: "t1", User: viewer, Created: now,
+		Calendars: []string{calID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	//2. Step: Check that unknown new owners and names
+	//		   taken in the namespace of the new owner are
+	//		   refused without changing anything.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.TransferCalendar(calID, "unknown", "proj", model.None); err != model.ErrNotFound {
+		t.Fatal("Calendar has been transferred to an unknown user.")
+	}
+	if err := db.TransferCalendar(calID, heir, "proj", model.None); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Calendar has been transferred onto '%s/proj'.", heir))
+	}
+	if c, err := db.GetCalendar(calID); err != nil || c.Owner.Val != owner {
+		t.Fatal(fmt.Sprintf("Calendar '%s' changed by refused transfer: %v, %v", calID, c, err))
+	}
+
+	//3. Step: Transfer the calendar under a new name, the
+	//		   previous owner keeps the edit permission.
+	//		   Check that all references moved along, also
+	//		   after reloading the database.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.TransferCalendar(calID, heir, "renamed", model.Edit); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		if _, err := database.GetCalendar(calID); err != model.ErrNotFound {
+			t.Fatal(fmt.Sprintf("Calendar '%s' still exists after the transfer.", calID))
+		}
+
+		var c, err = database.GetCalendar(newID)
+		if err != nil || c.ID.Val != newID || c.Owner.Val != heir || c.Name.Val != "renamed" {
+			t.Fatal(fmt.Sprintf("Calendar '%s' not transferred correctly: %v, %v", newID, c, err))
+		}
+		if model.CalendarPermissions(c, owner) != model.Edit || model.CalendarPermissions(c, viewer) != model.Read {
+			t.Fatal(fmt.Sprintf("Wrong permissions of calendar '%s': %v", newID, c.Permissions))
+		}
+
+		for userID, perm := range map[string]model.Permission{owner: model.Edit, heir: model.Owner,
+			viewer: model.Read} {
+			var refs = database.users[userID].Items.Calendars
+			var found = false
+			for _, ref := range refs {
+				if ref.Link == calID {
+					t.Fatal(fmt.Sprintf("User '%s' still references '%s'.", userID, calID))
+				}
+				found = found || (ref.Link == newID && ref.Perm == perm.String())
+			}
+			if !found {
+				t.Fatal(fmt.Sprintf("User '%s' doesn't reference '%s' as %s: %v", userID, newID, perm, refs))
+			}
+		}
+
+		if g, _ := database.GetGroup(group.ID); len(g.Calendars) != 1 || g.Calendars[0].Link != newID {
+			t.Fatal(fmt.Sprintf("Group '%s' not rekeyed: %v", group.ID, g.Calendars))
+		}
+		if _, err := database.GetInvitation("i1"); err != model.ErrNotFound {
+			t.Fatal("Invitation of the new owner has not been deleted.")
+		}
+		if i, _ := database.GetInvitation("i2"); i.Calendar != newID || i.From != heir {
+			t.Fatal(fmt.Sprintf("Invitation 'i2' not rekeyed: %v", i))
+		}
+		if l, _ := database.GetShareLink("l1"); l.Calendar != newID || l.Owner != heir {
+			t.Fatal(fmt.Sprintf("Share link 'l1' not rekeyed: %v", l))
+		}
+		if tok, _ := database.GetAccessToken("t1"); len(tok.Calendars) != 1 || tok.Calendars[0] != newID {
+			t.Fatal(fmt.Sprintf("Access token 't1' not rekeyed: %v", tok.Calendars))
+		}
+	}
+
+	if exists(fmt.Sprintf("%s/%s.xml", db.config.CalendarDir, calID)) {
+		t.Fatal(fmt.Sprintf("File of calendar '%s' has not been removed.", calID))
+	}
+	if err := db.AddCalendar(owner, "proj"); err != nil {
+		t.Fatal(fmt.Sprintf("Name of transferred calendar '%s' can not be reused: %v", calID, err))
+	}
+}
+
+//DONE
+func TestRenameCalendar(t *testing.T) {
+	//1. Step: Construct a database with a calendar shared
+	//		   with a second user and rename it twice, so
+	//		   that both former IDs redirect to the last.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner, viewer = "a", "b"
+	for _, userID := range []string{owner, viewer} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"typo", "other"} {
+		if err := db.AddCalendar(owner, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var cal, _ = db.GetCalendar(owner + "/typo")
+	if err := db.AssociateCalendar(db.users[viewer], cal, model.Read); err != nil {
+		t.Fatal(err)
+	}
+
+	var until = time.Now().Add(time.Hour).Round(time.Second)
+	if err := db.RenameCalendar(owner+"/typo", "other", until); err != model.ErrAlreadyExists {
+		t.Fatal(fmt.Sprintf("Calendar has been renamed onto '%s/other'.", owner))
+	}
+	if err := db.RenameCalendar(owner+"/unknown", "fixed", until); err != model.ErrNotFound {
+		t.Fatal("Unknown calendar has been renamed.")
+	}
+	for _, rename := range [][2]string{{"typo", "fixed"}, {"fixed", "final"}} {
+		if err := db.RenameCalendar(owner+"/"+rename[0], rename[1], until); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//2. Step: Check the calendar, the references and the
+	//		   aliases, also after reloading the database.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var newID = owner + "/final"
+	for _, database := range []database{db, reloaded} {
+		var c, err = database.GetCalendar(newID)
+		if err != nil || c.ID.Val != newID || c.Name.Val != "final" || c.Owner.Val != owner ||
+			model.CalendarPermissions(c, viewer) != model.Read {
+			t.Fatal(fmt.Sprintf("Calendar '%s' not renamed correctly: %v, %v", newID, c, err))
+		}
+
+		for userID, perm := range map[string]model.Permission{owner: model.Owner, viewer: model.Read} {
+			var found = false
+			for _, ref := range database.users[userID].Items.Calendars {
+				found = found || (ref.Link == newID && ref.Perm == perm.String())
+			}
+			if !found {
+				t.Fatal(fmt.Sprintf("User '%s' doesn't reference '%s' as %s.", userID, newID, perm))
+			}
+		}
+
+		for _, name := range []string{"typo", "fixed"} {
+			if _, err := database.GetCalendar(owner + "/" + name); err != model.ErrNotFound {
+				t.Fatal(fmt.Sprintf("Former calendar '%s/%s' still exists.", owner, name))
+			}
+			if a, err := database.GetCalendarAlias(owner + "/" + name); err != nil || a.To != newID ||
+				!a.Expires.Equal(until) {
+				t.Fatal(fmt.Sprintf("Alias of '%s/%s' not retrieved correctly: %v, %v", owner, name, a, err))
+			}
+		}
+	}
+
+	//3. Step: Check that renaming back drops the alias of
+	//		   the name taken again, that expired aliases
+	//		   are not retrieved and that deleting the
+	//		   calendar deletes its aliases.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.RenameCalendar(newID, "typo", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetCalendarAlias(owner + "/typo"); err != model.ErrNotFound {
+		t.Fatal("Alias of a taken calendar ID has been retrieved.")
+	}
+	if _, err := db.GetCalendarAlias(newID); err != model.ErrNotFound {
+		t.Fatal("Expired alias has been retrieved.")
+	}
+	if a, err := db.GetCalendarAlias(owner + "/fixed"); err != nil || a.To != owner+"/typo" {
+		t.Fatal(fmt.Sprintf("Alias of '%s/fixed' not rekeyed: %v, %v", owner, a, err))
+	}
+
+	if err := db.DeleteCalendar(owner + "/typo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetCalendarAlias(owner + "/fixed"); err != model.ErrNotFound {
+		t.Fatal("Alias of a deleted calendar has been retrieved.")
+	}
+}
+
+//DONE
+func TestRekeyUsersRollback(t *testing.T) {
+	//1. Step: Construct a database with a calendar shared
+	//		   with a second user and rekey the references.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner, viewer = "a", "b"
+	for _, userID := range []string{owner, viewer} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var calID, newID = owner + "/" + owner, owner + "/renamed"
+	if err := db.AssociateCalendar(db.users[viewer], db.calendars[calID], model.Read); err != nil {
+		t.Fatal(err)
+	}
+
+	var undo undoLog
+	if err := db.rekeyUsers(calID, newID, owner, owner, model.None, &undo); err != nil {
+		t.Fatal(err)
+	}
+	if refs := db.users[viewer].Items.Calendars; len(refs) != 2 || refs[1].Link != newID {
+		t.Fatal(fmt.Sprintf("Reference of user '%s' not rekeyed: %v", viewer, refs))
+	}
+
+	//2. Step: Modify the user meanwhile and check that
+	//		   the rollback keeps the modification.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	var user = db.users[viewer]
+	user.Items.Calendars = append(user.Items.Calendars, model.CalendarReference{Link: "c/c", Perm: "view"})
+	if err := db.SetUser(viewer, user); err != nil {
+		t.Fatal(err)
+	}
+
+	undo.rollback(nil)
+	var refs = db.users[viewer].Items.Calendars
+	if len(refs) != 3 || refs[1].Link != calID || refs[1].Perm != model.Read.String() || refs[2].Link != "c/c" {
+		t.Fatal(fmt.Sprintf("References of user '%s' not rolled back correctly: %v", viewer, refs))
+	}
+}
+
+//DONE
+func TestMoveItem(t *testing.T) {
+	//1. Step: Construct a database with two calendars,
+	//		   the first one having a task linked to a
+	//		   milestone, the second one a task of the
+	//		   same ID as the other task of the first.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner = "a"
+	if err := db.AddUser(owner, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"src", "dst"} {
+		if err := db.AddCalendar(owner, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var srcID, dstID = owner + "/src", owner + "/dst"
+	var src, _ = db.GetCalendar(srcID)
+	src.Items.Milestones.Milestone = []model.Milestone{{ID: "m1"}}
+	src.Items.Tasks.Task = []model.Task{{ID: "t1"}, {ID: "t2"}}
+	src.Items.Tasks.Task[0].Milestone.ID = "m1"
+	if err := db.SetCalendar(srcID, src); err != nil {
+		t.Fatal(err)
+	}
+	var dst, _ = db.GetCalendar(dstID)
+	dst.Items.Tasks.Task = []model.Task{{ID: "t2"}}
+	if err := db.SetCalendar(dstID, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	//2. Step: Move and copy the items, and check that
+	//		   failed moves don't change either calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.MoveItem(srcID, dstID, model.KindTask, "t2"); err != model.ErrAlreadyExists {
+		t.Fatal("Task has been moved onto a task of the same ID.")
+	}
+	if err := db.MoveItem(srcID, owner+"/unknown", model.KindTask, "t1"); err != model.ErrNotFound {
+		t.Fatal("Task has been moved to an unknown calendar.")
+	}
+	if err := db.MoveItem(srcID, dstID, model.KindMilestone, "t1"); err != model.ErrNotFound {
+		t.Fatal("Task has been moved as a milestone.")
+	}
+	if err := db.MoveItem(srcID, dstID, model.KindTask, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	var copyID, err = db.CopyItem(srcID, dstID, model.KindTask, "t2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//3. Step: Check both calendars, also after reloading
+	//		   the database.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	reloaded, err := New(db.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, database := range []database{db, reloaded} {
+		var src, _ = database.GetCalendar(srcID)
+		var dst, _ = database.GetCalendar(dstID)
+
+		if len(src.Items.Tasks.Task) != 1 || !src.HasItem("t2") || !src.HasItem("m1") {
+			t.Fatal(fmt.Sprintf("Calendar '%s' has wrong items: %v", srcID, src.Items))
+		}
+
+		var ts = dst.Items.Tasks.Task
+		if len(ts) != 3 || ts[1].ID != "t1" || ts[1].Milestone.ID != "" || ts[2].ID != copyID || copyID == "t2" {
+			t.Fatal(fmt.Sprintf("Calendar '%s' has wrong tasks: %v", dstID, ts))
+		}
+	}
+}
+
+//DONE
+func TestAuditLog(t *testing.T) {
+	//1. Step: Construct a database with a calendar and
+	//		   record changes of two users.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner = "a"
+	if err := db.AddUser(owner, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddCalendar(owner, "proj"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID, now = owner + "/proj", time.Now().Round(time.Second)
+	for i, e := range []model.AuditEntry{
+		{Time: now.Add(-time.Hour), User: owner, Calendar: calID, Action: model.AuditUpdate},
+		{Time: now, User: "b", Calendar: calID, Action: model.AuditCreate, Kind: model.KindTask, Item: "t1",
+			Changes: []model.Change{{Field: "name", After: "Write docs"}}},
+		{Time: now, User: "b", Calendar: calID, Action: model.AuditDelete, Kind: model.KindTask, Item: "t1",
+			Changes: []model.Change{{Field: "name", Before: "Write docs"}}},
+	} {
+		if err := db.AddAuditEntry(e); err != nil {
+			t.Fatal(fmt.Sprintf("Entry %d not recorded: %v", i, err))
+		}
+	}
+	if err := db.AddAuditEntry(model.AuditEntry{Calendar: owner + "/unknown"}); err != model.ErrNotFound {
+		t.Fatal("Entry has been recorded for an unknown calendar.")
+	}
+
+	//2. Step: Query the log, also after renaming the
+	//		   calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var check = func(calID string, q model.AuditQuery, actions ...model.AuditAction) {
+		var es, err = db.GetAuditLog(calID, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []model.AuditAction
+		for _, e := range es {
+			got = append(got, e.Action)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(actions) {
+			t.Fatal(fmt.Sprintf("Audit log of '%s' has entries %v, expected %v.", calID, got, actions))
+		}
+	}
+
+	check(calID, model.AuditQuery{}, model.AuditDelete, model.AuditCreate, model.AuditUpdate)
+	check(calID, model.AuditQuery{User: "b", Item: "t1"}, model.AuditDelete, model.AuditCreate)
+	check(calID, model.AuditQuery{To: now}, model.AuditUpdate)
+
+	var es, _ = db.GetAuditLog(calID, model.AuditQuery{Item: "t1"})
+	if len(es) != 2 || !es[0].Time.Equal(now) || es[1].Changes[0].After != "Write docs" {
+		t.Fatal(fmt.Sprintf("Audit entries not read correctly: %v", es))
+	}
+
+	if err := db.RenameCalendar(calID, "renamed", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	check(owner+"/renamed", model.AuditQuery{}, model.AuditDelete, model.AuditCreate, model.AuditUpdate)
+	if _, err := db.GetAuditLog(calID, model.AuditQuery{}); err != model.ErrNotFound {
+		t.Fatal("Audit log has been found under the former ID.")
+	}
+
+	//3. Step: Check that the log is deleted along with
+	//		   the calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.DeleteCalendar(owner + "/renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddCalendar(owner, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	check(owner+"/renamed", model.AuditQuery{})
+}
+
+//DONE
+func TestRevisions(t *testing.T) {
+	//1. Step: Construct a database with a calendar and
+	//		   change its item twice, then set it once
+	//		   more without any change.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner = "a"
+	if err := db.AddUser(owner, "hash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddCalendar(owner, "proj"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID = owner + "/proj"
+	var cal, _ = db.GetCalendar(calID)
+	for _, name := range []string{"draft", "final", "final"} {
+		cal.Items.Tasks.Task = []model.Task{{ID: "t1", Name: model.Attribute{Val: name}}}
+		if err := db.SetCalendar(calID, cal); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var between = time.Now()
+
+	//2. Step: Check the revisions and roll the calendar
+	//		   back to the draft.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var revs, err = db.GetRevisions(calID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 2 || len(revs[0].Calendar.Items.Tasks.Task) != 1 ||
+		revs[0].Calendar.Items.Tasks.Task[0].Name.Val != "draft" || len(revs[1].Calendar.Items.Tasks.Task) != 0 {
+		t.Fatal(fmt.Sprintf("Calendar '%s' has wrong revisions: %v", calID, revs))
+	}
+	if rev, err := db.GetRevision(calID, revs[0].ID); err != nil || rev.Until != revs[0].Until {
+		t.Fatal(fmt.Sprintf("Revision '%s' not found: %v", revs[0].ID, err))
+	}
+	if _, err := db.GetRevision(calID, "../../calendars/a/proj"); err != model.ErrNotFound {
+		t.Fatal("Revision has been read from outside the revisions.")
+	}
+
+	if err := db.RollbackCalendar(calID, revs[0].Until.Add(-time.Nanosecond)); err != nil {
+		t.Fatal(err)
+	}
+	cal, _ = db.GetCalendar(calID)
+	if len(cal.Items.Tasks.Task) != 1 || cal.Items.Tasks.Task[0].Name.Val != "draft" {
+		t.Fatal(fmt.Sprintf("Calendar '%s' not rolled back: %v", calID, cal.Items))
+	}
+	if err := db.RollbackCalendar(calID, between); err != nil {
+		t.Fatal(err)
+	}
+	cal, _ = db.GetCalendar(calID)
+	if cal.Items.Tasks.Task[0].Name.Val != "final" {
+		t.Fatal("Rollback has not been undone.")
+	}
+	var expired = time.Now().Add(-db.config.RevisionRetention - time.Hour)
+	if err := db.RollbackCalendar(calID, expired); err != model.ErrExpired {
+		t.Fatal("Calendar has been rolled back beyond the retention.")
+	}
+
+	//3. Step: Check that old revisions and those beyond
+	//		   the limit are dropped and that revisions
+	//		   follow the calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if revs, _ = db.GetRevisions(calID); len(revs) != 4 {
+		t.Fatal(fmt.Sprintf("Rollbacks have not been kept as revisions: %v", revs))
+	}
+	if err := os.Chtimes(db.revisionPath(calID, revs[3].ID), expired, expired); err != nil {
+		t.Fatal(err)
+	}
+	if revs, _ = db.GetRevisions(calID); len(revs) != 3 {
+		t.Fatal("Revision beyond the retention has been kept.")
+	}
+
+	revs[0].Calendar = model.Calendar{}
+	db.config.RevisionLimit = 2
+	var kept, _ = db.GetRevisions(calID)
+	if len(kept) != 2 || kept[0].ID != revs[0].ID || kept[1].ID != revs[1].ID {
+		t.Fatal(fmt.Sprintf("Revisions beyond the limit have been kept: %v", kept))
+	}
+	if len(kept[0].Calendar.Items.Tasks.Task) != 1 {
+		t.Fatal("Revisions read have been changed by the caller.")
+	}
+	if exists(db.revisionPath(calID, revs[2].ID)) {
+		t.Fatal("Revision beyond the limit has not been removed.")
+	}
+	revs = kept
+
+	if err := db.RenameCalendar(calID, "renamed", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if revs, _ = db.GetRevisions(owner + "/renamed"); len(revs) != 2 {
+		t.Fatal("Revisions have not been renamed along with the calendar.")
+	}
+	if err := db.DeleteCalendar(owner + "/renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if exists(db.revisionDir(owner + "/renamed")) {
+		t.Fatal("Revisions have not been deleted along with the calendar.")
+	}
+}
+
+//DONE
+func TestTrash(t *testing.T) {
+	//1. Step: Construct a database with a calendar
+	//		   shared with another user and trash its
+	//		   task, then restore it.
+	//――――――――――――――――――――――――――――――――――――――――――――――――
+	var db = GetDatabase(t)
+	t.Cleanup(func() { DeleteDatabase(db, t) })
+
+	var owner, viewer = "a", "b"
+	for _, userID := range []string{owner, viewer} {
+		if err := db.AddUser(userID, "hash"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.AddCalendar(owner, "proj"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calID = owner + "/proj"
+	var cal, _ = db.GetCalendar(calID)
+	cal.Items.Tasks.Task = []model.Task{{ID: "t1"}}
+	if err := db.SetCalendar(calID, cal); err != nil {
+		t.Fatal(err)
+	}
+	var user, _ = db.GetUser(viewer)
+	cal, _ = db.GetCalendar(calID)
+	if err := db.AssociateCalendar(user, cal, model.Read); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddAuditEntry(model.AuditEntry{Time: time.Now(), User: owner, Calendar: calID}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.TrashItem(calID, model.KindTask, "t1", viewer); err != nil {
+		t.Fatal(err)
+	}
+	var entries, err = db.GetTrash(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cal, _ = db.GetCalendar(calID); cal.HasItem("t1") || len(entries) != 1 || entries[0].Task == nil ||
+		entries[0].DeletedBy != viewer {
+		t.Fatal(fmt.Sprintf("Task has not been moved into the trash: %v", entries))
+	}
+	if _, err := db.RestoreTrash(owner, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if cal, _ = db.GetCalendar(calID); !cal.HasItem("t1") {
+		t.Fatal("Task has not been restored.")
+	}
+	if entries, _ = db.GetTrash(owner); len(entries) != 0 {
+		t.Fatal(fmt.Sprintf("Restored task is still in the trash: %v", entries))
+	}
+
+	//2. Step: Trash the task and the calendar, which
+	//		   has to be restored before the task.
+	//――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.TrashItem(calID, model.KindTask, "t1", owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.TrashCalendar(calID, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetCalendar(calID); err != model.ErrNotFound {
+		t.Fatal("Calendar has not been deleted.")
+	}
+	if user, _ = db.GetUser(viewer); len(user.Items.Calendars) != 1 {
+		t.Fatal("Calendar is still referenced by the viewer.")
+	}
+
+	entries, _ = db.GetTrash(owner)
+	if len(entries) != 2 || entries[0].Content == nil || entries[1].Task == nil {
+		t.Fatal(fmt.Sprintf("Calendar has not been moved into the trash: %v", entries))
+	}
+	if _, err := db.RestoreTrash(owner, entries[1].ID); err != model.ErrNotFound {
+		t.Fatal("Task has been restored without its calendar.")
+	}
+	if _, err := db.RestoreTrash(viewer, entries[0].ID); err != model.ErrNotFound {
+		t.Fatal("Calendar has been restored from the trash of another user.")
+	}
+	if _, err := db.RestoreTrash(owner, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.RestoreTrash(owner, entries[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	cal, _ = db.GetCalendar(calID)
+	if !cal.HasItem("t1") || len(cal.Permissions.View.User) != 1 {
+		t.Fatal(fmt.Sprintf("Calendar '%s' has not been restored: %v", calID, cal))
+	}
+	if user, _ = db.GetUser(viewer); len(user.Items.Calendars) != 2 || user.Items.Calendars[1].Link != calID {
+		t.Fatal("Calendar is not referenced by the viewer again.")
+	}
+	if es, _ := db.GetAuditLog(calID, model.AuditQuery{}); len(es) != 2 || es[0].Action != model.AuditDelete ||
+		es[0].User != owner {
+		t.Fatal(fmt.Sprintf("Audit log has not been restored along with the calendar and the deletion: %v", es))
+	}
+
+	//3. Step: Check that deleted items follow their
+	//		   calendar, that a calendar of the same name
+	//		   is not replaced and that expired entries are
+	//		   destroyed along with their audit logs.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.TrashItem(calID, model.KindTask, "t1", owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RenameCalendar(calID, "renamed", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	calID = owner + "/renamed"
+	if entries, _ = db.GetTrash(owner); len(entries) != 1 || entries[0].Calendar != calID {
+		t.Fatal(fmt.Sprintf("Deleted task has not been renamed along with the calendar: %v", entries))
+	}
+	if _, err := db.RestoreTrash(owner, entries[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.TrashCalendar(calID, owner); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddCalendar(owner, "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ = db.GetTrash(owner)
+	if _, err := db.RestoreTrash(owner, entries[0].ID); err != model.ErrAlreadyExists {
+		t.Fatal("Calendar of the same name has been replaced.")
+	}
+	var auditPath, deleted = db.auditPath(db.trashID(entries[0])), entries[0].Deleted
+	if !exists(auditPath) {
+		t.Fatal("Audit log has not been moved into the trash along with the calendar.")
+	}
+
+	if err := db.PurgeTrash(time.Now().Add(db.config.TrashRetention)); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ = db.GetTrash(owner); len(entries) != 0 {
+		t.Fatal(fmt.Sprintf("Expired entries have not been purged: %v", entries))
+	}
+	if exists(auditPath) {
+		t.Fatal("Audit log has not been purged along with the calendar.")
+	}
+	var logs, _ = db.GetDeletedAuditLogs(owner)
+	if len(logs) != 1 || logs[0].Calendar != calID || !logs[0].Deleted.Equal(deleted) {
+		t.Fatal(fmt.Sprintf("Audit log has not been kept for the owner: %v", logs))
+	}
+	audit, err := db.GetDeletedAuditLog(owner, logs[0].ID, model.AuditQuery{})
+	if err != nil || len(audit) == 0 || audit[0].Action != model.AuditDelete || audit[0].User != owner {
+		t.Fatal(fmt.Sprintf("Deletion has not been recorded in the kept audit log: %v, %v", audit, err))
+	}
+	for _, logID := range []string{"unknown", "../renamed"} {
+		if _, err := db.GetDeletedAuditLog(owner, logID, model.AuditQuery{}); err != model.ErrNotFound {
+			t.Fatal(fmt.Sprintf("Deleted audit log '%s' has been retrieved.", logID))
+		}
+	}
+
+	//4. Step: Schedule the deletion of the owner.
+	//――――――――――――――――――――――――――――――――――――――――――――
+	var login, _ = db.GetLogin(owner)
+	var due = time.Now().Add(time.Hour)
+	login.Deletion = &due
+	if err := db.SetLogin(owner, login); err != nil {
+		t.Fatal(err)
+	}
+	if users, _ := db.GetScheduledDeletions(time.Now()); len(users) != 0 {
+		t.Fatal(fmt.Sprintf("Users deleted too early: %v", users))
+	}
+	if users, _ := db.GetScheduledDeletions(due); len(users) != 1 || users[0] != owner {
+		t.Fatal(fmt.Sprintf("Wrong users due to be deleted: %v", users))
+	}
+}
+
 //GetDatabase loads and constructs a new database struct for testing.
 func GetDatabase(t *testing.T) database {
 	var config = DBConfig{
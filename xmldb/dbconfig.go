@@ -1,5 +1,7 @@
 package xmldb
 
+import "time"
+
 //DBConfig of the XML database
 type DBConfig struct {
 	//RootDir - here all database files reside
@@ -23,6 +25,75 @@ type DBConfig struct {
 	//CalendarDir
 	CalendarDir string
 
+	//SessionRelDir - relative path (to root dir) where session files are stored.
+	SessionRelDir string
+
+	//SessionDir
+	SessionDir string
+
+	//ResetRelDir - relative path (to root dir) where password reset tokens are stored.
+	ResetRelDir string
+
+	//ResetDir
+	ResetDir string
+
+	//TokenRelDir - relative path (to root dir) where personal access tokens are stored.
+	TokenRelDir string
+
+	//TokenDir
+	TokenDir string
+
+	//GroupRelDir - relative path (to root dir) where groups are stored.
+	GroupRelDir string
+
+	//GroupDir
+	GroupDir string
+
+	//InvitationRelDir - relative path (to root dir) where invitations to calendars are stored.
+	InvitationRelDir string
+
+	//InvitationDir
+	InvitationDir string
+
+	//LinkRelDir - relative path (to root dir) where public share links of calendars are stored.
+	LinkRelDir string
+
+	//LinkDir
+	LinkDir string
+
+	//AliasRelDir - relative path (to root dir) where former IDs of renamed calendars are stored.
+	AliasRelDir string
+
+	//AliasDir
+	AliasDir string
+
+	//AuditRelDir - relative path (to root dir) where the audit logs of calendars are stored.
+	AuditRelDir string
+
+	//AuditDir
+	AuditDir string
+
+	//RevisionRelDir - relative path (to root dir) where prior versions of calendars are stored.
+	RevisionRelDir string
+
+	//RevisionDir
+	RevisionDir string
+
+	//RevisionRetention - how long prior versions of calendars are kept to be restored, 30 days by default.
+	RevisionRetention time.Duration `yaml:"revision_retention"`
+
+	//RevisionLimit - how many prior versions are kept per calendar, 50 by default.
+	RevisionLimit int `yaml:"revision_limit"`
+
+	//TrashRelDir - relative path (to root dir) where deleted items and calendars are kept.
+	TrashRelDir string
+
+	//TrashDir
+	TrashDir string
+
+	//TrashRetention - how long deleted items and calendars are kept to be restored, 30 days by default.
+	TrashRetention time.Duration `yaml:"trash_retention"`
+
 	// CacheSize - how many bytes (e.g. for users) will be cached simultaneously;
 	//			   cache can be used to prevent RAM getting flooded with elements.
 	CacheSize int
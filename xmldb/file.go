@@ -17,6 +17,20 @@ func write(path, content string) error {
 	return ioutil.WriteFile(path, []byte(content), 0666)
 }
 
+//appendLine appends @content as a line to the file behind @path,
+//creating the file if necessary.
+func appendLine(path, content string) error {
+	var file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(content + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
 //ensureDir makes sure that a directory at the given
 //path exists by creating it if necessary.
 func ensureDir(path string) error {
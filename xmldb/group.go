@@ -0,0 +1,233 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//groupStore holds the groups of all users. Each group is
+//stored in its own file named after its ID.
+type groupStore struct {
+	mutex  sync.RWMutex
+	groups map[string]model.Group
+}
+
+//loadGroups parses all group files in @dir.
+func loadGroups(dir string) (*groupStore, error) {
+	var store = &groupStore{groups: make(map[string]model.Group)}
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var group model.Group
+		if err := parse(file, &group); err != nil {
+			return err
+		}
+		store.groups[group.ID] = group
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddGroup stores the group @g on disk and in the collection.
+//If a group with the same ID exists, an error is thrown.
+func (db database) AddGroup(g model.Group) error {
+	db.groups.mutex.Lock()
+	defer db.groups.mutex.Unlock()
+
+	if _, ok := db.groups.groups[g.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+	return db.setGroup(g)
+}
+
+//GetGroup retrieves the group to a given @id.
+//If the group doesn't exist, an error is thrown.
+func (db database) GetGroup(id string) (model.Group, error) {
+	db.groups.mutex.RLock()
+	defer db.groups.mutex.RUnlock()
+
+	var val, ok = db.groups.groups[id]
+	if !ok {
+		return model.Group{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//GetGroups retrieves all groups the user with the given
+//@userID is a member or an admin of, sorted by their ID.
+func (db database) GetGroups(userID string) ([]model.Group, error) {
+	db.groups.mutex.RLock()
+	defer db.groups.mutex.RUnlock()
+
+	var res []model.Group
+	for _, g := range db.groups.groups {
+		if g.IsMember(userID) {
+			res = append(res, g)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+//SetGroup overwrites the existing group with the same ID
+//as @g. If the group doesn't exist, an error is thrown.
+func (db database) SetGroup(g model.Group) error {
+	db.groups.mutex.Lock()
+	defer db.groups.mutex.Unlock()
+
+	if _, ok := db.groups.groups[g.ID]; !ok {
+		return model.ErrNotFound
+	}
+	return db.setGroup(g)
+}
+
+//DeleteGroup deletes the group to a given @id and revokes
+//the permissions granted to it by the calendars shared with
+//it, so that a new group of the same name inherits none.
+func (db database) DeleteGroup(id string) error {
+	//1. Step: Remove the group from disk and from
+	//		   the collection.
+	//―――――――――――――――――――――――――――――――――――――――――――――
+	db.groups.mutex.Lock()
+	var group, ok = db.groups.groups[id]
+	if !ok {
+		db.groups.mutex.Unlock()
+		return model.ErrNotFound
+	}
+	var err = db.deleteGroup(id)
+	db.groups.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	//2. Step: Revoke the permissions of the group. The
+	//		   group lock is released before, as calendars
+	//		   are locked before groups elsewhere.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	return db.revokeGroup(group)
+}
+
+//revokeGroup removes the deleted group @g from the permissions
+//of the calendars shared with it. The caller must not hold the
+//group lock.
+func (db database) revokeGroup(g model.Group) error {
+	for _, reference := range g.Calendars {
+		var calMutex, ok = db.mutexes[reference.Link]
+		if !ok {
+			continue
+		}
+
+		calMutex.Lock()
+		var cal, exists = db.calendars[reference.Link]
+		var err error
+		if exists {
+			cal.Permissions.View.Group = withoutAttribute(cal.Permissions.View.Group, g.ID)
+			cal.Permissions.Edit.Group = withoutAttribute(cal.Permissions.Edit.Group, g.ID)
+			err = db.setCalendar(reference.Link, cal)
+		}
+		calMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//leaveGroups removes the user with the given @userID from
+//all groups. Groups losing their last admin are handed over
+//to their first member, groups losing their last member are
+//deleted and returned, so that the caller can revoke them
+//after releasing the write lock, which the caller must hold.
+func (db database) leaveGroups(userID string) ([]model.Group, error) {
+	var deleted []model.Group
+	for id, g := range db.groups.groups {
+		if !g.IsMember(userID) {
+			continue
+		}
+
+		g.Remove(userID)
+		if len(g.Admins) == 0 && len(g.Members) > 0 {
+			g.SetRole(g.Members[0], model.RoleAdmin)
+		}
+
+		if len(g.Admins) > 0 {
+			if err := db.setGroup(g); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+
+		if err := db.deleteGroup(id); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, g)
+	}
+	return deleted, nil
+}
+
+//unshareFromGroups unlists the calendar to a given @calID
+//from the groups it is shared with, e.g. after deleting it.
+func (db database) unshareFromGroups(cal model.Calendar, calID string) error {
+	db.groups.mutex.Lock()
+	defer db.groups.mutex.Unlock()
+
+	for _, entry := range append(cal.Permissions.View.Group, cal.Permissions.Edit.Group...) {
+		var g, ok = db.groups.groups[entry.Val]
+		if !ok {
+			continue
+		}
+		g.ShareCalendar(calID, model.None)
+		if err := db.setGroup(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setGroup writes the group @g to disk and to the collection.
+//The caller must hold the write lock.
+func (db database) setGroup(g model.Group) error {
+	if err := write(db.groupPath(g.ID), g.String()); err != nil {
+		return err
+	}
+	db.groups.groups[g.ID] = g
+	return nil
+}
+
+//deleteGroup removes the group file behind @id and the
+//collection entry. The caller must hold the write lock.
+func (db database) deleteGroup(id string) error {
+	delete(db.groups.groups, id)
+	if err := os.Remove(db.groupPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//groupPath returns the path of the group file of @id.
+func (db database) groupPath(id string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.GroupDir, id)
+}
+
+//withoutAttribute returns @attrs without the ones of value @val.
+func withoutAttribute(attrs []model.Attribute, val string) []model.Attribute {
+	var res []model.Attribute
+	for _, a := range attrs {
+		if a.Val != val {
+			res = append(res, a)
+		}
+	}
+	return res
+}
@@ -0,0 +1,75 @@
+package xmldb
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"sort"
+	"time"
+)
+
+//dateIndex lists the items of a calendar sorted by their end date,
+//so that items ending before a queried window can be skipped by
+//a binary search instead of looking at every item.
+type dateIndex []indexEntry
+
+//indexEntry points to an item of the calendar the index has been
+//built from. Items without a parsable date have zero start and end.
+type indexEntry struct {
+	start time.Time
+	end   time.Time
+	kind  model.ItemKind
+	idx   int
+}
+
+//newDateIndex builds the index of the given calendar. It has to be
+//rebuilt whenever the calendar changes, since it references items
+//by their position.
+func newDateIndex(cal model.Calendar) dateIndex {
+	var index dateIndex
+	add := func(kind model.ItemKind, idx int, start, end time.Time, err error) {
+		if err != nil {
+			start, end = time.Time{}, time.Time{}
+		}
+		index = append(index, indexEntry{start: start, end: end, kind: kind, idx: idx})
+	}
+
+	for i, a := range cal.Items.Appointments.Appointment {
+		var start, end, err = a.Span()
+		add(model.KindAppointment, i, start, end, err)
+	}
+	for i, m := range cal.Items.Milestones.Milestone {
+		var start, end, err = m.Span()
+		add(model.KindMilestone, i, start, end, err)
+	}
+	for i, t := range cal.Items.Tasks.Task {
+		var start, end, err = t.Span()
+		add(model.KindTask, i, start, end, err)
+	}
+
+	sort.SliceStable(index, func(i, j int) bool {
+		return index[i].end.Before(index[j].end)
+	})
+	return index
+}
+
+//window returns all entries overlapping the window of the query. If
+//the query isn't dated, all entries are returned, even undated ones.
+func (index dateIndex) window(q model.CalendarQuery) []indexEntry {
+	if !q.Dated() {
+		return index
+	}
+
+	//Skip everything ending before the window, this
+	//includes the undated entries with their zero time.
+	var first = sort.Search(len(index), func(i int) bool {
+		var e = index[i]
+		return !e.end.IsZero() && !e.end.Before(q.From)
+	})
+
+	var entries []indexEntry
+	for _, e := range index[first:] {
+		if q.InWindow(e.start, e.end) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,141 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//invitationStore holds the pending invitations to calendars. Each
+//invitation is stored in its own file named after its ID.
+type invitationStore struct {
+	mutex       sync.RWMutex
+	invitations map[string]model.Invitation
+}
+
+//loadInvitations parses all invitation files in @dir, dropping the
+//ones that have expired in the meantime.
+func loadInvitations(dir string) (*invitationStore, error) {
+	var store = &invitationStore{invitations: make(map[string]model.Invitation)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var invitation model.Invitation
+		if err := parse(file, &invitation); err != nil {
+			return err
+		}
+		if invitation.Expired(now) {
+			return os.Remove(file)
+		}
+		store.invitations[invitation.ID] = invitation
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddInvitation stores the invitation @i on disk and in the
+//collection. The invitee doesn't need to exist (yet).
+func (db database) AddInvitation(i model.Invitation) error {
+	db.invites.mutex.Lock()
+	defer db.invites.mutex.Unlock()
+
+	if _, ok := db.invites.invitations[i.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	return db.setInvitation(i)
+}
+
+//GetInvitation retrieves the invitation to a given @id. If the
+//invitation doesn't exist or has expired, an error is thrown.
+func (db database) GetInvitation(id string) (model.Invitation, error) {
+	db.invites.mutex.RLock()
+	defer db.invites.mutex.RUnlock()
+
+	var val, ok = db.invites.invitations[id]
+	if !ok || val.Expired(time.Now()) {
+		return model.Invitation{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//GetInvitations retrieves all unexpired invitations sent to or by
+//the user with the given @userID, the most recently created first.
+func (db database) GetInvitations(userID string) ([]model.Invitation, error) {
+	db.invites.mutex.RLock()
+	defer db.invites.mutex.RUnlock()
+
+	var now = time.Now()
+	var res []model.Invitation
+	for _, i := range db.invites.invitations {
+		if (i.To == userID || i.From == userID) && !i.Expired(now) {
+			res = append(res, i)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res, nil
+}
+
+//DeleteInvitation deletes the invitation to a given @id
+//from disk and from the collection.
+func (db database) DeleteInvitation(id string) error {
+	db.invites.mutex.Lock()
+	defer db.invites.mutex.Unlock()
+
+	if _, ok := db.invites.invitations[id]; !ok {
+		return model.ErrNotFound
+	}
+	return db.deleteInvitation(id)
+}
+
+//deleteInvitations deletes all invitations @match returns
+//true for. The caller must hold the write lock.
+func (db database) deleteInvitations(match func(model.Invitation) bool) error {
+	for id, i := range db.invites.invitations {
+		if !match(i) {
+			continue
+		}
+		if err := db.deleteInvitation(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setInvitation writes the invitation @i to disk and to the
+//collection. The caller must hold the write lock.
+func (db database) setInvitation(i model.Invitation) error {
+	if err := write(db.invitationPath(i.ID), i.String()); err != nil {
+		return err
+	}
+	db.invites.invitations[i.ID] = i
+	return nil
+}
+
+//deleteInvitation removes the invitation file behind @id and the
+//collection entry. The caller must hold the write lock.
+func (db database) deleteInvitation(id string) error {
+	delete(db.invites.invitations, id)
+	if err := os.Remove(db.invitationPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//invitationPath returns the path of the invitation file of @id.
+func (db database) invitationPath(id string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.InvitationDir, id)
+}
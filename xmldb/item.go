@@ -0,0 +1,98 @@
+package xmldb
+
+import (
+	"github.com/Project-Planner/backend/model"
+)
+
+//MoveItem moves the item of @kind with the given @itemID from the
+//calendar @fromID to the calendar @toID, keeping its ID.
+//If a calendar or the item doesn't exist, an error is thrown, as
+//well as if the target calendar already has an item of this ID.
+func (db database) MoveItem(fromID, toID string, kind model.ItemKind, itemID string) error {
+	var _, err = db.relocateItem(fromID, toID, kind, itemID, false)
+	return err
+}
+
+//CopyItem copies the item of @kind with the given @itemID from the
+//calendar @fromID to the calendar @toID and returns the fresh ID
+//of the copy.
+//If a calendar or the item doesn't exist, an error is thrown.
+func (db database) CopyItem(fromID, toID string, kind model.ItemKind, itemID string) (string, error) {
+	return db.relocateItem(fromID, toID, kind, itemID, true)
+}
+
+//relocateItem moves or, if @copy is set, copies an item between
+//two calendars. Both calendar files are written or, if any write
+//fails, none is changed, so that an item is never lost or
+//duplicated.
+func (db database) relocateItem(fromID, toID string, kind model.ItemKind, itemID string, copy bool) (string, error) {
+	//1. Step: Lock both calendars, always in the order of
+	//		   their IDs, so that concurrent moves in the
+	//		   opposite direction don't deadlock.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var fromMutex, ok = db.mutexes[fromID]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+	toMutex, ok := db.mutexes[toID]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+
+	var first, second = fromMutex, toMutex
+	if toID < fromID {
+		first, second = toMutex, fromMutex
+	}
+	first.Lock()
+	defer first.Unlock()
+	if fromID != toID {
+		second.Lock()
+		defer second.Unlock()
+	}
+
+	//2. Step: Move the item between the in-memory copies
+	//		   of the calendars.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	src, ok := db.calendars[fromID]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+	dst, ok := db.calendars[toID]
+	if !ok {
+		return "", model.ErrNotFound
+	}
+
+	var original = dst
+	var target = &dst
+	if fromID == toID {
+		target = &src
+	}
+	var id, err = model.MoveItem(&src, target, kind, itemID, copy)
+	if err != nil {
+		return "", err
+	}
+
+	//3. Step: Write the target calendar first, then the
+	//		   source. If the latter fails, the former is
+	//		   restored.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	if fromID == toID {
+		return id, db.setCalendar(toID, src)
+	}
+
+	var undo undoLog
+	undo.add(func() { db.setCalendar(toID, original) })
+	if err := db.setCalendar(toID, dst); err != nil {
+		return "", undo.rollback(err)
+	}
+	if copy {
+		return id, nil
+	}
+
+	var before = db.calendars[fromID]
+	undo.add(func() { db.setCalendar(fromID, before) })
+	if err := db.setCalendar(fromID, src); err != nil {
+		return "", undo.rollback(err)
+	}
+	return id, nil
+}
@@ -0,0 +1,82 @@
+package xmldb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"io"
+	"os"
+	"sync"
+)
+
+//lockoutLog holds all recorded lockouts in order of their occurrence.
+//They are stored in a single file, to which each lockout is appended,
+//so that a burst of lockouts doesn't rewrite the file over and over.
+type lockoutLog struct {
+	mutex    sync.Mutex
+	lockouts []model.Lockout
+}
+
+//loadLockouts parses the lockout file @path, if it exists. Lockouts
+//are read wherever they are, as files written before lockouts were
+//appended wrap them in a single lockouts element.
+func loadLockouts(path string) (*lockoutLog, error) {
+	var log = &lockoutLog{}
+	var file, err = os.Open(path)
+	if os.IsNotExist(err) {
+		return log, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var decoder = xml.NewDecoder(file)
+	for {
+		var token, err = decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var start, ok = token.(xml.StartElement)
+		if !ok || start.Name.Local != "lockout" {
+			continue
+		}
+		var l model.Lockout
+		if err := decoder.DecodeElement(&l, &start); err != nil {
+			return nil, err
+		}
+		log.lockouts = append(log.lockouts, l)
+	}
+	return log, nil
+}
+
+//AddLockout appends the lockout @l to the log on disk and in memory.
+func (db database) AddLockout(l model.Lockout) error {
+	db.lockouts.mutex.Lock()
+	defer db.lockouts.mutex.Unlock()
+
+	if err := appendLine(db.lockoutPath(), l.String()); err != nil {
+		return err
+	}
+	db.lockouts.lockouts = append(db.lockouts.lockouts, l)
+	return nil
+}
+
+//GetLockouts retrieves all recorded lockouts, the most recent first.
+func (db database) GetLockouts() ([]model.Lockout, error) {
+	db.lockouts.mutex.Lock()
+	defer db.lockouts.mutex.Unlock()
+
+	var res = make([]model.Lockout, len(db.lockouts.lockouts))
+	for i, l := range db.lockouts.lockouts {
+		res[len(res)-1-i] = l
+	}
+	return res, nil
+}
+
+//lockoutPath returns the path of the lockout file.
+func (db database) lockoutPath() string {
+	return fmt.Sprintf("%s/lockouts.xml", db.config.DBDir)
+}
@@ -0,0 +1,397 @@
+package xmldb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"sync"
+	"time"
+)
+
+//undoLog collects the steps undoing the writes of an operation
+//spanning several files, so that it is either applied completely
+//or not at all.
+type undoLog []func()
+
+//add registers @f to be run on rollback.
+func (u *undoLog) add(f func()) {
+	*u = append(*u, f)
+}
+
+//rollback undoes all registered writes, the latest first,
+//and returns @err.
+func (u undoLog) rollback(err error) error {
+	for i := len(u) - 1; i >= 0; i-- {
+		u[i]()
+	}
+	return err
+}
+
+//TransferCalendar hands the calendar to a given @calID over to
+//the user @newOwner, under the name @newName in the namespace of
+//the new owner. The previous owner keeps the permission @keep,
+//which may be model.None.
+//If the calendar or the new owner doesn't exist, an error is thrown,
+//as well as if the new owner already has a calendar named @newName.
+func (db database) TransferCalendar(calID, newOwner, newName string, keep model.Permission) error {
+	if _, ok := db.users[newOwner]; !ok {
+		return model.ErrNotFound
+	}
+	return db.rekeyCalendar(calID, newOwner, newName, keep, time.Time{})
+}
+
+//rekeyCalendar moves the calendar to a given @calID to the new ID
+//<@newOwner>/<@newName> and rewrites every reference to it: the
+//calendar references of the users, the groups it is shared with,
+//pending invitations, share links, access tokens restricted to
+//it, aliases and its deleted items. If the owner changes, the
+//previous one is granted @keep. If @redirectUntil is set, @calID
+//redirects to the new ID until then.
+//Either all files are rewritten or, if any write fails, the ones
+//written so far are restored and the error is returned.
+func (db database) rekeyCalendar(calID, newOwner, newName string, keep model.Permission,
+	redirectUntil time.Time) error {
+	//1. Step: Lock the calendar and reserve the new ID,
+	//		   so that no calendar of the same name can be
+	//		   created in the meantime.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var calMutex, ok = db.mutexes[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	calMutex.Lock()
+	defer calMutex.Unlock()
+
+	cal, ok := db.calendars[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	var oldOwner = cal.Owner.Val
+	var newID = fmt.Sprintf("%s/%s", newOwner, newName)
+	if newID == calID {
+		return nil
+	}
+	if _, ok := db.mutexes[newID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	var newMutex = new(sync.Mutex)
+	newMutex.Lock()
+	defer newMutex.Unlock()
+	db.mutexes[newID] = newMutex
+
+	var undo = undoLog{func() { delete(db.mutexes, newID) }}
+
+	//2. Step: Write the calendar under its new ID. On a
+	//		   transfer, the new owner is removed from the
+	//		   permitted users and the previous one added.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var moved = cal
+	moved.ID.Val = newID
+	moved.Name.Val = newName
+	moved.Owner.Val = newOwner
+	if newOwner != oldOwner {
+		moved.Permissions.View.User = withoutAttribute(cal.Permissions.View.User, newOwner)
+		moved.Permissions.Edit.User = withoutAttribute(cal.Permissions.Edit.User, newOwner)
+		var entry = model.Attribute{Val: oldOwner}
+		if keep == model.Read {
+			moved.Permissions.View.User = append(moved.Permissions.View.User, entry)
+		} else if keep == model.Edit {
+			moved.Permissions.Edit.User = append(moved.Permissions.Edit.User, entry)
+		}
+	}
+
+	if err := ensureDir(fmt.Sprintf("%s/%s", db.config.CalendarDir, newOwner)); err != nil {
+		return undo.rollback(err)
+	}
+	var newPath = db.calendarPath(newID)
+	if err := write(newPath, moved.String()); err != nil {
+		return undo.rollback(err)
+	}
+	undo.add(func() { os.Remove(newPath) })
+
+	//3. Step: Rewrite the references of the users and of
+	//		   all other resources pointing to the calendar.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var err = db.rekeyUsers(calID, newID, oldOwner, newOwner, keep, &undo)
+	if err == nil {
+		err = db.rekeyGroups(calID, newID, &undo)
+	}
+	if err == nil {
+		err = db.rekeyInvitations(calID, newID, oldOwner, newOwner, &undo)
+	}
+	if err == nil {
+		err = db.rekeyShareLinks(calID, newID, newOwner, &undo)
+	}
+	if err == nil {
+		err = db.rekeyAccessTokens(calID, newID, &undo)
+	}
+	if err == nil {
+		err = db.rekeyAliases(calID, newID, redirectUntil, &undo)
+	}
+	if err == nil {
+		err = db.rekeyAuditLog(calID, newID, &undo)
+	}
+	if err == nil {
+		err = db.rekeyRevisions(calID, newID, &undo)
+	}
+	if err == nil {
+		err = db.rekeyTrash(calID, newID, &undo)
+	}
+	if err != nil {
+		return undo.rollback(err)
+	}
+
+	//4. Step: Remove the old file as the last write and
+	//		   move the calendar in the collections.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := os.Remove(db.calendarPath(calID)); err != nil && !os.IsNotExist(err) {
+		return undo.rollback(err)
+	}
+
+	delete(db.calendars, calID)
+	delete(db.indexes, calID)
+	db.search.delete(calID)
+	delete(db.mutexes, calID)
+
+	db.calendars[newID] = moved
+	db.indexes[newID] = newDateIndex(moved)
+	db.search.index(newID, moved)
+
+	return nil
+}
+
+//rekeyUsers rewrites the calendar references to @calID of all
+//users to @newID. On a transfer, the reference of the new owner
+//becomes the owning one and the previous owner is left with @keep.
+func (db database) rekeyUsers(calID, newID, oldOwner, newOwner string, keep model.Permission, undo *undoLog) error {
+	var userIDs []string
+	for userID := range db.users {
+		userIDs = append(userIDs, userID)
+	}
+
+	for _, userID := range userIDs {
+		//Locking the user before reading it is crucial
+		//in order not to lose concurrent modifications
+		//of the same user.
+		var userMutex, ok = db.mutexes[userID]
+		if !ok {
+			continue
+		}
+		userMutex.Lock()
+		var user, exists = db.users[userID]
+		var refs, before, found = rekeyReferences(user.Items.Calendars, userID, calID, newID, oldOwner, newOwner,
+			keep)
+		if !exists || (!found && userID != newOwner) {
+			userMutex.Unlock()
+			continue
+		}
+
+		var original = user
+		user.Items.Calendars = refs
+		var err = db.setUser(userID, user)
+		userMutex.Unlock()
+		if err != nil {
+			db.users[userID] = original
+			return err
+		}
+
+		//Only the reference is rolled back, the user
+		//may have been modified otherwise meanwhile.
+		var id = userID
+		undo.add(func() {
+			userMutex.Lock()
+			defer userMutex.Unlock()
+			var current = db.users[id]
+			var refs []model.CalendarReference
+			var restored = false
+			for _, ref := range current.Items.Calendars {
+				if ref.Link != newID {
+					refs = append(refs, ref)
+				} else if found {
+					refs = append(refs, before)
+					restored = true
+				}
+			}
+			if found && !restored {
+				refs = append(refs, before)
+			}
+			current.Items.Calendars = refs
+			db.setUser(id, current)
+		})
+	}
+	return nil
+}
+
+//rekeyReferences returns the calendar references @refs of the
+//user @userID with the reference to @calID rewritten to @newID,
+//along with the former reference and whether there has been one.
+func rekeyReferences(refs []model.CalendarReference, userID, calID, newID, oldOwner, newOwner string,
+	keep model.Permission) ([]model.CalendarReference, model.CalendarReference, bool) {
+	var res []model.CalendarReference
+	var before model.CalendarReference
+	var found = false
+	for _, ref := range refs {
+		if ref.Link != calID {
+			res = append(res, ref)
+			continue
+		}
+
+		found, before = true, ref
+		ref.Link = newID
+		if userID == newOwner {
+			ref.Perm = model.Owner.String()
+		} else if userID == oldOwner && oldOwner != newOwner {
+			if keep == model.None {
+				continue
+			}
+			ref.Perm = keep.String()
+		}
+		res = append(res, ref)
+	}
+
+	if !found && userID == newOwner {
+		res = append(res, model.CalendarReference{
+			XMLName: xml.Name{Local: "calendar"},
+			Link:    newID,
+			Perm:    model.Owner.String(),
+		})
+	}
+	return res, before, found
+}
+
+//rekeyGroups rewrites the references to @calID of the groups
+//the calendar is shared with to @newID.
+func (db database) rekeyGroups(calID, newID string, undo *undoLog) error {
+	db.groups.mutex.Lock()
+	defer db.groups.mutex.Unlock()
+
+	for _, g := range db.groups.groups {
+		var original = g
+		var refs []model.CalendarReference
+		var found = false
+		for _, ref := range g.Calendars {
+			if ref.Link == calID {
+				found = true
+				ref.Link = newID
+			}
+			refs = append(refs, ref)
+		}
+		if !found {
+			continue
+		}
+
+		g.Calendars = refs
+		if err := db.setGroup(g); err != nil {
+			return err
+		}
+		undo.add(func() {
+			db.groups.mutex.Lock()
+			db.setGroup(original)
+			db.groups.mutex.Unlock()
+		})
+	}
+	return nil
+}
+
+//rekeyInvitations rewrites the pending invitations to @calID to
+//@newID. On a transfer, they are sent on behalf of the new owner,
+//except for the ones to the new owner, which are deleted.
+func (db database) rekeyInvitations(calID, newID, oldOwner, newOwner string, undo *undoLog) error {
+	db.invites.mutex.Lock()
+	defer db.invites.mutex.Unlock()
+
+	for id, i := range db.invites.invitations {
+		if i.Calendar != calID {
+			continue
+		}
+
+		var original = i
+		var err error
+		if i.To == newOwner {
+			err = db.deleteInvitation(id)
+		} else {
+			i.Calendar = newID
+			if i.From == oldOwner {
+				i.From = newOwner
+			}
+			err = db.setInvitation(i)
+		}
+		if err != nil {
+			return err
+		}
+		undo.add(func() {
+			db.invites.mutex.Lock()
+			db.setInvitation(original)
+			db.invites.mutex.Unlock()
+		})
+	}
+	return nil
+}
+
+//rekeyShareLinks rewrites the share links of @calID to @newID,
+//so that they keep working and are managed by @newOwner.
+func (db database) rekeyShareLinks(calID, newID, newOwner string, undo *undoLog) error {
+	db.links.mutex.Lock()
+	defer db.links.mutex.Unlock()
+
+	for _, l := range db.links.links {
+		if l.Calendar != calID {
+			continue
+		}
+
+		var original = l
+		l.Calendar = newID
+		l.Owner = newOwner
+		if err := db.setShareLink(l); err != nil {
+			return err
+		}
+		undo.add(func() {
+			db.links.mutex.Lock()
+			db.setShareLink(original)
+			db.links.mutex.Unlock()
+		})
+	}
+	return nil
+}
+
+//rekeyAccessTokens rewrites the access tokens restricted to @calID
+//to @newID.
+func (db database) rekeyAccessTokens(calID, newID string, undo *undoLog) error {
+	db.tokens.mutex.Lock()
+	defer db.tokens.mutex.Unlock()
+
+	for _, t := range db.tokens.tokens {
+		var original = t
+		var calendars []string
+		var found = false
+		for _, c := range t.Calendars {
+			if c == calID {
+				found = true
+				c = newID
+			}
+			calendars = append(calendars, c)
+		}
+		if !found {
+			continue
+		}
+
+		t.Calendars = calendars
+		if err := db.setAccessToken(t); err != nil {
+			return err
+		}
+		undo.add(func() {
+			db.tokens.mutex.Lock()
+			db.setAccessToken(original)
+			db.tokens.mutex.Unlock()
+		})
+	}
+	return nil
+}
+
+//calendarPath returns the path of the calendar file of @calID.
+func (db database) calendarPath(calID string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.CalendarDir, calID)
+}
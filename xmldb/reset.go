@@ -0,0 +1,135 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//maxResetTokens is the number of open reset tokens per user. Adding
+//another one drops the one expiring first.
+const maxResetTokens = 3
+
+//resetStore holds the password reset tokens of all users. Each token
+//is stored in its own file named after its hash.
+type resetStore struct {
+	mutex  sync.Mutex
+	tokens map[string]model.ResetToken
+}
+
+//loadResetTokens parses all reset token files in @dir, dropping the
+//ones that have expired in the meantime.
+func loadResetTokens(dir string) (*resetStore, error) {
+	var store = &resetStore{tokens: make(map[string]model.ResetToken)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var token model.ResetToken
+		if err := parse(file, &token); err != nil {
+			return err
+		}
+		if token.Expired(now) {
+			return os.Remove(file)
+		}
+		store.tokens[token.Hash] = token
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddResetToken stores the reset token @t on disk and in the collection.
+//Only the latest maxResetTokens tokens of its user are kept.
+func (db database) AddResetToken(t model.ResetToken) error {
+	db.resets.mutex.Lock()
+	defer db.resets.mutex.Unlock()
+
+	if _, ok := db.resets.tokens[t.Hash]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	var open []model.ResetToken
+	for _, token := range db.resets.tokens {
+		if token.User == t.User {
+			open = append(open, token)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Expires.Before(open[j].Expires) })
+	for ; len(open) >= maxResetTokens; open = open[1:] {
+		delete(db.resets.tokens, open[0].Hash)
+		if err := os.Remove(db.resetPath(open[0].Hash)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := write(db.resetPath(t.Hash), t.String()); err != nil {
+		return err
+	}
+	db.resets.tokens[t.Hash] = t
+	return nil
+}
+
+//GetResetToken retrieves the reset token to a given @hash, keeping it.
+//If the token doesn't exist or has expired, an error is thrown.
+func (db database) GetResetToken(hash string) (model.ResetToken, error) {
+	db.resets.mutex.Lock()
+	defer db.resets.mutex.Unlock()
+
+	var token, ok = db.resets.tokens[hash]
+	if !ok || token.Expired(time.Now()) {
+		return model.ResetToken{}, model.ErrNotFound
+	}
+	return token, nil
+}
+
+//ConsumeResetToken retrieves the reset token to a given @hash and
+//deletes all reset tokens of its user, so that it can't be used twice.
+//If the token doesn't exist or has expired, an error is thrown.
+func (db database) ConsumeResetToken(hash string) (model.ResetToken, error) {
+	db.resets.mutex.Lock()
+	defer db.resets.mutex.Unlock()
+
+	var token, ok = db.resets.tokens[hash]
+	if !ok {
+		return model.ResetToken{}, model.ErrNotFound
+	}
+
+	if err := db.deleteResetTokens(token.User); err != nil {
+		return model.ResetToken{}, err
+	}
+
+	if token.Expired(time.Now()) {
+		return model.ResetToken{}, model.ErrNotFound
+	}
+	return token, nil
+}
+
+//deleteResetTokens removes all reset tokens of the user with the given
+//@userID. The caller must hold the lock.
+func (db database) deleteResetTokens(userID string) error {
+	for hash, t := range db.resets.tokens {
+		if t.User != userID {
+			continue
+		}
+
+		delete(db.resets.tokens, hash)
+		if err := os.Remove(db.resetPath(hash)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+//resetPath returns the path of the reset token file of @hash.
+func (db database) resetPath(hash string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.ResetDir, hash)
+}
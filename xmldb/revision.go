@@ -0,0 +1,251 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//defaultRevisionRetention is how long revisions are kept,
+//if the config doesn't say otherwise.
+const defaultRevisionRetention = 30 * 24 * time.Hour
+
+//defaultRevisionLimit is how many revisions are kept per calendar,
+//if the config doesn't say otherwise.
+const defaultRevisionLimit = 50
+
+//revisionStore guards the revisions of the calendars. Each prior
+//version of a calendar is stored in its own file, in a directory
+//placed like the calendar file: <owner>/<name>/<ID>.xml. They are
+//dropped once they are older than the configured retention or
+//once the calendar has more than the configured limit. As the
+//history of an item is read from all revisions, the revisions of
+//a calendar are parsed once, when they are first read, and kept
+//in memory from then on.
+type revisionStore struct {
+	mutex  sync.Mutex
+	parsed map[string][]model.Revision
+}
+
+//GetRevisions retrieves the retained revisions of @calID, the
+//most recent first. If the calendar doesn't exist, an error is
+//thrown.
+func (db database) GetRevisions(calID string) ([]model.Revision, error) {
+	if _, ok := db.mutexes[calID]; !ok {
+		return nil, model.ErrNotFound
+	}
+
+	db.revisions.mutex.Lock()
+	defer db.revisions.mutex.Unlock()
+	return db.revisionsOf(calID)
+}
+
+//GetRevision retrieves the revision @revID of @calID. If either
+//doesn't exist or the revision is not retained anymore, an error
+//is thrown.
+func (db database) GetRevision(calID, revID string) (model.Revision, error) {
+	if _, ok := db.mutexes[calID]; !ok {
+		return model.Revision{}, model.ErrNotFound
+	}
+
+	db.revisions.mutex.Lock()
+	defer db.revisions.mutex.Unlock()
+
+	if err := db.pruneRevisions(calID); err != nil {
+		return model.Revision{}, err
+	}
+	for _, rev := range db.revisions.parsed[calID] {
+		if rev.ID == revID {
+			return rev, nil
+		}
+	}
+
+	//IDs are checked, as they are part of the path
+	var path = db.revisionPath(calID, revID)
+	if strings.ContainsAny(revID, `/\.`) || !exists(path) {
+		return model.Revision{}, model.ErrNotFound
+	}
+
+	var rev model.Revision
+	if err := parse(path, &rev); err != nil {
+		return model.Revision{}, err
+	}
+	return rev, nil
+}
+
+//RollbackCalendar restores the items and the description @calID
+//had at time @t. The current version is kept as a revision, so
+//that the rollback can be undone. If the calendar doesn't exist,
+//an error is thrown, as well as if @t lies beyond the retention.
+func (db database) RollbackCalendar(calID string, t time.Time) error {
+	var mutex, ok = db.mutexes[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+	if t.Before(time.Now().Add(-db.config.RevisionRetention)) {
+		return model.ErrExpired
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cal, ok := db.calendars[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	db.revisions.mutex.Lock()
+	var revs, err = db.revisionsOf(calID)
+	db.revisions.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return db.setCalendar(calID, cal.AsOf(revs, t))
+}
+
+//addRevision keeps @cal as the version of @calID replaced just
+//now. The caller must hold the lock of the calendar.
+func (db database) addRevision(calID string, cal model.Calendar) error {
+	db.revisions.mutex.Lock()
+	defer db.revisions.mutex.Unlock()
+
+	var id, err = uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	var rev = model.Revision{ID: id.String(), Until: time.Now(), Calendar: cal}
+
+	var path = db.revisionPath(calID, rev.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := write(path, rev.String()); err != nil {
+		return err
+	}
+	if revs, ok := db.revisions.parsed[calID]; ok {
+		db.revisions.parsed[calID] = append([]model.Revision{rev}, revs...)
+	}
+	return db.pruneRevisions(calID)
+}
+
+//revisionsOf returns the retained revisions of @calID, the most
+//recent first. They are parsed only if they haven't been before.
+//The caller must hold the lock.
+func (db database) revisionsOf(calID string) ([]model.Revision, error) {
+	if err := db.pruneRevisions(calID); err != nil {
+		return nil, err
+	}
+	if revs, ok := db.revisions.parsed[calID]; ok {
+		return append([]model.Revision(nil), revs...), nil
+	}
+
+	var infos, err = ioutil.ReadDir(db.revisionDir(calID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var revs = make([]model.Revision, 0, len(infos))
+	for _, info := range infos {
+		var rev model.Revision
+		if err := parse(filepath.Join(db.revisionDir(calID), info.Name()), &rev); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+
+	sort.Slice(revs, func(i, j int) bool { return revs[i].Until.After(revs[j].Until) })
+	db.revisions.parsed[calID] = revs
+	return append([]model.Revision(nil), revs...), nil
+}
+
+//pruneRevisions removes the revisions of @calID which have been
+//written before the retention or exceed the limit, the oldest
+//first. The caller must hold the lock.
+func (db database) pruneRevisions(calID string) error {
+	var infos, err = ioutil.ReadDir(db.revisionDir(calID))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ModTime().After(infos[j].ModTime()) })
+
+	var cutoff = time.Now().Add(-db.config.RevisionRetention)
+	var removed = make(map[string]bool)
+	for i, info := range infos {
+		if i < db.config.RevisionLimit && !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(db.revisionDir(calID), info.Name())); err != nil {
+			return err
+		}
+		removed[strings.TrimSuffix(info.Name(), ".xml")] = true
+	}
+
+	if revs, ok := db.revisions.parsed[calID]; ok && len(removed) > 0 {
+		var kept = make([]model.Revision, 0, len(revs))
+		for _, rev := range revs {
+			if !removed[rev.ID] {
+				kept = append(kept, rev)
+			}
+		}
+		db.revisions.parsed[calID] = kept
+	}
+	return nil
+}
+
+//rekeyRevisions moves the revisions of @calID to @newID along
+//with the calendar.
+func (db database) rekeyRevisions(calID, newID string, undo *undoLog) error {
+	db.revisions.mutex.Lock()
+	defer db.revisions.mutex.Unlock()
+
+	//parsed again, once read under the new ID
+	delete(db.revisions.parsed, calID)
+
+	var dir, newDir = db.revisionDir(calID), db.revisionDir(newID)
+	if !exists(dir) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(dir, newDir); err != nil {
+		return err
+	}
+	undo.add(func() {
+		db.revisions.mutex.Lock()
+		delete(db.revisions.parsed, newID)
+		os.Rename(newDir, dir)
+		db.revisions.mutex.Unlock()
+	})
+	return nil
+}
+
+//deleteRevisions removes all revisions of @calID.
+func (db database) deleteRevisions(calID string) error {
+	db.revisions.mutex.Lock()
+	defer db.revisions.mutex.Unlock()
+	delete(db.revisions.parsed, calID)
+	return os.RemoveAll(db.revisionDir(calID))
+}
+
+//revisionDir returns the directory of the revisions of @calID.
+func (db database) revisionDir(calID string) string {
+	return fmt.Sprintf("%s/%s", db.config.RevisionDir, calID)
+}
+
+//revisionPath returns the path of the revision @revID of @calID.
+func (db database) revisionPath(calID, revID string) string {
+	return fmt.Sprintf("%s/%s.xml", db.revisionDir(calID), revID)
+}
@@ -0,0 +1,227 @@
+package xmldb
+
+import (
+	"github.com/Project-Planner/backend/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//snippetWidth is the number of characters of a result snippet.
+const snippetWidth = 80
+
+//searchIndex is an inverted index mapping the words of names and
+//descriptions of calendars and their items to the documents containing
+//them. It is kept in memory only and rebuilt from the calendars on
+//start-up.
+type searchIndex struct {
+	mutex sync.RWMutex
+	//postings maps a word to the documents containing it.
+	postings map[string]map[document]struct{}
+	//texts holds name and description of each indexed document.
+	texts map[document]documentText
+	//documents lists the documents of each calendar, so that they can
+	//be removed when the calendar is reindexed.
+	documents map[string][]document
+	//words is the sorted list of all keys of postings, used for prefix
+	//matching. It is nil if it has to be rebuilt.
+	words []string
+}
+
+//document is a calendar (with empty id) or an item of a calendar.
+type document struct {
+	calID string
+	kind  model.ItemKind
+	id    string
+}
+
+type documentText struct {
+	name string
+	desc string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings:  make(map[string]map[document]struct{}),
+		texts:     make(map[document]documentText),
+		documents: make(map[string][]document),
+	}
+}
+
+//index replaces all documents of the given calendar by its current
+//name, description and items.
+func (index *searchIndex) index(calID string, cal model.Calendar) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.remove(calID)
+
+	index.add(document{calID, model.KindCalendar, ""}, cal.Name.Val, cal.Desc)
+	for _, a := range cal.Items.Appointments.Appointment {
+		index.add(document{calID, model.KindAppointment, a.ID}, a.Name.Val, a.Desc)
+	}
+	for _, m := range cal.Items.Milestones.Milestone {
+		index.add(document{calID, model.KindMilestone, m.ID}, m.Name.Val, m.Desc)
+	}
+	for _, t := range cal.Items.Tasks.Task {
+		index.add(document{calID, model.KindTask, t.ID}, t.Name.Val, t.Desc)
+	}
+}
+
+//delete removes all documents of the given calendar.
+func (index *searchIndex) delete(calID string) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.remove(calID)
+}
+
+//add indexes a single document. The caller must hold the write lock.
+func (index *searchIndex) add(doc document, name, desc string) {
+	index.texts[doc] = documentText{name: name, desc: desc}
+	index.documents[doc.calID] = append(index.documents[doc.calID], doc)
+
+	for _, word := range append(model.Tokenize(name), model.Tokenize(desc)...) {
+		var docs, ok = index.postings[word]
+		if !ok {
+			docs = make(map[document]struct{})
+			index.postings[word] = docs
+			index.words = nil
+		}
+		docs[doc] = struct{}{}
+	}
+}
+
+//remove drops all documents of the given calendar. The caller must
+//hold the write lock.
+func (index *searchIndex) remove(calID string) {
+	for _, doc := range index.documents[calID] {
+		var text = index.texts[doc]
+		for _, word := range append(model.Tokenize(text.name), model.Tokenize(text.desc)...) {
+			var docs = index.postings[word]
+			delete(docs, doc)
+			if len(docs) == 0 {
+				delete(index.postings, word)
+				index.words = nil
+			}
+		}
+		delete(index.texts, doc)
+	}
+	delete(index.documents, calID)
+}
+
+//search returns all documents within the queried calendars containing
+//words starting with each of the terms, the best matches first.
+func (index *searchIndex) search(q model.SearchQuery) model.SearchResults {
+	var words = index.sortedWords()
+
+	index.mutex.RLock()
+	defer index.mutex.RUnlock()
+
+	var calendars = make(map[string]bool)
+	for _, calID := range q.Calendars {
+		calendars[calID] = true
+	}
+
+	//1. Step: Intersect the documents matching each term,
+	//		   beginning with all documents of the calendars.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var matches map[document]struct{}
+	for _, term := range q.Terms {
+		var found = make(map[document]struct{})
+		for i := sort.SearchStrings(words, term); i < len(words) && strings.HasPrefix(words[i], term); i++ {
+			for doc := range index.postings[words[i]] {
+				if matches == nil && calendars[doc.calID] {
+					found[doc] = struct{}{}
+				} else if _, ok := matches[doc]; ok {
+					found[doc] = struct{}{}
+				}
+			}
+		}
+		matches = found
+	}
+
+	//2. Step: Count the facets and build the results
+	//		   of the requested kinds.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――
+	var res = model.SearchResults{Query: strings.Join(q.Terms, " ")}
+	var facets = make(map[model.ItemKind]int)
+	for doc := range matches {
+		facets[doc.kind]++
+		if !q.HasKind(doc.kind) {
+			continue
+		}
+
+		var text = index.texts[doc]
+		res.Results = append(res.Results, model.SearchResult{
+			Kind:     doc.kind,
+			ID:       doc.id,
+			Calendar: doc.calID,
+			Name:     text.name,
+			Snippet:  model.Snippet(text.desc, q.Terms, snippetWidth),
+			Score:    score(text, q.Terms),
+		})
+	}
+
+	for _, kind := range append([]model.ItemKind{model.KindCalendar}, model.ItemKinds...) {
+		if facets[kind] > 0 {
+			res.Facets = append(res.Facets, model.SearchFacet{Kind: kind, Count: facets[kind]})
+		}
+	}
+
+	sort.Slice(res.Results, func(i, j int) bool {
+		var a, b = res.Results[i], res.Results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Calendar+a.ID < b.Calendar+b.ID
+	})
+
+	res.Total = len(res.Results)
+	if q.Limit > 0 && len(res.Results) > q.Limit {
+		res.Results = res.Results[:q.Limit]
+	}
+	return res
+}
+
+//sortedWords returns the sorted list of all indexed words, rebuilding
+//it if documents have been added or removed since.
+func (index *searchIndex) sortedWords() []string {
+	index.mutex.RLock()
+	var words = index.words
+	index.mutex.RUnlock()
+	if words != nil {
+		return words
+	}
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	words = make([]string, 0, len(index.postings))
+	for word := range index.postings {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	index.words = words
+	return words
+}
+
+//score rates how well a document matches the terms; words of the name
+//count twice as much as those of the description.
+func score(text documentText, terms []string) int {
+	var count = func(s string, weight int) int {
+		var sum int
+		for _, word := range model.Tokenize(s) {
+			for _, term := range terms {
+				if strings.HasPrefix(word, term) {
+					sum += weight
+				}
+			}
+		}
+		return sum
+	}
+	return count(text.name, 2) + count(text.desc, 1)
+}
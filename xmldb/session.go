@@ -0,0 +1,175 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//sessionStore holds the sessions of all users. Each session is stored
+//in its own file named after its token ID. Since sessions are created and
+//revoked independently of the user resource, they are guarded by their
+//own lock instead of the user's mutex.
+type sessionStore struct {
+	mutex    sync.RWMutex
+	sessions map[string]model.Session
+}
+
+//loadSessions parses all session files in @dir, dropping the ones
+//that have expired in the meantime.
+func loadSessions(dir string) (*sessionStore, error) {
+	var store = &sessionStore{sessions: make(map[string]model.Session)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var session model.Session
+		if err := parse(file, &session); err != nil {
+			return err
+		}
+		if session.Expired(now) {
+			return os.Remove(file)
+		}
+		store.sessions[session.ID] = session
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddSession stores the session @s on disk and in the collection.
+func (db database) AddSession(s model.Session) error {
+	db.sessions.mutex.Lock()
+	defer db.sessions.mutex.Unlock()
+
+	if _, ok := db.sessions.sessions[s.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	s.Current = false
+	if err := write(db.sessionPath(s.ID), s.String()); err != nil {
+		return err
+	}
+	db.sessions.sessions[s.ID] = s
+	return nil
+}
+
+//SetSession overwrites the session with the ID of @s, on disk
+//as well as in the collection, if it yet exists.
+func (db database) SetSession(s model.Session) error {
+	db.sessions.mutex.Lock()
+	defer db.sessions.mutex.Unlock()
+
+	if _, ok := db.sessions.sessions[s.ID]; !ok {
+		return model.ErrNotFound
+	}
+
+	s.Current = false
+	if err := write(db.sessionPath(s.ID), s.String()); err != nil {
+		return err
+	}
+	db.sessions.sessions[s.ID] = s
+	return nil
+}
+
+//GetSession retrieves the session to a given @tokenID.
+//If the session doesn't exist or has expired, an error is thrown.
+func (db database) GetSession(tokenID string) (model.Session, error) {
+	db.sessions.mutex.RLock()
+	defer db.sessions.mutex.RUnlock()
+
+	var val, ok = db.sessions.sessions[tokenID]
+	if !ok || val.Expired(time.Now()) {
+		return model.Session{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//GetSessions retrieves all unexpired sessions of the user with
+//the given @userID, the most recently created first.
+func (db database) GetSessions(userID string) ([]model.Session, error) {
+	db.sessions.mutex.RLock()
+	defer db.sessions.mutex.RUnlock()
+
+	var now = time.Now()
+	var res []model.Session
+	for _, s := range db.sessions.sessions {
+		if s.User == userID && !s.Expired(now) {
+			res = append(res, s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res, nil
+}
+
+//DeleteSession revokes the session to a given @tokenID by
+//removing it from disk and from the collection.
+func (db database) DeleteSession(tokenID string) error {
+	db.sessions.mutex.Lock()
+	defer db.sessions.mutex.Unlock()
+
+	if _, ok := db.sessions.sessions[tokenID]; !ok {
+		return model.ErrNotFound
+	}
+	return db.deleteSession(tokenID)
+}
+
+//DeleteSessions revokes all sessions of the user with the given
+//@userID, except the one with the token ID @except.
+func (db database) DeleteSessions(userID, except string) error {
+	db.sessions.mutex.Lock()
+	defer db.sessions.mutex.Unlock()
+
+	for id, s := range db.sessions.sessions {
+		if s.User != userID || id == except {
+			continue
+		}
+		if err := db.deleteSession(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//PurgeSessions removes all sessions which expired until @t
+//from disk and from the collection.
+func (db database) PurgeSessions(t time.Time) error {
+	db.sessions.mutex.Lock()
+	defer db.sessions.mutex.Unlock()
+
+	for id, s := range db.sessions.sessions {
+		if !s.Expired(t) {
+			continue
+		}
+		if err := db.deleteSession(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//deleteSession removes the session file behind @tokenID and the
+//collection entry. The caller must hold the write lock.
+func (db database) deleteSession(tokenID string) error {
+	delete(db.sessions.sessions, tokenID)
+	if err := os.Remove(db.sessionPath(tokenID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//sessionPath returns the path of the session file of @tokenID.
+func (db database) sessionPath(tokenID string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.SessionDir, tokenID)
+}
@@ -0,0 +1,157 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//linkStore holds the public share links of all calendars. Each link
+//is stored in its own file named after its ID.
+type linkStore struct {
+	mutex sync.RWMutex
+	links map[string]model.ShareLink
+}
+
+//loadShareLinks parses all share link files in @dir, dropping the
+//ones that have expired in the meantime.
+func loadShareLinks(dir string) (*linkStore, error) {
+	var store = &linkStore{links: make(map[string]model.ShareLink)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var link model.ShareLink
+		if err := parse(file, &link); err != nil {
+			return err
+		}
+		if link.Expired(now) {
+			return os.Remove(file)
+		}
+		store.links[link.ID] = link
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddShareLink stores the share link @l on disk and in the
+//collection. The plain token and URL are never stored.
+func (db database) AddShareLink(l model.ShareLink) error {
+	db.links.mutex.Lock()
+	defer db.links.mutex.Unlock()
+
+	if _, ok := db.links.links[l.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	l.Token, l.URL = "", ""
+	return db.setShareLink(l)
+}
+
+//GetShareLink retrieves the share link to a given @id.
+//If the link doesn't exist or has expired, an error is thrown.
+func (db database) GetShareLink(id string) (model.ShareLink, error) {
+	db.links.mutex.RLock()
+	defer db.links.mutex.RUnlock()
+
+	var val, ok = db.links.links[id]
+	if !ok || val.Expired(time.Now()) {
+		return model.ShareLink{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//GetShareLinks retrieves all unexpired share links of the user
+//with the given @userID, the most recently created first.
+func (db database) GetShareLinks(userID string) ([]model.ShareLink, error) {
+	db.links.mutex.RLock()
+	defer db.links.mutex.RUnlock()
+
+	var now = time.Now()
+	var res []model.ShareLink
+	for _, l := range db.links.links {
+		if l.Owner == userID && !l.Expired(now) {
+			res = append(res, l)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res, nil
+}
+
+//CountShareLinkAccess increments the access counter of the share
+//link to a given @id and records the time @at of the access.
+func (db database) CountShareLinkAccess(id string, at time.Time) error {
+	db.links.mutex.Lock()
+	defer db.links.mutex.Unlock()
+
+	var l, ok = db.links.links[id]
+	if !ok {
+		return model.ErrNotFound
+	}
+	l.Accesses++
+	l.LastAccess = &at
+	return db.setShareLink(l)
+}
+
+//DeleteShareLink revokes the share link to a given @id by
+//removing it from disk and from the collection.
+func (db database) DeleteShareLink(id string) error {
+	db.links.mutex.Lock()
+	defer db.links.mutex.Unlock()
+
+	if _, ok := db.links.links[id]; !ok {
+		return model.ErrNotFound
+	}
+	return db.deleteShareLink(id)
+}
+
+//deleteShareLinks revokes all share links @match returns
+//true for. The caller must hold the write lock.
+func (db database) deleteShareLinks(match func(model.ShareLink) bool) error {
+	for id, l := range db.links.links {
+		if !match(l) {
+			continue
+		}
+		if err := db.deleteShareLink(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setShareLink writes the share link @l to disk and to the
+//collection. The caller must hold the write lock.
+func (db database) setShareLink(l model.ShareLink) error {
+	if err := write(db.linkPath(l.ID), l.String()); err != nil {
+		return err
+	}
+	db.links.links[l.ID] = l
+	return nil
+}
+
+//deleteShareLink removes the link file behind @id and the
+//collection entry. The caller must hold the write lock.
+func (db database) deleteShareLink(id string) error {
+	delete(db.links.links, id)
+	if err := os.Remove(db.linkPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//linkPath returns the path of the share link file of @id.
+func (db database) linkPath(id string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.LinkDir, id)
+}
@@ -0,0 +1,142 @@
+package xmldb
+
+import (
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//tokenStore holds the personal access tokens of all users. Each token
+//is stored in its own file named after its ID.
+type tokenStore struct {
+	mutex  sync.RWMutex
+	tokens map[string]model.AccessToken
+}
+
+//loadAccessTokens parses all access token files in @dir, dropping the
+//ones that have expired in the meantime.
+func loadAccessTokens(dir string) (*tokenStore, error) {
+	var store = &tokenStore{tokens: make(map[string]model.AccessToken)}
+	var now = time.Now()
+	if err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".xml") {
+			return nil
+		}
+
+		var token model.AccessToken
+		if err := parse(file, &token); err != nil {
+			return err
+		}
+		if token.Expired(now) {
+			return os.Remove(file)
+		}
+		store.tokens[token.ID] = token
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+//AddAccessToken stores the access token @t on disk and in the
+//collection. The plain token is never stored.
+func (db database) AddAccessToken(t model.AccessToken) error {
+	db.tokens.mutex.Lock()
+	defer db.tokens.mutex.Unlock()
+
+	if _, ok := db.tokens.tokens[t.ID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	t.Token = ""
+	return db.setAccessToken(t)
+}
+
+//GetAccessToken retrieves the access token to a given @id.
+//If the token doesn't exist or has expired, an error is thrown.
+func (db database) GetAccessToken(id string) (model.AccessToken, error) {
+	db.tokens.mutex.RLock()
+	defer db.tokens.mutex.RUnlock()
+
+	var val, ok = db.tokens.tokens[id]
+	if !ok || val.Expired(time.Now()) {
+		return model.AccessToken{}, model.ErrNotFound
+	}
+	return val, nil
+}
+
+//GetAccessTokens retrieves all unexpired access tokens of the user
+//with the given @userID, the most recently created first.
+func (db database) GetAccessTokens(userID string) ([]model.AccessToken, error) {
+	db.tokens.mutex.RLock()
+	defer db.tokens.mutex.RUnlock()
+
+	var now = time.Now()
+	var res []model.AccessToken
+	for _, t := range db.tokens.tokens {
+		if t.User == userID && !t.Expired(now) {
+			res = append(res, t)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Created.After(res[j].Created)
+	})
+	return res, nil
+}
+
+//DeleteAccessToken revokes the access token to a given @id by
+//removing it from disk and from the collection.
+func (db database) DeleteAccessToken(id string) error {
+	db.tokens.mutex.Lock()
+	defer db.tokens.mutex.Unlock()
+
+	if _, ok := db.tokens.tokens[id]; !ok {
+		return model.ErrNotFound
+	}
+	return db.deleteAccessToken(id)
+}
+
+//deleteAccessTokens revokes all access tokens of the user with the
+//given @userID. The caller must hold the write lock.
+func (db database) deleteAccessTokens(userID string) error {
+	for id, t := range db.tokens.tokens {
+		if t.User != userID {
+			continue
+		}
+		if err := db.deleteAccessToken(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//setAccessToken writes the access token @t to disk and to the
+//collection. The caller must hold the write lock.
+func (db database) setAccessToken(t model.AccessToken) error {
+	if err := write(db.tokenPath(t.ID), t.String()); err != nil {
+		return err
+	}
+	db.tokens.tokens[t.ID] = t
+	return nil
+}
+
+//deleteAccessToken removes the token file behind @id and the
+//collection entry. The caller must hold the write lock.
+func (db database) deleteAccessToken(id string) error {
+	delete(db.tokens.tokens, id)
+	if err := os.Remove(db.tokenPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+//tokenPath returns the path of the access token file of @id.
+func (db database) tokenPath(id string) string {
+	return fmt.Sprintf("%s/%s.xml", db.config.TokenDir, id)
+}
@@ -0,0 +1,574 @@
+package xmldb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/Project-Planner/backend/model"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//defaultTrashRetention is how long deleted items and calendars
+//are kept, if the config doesn't say otherwise.
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+//trashStore guards the trashes of the users. Each entry is stored
+//in its own file, in a directory named after the owner of its
+//calendar: <owner>/<ID>.xml. Like the revisions, entries are not
+//kept in memory; they are destroyed by PurgeTrash once expired.
+//The audit logs and revisions of deleted calendars are kept along
+//with their entries, under the ID returned by trashID.
+type trashStore struct {
+	mutex sync.Mutex
+}
+
+//TrashItem removes the item @itemID of @kind from @calID and keeps
+//it in the trash of the owner of the calendar, as deleted by
+//@userID. If the calendar or the item doesn't exist, an error is
+//thrown.
+func (db database) TrashItem(calID string, kind model.ItemKind, itemID, userID string) error {
+	var mutex, ok = db.mutexes[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cal, ok := db.calendars[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	var entry, err = cal.TrashItem(kind, itemID)
+	if err != nil {
+		return err
+	}
+	entry.Calendar = calID
+
+	db.trash.mutex.Lock()
+	err = db.addTrashEntry(&entry, userID)
+	db.trash.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	//The entry is dropped again, so that the item is
+	//never in the calendar and in the trash at once.
+	if err := db.setCalendar(calID, cal); err != nil {
+		db.trash.mutex.Lock()
+		os.Remove(db.trashPath(entry.Owner, entry.ID))
+		db.trash.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+//TrashCalendar deletes the calendar to a given @calID like
+//DeleteCalendar does, but keeps it in the trash of its owner, as
+//deleted by @userID, along with its audit log and revisions. The
+//deletion is recorded in the audit log.
+//Invitations, share links and aliases are deleted for good.
+//If the calendar doesn't exist, an error is thrown.
+func (db database) TrashCalendar(calID, userID string) error {
+	//1. Step: Lock the calendar and its owner, like
+	//		   DeleteCalendar does.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――
+	var calMutex, ok = db.mutexes[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	var ownerID = strings.Split(calID, "/")[0]
+	ownerMutex, ok := db.mutexes[ownerID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	calMutex.Lock()
+	defer calMutex.Unlock()
+
+	ownerMutex.Lock()
+	defer ownerMutex.Unlock()
+
+	cal, ok := db.calendars[calID]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	//2. Step: Write the entry, record the deletion and
+	//		   move the audit log and the revisions along
+	//		   with it.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――
+	var entry = model.TrashEntry{Owner: cal.Owner.Val, Calendar: calID, Content: &cal}
+	db.trash.mutex.Lock()
+	var err = db.addTrashEntry(&entry, userID)
+	db.trash.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var path = db.trashPath(entry.Owner, entry.ID)
+	var undo = undoLog{func() {
+		db.trash.mutex.Lock()
+		os.Remove(path)
+		db.trash.mutex.Unlock()
+	}}
+
+	//The deletion is recorded before, so that the entry
+	//is moved along with the log.
+	err = db.addAuditEntry(model.AuditEntry{
+		Time:     entry.Deleted,
+		User:     userID,
+		Calendar: calID,
+		Action:   model.AuditDelete,
+		Changes:  model.Diff(cal.Metadata(), nil),
+	}, &undo)
+	if err == nil {
+		err = db.rekeyAuditLog(calID, db.trashID(entry), &undo)
+	}
+	if err == nil {
+		err = db.rekeyRevisions(calID, db.trashID(entry), &undo)
+	}
+	if err != nil {
+		return undo.rollback(err)
+	}
+
+	//3. Step: Delete the calendar and its references.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――
+	if err := db.deleteCalendar(calID); err != nil {
+		return undo.rollback(err)
+	}
+	return nil
+}
+
+//GetTrash retrieves the unexpired entries of the trash of
+//@userID, the most recent first.
+func (db database) GetTrash(userID string) ([]model.TrashEntry, error) {
+	db.trash.mutex.Lock()
+	defer db.trash.mutex.Unlock()
+
+	var entries, err = db.trashOf(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var now = time.Now()
+	var res = make([]model.TrashEntry, 0, len(entries))
+	for _, e := range entries {
+		if e.Expires.After(now) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+//RestoreTrash restores the entry @entryID from the trash of
+//@userID and removes it from the trash. If the entry doesn't
+//exist or has expired, an error is thrown, as well as if the
+//calendar of a deleted item doesn't exist anymore or if the item
+//or calendar has been replaced by one of the same ID meanwhile.
+func (db database) RestoreTrash(userID, entryID string) (model.TrashEntry, error) {
+	//IDs are checked, as they are part of the path
+	var path = db.trashPath(userID, entryID)
+	if strings.ContainsAny(entryID, `/\.`) {
+		return model.TrashEntry{}, model.ErrNotFound
+	}
+
+	db.trash.mutex.Lock()
+	var entry model.TrashEntry
+	var err = model.ErrNotFound
+	if exists(path) {
+		err = parse(path, &entry)
+	}
+	db.trash.mutex.Unlock()
+	if err != nil {
+		return model.TrashEntry{}, err
+	}
+	if !entry.Expires.After(time.Now()) {
+		return model.TrashEntry{}, model.ErrNotFound
+	}
+
+	if entry.Content != nil {
+		err = db.restoreCalendar(entry)
+	} else {
+		err = db.restoreItem(entry)
+	}
+	if err != nil {
+		return model.TrashEntry{}, err
+	}
+
+	db.trash.mutex.Lock()
+	defer db.trash.mutex.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return model.TrashEntry{}, err
+	}
+	return entry, nil
+}
+
+//PurgeTrash destroys the entries of all trashes which expired
+//until @t, along with the audit logs and revisions of deleted
+//calendars.
+func (db database) PurgeTrash(t time.Time) error {
+	db.trash.mutex.Lock()
+	defer db.trash.mutex.Unlock()
+
+	var owners, err = ioutil.ReadDir(db.config.TrashDir)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		if err := db.purgeTrash(owner.Name(), func(e model.TrashEntry) bool {
+			return !e.Expires.After(t)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//restoreItem adds the item kept by @entry to its calendar again.
+func (db database) restoreItem(entry model.TrashEntry) error {
+	var mutex, ok = db.mutexes[entry.Calendar]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	cal, ok := db.calendars[entry.Calendar]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	if err := entry.RestoreItem(&cal); err != nil {
+		return err
+	}
+	return db.setCalendar(entry.Calendar, cal)
+}
+
+//restoreCalendar adds the calendar kept by @entry again, along
+//with its audit log and revisions. It is shared with the users
+//and groups it has been shared with again, unless they have been
+//deleted meanwhile.
+func (db database) restoreCalendar(entry model.TrashEntry) error {
+	//1. Step: Lock the owner and reserve the ID of the
+	//		   calendar, unless a calendar of the same name
+	//		   has been created meanwhile.
+	//―――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var ownerMutex, ok = db.mutexes[entry.Owner]
+	if !ok {
+		return model.ErrNotFound
+	}
+
+	ownerMutex.Lock()
+	defer ownerMutex.Unlock()
+
+	var calID = entry.Calendar
+	if _, ok := db.mutexes[calID]; ok {
+		return model.ErrAlreadyExists
+	}
+
+	var calMutex = new(sync.Mutex)
+	calMutex.Lock()
+	defer calMutex.Unlock()
+	db.mutexes[calID] = calMutex
+
+	var undo = undoLog{func() { delete(db.mutexes, calID) }}
+
+	//2. Step: Drop the users and groups deleted meanwhile
+	//		   and write the calendar along with its audit
+	//		   log and revisions.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――――
+	var cal = *entry.Content
+	cal.Permissions.View.User = db.existingUsers(cal.Permissions.View.User, entry.Owner)
+	cal.Permissions.Edit.User = db.existingUsers(cal.Permissions.Edit.User, entry.Owner)
+	db.groups.mutex.Lock()
+	cal.Permissions.View.Group = db.existingGroups(cal.Permissions.View.Group)
+	cal.Permissions.Edit.Group = db.existingGroups(cal.Permissions.Edit.Group)
+	db.groups.mutex.Unlock()
+
+	var err = db.rekeyAuditLog(db.trashID(entry), calID, &undo)
+	if err == nil {
+		err = db.rekeyRevisions(db.trashID(entry), calID, &undo)
+	}
+	if err == nil {
+		err = ensureDir(fmt.Sprintf("%s/%s", db.config.CalendarDir, entry.Owner))
+	}
+	if err == nil {
+		//The calendar is not in the collection yet,
+		//so no revision of it is kept.
+		err = db.setCalendar(calID, cal)
+		undo.add(func() {
+			os.Remove(db.calendarPath(calID))
+			delete(db.calendars, calID)
+			delete(db.indexes, calID)
+			db.search.delete(calID)
+		})
+	}
+	if err != nil {
+		return undo.rollback(err)
+	}
+
+	//3. Step: List the calendar in the calendars of the
+	//		   users and groups it is shared with and, as
+	//		   the last write, in the ones of the owner.
+	//――――――――――――――――――――――――――――――――――――――――――――――――――――
+	for _, user := range cal.Permissions.View.User {
+		if err := db.referenceCalendar(user.Val, calID, model.Read, &undo); err != nil {
+			return undo.rollback(err)
+		}
+	}
+	for _, user := range cal.Permissions.Edit.User {
+		if err := db.referenceCalendar(user.Val, calID, model.Edit, &undo); err != nil {
+			return undo.rollback(err)
+		}
+	}
+
+	if err := db.shareWithGroups(cal, calID, &undo); err != nil {
+		return undo.rollback(err)
+	}
+
+	var owner = db.users[entry.Owner]
+	var original = owner
+	owner.Items.Calendars = append(owner.Items.Calendars, model.CalendarReference{
+		XMLName: xml.Name{Local: "calendar"},
+		Link:    calID,
+		Perm:    model.Owner.String(),
+	})
+	if err := db.setUser(entry.Owner, owner); err != nil {
+		db.users[entry.Owner] = original
+		return undo.rollback(err)
+	}
+	return nil
+}
+
+//referenceCalendar lists @calID in the calendars of @userID with
+//the permission @perm.
+func (db database) referenceCalendar(userID, calID string, perm model.Permission, undo *undoLog) error {
+	var userMutex = db.mutexes[userID]
+	userMutex.Lock()
+	defer userMutex.Unlock()
+
+	var user = db.users[userID]
+	var original = user
+	user.Items.Calendars = append(user.Items.Calendars, model.CalendarReference{
+		XMLName: xml.Name{Local: "calendar"},
+		Link:    calID,
+		Perm:    perm.String(),
+	})
+	if err := db.setUser(userID, user); err != nil {
+		db.users[userID] = original
+		return err
+	}
+
+	undo.add(func() {
+		userMutex.Lock()
+		db.setUser(userID, original)
+		userMutex.Unlock()
+	})
+	return nil
+}
+
+//shareWithGroups lists @calID in the calendars of the groups
+//@cal is shared with.
+func (db database) shareWithGroups(cal model.Calendar, calID string, undo *undoLog) error {
+	db.groups.mutex.Lock()
+	defer db.groups.mutex.Unlock()
+
+	var perms = map[model.Permission][]model.Attribute{
+		model.Read: cal.Permissions.View.Group,
+		model.Edit: cal.Permissions.Edit.Group,
+	}
+	for perm, groups := range perms {
+		for _, entry := range groups {
+			var g = db.groups.groups[entry.Val]
+			var original = g
+			g.ShareCalendar(calID, perm)
+			if err := db.setGroup(g); err != nil {
+				return err
+			}
+			undo.add(func() {
+				db.groups.mutex.Lock()
+				db.setGroup(original)
+				db.groups.mutex.Unlock()
+			})
+		}
+	}
+	return nil
+}
+
+//existingUsers returns @users without the ones which don't exist
+//anymore and without @owner.
+func (db database) existingUsers(users []model.Attribute, owner string) []model.Attribute {
+	var res []model.Attribute
+	for _, user := range users {
+		if _, ok := db.users[user.Val]; ok && user.Val != owner {
+			res = append(res, user)
+		}
+	}
+	return res
+}
+
+//existingGroups returns @groups without the ones which don't
+//exist anymore. The caller must hold the lock of the groups.
+func (db database) existingGroups(groups []model.Attribute) []model.Attribute {
+	var res []model.Attribute
+	for _, g := range groups {
+		if _, ok := db.groups.groups[g.Val]; ok {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+//addTrashEntry completes @entry as deleted just now by @userID
+//and writes it to the trash of its owner. The caller must hold
+//the lock.
+func (db database) addTrashEntry(entry *model.TrashEntry, userID string) error {
+	var id, err = uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = id.String()
+	entry.DeletedBy = userID
+	entry.Deleted = time.Now()
+	entry.Expires = entry.Deleted.Add(db.config.TrashRetention)
+
+	if err := os.MkdirAll(db.trashDir(entry.Owner), 0755); err != nil {
+		return err
+	}
+	return write(db.trashPath(entry.Owner, entry.ID), entry.String())
+}
+
+//trashOf parses all entries of the trash of @owner, expired ones
+//included, the most recent first. The caller must hold the lock.
+func (db database) trashOf(owner string) ([]model.TrashEntry, error) {
+	var infos, err = ioutil.ReadDir(db.trashDir(owner))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries = make([]model.TrashEntry, 0, len(infos))
+	for _, info := range infos {
+		var e model.TrashEntry
+		if err := parse(filepath.Join(db.trashDir(owner), info.Name()), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Deleted.After(entries[j].Deleted) })
+	return entries, nil
+}
+
+//purgeTrash destroys the entries of the trash of @owner which
+//match, along with the audit logs and revisions of deleted
+//calendars. The caller must hold the lock.
+func (db database) purgeTrash(owner string, match func(model.TrashEntry) bool) error {
+	var entries, err = db.trashOf(owner)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !match(e) {
+			continue
+		}
+		if e.Content != nil {
+			if err := db.archiveAuditLog(e.Calendar, db.trashID(e), e.Deleted); err != nil {
+				return err
+			}
+			if err := db.deleteRevisions(db.trashID(e)); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(db.trashPath(owner, e.ID)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+//rekeyTrash moves the deleted items of @calID to @newID along with
+//the calendar, into the trash of the new owner. Deleted calendars
+//of the same ID are left alone, as they are other calendars.
+func (db database) rekeyTrash(calID, newID string, undo *undoLog) error {
+	db.trash.mutex.Lock()
+	defer db.trash.mutex.Unlock()
+
+	var oldOwner, newOwner = strings.Split(calID, "/")[0], strings.Split(newID, "/")[0]
+	var entries, err = db.trashOf(oldOwner)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if e.Calendar != calID || e.Content != nil {
+			continue
+		}
+
+		var original = e
+		var path, newPath = db.trashPath(oldOwner, e.ID), db.trashPath(newOwner, e.ID)
+		e.Calendar, e.Owner = newID, newOwner
+		if err := os.MkdirAll(db.trashDir(newOwner), 0755); err != nil {
+			return err
+		}
+		if err := write(newPath, e.String()); err != nil {
+			return err
+		}
+		if path != newPath {
+			if err := os.Remove(path); err != nil {
+				os.Remove(newPath)
+				return err
+			}
+		}
+		undo.add(func() {
+			db.trash.mutex.Lock()
+			if path != newPath {
+				os.Remove(newPath)
+			}
+			write(path, original.String())
+			db.trash.mutex.Unlock()
+		})
+	}
+	return nil
+}
+
+//deleteTrash destroys the trash of @owner. The caller must hold
+//the lock.
+func (db database) deleteTrash(owner string) error {
+	if err := db.purgeTrash(owner, func(model.TrashEntry) bool { return true }); err != nil {
+		return err
+	}
+	return os.RemoveAll(db.trashDir(owner))
+}
+
+//trashID returns the ID the audit log and revisions of the
+//calendar deleted by @entry are kept under. Calendar names never
+//start with a dot, so it doesn't clash with calendar IDs.
+func (db database) trashID(entry model.TrashEntry) string {
+	return fmt.Sprintf("%s/.trash/%s", entry.Owner, entry.ID)
+}
+
+//trashDir returns the directory of the trash of @owner.
+func (db database) trashDir(owner string) string {
+	return fmt.Sprintf("%s/%s", db.config.TrashDir, owner)
+}
+
+//trashPath returns the path of the entry @entryID in the trash
+//of @owner.
+func (db database) trashPath(owner, entryID string) string {
+	return fmt.Sprintf("%s/%s.xml", db.trashDir(owner), entryID)
+}